@@ -0,0 +1,201 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrorKind classifies the errors returned by exchange adapters so callers can decide whether to retry, back off,
+// treat the operation as done, or give up and delete offers
+type ErrorKind uint8
+
+// constants for the ErrorKind
+const (
+	ErrorKindUnknown ErrorKind = iota
+	ErrorKindRateLimited
+	ErrorKindAuth
+	ErrorKindInsufficientFunds
+	ErrorKindOrderNotFound
+	ErrorKindInvalidOrder
+	ErrorKindTransient
+	ErrorKindUnsupported
+)
+
+// String is the Stringer method
+func (k ErrorKind) String() string {
+	switch k {
+	case ErrorKindRateLimited:
+		return "rate_limited"
+	case ErrorKindAuth:
+		return "auth"
+	case ErrorKindInsufficientFunds:
+		return "insufficient_funds"
+	case ErrorKindOrderNotFound:
+		return "order_not_found"
+	case ErrorKindInvalidOrder:
+		return "invalid_order"
+	case ErrorKindTransient:
+		return "transient"
+	case ErrorKindUnsupported:
+		return "unsupported"
+	default:
+		return "unknown"
+	}
+}
+
+// IsRetryable returns true if the same request may succeed when it is sent again later
+func (k ErrorKind) IsRetryable() bool {
+	return k == ErrorKindRateLimited || k == ErrorKindTransient
+}
+
+// ExchangeError is an error returned by an exchange adapter that is classified by its Kind
+type ExchangeError struct {
+	Kind     ErrorKind
+	Exchange string
+	// RetryAfter is how long the exchange asked us to wait before the next request, zero when unknown
+	RetryAfter time.Duration
+	Err        error
+}
+
+// Error impl.
+func (e *ExchangeError) Error() string {
+	return fmt.Sprintf("%s error from %s: %s", e.Kind, e.Exchange, e.Err)
+}
+
+// Cause returns the underlying error, compatible with github.com/pkg/errors
+func (e *ExchangeError) Cause() error {
+	return e.Err
+}
+
+// MakeExchangeError is a factory method
+func MakeExchangeError(kind ErrorKind, exchange string, e error) *ExchangeError {
+	return &ExchangeError{
+		Kind:     kind,
+		Exchange: exchange,
+		Err:      e,
+	}
+}
+
+// MakeErrRateLimited is a factory method, retryAfter can be zero when the exchange does not tell us how long to wait
+func MakeErrRateLimited(exchange string, retryAfter time.Duration, e error) *ExchangeError {
+	return &ExchangeError{
+		Kind:       ErrorKindRateLimited,
+		Exchange:   exchange,
+		RetryAfter: retryAfter,
+		Err:        e,
+	}
+}
+
+// MakeErrUnsupported is a factory method for requests that use a feature the exchange does not support, e.g. an order flag
+func MakeErrUnsupported(exchange string, feature string) *ExchangeError {
+	return MakeExchangeError(ErrorKindUnsupported, exchange, fmt.Errorf("%s is not supported", feature))
+}
+
+// ErrorKindFromStatusCode classifies an HTTP status code, returns ErrorKindUnknown when the status code says nothing about the kind
+func ErrorKindFromStatusCode(statusCode int) ErrorKind {
+	switch {
+	case statusCode == http.StatusTooManyRequests:
+		return ErrorKindRateLimited
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		return ErrorKindAuth
+	case statusCode == http.StatusRequestTimeout || statusCode >= 500:
+		return ErrorKindTransient
+	default:
+		return ErrorKindUnknown
+	}
+}
+
+// causer is implemented by errors wrapped with github.com/pkg/errors
+type causer interface {
+	Cause() error
+}
+
+// AsExchangeError returns the first ExchangeError in the chain of wrapped errors, or nil if there is none
+func AsExchangeError(e error) *ExchangeError {
+	for e != nil {
+		if xe, ok := e.(*ExchangeError); ok {
+			return xe
+		}
+		c, ok := e.(causer)
+		if !ok {
+			return nil
+		}
+		e = c.Cause()
+	}
+	return nil
+}
+
+// wrappedError adds context to an error and keeps the error in the chain that AsExchangeError walks
+type wrappedError struct {
+	msg   string
+	cause error
+}
+
+// Error impl.
+func (w *wrappedError) Error() string {
+	return fmt.Sprintf("%s: %s", w.msg, w.cause)
+}
+
+// Cause returns the underlying error, compatible with github.com/pkg/errors
+func (w *wrappedError) Cause() error {
+	return w.cause
+}
+
+// WrapError adds context to the error like fmt.Errorf("<msg>: %s", e) does, without losing the ErrorKind of the error
+func WrapError(e error, format string, args ...interface{}) error {
+	if e == nil {
+		return nil
+	}
+	return &wrappedError{
+		msg:   fmt.Sprintf(format, args...),
+		cause: e,
+	}
+}
+
+// ErrorKindOf returns the kind of the error, ErrorKindUnknown if it was not classified by the exchange adapter
+func ErrorKindOf(e error) ErrorKind {
+	xe := AsExchangeError(e)
+	if xe == nil {
+		return ErrorKindUnknown
+	}
+	return xe.Kind
+}
+
+// IsRetryable returns true if the request that returned the error may succeed when it is sent again later
+func IsRetryable(e error) bool {
+	return ErrorKindOf(e).IsRetryable()
+}
+
+// RetryAfter returns how long the exchange asked us to wait before retrying, zero when unknown
+func RetryAfter(e error) time.Duration {
+	xe := AsExchangeError(e)
+	if xe == nil {
+		return 0
+	}
+	return xe.RetryAfter
+}
+
+// ErrorMessageRule classifies errors whose message contains Substring as Kind
+type ErrorMessageRule struct {
+	Substring string
+	Kind      ErrorKind
+}
+
+// ClassifyErrorMessage wraps the error in an ExchangeError using the first rule that matches its message, this is meant
+// for exchange clients that only return untyped errors. Errors that are already classified or that do not match any
+// rule are returned unchanged.
+func ClassifyErrorMessage(exchange string, e error, rules []ErrorMessageRule) error {
+	if e == nil || AsExchangeError(e) != nil {
+		return e
+	}
+
+	msg := e.Error()
+	for _, r := range rules {
+		if strings.Contains(msg, r.Substring) {
+			return MakeExchangeError(r.Kind, exchange, e)
+		}
+	}
+	return e
+}
@@ -117,9 +117,119 @@ type TradeAPI interface {
 type PrepareDepositResult struct {
 	Fee      *model.Number // fee that will be deducted from your deposit, i.e. amount available is depositAmount - fee
 	Address  string        // address you should send the funds to
+	Memo     string        // memo you should attach when sending the funds, empty if not needed
 	ExpireTs int64         // expire time as a unix timestamp, 0 if it does not expire
 }
 
+// OrderStatusAPI is implemented by exchanges that can look up our orders by ID, including orders that are no longer open,
+// so partial fills can be tracked between calls to GetOpenOrders
+type OrderStatusAPI interface {
+	/*
+		Input:
+			txID - ID of the order returned by AddOrder
+			pair - trading pair of the order
+		Output:
+			OpenOrder - the order with its status and its executed and remaining volume
+			error - an ExchangeError of kind ErrorKindOrderNotFound if the exchange does not know the order, or any other error
+	*/
+	GetOrderStatus(txID *model.TransactionID, pair model.TradingPair) (*model.OpenOrder, error)
+
+	/*
+		Input:
+			txIDs - IDs of the orders returned by AddOrder
+			pair - trading pair of the orders
+		Output:
+			map[string]model.OpenOrder - the orders by ID, orders that the exchange does not know are left out
+			error - any error
+	*/
+	QueryOrders(txIDs []*model.TransactionID, pair model.TradingPair) (map[string]model.OpenOrder, error)
+}
+
+// QueryOrdersOneByOne implements QueryOrders for exchanges that can only look up one order per request
+func QueryOrdersOneByOne(
+	getOrderStatus func(txID *model.TransactionID, pair model.TradingPair) (*model.OpenOrder, error),
+	txIDs []*model.TransactionID,
+	pair model.TradingPair,
+) (map[string]model.OpenOrder, error) {
+	m := map[string]model.OpenOrder{}
+	for _, txID := range txIDs {
+		o, e := getOrderStatus(txID, pair)
+		if ErrorKindOf(e) == ErrorKindOrderNotFound {
+			continue
+		}
+		if e != nil {
+			// not wrapped so callers can still classify the error
+			return nil, e
+		}
+		m[txID.String()] = *o
+	}
+	return m, nil
+}
+
+// ModifyOrder is implemented by exchanges that can amend an open order in a single request, so the order is never
+// missing from the book while it is being changed
+type ModifyOrder interface {
+	/*
+		Input:
+			txID - ID of the open order to amend
+			order - the amended order, only the price and volume can differ from the open order
+		Output:
+			TransactionID - ID of the amended order, which is a new ID on exchanges that replace the order
+			error - an ExchangeError of kind ErrorKindOrderNotFound if the open order is gone, ErrorKindUnsupported if the
+				order cannot be amended on this exchange, or any other error
+	*/
+	ModifyOrder(txID *model.TransactionID, order *model.Order) (*model.TransactionID, error)
+}
+
+// BatchTradeAPI is implemented by exchanges that can place and cancel many orders in a single request, exchanges split
+// the orders into as many requests as their bulk endpoints need
+type BatchTradeAPI interface {
+	/*
+		Input:
+			orders - orders to place
+		Output:
+			[]BatchAddResult - one result per order in the same order as the input, the error of a request that failed is
+				set on every order that was sent in it
+			error - nothing was sent to the exchange
+	*/
+	AddOrders(orders []*model.Order) ([]BatchAddResult, error)
+
+	/*
+		Input:
+			txIDs - IDs of the orders to cancel
+			pair - trading pair of the orders
+		Output:
+			[]BatchCancelResult - one result per ID in the same order as the input, the error of a request that failed is
+				set on every order that was sent in it
+			error - nothing was sent to the exchange
+	*/
+	CancelOrders(txIDs []*model.TransactionID, pair model.TradingPair) ([]BatchCancelResult, error)
+}
+
+// BatchAddResult is the result of placing a single order with BatchTradeAPI.AddOrders, TxID is nil when Error is set
+type BatchAddResult struct {
+	TxID  *model.TransactionID
+	Error error
+}
+
+// BatchCancelResult is the result of cancelling a single order with BatchTradeAPI.CancelOrders
+type BatchCancelResult struct {
+	Result model.CancelOrderResult
+	Error  error
+}
+
+// KeyUsageReporter is implemented by exchanges that rotate API keys, it reports how each key is used so the usage can
+// be exposed on the /metrics endpoint
+type KeyUsageReporter interface {
+	KeyUsageMetrics() map[string]interface{}
+}
+
+// ClientOrderIDAPI is implemented by exchanges that take our own client order ID on new orders and report it back on
+// open orders, the orders of bots that share an account can only be told apart on these exchanges
+type ClientOrderIDAPI interface {
+	SupportsClientOrderIDs() bool
+}
+
 // DepositAPI is defined by anything where you can deposit funds.
 type DepositAPI interface {
 	/*
@@ -226,6 +336,9 @@ type ExchangeShim interface {
 	SubmitOpsSynch(ops []build.TransactionMutator, asyncCallback func(hash string, e error)) error // forced synchronous version of SubmitOps
 	GetBalanceHack(asset horizon.Asset) (*Balance, error)
 	LoadOffersHack() ([]horizon.Offer, error)
+	// LoadAllOffersHack loads the offers of every bot that trades on the account, which is the same as LoadOffersHack
+	// unless the exchange can tell the orders of this bot apart from the others
+	LoadAllOffersHack() ([]horizon.Offer, error)
 	Constrainable
 	OrderbookFetcher
 	FillTrackable
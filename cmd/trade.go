@@ -23,6 +23,7 @@ import (
 	"github.com/stellar/kelp/support/monitoring"
 	"github.com/stellar/kelp/support/networking"
 	"github.com/stellar/kelp/support/prefs"
+	"github.com/stellar/kelp/support/sdk"
 	"github.com/stellar/kelp/support/utils"
 	"github.com/stellar/kelp/trader"
 )
@@ -32,6 +33,9 @@ const tradeExamples = `  kelp trade --botConf ./path/trader.cfg --strategy buyse
 
 const prefsFilename = "kelp.prefs"
 
+// keyUsageMetricsInterval is how often the usage of the exchange API keys is copied into the /metrics endpoint
+const keyUsageMetricsInterval = 10 * time.Second
+
 var tradeCmd = &cobra.Command{
 	Use:     "trade",
 	Short:   "Trades against the Stellar universal marketplace using the specified strategy",
@@ -228,7 +232,13 @@ func makeExchangeShimSdex(
 			return nil, nil
 		}
 
-		exchangeShim = plugins.MakeBatchedExchange(exchangeAPI, *options.simMode, botConfig.AssetBase(), botConfig.AssetQuote(), botConfig.TradingAccount())
+		e = plugins.CheckClientOrderIDPrefix(exchangeAPI, botConfig.CentralizedClientOrderIDPrefix)
+		if e != nil {
+			logger.Fatal(l, fmt.Errorf("invalid CENTRALIZED_CLIENT_ORDER_ID_PREFIX for the '%s' exchange: %s", botConfig.TradingExchange, e))
+			return nil, nil
+		}
+
+		exchangeShim = plugins.MakeBatchedExchange(exchangeAPI, *options.simMode, botConfig.AssetBase(), botConfig.AssetQuote(), botConfig.TradingAccount(), botConfig.CentralizedSubmitConcurrency, botConfig.CentralizedClientOrderIDPrefix)
 
 		// update precision overrides
 		exchangeShim.OverrideOrderConstraints(tradingPair, model.MakeOrderConstraintsOverride(
@@ -256,6 +266,10 @@ func makeExchangeShimSdex(
 				&minQuoteVolume,
 			))
 		}
+		if exchangeShim.GetOrderConstraints(tradingPair) == nil {
+			logger.Fatal(l, fmt.Errorf("exchange %s has no order constraints for trading pair %s, provide them using the CENTRALIZED_* config values", botConfig.TradingExchange, tradingPair))
+			return nil, nil
+		}
 	}
 
 	sdexAssetMap := map[model.Asset]horizon.Asset{
@@ -387,6 +401,15 @@ func runTradeCmd(options inputs) {
 	botConfig = convertDeprecatedBotConfigValues(l, botConfig)
 	l.Infof("Trading %s:%s for %s:%s\n", botConfig.AssetCodeA, botConfig.IssuerA, botConfig.AssetCodeB, botConfig.IssuerB)
 
+	// point ccxt at the configured ccxt-rest server before any exchanges or price feeds are made
+	if botConfig.CcxtRestURL != "" {
+		e := sdk.SetBaseURL(botConfig.CcxtRestURL)
+		if e != nil {
+			logger.Fatal(l, fmt.Errorf("unable to set the ccxt-rest URL: %s", e))
+		}
+		l.Infof("using ccxt-rest at %s\n", botConfig.CcxtRestURL)
+	}
+
 	// --- start initialization of objects ----
 	threadTracker := multithreading.MakeThreadTracker()
 	assetBase := botConfig.AssetBase()
@@ -467,7 +490,7 @@ func runTradeCmd(options inputs) {
 	validateTrustlines(l, client, &botConfig)
 	if botConfig.MonitoringPort != 0 {
 		go func() {
-			e := startMonitoringServer(l, botConfig)
+			e := startMonitoringServer(l, botConfig, exchangeShim)
 			if e != nil {
 				l.Info("")
 				l.Info("unable to start the monitoring server or problem encountered while running server:")
@@ -495,7 +518,7 @@ func runTradeCmd(options inputs) {
 	bot.Start()
 }
 
-func startMonitoringServer(l logger.Logger, botConfig trader.BotConfig) error {
+func startMonitoringServer(l logger.Logger, botConfig trader.BotConfig, exchangeShim api.ExchangeShim) error {
 	healthMetrics, e := monitoring.MakeMetricsRecorder(map[string]interface{}{"success": true})
 	if e != nil {
 		return fmt.Errorf("unable to make metrics recorder for the /health endpoint: %s", e)
@@ -509,6 +532,15 @@ func startMonitoringServer(l logger.Logger, botConfig trader.BotConfig) error {
 	if e != nil {
 		return fmt.Errorf("unable to make metrics recorder for the /metrics endpoint: %s", e)
 	}
+	if reporter, ok := exchangeShim.(api.KeyUsageReporter); ok {
+		// the usage of the API keys changes on every request so it is copied into the metrics periodically
+		go func() {
+			for {
+				kelpMetrics.UpdateMetrics(reporter.KeyUsageMetrics())
+				time.Sleep(keyUsageMetricsInterval)
+			}
+		}()
+	}
 	metricsAuth := networking.NoAuth
 	if botConfig.GoogleClientID != "" || botConfig.GoogleClientSecret != "" {
 		metricsAuth = networking.GoogleAuth
@@ -631,7 +663,14 @@ func deleteAllOffersAndExit(
 	l.Info("")
 	l.Info("deleting all offers and then exiting...")
 
-	offers, e := utils.LoadAllOffers(botConfig.TradingAccount(), client)
+	var offers []horizon.Offer
+	var e error
+	if botConfig.IsTradingSdex() {
+		offers, e = utils.LoadAllOffers(botConfig.TradingAccount(), client)
+	} else {
+		// only the orders of this bot, the account on the centralized exchange may be shared with other bots
+		offers, e = exchangeShim.LoadOffersHack()
+	}
 	if e != nil {
 		logger.Fatal(l, e)
 		return
@@ -1,24 +1,22 @@
-package plugins
-
-import "github.com/stellar/kelp/model"
+package model
 
 // OrderConstraintsOverridesHandler knows how to capture overrides and apply them onto OrderConstraints
 type OrderConstraintsOverridesHandler struct {
-	overrides map[string]*model.OrderConstraintsOverride
+	overrides map[string]*OrderConstraintsOverride
 }
 
 // MakeEmptyOrderConstraintsOverridesHandler is a factory method
 func MakeEmptyOrderConstraintsOverridesHandler() *OrderConstraintsOverridesHandler {
 	return &OrderConstraintsOverridesHandler{
-		overrides: map[string]*model.OrderConstraintsOverride{},
+		overrides: map[string]*OrderConstraintsOverride{},
 	}
 }
 
 // MakeOrderConstraintsOverridesHandler is a factory method
-func MakeOrderConstraintsOverridesHandler(inputs map[model.TradingPair]model.OrderConstraints) *OrderConstraintsOverridesHandler {
-	overrides := map[string]*model.OrderConstraintsOverride{}
+func MakeOrderConstraintsOverridesHandler(inputs map[TradingPair]OrderConstraints) *OrderConstraintsOverridesHandler {
+	overrides := map[string]*OrderConstraintsOverride{}
 	for p, oc := range inputs {
-		overrides[p.String()] = model.MakeOrderConstraintsOverrideFromConstraints(&oc)
+		overrides[p.String()] = MakeOrderConstraintsOverrideFromConstraints(&oc)
 	}
 
 	return &OrderConstraintsOverridesHandler{
@@ -27,21 +25,21 @@ func MakeOrderConstraintsOverridesHandler(inputs map[model.TradingPair]model.Ord
 }
 
 // Apply creates a new order constraints after checking for any existing overrides
-func (ocHandler *OrderConstraintsOverridesHandler) Apply(pair *model.TradingPair, oc *model.OrderConstraints) *model.OrderConstraints {
+func (ocHandler *OrderConstraintsOverridesHandler) Apply(pair *TradingPair, oc *OrderConstraints) *OrderConstraints {
 	override, has := ocHandler.overrides[pair.String()]
 	if !has {
 		return oc
 	}
-	return model.MakeOrderConstraintsWithOverride(*oc, override)
+	return MakeOrderConstraintsWithOverride(*oc, override)
 }
 
 // Get impl, panics if the override does not exist
-func (ocHandler *OrderConstraintsOverridesHandler) Get(pair *model.TradingPair) *model.OrderConstraintsOverride {
+func (ocHandler *OrderConstraintsOverridesHandler) Get(pair *TradingPair) *OrderConstraintsOverride {
 	return ocHandler.overrides[pair.String()]
 }
 
 // Upsert allows you to set overrides to partially override values for specific pairs
-func (ocHandler *OrderConstraintsOverridesHandler) Upsert(pair *model.TradingPair, override *model.OrderConstraintsOverride) {
+func (ocHandler *OrderConstraintsOverridesHandler) Upsert(pair *TradingPair, override *OrderConstraintsOverride) {
 	existingOverride, exists := ocHandler.overrides[pair.String()]
 	if !exists {
 		ocHandler.overrides[pair.String()] = override
@@ -53,7 +51,7 @@ func (ocHandler *OrderConstraintsOverridesHandler) Upsert(pair *model.TradingPai
 }
 
 // IsCompletelyOverriden returns true if the override exists and is complete for the given trading pair
-func (ocHandler *OrderConstraintsOverridesHandler) IsCompletelyOverriden(pair *model.TradingPair) bool {
+func (ocHandler *OrderConstraintsOverridesHandler) IsCompletelyOverriden(pair *TradingPair) bool {
 	override, has := ocHandler.overrides[pair.String()]
 	if !has {
 		return false
@@ -96,6 +96,31 @@ func OrderTypeFromString(s string) OrderType {
 	return orderTypeMap[s]
 }
 
+// TimeInForce is how long an order can stay on the book before the exchange cancels it
+type TimeInForce int8
+
+// These are the available time in force values, good-till-canceled is the zero value since it is the default on all exchanges
+const (
+	// TimeInForceGTC rests on the book until it is filled or canceled
+	TimeInForceGTC TimeInForce = 0
+	// TimeInForceIOC fills what it can immediately and cancels the rest
+	TimeInForceIOC TimeInForce = 1
+	// TimeInForceFOK fills the entire volume immediately or is canceled without any fill
+	TimeInForceFOK TimeInForce = 2
+)
+
+// String is the stringer function
+func (t TimeInForce) String() string {
+	if t == TimeInForceGTC {
+		return "gtc"
+	} else if t == TimeInForceIOC {
+		return "ioc"
+	} else if t == TimeInForceFOK {
+		return "fok"
+	}
+	return "error, unrecognized TimeInForce"
+}
+
 // Order represents an order in the orderbook
 type Order struct {
 	Pair        *TradingPair
@@ -104,6 +129,12 @@ type Order struct {
 	Price       *Number
 	Volume      *Number
 	Timestamp   *Timestamp
+	// the fields below are only used when placing orders, exchanges that cannot honor them reject the order
+	TimeInForce TimeInForce
+	// PostOnly orders are rejected by the exchange instead of taking liquidity
+	PostOnly bool
+	// ClientOrderID is our own identifier for the order, it is reported back on open orders when the exchange supports it
+	ClientOrderID string
 }
 
 // String is the stringer function
@@ -113,13 +144,25 @@ func (o Order) String() string {
 		tsString = fmt.Sprintf("%d", o.Timestamp.AsInt64())
 	}
 
-	return fmt.Sprintf("Order[pair=%s, action=%s, type=%s, price=%s, vol=%s, ts=%s]",
+	flagsString := ""
+	if o.TimeInForce != TimeInForceGTC {
+		flagsString += fmt.Sprintf(", tif=%s", o.TimeInForce)
+	}
+	if o.PostOnly {
+		flagsString += ", postOnly"
+	}
+	if o.ClientOrderID != "" {
+		flagsString += fmt.Sprintf(", clientOrderID=%s", o.ClientOrderID)
+	}
+
+	return fmt.Sprintf("Order[pair=%s, action=%s, type=%s, price=%s, vol=%s, ts=%s%s]",
 		o.Pair,
 		o.OrderAction,
 		o.OrderType,
 		o.Price.AsString(),
 		o.Volume.AsString(),
 		tsString,
+		flagsString,
 	)
 }
 
@@ -184,13 +227,40 @@ func MakeTransactionID(s string) *TransactionID {
 	return &t
 }
 
-// OpenOrder represents an open order for a trading account
+// OrderStatus is the state of an order on an exchange, a partially filled order is still open
+type OrderStatus int8
+
+// These are the available types, open is the zero value since most orders we see are open
+const (
+	OrderStatusOpen     OrderStatus = 0
+	OrderStatusFilled   OrderStatus = 1
+	OrderStatusCanceled OrderStatus = 2
+)
+
+// String is the stringer function
+func (s OrderStatus) String() string {
+	if s == OrderStatusOpen {
+		return "open"
+	} else if s == OrderStatusFilled {
+		return "filled"
+	} else if s == OrderStatusCanceled {
+		return "canceled"
+	}
+	return "error, unrecognized OrderStatus"
+}
+
+// OpenOrder represents an order for a trading account, the Volume of the embedded Order is the volume the order
+// was placed with
 type OpenOrder struct {
 	Order
 	ID             string
 	StartTime      *Timestamp
 	ExpireTime     *Timestamp
 	VolumeExecuted *Number
+	// VolumeRemaining is the volume that was not filled, which is only on the book while the order is open. It is nil
+	// if the exchange does not report it.
+	VolumeRemaining *Number
+	Status          OrderStatus
 }
 
 // String is the stringer function
@@ -199,15 +269,37 @@ func (o OpenOrder) String() string {
 	if o.ExpireTime != nil {
 		expireTimeString = fmt.Sprintf("%d", o.ExpireTime.AsInt64())
 	}
-	return fmt.Sprintf("OpenOrder[order=%s, ID=%s, startTime=%d, expireTime=%s, volumeExecuted=%s]",
+	volumeRemainingString := nilString
+	if o.VolumeRemaining != nil {
+		volumeRemainingString = o.VolumeRemaining.AsString()
+	}
+	return fmt.Sprintf("OpenOrder[order=%s, ID=%s, startTime=%d, expireTime=%s, volumeExecuted=%s, volumeRemaining=%s, status=%s]",
 		o.Order.String(),
 		o.ID,
 		o.StartTime.AsInt64(),
 		expireTimeString,
 		o.VolumeExecuted.AsString(),
+		volumeRemainingString,
+		o.Status.String(),
 	)
 }
 
+// RemainingVolume returns the volume that was not filled, derived from the executed volume when the exchange
+// does not report the remaining volume and the full volume when it reports neither
+func (o OpenOrder) RemainingVolume() *Number {
+	if o.VolumeRemaining != nil {
+		return o.VolumeRemaining
+	}
+	if o.Volume == nil || o.VolumeExecuted == nil {
+		return o.Volume
+	}
+	remaining := o.Volume.Subtract(*o.VolumeExecuted)
+	if remaining.AsFloat() < 0 {
+		return NumberConstants.Zero
+	}
+	return remaining
+}
+
 // CancelOrderResult is the result of a CancelOrder call
 type CancelOrderResult int8
 
@@ -5,6 +5,10 @@ import (
 	"log"
 	"math"
 	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"math/rand"
@@ -21,16 +25,24 @@ import (
 // largePrecision is a large precision value for in-memory calculations
 const largePrecision = 10
 
+// maxSubmitAttempts is the number of times a command is sent to the inner exchange when it fails with a retryable error
+const maxSubmitAttempts = 3
+
+// submitRetryDelay is how long we wait before retrying a command when the exchange does not tell us how long to wait
+const submitRetryDelay = 500 * time.Millisecond
+
 // BatchedExchange accumulates instructions that can be read out and processed in a batch-style later
 type BatchedExchange struct {
-	commands        []Command
-	inner           api.Exchange
-	simMode         bool
-	baseAsset       horizon.Asset
-	quoteAsset      horizon.Asset
-	tradingAccount  string
-	orderID2OfferID map[string]int64
-	offerID2OrderID map[int64]string
+	commands          []Command
+	inner             api.Exchange
+	simMode           bool
+	submitConcurrency int    // number of commands that are sent to the inner exchange at the same time, 1 sends them in order
+	clientOrderPrefix string // orders of this bot have client order IDs that start with this, all orders are ours if empty
+	baseAsset         horizon.Asset
+	quoteAsset        horizon.Asset
+	tradingAccount    string
+	orderID2OfferID   map[string]int64
+	offerID2OrderID   map[int64]string
 }
 
 var _ api.ExchangeShim = BatchedExchange{}
@@ -42,17 +54,38 @@ func MakeBatchedExchange(
 	baseAsset horizon.Asset,
 	quoteAsset horizon.Asset,
 	tradingAccount string,
+	submitConcurrency int,
+	clientOrderPrefix string,
 ) *BatchedExchange {
+	if submitConcurrency < 1 {
+		submitConcurrency = 1
+	}
+
 	return &BatchedExchange{
-		commands:        []Command{},
-		inner:           inner,
-		simMode:         simMode,
-		baseAsset:       baseAsset,
-		quoteAsset:      quoteAsset,
-		tradingAccount:  tradingAccount,
-		orderID2OfferID: map[string]int64{},
-		offerID2OrderID: map[int64]string{},
+		commands:          []Command{},
+		inner:             inner,
+		simMode:           simMode,
+		submitConcurrency: submitConcurrency,
+		clientOrderPrefix: clientOrderPrefix,
+		baseAsset:         baseAsset,
+		quoteAsset:        quoteAsset,
+		tradingAccount:    tradingAccount,
+		orderID2OfferID:   map[string]int64{},
+		offerID2OrderID:   map[int64]string{},
+	}
+}
+
+// CheckClientOrderIDPrefix returns an error when the orders of the inner exchange cannot be tagged with the prefix,
+// exchanges that do not support client order IDs would reject every order
+func CheckClientOrderIDPrefix(inner api.Exchange, clientOrderPrefix string) error {
+	if clientOrderPrefix == "" {
+		return nil
+	}
+
+	if x, ok := inner.(api.ClientOrderIDAPI); ok && x.SupportsClientOrderIDs() {
+		return nil
 	}
+	return fmt.Errorf("the exchange does not support client order IDs so orders cannot be tagged with the prefix '%s'", clientOrderPrefix)
 }
 
 // Operation represents a type of operation
@@ -62,6 +95,7 @@ type Operation int8
 const (
 	OpAdd Operation = iota
 	OpCancel
+	OpModify
 )
 
 // Command struct allows us to follow the Command pattern
@@ -92,6 +126,14 @@ func (c *Command) GetCancel() (*model.OpenOrder, error) {
 	return c.cancel, nil
 }
 
+// GetModify returns the open order and the order it should be amended to for a modify op
+func (c *Command) GetModify() (*model.OpenOrder, *model.Order, error) {
+	if c.op != OpModify {
+		return nil, nil, fmt.Errorf("modify op does not exist")
+	}
+	return c.cancel, c.add, nil
+}
+
 // MakeCommandAdd impl
 func MakeCommandAdd(order *model.Order) Command {
 	return Command{
@@ -108,6 +150,15 @@ func MakeCommandCancel(openOrder *model.OpenOrder) Command {
 	}
 }
 
+// MakeCommandModify impl, the open order is amended to the new order in place when the exchange supports it
+func MakeCommandModify(openOrder *model.OpenOrder, order *model.Order) Command {
+	return Command{
+		op:     OpModify,
+		add:    order,
+		cancel: openOrder,
+	}
+}
+
 // GetBalanceHack impl
 func (b BatchedExchange) GetBalanceHack(asset horizon.Asset) (*api.Balance, error) {
 	modelAsset := model.FromHorizonAsset(asset)
@@ -126,8 +177,17 @@ func (b BatchedExchange) GetBalanceHack(asset horizon.Asset) (*api.Balance, erro
 	return nil, fmt.Errorf("asset was missing in GetBalanceHack result: %s", utils.Asset2String(asset))
 }
 
-// LoadOffersHack impl
+// LoadOffersHack impl, only loads the orders of this bot
 func (b BatchedExchange) LoadOffersHack() ([]horizon.Offer, error) {
+	return b.loadOffers(true)
+}
+
+// LoadAllOffersHack impl
+func (b BatchedExchange) LoadAllOffersHack() ([]horizon.Offer, error) {
+	return b.loadOffers(false)
+}
+
+func (b BatchedExchange) loadOffers(ownOnly bool) ([]horizon.Offer, error) {
 	pair := &model.TradingPair{
 		Base:  model.FromHorizonAsset(b.baseAsset),
 		Quote: model.FromHorizonAsset(b.quoteAsset),
@@ -136,6 +196,9 @@ func (b BatchedExchange) LoadOffersHack() ([]horizon.Offer, error) {
 	if e != nil {
 		return nil, fmt.Errorf("error fetching open orders in LoadOffersHack: %s", e)
 	}
+	if ownOnly {
+		openOrders = b.ownOrders(openOrders)
+	}
 
 	offers := []horizon.Offer{}
 	for i, v := range openOrders {
@@ -149,6 +212,48 @@ func (b BatchedExchange) LoadOffersHack() ([]horizon.Offer, error) {
 	return offers, nil
 }
 
+// ownOrders returns the orders that this bot placed, which are tagged with its client order ID prefix
+func (b BatchedExchange) ownOrders(openOrders map[model.TradingPair][]model.OpenOrder) map[model.TradingPair][]model.OpenOrder {
+	if b.clientOrderPrefix == "" {
+		return openOrders
+	}
+
+	m := map[model.TradingPair][]model.OpenOrder{}
+	for pair, orders := range openOrders {
+		m[pair] = []model.OpenOrder{}
+		for _, o := range orders {
+			if strings.HasPrefix(o.ClientOrderID, b.clientOrderPrefix) {
+				m[pair] = append(m[pair], o)
+			}
+		}
+	}
+	return m
+}
+
+// tagOrders gives the new orders of the commands a unique client order ID that starts with the prefix of this bot, an
+// order that is modified keeps its client order ID since the exchange keeps it when the order is amended in place
+func (b BatchedExchange) tagOrders(commands []Command) {
+	if b.clientOrderPrefix == "" {
+		return
+	}
+
+	for _, c := range commands {
+		if c.op == OpModify && c.cancel.ClientOrderID != "" {
+			c.add.ClientOrderID = c.cancel.ClientOrderID
+		} else if c.op == OpAdd || c.op == OpModify {
+			c.add.ClientOrderID = b.clientOrderPrefix + strconv.FormatInt(rand.Int63(), 36)
+		}
+	}
+}
+
+// KeyUsageMetrics impl, empty when the inner exchange does not rotate API keys
+func (b BatchedExchange) KeyUsageMetrics() map[string]interface{} {
+	if reporter, ok := b.inner.(api.KeyUsageReporter); ok {
+		return reporter.KeyUsageMetrics()
+	}
+	return map[string]interface{}{}
+}
+
 // GetOrderConstraints impl
 func (b BatchedExchange) GetOrderConstraints(pair *model.TradingPair) *model.OrderConstraints {
 	return b.inner.GetOrderConstraints(pair)
@@ -189,6 +294,7 @@ func (b BatchedExchange) SubmitOps(ops []build.TransactionMutator, asyncCallback
 		}
 		return fmt.Errorf("could not convert ops2commands: %s | allOps = %v", e, ops)
 	}
+	b.tagOrders(b.commands)
 
 	if b.simMode {
 		log.Printf("running in simulation mode so not submitting to the inner exchange\n")
@@ -198,34 +304,365 @@ func (b BatchedExchange) SubmitOps(ops []build.TransactionMutator, asyncCallback
 		return nil
 	}
 
+	var results []submitResult
+	if batchAPI, ok := b.inner.(api.BatchTradeAPI); ok {
+		results, e = execBatch(b.inner, batchAPI, b.commands, b.submitConcurrency)
+	} else if b.submitConcurrency > 1 {
+		results, e = execConcurrent(b.inner, b.commands, b.submitConcurrency)
+	} else {
+		results, e = execSequential(b.inner, b.commands)
+	}
+
+	b.logResults(results)
+	if e == nil {
+		e = b.reconcile(results)
+	}
+	if asyncCallback != nil {
+		go asyncCallback("", e)
+	}
+	return e
+}
+
+// execSequential sends the commands to the exchange one at a time
+func execSequential(x api.Exchange, commands []Command) ([]submitResult, error) {
 	results := []submitResult{}
-	numProcessed := 0
-	for _, c := range b.commands {
-		r := c.exec(b.inner)
+	for _, c := range commands {
+		r := c.exec(x)
 		if r == nil {
-			// remove all processed commands
-			// b.commands = b.commands[numProcessed:]
-			b.logResults(results)
-			e := fmt.Errorf("unrecognized operation '%v', stopped submitting", c.op)
-			if asyncCallback != nil {
-				go asyncCallback("", e)
-			}
-			return e
+			return results, fmt.Errorf("unrecognized operation '%v', stopped submitting", c.op)
 		}
 		results = append(results, *r)
-		numProcessed++
+
+		if api.ErrorKindOf(r.e) == api.ErrorKindAuth {
+			// every remaining command would fail in the same way so give up and let the trader delete its offers
+			return results, fmt.Errorf("stopped submitting after an auth error: %s", r.e)
+		}
 	}
+	return results, nil
+}
 
-	// remove all processed commands
-	// b.commands = b.commands[numProcessed:]
+// execConcurrent sends all the cancels before the other commands so the funds they release can be used by the new
+// orders, the commands in each of the two groups are sent on up to concurrency workers at the same time. The exchange
+// still spaces out the requests of each API key according to its rate limits.
+func execConcurrent(x api.Exchange, commands []Command, concurrency int) ([]submitResult, error) {
+	cancels := []Command{}
+	others := []Command{}
+	for _, c := range commands {
+		if c.op == OpCancel {
+			cancels = append(cancels, c)
+		} else {
+			others = append(others, c)
+		}
+	}
 
-	b.logResults(results)
-	if asyncCallback != nil {
-		go asyncCallback("", nil)
+	results, e := execParallel(x, cancels, concurrency)
+	if e != nil {
+		return results, e
+	}
+	otherResults, e := execParallel(x, others, concurrency)
+	return append(results, otherResults...), e
+}
+
+// execParallel sends the commands on up to concurrency workers, the results are in the same order as the commands. The
+// commands that were not started when one of them failed with an auth error are skipped and have no result.
+func execParallel(x api.Exchange, commands []Command, concurrency int) ([]submitResult, error) {
+	for _, c := range commands {
+		if c.op != OpAdd && c.op != OpCancel && c.op != OpModify {
+			return nil, fmt.Errorf("unrecognized operation '%v', stopped submitting", c.op)
+		}
+	}
+
+	results := make([]*submitResult, len(commands))
+	indices := make(chan int)
+	var stopped int32
+	wg := &sync.WaitGroup{}
+	for w := 0; w < concurrency && w < len(commands); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				if atomic.LoadInt32(&stopped) == 1 {
+					continue
+				}
+				results[i] = commands[i].exec(x)
+				if api.ErrorKindOf(results[i].e) == api.ErrorKindAuth {
+					// every remaining command would fail in the same way
+					atomic.StoreInt32(&stopped, 1)
+				}
+			}
+		}()
+	}
+	for i := range commands {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+
+	executed := []submitResult{}
+	for _, r := range results {
+		if r != nil {
+			executed = append(executed, *r)
+		}
+	}
+	if e := firstAuthError(executed); e != nil {
+		return executed, fmt.Errorf("stopped submitting after an auth error: %s", e)
+	}
+	return executed, nil
+}
+
+// execBatch sends the commands with the bulk endpoints of the exchange, the cancels go first so the funds they release
+// can be used by the new orders. There are no bulk endpoints for amending orders so modifies are sent to the exchange
+// on up to concurrency workers after the cancels.
+func execBatch(x api.Exchange, batchAPI api.BatchTradeAPI, commands []Command, concurrency int) ([]submitResult, error) {
+	pairs := []model.TradingPair{}
+	cancels := map[model.TradingPair][]*model.OpenOrder{}
+	modifies := []Command{}
+	adds := []*model.Order{}
+	for _, c := range commands {
+		switch c.op {
+		case OpAdd:
+			adds = append(adds, c.add)
+		case OpModify:
+			modifies = append(modifies, c)
+		case OpCancel:
+			pair := *c.cancel.Pair
+			if _, ok := cancels[pair]; !ok {
+				pairs = append(pairs, pair)
+			}
+			cancels[pair] = append(cancels[pair], c.cancel)
+		default:
+			return nil, fmt.Errorf("unrecognized operation '%v', stopped submitting", c.op)
+		}
+	}
+
+	results := []submitResult{}
+	for _, pair := range pairs {
+		cancelResults, e := execBatchCancel(batchAPI, cancels[pair], pair)
+		if e != nil {
+			return results, fmt.Errorf("could not cancel a batch of %d orders: %s", len(cancels[pair]), e)
+		}
+		results = append(results, cancelResults...)
+	}
+	if e := firstAuthError(results); e != nil {
+		return results, fmt.Errorf("stopped submitting after an auth error: %s", e)
+	}
+
+	modifyResults, e := execParallel(x, modifies, concurrency)
+	results = append(results, modifyResults...)
+	if e != nil {
+		return results, e
+	}
+
+	if len(adds) > 0 {
+		addResults, e := execBatchAdd(batchAPI, adds)
+		if e != nil {
+			return results, fmt.Errorf("could not add a batch of %d orders: %s", len(adds), e)
+		}
+		results = append(results, addResults...)
+	}
+	if e := firstAuthError(results); e != nil {
+		return results, fmt.Errorf("stopped submitting after an auth error: %s", e)
+	}
+	return results, nil
+}
+
+func execBatchCancel(x api.BatchTradeAPI, openOrders []*model.OpenOrder, pair model.TradingPair) ([]submitResult, error) {
+	cancelResults := make([]model.CancelOrderResult, len(openOrders))
+	itemErrors, e := retryBatch(len(openOrders), api.IsRetryable, func(indices []int) ([]error, error) {
+		txIDs := []*model.TransactionID{}
+		for _, i := range indices {
+			txIDs = append(txIDs, model.MakeTransactionID(openOrders[i].ID))
+		}
+		batchResults, e := x.CancelOrders(txIDs, pair)
+		if e != nil {
+			return nil, e
+		}
+
+		batchErrors := []error{}
+		for j, r := range batchResults {
+			cancelResults[indices[j]] = r.Result
+			batchErrors = append(batchErrors, r.Error)
+		}
+		return batchErrors, nil
+	})
+	if e != nil {
+		return nil, e
+	}
+
+	results := []submitResult{}
+	for i, e := range itemErrors {
+		v := cancelResults[i]
+		if api.ErrorKindOf(e) == api.ErrorKindOrderNotFound {
+			// the order is already gone (filled or cancelled) which is what we wanted
+			log.Printf("order %s was not found on the exchange when cancelling, treating it as cancelled: %s\n", openOrders[i].ID, e)
+			v = model.CancelResultCancelSuccessful
+			e = nil
+		}
+		results = append(results, submitResult{
+			op:      OpCancel,
+			command: MakeCommandCancel(openOrders[i]),
+			e:       e,
+			cancel:  &v,
+		})
+	}
+	return results, nil
+}
+
+func execBatchAdd(x api.BatchTradeAPI, orders []*model.Order) ([]submitResult, error) {
+	txIDs := make([]*model.TransactionID, len(orders))
+	// a transient error does not tell us whether the orders were placed so only retry when they were rejected outright
+	itemErrors, e := retryBatch(len(orders), isRateLimited, func(indices []int) ([]error, error) {
+		batch := []*model.Order{}
+		for _, i := range indices {
+			batch = append(batch, orders[i])
+		}
+		batchResults, e := x.AddOrders(batch)
+		if e != nil {
+			return nil, e
+		}
+
+		batchErrors := []error{}
+		for j, r := range batchResults {
+			txIDs[indices[j]] = r.TxID
+			batchErrors = append(batchErrors, r.Error)
+		}
+		return batchErrors, nil
+	})
+	if e != nil {
+		return nil, e
+	}
+
+	results := []submitResult{}
+	for i, e := range itemErrors {
+		results = append(results, submitResult{
+			op:      OpAdd,
+			command: MakeCommandAdd(orders[i]),
+			e:       e,
+			add:     txIDs[i],
+		})
+	}
+	return results, nil
+}
+
+func firstAuthError(results []submitResult) error {
+	for _, r := range results {
+		if api.ErrorKindOf(r.e) == api.ErrorKindAuth {
+			return r.e
+		}
 	}
 	return nil
 }
 
+// reconcile compares the results of the commands with the open orders on the exchange. Orders that are still open after
+// they were cancelled are cancelled again. Adds that failed without being rejected by the exchange are sent again, unless
+// the order was placed after all. Returns an error describing the orders that are still not as intended.
+func (b BatchedExchange) reconcile(results []submitResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+
+	pair := &model.TradingPair{
+		Base:  model.FromHorizonAsset(b.baseAsset),
+		Quote: model.FromHorizonAsset(b.quoteAsset),
+	}
+	openOrders, e := b.inner.GetOpenOrders([]*model.TradingPair{pair})
+	if e != nil {
+		return fmt.Errorf("could not fetch open orders to reconcile the submitted commands: %s", e)
+	}
+	open := map[string]model.OpenOrder{}
+	for _, o := range b.ownOrders(openOrders)[*pair] {
+		open[o.ID] = o
+	}
+
+	// open orders that we did not know about and did not get back from an add may have been placed by a failed add
+	unclaimed := map[string]model.OpenOrder{}
+	for ID, o := range open {
+		if _, ok := b.orderID2OfferID[ID]; !ok {
+			unclaimed[ID] = o
+		}
+	}
+	for _, r := range results {
+		if r.e == nil && r.add != nil {
+			delete(unclaimed, r.add.String())
+		}
+	}
+
+	cancels := []*model.OpenOrder{}
+	adds := []*model.Order{}
+	problems := []string{}
+	for _, r := range results {
+		c := r.command
+		if c.op == OpModify && r.e != nil {
+			if o, ok := open[c.cancel.ID]; ok && ordersMatch(o, c.add) {
+				// the order was amended in place even though the modify failed
+				continue
+			}
+		}
+
+		// the order should be gone after a cancel, or after a modify that replaced it with a new order
+		if c.op == OpCancel || (c.op == OpModify && (r.e != nil || r.add == nil || r.add.String() != c.cancel.ID)) {
+			if _, ok := open[c.cancel.ID]; ok {
+				cancels = append(cancels, c.cancel)
+			}
+		}
+
+		// the order should be on the book after an add or a modify, unless it was already filled
+		if (c.op == OpAdd || c.op == OpModify) && r.e != nil {
+			if isRejected(r.e) {
+				problems = append(problems, fmt.Sprintf("order %s was rejected: %s", *c.add, r.e))
+			} else if ID, ok := findMatchingOrder(unclaimed, c.add); ok {
+				log.Printf("order %s was placed with ID %s even though submitting it failed: %s\n", *c.add, ID, r.e)
+				delete(unclaimed, ID)
+			} else {
+				adds = append(adds, c.add)
+			}
+		}
+	}
+
+	for _, o := range cancels {
+		log.Printf("order %s is still open after submitting, cancelling it again\n", o.ID)
+		r := MakeCommandCancel(o).exec(b.inner)
+		if r.e != nil || *r.cancel == model.CancelResultFailed {
+			problems = append(problems, fmt.Sprintf("order %s could not be cancelled (result=%s): %v", o.ID, r.cancel, r.e))
+		}
+	}
+	for _, order := range adds {
+		log.Printf("order %s is missing after submitting, adding it again\n", *order)
+		r := MakeCommandAdd(order).exec(b.inner)
+		if r.e != nil {
+			problems = append(problems, fmt.Sprintf("order %s could not be added: %s", *order, r.e))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%d orders do not match the open orders on the exchange after submitting: %s", len(problems), strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// findMatchingOrder returns the ID of an open order that has the same client order ID as the order, or the same side,
+// price and volume when the order does not have a client order ID
+func findMatchingOrder(openOrders map[string]model.OpenOrder, order *model.Order) (string, bool) {
+	for ID, o := range openOrders {
+		if ordersMatch(o, order) {
+			return ID, true
+		}
+	}
+	return "", false
+}
+
+func ordersMatch(o model.OpenOrder, order *model.Order) bool {
+	if order.ClientOrderID != "" {
+		return o.ClientOrderID == order.ClientOrderID
+	}
+	if o.Price == nil || o.Volume == nil {
+		return false
+	}
+	return o.OrderAction == order.OrderAction &&
+		o.Price.EqualsPrecisionNormalized(*order.Price, math.Pow(10, -largePrecision)) &&
+		o.Volume.EqualsPrecisionNormalized(*order.Volume, math.Pow(10, -largePrecision))
+}
+
 func (b BatchedExchange) logResults(results []submitResult) {
 	log.Printf("Results from submitting:\n")
 	for _, r := range results {
@@ -235,11 +672,13 @@ func (b BatchedExchange) logResults(results []submitResult) {
 		if r.op == OpCancel {
 			opString = "cancel"
 			v = r.cancel
+		} else if r.op == OpModify {
+			opString = "modify"
 		}
 
 		errorSuffix := ""
 		if r.e != nil {
-			errorSuffix = fmt.Sprintf(", error=%s", r.e)
+			errorSuffix = fmt.Sprintf(", errorKind=%s, error=%s", api.ErrorKindOf(r.e), r.e)
 		}
 		log.Printf("    submitResult[op=%s, value=%v%s]\n", opString, v, errorSuffix)
 	}
@@ -248,24 +687,141 @@ func (b BatchedExchange) logResults(results []submitResult) {
 func (c Command) exec(x api.Exchange) *submitResult {
 	switch c.op {
 	case OpAdd:
-		v, e := x.AddOrder(c.add)
+		var v *model.TransactionID
+		// a transient error does not tell us whether the order was placed so only retry when it was rejected outright
+		e := retrySubmit(isRateLimited, func() error {
+			var e error
+			v, e = x.AddOrder(c.add)
+			return e
+		})
 		return &submitResult{
-			op:  c.op,
-			e:   e,
-			add: v,
+			op:      c.op,
+			command: c,
+			e:       e,
+			add:     v,
 		}
 	case OpCancel:
-		v, e := x.CancelOrder(model.MakeTransactionID(c.cancel.ID), *c.cancel.Pair)
+		var v model.CancelOrderResult
+		e := retrySubmit(api.IsRetryable, func() error {
+			var e error
+			v, e = x.CancelOrder(model.MakeTransactionID(c.cancel.ID), *c.cancel.Pair)
+			return e
+		})
+		if api.ErrorKindOf(e) == api.ErrorKindOrderNotFound {
+			// the order is already gone (filled or cancelled) which is what we wanted
+			log.Printf("order %s was not found on the exchange when cancelling, treating it as cancelled: %s\n", c.cancel.ID, e)
+			v = model.CancelResultCancelSuccessful
+			e = nil
+		}
 		return &submitResult{
-			op:     c.op,
-			e:      e,
-			cancel: &v,
+			op:      c.op,
+			command: c,
+			e:       e,
+			cancel:  &v,
+		}
+	case OpModify:
+		var v *model.TransactionID
+		var e error
+		m, ok := x.(api.ModifyOrder)
+		if ok {
+			// like an add, a transient error does not tell us whether the order was amended
+			e = retrySubmit(isRateLimited, func() error {
+				var e error
+				v, e = m.ModifyOrder(model.MakeTransactionID(c.cancel.ID), c.add)
+				return e
+			})
+		}
+		if !ok || api.ErrorKindOf(e) == api.ErrorKindUnsupported || api.ErrorKindOf(e) == api.ErrorKindOrderNotFound {
+			// fall back to cancel and add, the cancel treats an order that is already gone as cancelled
+			log.Printf("could not modify order %s, cancelling it and adding the new order instead: %v\n", c.cancel.ID, e)
+			r := MakeCommandCancel(c.cancel).exec(x)
+			if r.e == nil {
+				r = MakeCommandAdd(c.add).exec(x)
+			}
+			v, e = r.add, r.e
+		}
+		return &submitResult{
+			op:      c.op,
+			command: c,
+			e:       e,
+			add:     v,
 		}
 	default:
 		return nil
 	}
 }
 
+func isRateLimited(e error) bool {
+	return api.ErrorKindOf(e) == api.ErrorKindRateLimited
+}
+
+// isRejected returns true if the exchange refused the request, sending it again would fail in the same way
+func isRejected(e error) bool {
+	kind := api.ErrorKindOf(e)
+	return kind == api.ErrorKindAuth || kind == api.ErrorKindInsufficientFunds || kind == api.ErrorKindInvalidOrder || kind == api.ErrorKindUnsupported
+}
+
+// retrySubmit calls fn until it succeeds, fails with an error that shouldRetry rejects, or runs out of attempts
+func retrySubmit(shouldRetry func(error) bool, fn func() error) error {
+	var e error
+	for attempt := 1; attempt <= maxSubmitAttempts; attempt++ {
+		e = fn()
+		if e == nil || !shouldRetry(e) || attempt == maxSubmitAttempts {
+			return e
+		}
+
+		delay := submitRetryDelay
+		if retryAfter := api.RetryAfter(e); retryAfter > delay {
+			delay = retryAfter
+		}
+		log.Printf("retryable error on attempt %d of %d, retrying in %s: %s\n", attempt, maxSubmitAttempts, delay, e)
+		time.Sleep(delay)
+	}
+	return e
+}
+
+// retryBatch is retrySubmit for batches of n items, fn is called with the indices of the items to send and returns the
+// error of each of them. Only the items that failed with an error that shouldRetry accepts are sent again. The returned
+// errors are the last error of each item, the error is only set when fn could not send anything.
+func retryBatch(n int, shouldRetry func(error) bool, fn func(indices []int) ([]error, error)) ([]error, error) {
+	itemErrors := make([]error, n)
+	pending := []int{}
+	for i := 0; i < n; i++ {
+		pending = append(pending, i)
+	}
+
+	for attempt := 1; len(pending) > 0; attempt++ {
+		batchErrors, e := fn(pending)
+		if e != nil {
+			return nil, e
+		}
+		if len(batchErrors) != len(pending) {
+			return nil, fmt.Errorf("expected %d results from the batch but got %d", len(pending), len(batchErrors))
+		}
+
+		retry := []int{}
+		delay := submitRetryDelay
+		for j, i := range pending {
+			itemErrors[i] = batchErrors[j]
+			if batchErrors[j] == nil || !shouldRetry(batchErrors[j]) {
+				continue
+			}
+			retry = append(retry, i)
+			if retryAfter := api.RetryAfter(batchErrors[j]); retryAfter > delay {
+				delay = retryAfter
+			}
+		}
+		if len(retry) == 0 || attempt == maxSubmitAttempts {
+			break
+		}
+
+		log.Printf("retryable errors for %d of %d items in the batch on attempt %d of %d, retrying them in %s\n", len(retry), len(pending), attempt, maxSubmitAttempts, delay)
+		time.Sleep(delay)
+		pending = retry
+	}
+	return itemErrors, nil
+}
+
 // GetAccountBalances impl.
 func (b BatchedExchange) GetAccountBalances(assetList []interface{}) (map[interface{}]model.Number, error) {
 	return b.inner.GetAccountBalances(assetList)
@@ -277,10 +833,11 @@ func (b BatchedExchange) GetOpenOrders(pairs []*model.TradingPair) (map[model.Tr
 }
 
 type submitResult struct {
-	op     Operation
-	e      error
-	add    *model.TransactionID
-	cancel *model.CancelOrderResult
+	op      Operation
+	command Command
+	e       error
+	add     *model.TransactionID
+	cancel  *model.CancelOrderResult
 }
 
 func (b BatchedExchange) genUniqueID() int64 {
@@ -303,7 +860,9 @@ func (b BatchedExchange) OpenOrders2Offers(orders []model.OpenOrder, baseAsset h
 	for _, order := range orders {
 		sellingAsset := baseAsset
 		buyingAsset := quoteAsset
-		amount := order.Volume.AsString()
+		// offers only hold what is left on the book, which is less than the order volume after a partial fill
+		volume := order.RemainingVolume()
+		amount := volume.AsString()
 		price, e := convert2Price(order.Price)
 		if e != nil {
 			return nil, fmt.Errorf("unable to convert order price to a ratio: %s", e)
@@ -313,7 +872,7 @@ func (b BatchedExchange) OpenOrders2Offers(orders []model.OpenOrder, baseAsset h
 			sellingAsset = quoteAsset
 			buyingAsset = baseAsset
 			// TODO need to test price and volume conversions correctly
-			amount = fmt.Sprintf("%.8f", order.Volume.AsFloat()*order.Price.AsFloat())
+			amount = fmt.Sprintf("%.8f", volume.AsFloat()*order.Price.AsFloat())
 			invertedPrice := model.InvertNumber(order.Price)
 			// invert price ratio here instead of using convert2Price again since it has an overflow for XLM/BTC
 			price = horizon.Price{
@@ -428,7 +987,8 @@ func (b BatchedExchange) Ops2Commands(ops []build.TransactionMutator, baseAsset
 		Base:  model.FromHorizonAsset(baseAsset),
 		Quote: model.FromHorizonAsset(quoteAsset),
 	}
-	return Ops2CommandsHack(ops, baseAsset, quoteAsset, b.offerID2OrderID, b.inner.GetOrderConstraints(pair))
+	_, canModify := b.inner.(api.ModifyOrder)
+	return Ops2CommandsHack(ops, baseAsset, quoteAsset, b.offerID2OrderID, b.inner.GetOrderConstraints(pair), canModify)
 }
 
 // Ops2CommandsHack converts...
@@ -438,18 +998,19 @@ func Ops2CommandsHack(
 	quoteAsset horizon.Asset,
 	offerID2OrderID map[int64]string, // if map is nil then we ignore ID errors
 	orderConstraints *model.OrderConstraints,
+	canModify bool, // if true then modified offers become a single modify command instead of a cancel and an add
 ) ([]Command, error) {
 	commands := []Command{}
 	for _, op := range ops {
 		switch manageOffer := op.(type) {
 		case *build.ManageOfferBuilder:
-			c, e := op2CommandsHack(manageOffer, baseAsset, quoteAsset, offerID2OrderID, orderConstraints)
+			c, e := op2CommandsHack(manageOffer, baseAsset, quoteAsset, offerID2OrderID, orderConstraints, canModify)
 			if e != nil {
 				return nil, fmt.Errorf("unable to convert *build.ManageOfferBuilder to a Command: %s", e)
 			}
 			commands = append(commands, c...)
 		case build.ManageOfferBuilder:
-			c, e := op2CommandsHack(&manageOffer, baseAsset, quoteAsset, offerID2OrderID, orderConstraints)
+			c, e := op2CommandsHack(&manageOffer, baseAsset, quoteAsset, offerID2OrderID, orderConstraints, canModify)
 			if e != nil {
 				return nil, fmt.Errorf("unable to convert build.ManageOfferBuilder to a Command: %s", e)
 			}
@@ -468,6 +1029,7 @@ func op2CommandsHack(
 	quoteAsset horizon.Asset,
 	offerID2OrderID map[int64]string, // if map is nil then we ignore ID errors
 	orderConstraints *model.OrderConstraints,
+	canModify bool,
 ) ([]Command, error) {
 	commands := []Command{}
 	order, e := manageOffer2Order(manageOffer, baseAsset, quoteAsset, orderConstraints)
@@ -493,7 +1055,7 @@ func op2CommandsHack(
 		openOrder := order2OpenOrder(order, txID)
 		commands = append(commands, MakeCommandCancel(openOrder))
 	} else if manageOffer.MO.OfferId != 0 {
-		// modify is cancel followed by create
+		// modify is an amendment of the open order when the exchange supports it, otherwise cancel followed by create
 		// -- cancel
 		// fetch real orderID here (hoops we have to jump through because of the hacked approach to using centralized exchanges)
 		var orderID string
@@ -509,6 +1071,10 @@ func op2CommandsHack(
 		}
 		txID := model.MakeTransactionID(orderID)
 		openOrder := order2OpenOrder(order, txID)
+		if canModify {
+			commands = append(commands, MakeCommandModify(openOrder, order))
+			return commands, nil
+		}
 		commands = append(commands, MakeCommandCancel(openOrder))
 		// -- create
 		commands = append(commands, MakeCommandAdd(order))
@@ -0,0 +1,470 @@
+package plugins
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stellar/go/clients/horizon"
+	"github.com/stellar/kelp/api"
+	"github.com/stellar/kelp/model"
+	"github.com/stellar/kelp/support/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// testBatchTradeAPI records the batches it is called with and answers with the results of its funcs
+type testBatchTradeAPI struct {
+	addBatches    [][]*model.Order
+	cancelBatches [][]*model.TransactionID
+	addResult     func(attempt int, order *model.Order) api.BatchAddResult
+	cancelResult  func(attempt int, txID *model.TransactionID) api.BatchCancelResult
+}
+
+func (x *testBatchTradeAPI) AddOrders(orders []*model.Order) ([]api.BatchAddResult, error) {
+	x.addBatches = append(x.addBatches, orders)
+	results := []api.BatchAddResult{}
+	for _, o := range orders {
+		results = append(results, x.addResult(len(x.addBatches), o))
+	}
+	return results, nil
+}
+
+func (x *testBatchTradeAPI) CancelOrders(txIDs []*model.TransactionID, pair model.TradingPair) ([]api.BatchCancelResult, error) {
+	x.cancelBatches = append(x.cancelBatches, txIDs)
+	results := []api.BatchCancelResult{}
+	for _, txID := range txIDs {
+		results = append(results, x.cancelResult(len(x.cancelBatches), txID))
+	}
+	return results, nil
+}
+
+func makeTestCommands(numCancels int, numAdds int) []Command {
+	pair := &model.TradingPair{Base: model.XLM, Quote: model.BTC}
+	commands := []Command{}
+	for i := 0; i < numAdds; i++ {
+		commands = append(commands, MakeCommandAdd(&model.Order{
+			Pair:        pair,
+			OrderAction: model.OrderActionSell,
+			OrderType:   model.OrderTypeLimit,
+			Price:       model.NumberFromFloat(0.00003, 8),
+			Volume:      model.NumberFromFloat(float64(100+i), 0),
+		}))
+	}
+	for i := 0; i < numCancels; i++ {
+		commands = append(commands, MakeCommandCancel(&model.OpenOrder{
+			Order: model.Order{Pair: pair},
+			ID:    fmt.Sprintf("open%d", i),
+		}))
+	}
+	return commands
+}
+
+func TestExecBatch(t *testing.T) {
+	x := &testBatchTradeAPI{
+		addResult: func(attempt int, order *model.Order) api.BatchAddResult {
+			// the first order is throttled once and the second order is rejected
+			if order.Volume.AsFloat() == 100 && attempt == 1 {
+				return api.BatchAddResult{Error: api.MakeErrRateLimited("test", 0, fmt.Errorf("slow down"))}
+			}
+			if order.Volume.AsFloat() == 101 {
+				return api.BatchAddResult{Error: api.MakeExchangeError(api.ErrorKindInsufficientFunds, "test", fmt.Errorf("no funds"))}
+			}
+			return api.BatchAddResult{TxID: model.MakeTransactionID(order.Volume.AsString())}
+		},
+		cancelResult: func(attempt int, txID *model.TransactionID) api.BatchCancelResult {
+			if txID.String() == "open1" {
+				return api.BatchCancelResult{Result: model.CancelResultFailed, Error: api.MakeExchangeError(api.ErrorKindOrderNotFound, "test", fmt.Errorf("gone"))}
+			}
+			return api.BatchCancelResult{Result: model.CancelResultCancelSuccessful}
+		},
+	}
+
+	results, e := execBatch(nil, x, makeTestCommands(2, 3), 1)
+	if !assert.NoError(t, e) || !assert.Equal(t, 5, len(results)) {
+		return
+	}
+
+	// cancels are sent before adds and only the throttled order is sent again
+	assert.Equal(t, 1, len(x.cancelBatches))
+	assert.Equal(t, 2, len(x.cancelBatches[0]))
+	if assert.Equal(t, 2, len(x.addBatches)) {
+		assert.Equal(t, 3, len(x.addBatches[0]))
+		assert.Equal(t, 1, len(x.addBatches[1]))
+		assert.Equal(t, 100.0, x.addBatches[1][0].Volume.AsFloat())
+	}
+
+	for _, r := range results[:2] {
+		assert.Equal(t, OpCancel, r.op)
+		assert.NoError(t, r.e)
+		assert.Equal(t, model.CancelResultCancelSuccessful, *r.cancel)
+	}
+	assert.Equal(t, "100", results[2].add.String())
+	assert.NoError(t, results[2].e)
+	assert.Nil(t, results[3].add)
+	assert.Equal(t, api.ErrorKindInsufficientFunds, api.ErrorKindOf(results[3].e))
+	assert.Equal(t, "102", results[4].add.String())
+}
+
+func TestExecBatchStopsAfterAuthError(t *testing.T) {
+	x := &testBatchTradeAPI{
+		addResult: func(attempt int, order *model.Order) api.BatchAddResult {
+			return api.BatchAddResult{TxID: model.MakeTransactionID("added")}
+		},
+		cancelResult: func(attempt int, txID *model.TransactionID) api.BatchCancelResult {
+			return api.BatchCancelResult{Result: model.CancelResultFailed, Error: api.MakeExchangeError(api.ErrorKindAuth, "test", fmt.Errorf("bad key"))}
+		},
+	}
+
+	results, e := execBatch(nil, x, makeTestCommands(1, 2), 1)
+	assert.Error(t, e)
+	assert.Equal(t, 1, len(results))
+	assert.Equal(t, 0, len(x.addBatches))
+}
+
+// testModifyExchange answers modifies with modifyError and records the calls it gets, the embedded api.Exchange is nil so
+// any other call panics
+type testModifyExchange struct {
+	api.Exchange
+	modifyError error
+	calls       []string
+}
+
+func (x *testModifyExchange) ModifyOrder(txID *model.TransactionID, order *model.Order) (*model.TransactionID, error) {
+	x.calls = append(x.calls, "modify "+txID.String())
+	if x.modifyError != nil {
+		return nil, x.modifyError
+	}
+	return model.MakeTransactionID("modified"), nil
+}
+
+func (x *testModifyExchange) CancelOrder(txID *model.TransactionID, pair model.TradingPair) (model.CancelOrderResult, error) {
+	x.calls = append(x.calls, "cancel "+txID.String())
+	return model.CancelResultCancelSuccessful, nil
+}
+
+func (x *testModifyExchange) AddOrder(order *model.Order) (*model.TransactionID, error) {
+	x.calls = append(x.calls, "add")
+	return model.MakeTransactionID("added"), nil
+}
+
+func TestExecModify(t *testing.T) {
+	testCases := []struct {
+		modifyError error
+		wantCalls   []string
+		wantTxID    string
+	}{
+		{
+			modifyError: nil,
+			wantCalls:   []string{"modify open0"},
+			wantTxID:    "modified",
+		}, {
+			modifyError: api.MakeErrUnsupported("test", "amending this order"),
+			wantCalls:   []string{"modify open0", "cancel open0", "add"},
+			wantTxID:    "added",
+		}, {
+			modifyError: api.MakeExchangeError(api.ErrorKindOrderNotFound, "test", fmt.Errorf("gone")),
+			wantCalls:   []string{"modify open0", "cancel open0", "add"},
+			wantTxID:    "added",
+		},
+	}
+
+	for _, kase := range testCases {
+		t.Run(fmt.Sprintf("%v", kase.modifyError), func(t *testing.T) {
+			commands := makeTestCommands(1, 1)
+			x := &testModifyExchange{modifyError: kase.modifyError}
+			r := MakeCommandModify(commands[1].cancel, commands[0].add).exec(x)
+			if !assert.NotNil(t, r) {
+				return
+			}
+			assert.Equal(t, OpModify, r.op)
+			assert.NoError(t, r.e)
+			assert.Equal(t, kase.wantTxID, r.add.String())
+			assert.Equal(t, kase.wantCalls, x.calls)
+		})
+	}
+}
+
+// testConcurrentExchange records the order of the calls it gets and the number of calls that were running at the same
+// time, the embedded api.Exchange is nil so any other call panics
+type testConcurrentExchange struct {
+	api.Exchange
+	mutex       *sync.Mutex
+	inFlight    int
+	maxInFlight int
+	calls       []string
+	addError    error
+}
+
+func (x *testConcurrentExchange) call(name string) {
+	x.mutex.Lock()
+	x.inFlight++
+	if x.inFlight > x.maxInFlight {
+		x.maxInFlight = x.inFlight
+	}
+	x.mutex.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+
+	x.mutex.Lock()
+	x.inFlight--
+	x.calls = append(x.calls, name)
+	x.mutex.Unlock()
+}
+
+func (x *testConcurrentExchange) CancelOrder(txID *model.TransactionID, pair model.TradingPair) (model.CancelOrderResult, error) {
+	x.call("cancel")
+	return model.CancelResultCancelSuccessful, nil
+}
+
+func (x *testConcurrentExchange) AddOrder(order *model.Order) (*model.TransactionID, error) {
+	x.call("add")
+	if x.addError != nil {
+		return nil, x.addError
+	}
+	return model.MakeTransactionID(order.Volume.AsString()), nil
+}
+
+func TestExecConcurrent(t *testing.T) {
+	x := &testConcurrentExchange{mutex: &sync.Mutex{}}
+	results, e := execConcurrent(x, makeTestCommands(4, 6), 3)
+	if !assert.NoError(t, e) || !assert.Equal(t, 10, len(results)) {
+		return
+	}
+
+	assert.Equal(t, 3, x.maxInFlight)
+	// every cancel completes before the first add is sent
+	assert.Equal(t, []string{"cancel", "cancel", "cancel", "cancel"}, x.calls[:4])
+	for i, r := range results {
+		assert.NoError(t, r.e)
+		if i < 4 {
+			assert.Equal(t, OpCancel, r.op)
+			continue
+		}
+		// the adds are in the same order as the commands
+		assert.Equal(t, OpAdd, r.op)
+		assert.Equal(t, fmt.Sprintf("%d", 100+i-4), r.add.String())
+	}
+}
+
+func TestExecConcurrentStopsAfterAuthError(t *testing.T) {
+	x := &testConcurrentExchange{
+		mutex:    &sync.Mutex{},
+		addError: api.MakeExchangeError(api.ErrorKindAuth, "test", fmt.Errorf("bad key")),
+	}
+	results, e := execConcurrent(x, makeTestCommands(0, 10), 2)
+	assert.Error(t, e)
+	// the commands that were already running when the first auth error came back still have a result
+	assert.True(t, len(results) >= 1 && len(results) <= 3, "number of results: %d", len(results))
+	assert.Equal(t, len(results), len(x.calls))
+}
+
+// testReconcileExchange keeps the open orders of the XLM/BTC pair, adds of the volumes in placeAndFail are placed but
+// fail with a transient error, and cancels of the IDs in ignoreCancels succeed without cancelling the order
+type testReconcileExchange struct {
+	api.Exchange
+	open          map[string]model.OpenOrder
+	nextID        int
+	placeAndFail  map[float64]bool
+	ignoreCancels map[string]bool
+	numAdds       int
+	numCancels    int
+}
+
+func (x *testReconcileExchange) GetOpenOrders(pairs []*model.TradingPair) (map[model.TradingPair][]model.OpenOrder, error) {
+	m := map[model.TradingPair][]model.OpenOrder{}
+	for _, pair := range pairs {
+		m[*pair] = []model.OpenOrder{}
+		for _, o := range x.open {
+			m[*pair] = append(m[*pair], o)
+		}
+	}
+	return m, nil
+}
+
+func (x *testReconcileExchange) AddOrder(order *model.Order) (*model.TransactionID, error) {
+	x.numAdds++
+	x.nextID++
+	ID := fmt.Sprintf("new%d", x.nextID)
+	x.open[ID] = model.OpenOrder{Order: *order, ID: ID}
+	if x.placeAndFail[order.Volume.AsFloat()] {
+		delete(x.placeAndFail, order.Volume.AsFloat())
+		return nil, api.MakeExchangeError(api.ErrorKindTransient, "test", fmt.Errorf("timeout"))
+	}
+	return model.MakeTransactionID(ID), nil
+}
+
+func (x *testReconcileExchange) CancelOrder(txID *model.TransactionID, pair model.TradingPair) (model.CancelOrderResult, error) {
+	x.numCancels++
+	if x.ignoreCancels[txID.String()] {
+		delete(x.ignoreCancels, txID.String())
+		return model.CancelResultCancelSuccessful, nil
+	}
+	if _, ok := x.open[txID.String()]; !ok {
+		return model.CancelResultFailed, api.MakeExchangeError(api.ErrorKindOrderNotFound, "test", fmt.Errorf("gone"))
+	}
+	delete(x.open, txID.String())
+	return model.CancelResultCancelSuccessful, nil
+}
+
+func TestReconcile(t *testing.T) {
+	commands := makeTestCommands(2, 4)
+	x := &testReconcileExchange{
+		open:          map[string]model.OpenOrder{},
+		placeAndFail:  map[float64]bool{100: true},
+		ignoreCancels: map[string]bool{"open0": true},
+	}
+	b := MakeBatchedExchange(x, false, horizon.Asset{Type: utils.Native}, horizon.Asset{Type: "credit_alphanum4", Code: "BTC"}, "", 1, "")
+	for i, c := range commands[4:] {
+		x.open[c.cancel.ID] = *c.cancel
+		b.orderID2OfferID[c.cancel.ID] = int64(i)
+	}
+
+	results := []submitResult{}
+	for _, c := range commands {
+		results = append(results, *c.exec(x))
+	}
+	// the order of volume 101 failed without being placed and the order of volume 102 was rejected
+	results[1].add = nil
+	results[1].e = api.MakeExchangeError(api.ErrorKindTransient, "test", fmt.Errorf("timeout"))
+	delete(x.open, "new2")
+	results[2].add = nil
+	results[2].e = api.MakeExchangeError(api.ErrorKindInsufficientFunds, "test", fmt.Errorf("no funds"))
+	delete(x.open, "new3")
+
+	e := b.reconcile(results)
+	if !assert.Error(t, e) {
+		return
+	}
+	assert.Contains(t, e.Error(), "1 orders do not match")
+	assert.Contains(t, e.Error(), "was rejected")
+
+	// open0 was cancelled again, the order of volume 100 was placed after all and the order of volume 101 was added again
+	assert.Equal(t, 3, x.numCancels)
+	assert.Equal(t, 5, x.numAdds)
+	volumes := []float64{}
+	for ID, o := range x.open {
+		assert.NotContains(t, []string{"open0", "open1"}, ID)
+		volumes = append(volumes, o.Volume.AsFloat())
+	}
+	sort.Float64s(volumes)
+	assert.Equal(t, []float64{100, 101, 103}, volumes)
+
+	// nothing is left to reconcile
+	results = []submitResult{*MakeCommandAdd(commands[3].add).exec(x)}
+	assert.NoError(t, b.reconcile(results))
+}
+
+func TestBatchedExchangeOwnOrders(t *testing.T) {
+	pair := &model.TradingPair{Base: model.XLM, Quote: model.BTC}
+	x := &testReconcileExchange{open: map[string]model.OpenOrder{}}
+	for _, clientOrderID := range []string{"bot1-a", "", "bot2-a", "bot1-b"} {
+		x.open["order-"+clientOrderID] = model.OpenOrder{
+			Order: model.Order{
+				Pair:          pair,
+				OrderAction:   model.OrderActionSell,
+				OrderType:     model.OrderTypeLimit,
+				Price:         model.NumberFromFloat(0.00003, 8),
+				Volume:        model.NumberFromFloat(100, 0),
+				ClientOrderID: clientOrderID,
+			},
+			ID: "order-" + clientOrderID,
+		}
+	}
+	b := MakeBatchedExchange(x, false, horizon.Asset{Type: utils.Native}, horizon.Asset{Type: "credit_alphanum4", Code: "BTC"}, "", 1, "bot1-")
+
+	offers, e := b.LoadOffersHack()
+	if assert.NoError(t, e) {
+		assert.Equal(t, 2, len(offers))
+		for _, offer := range offers {
+			assert.Contains(t, []string{"order-bot1-a", "order-bot1-b"}, b.offerID2OrderID[offer.ID])
+		}
+	}
+
+	// liabilities include the orders of the other bots
+	offers, e = b.LoadAllOffersHack()
+	if assert.NoError(t, e) {
+		assert.Equal(t, 4, len(offers))
+	}
+
+	commands := makeTestCommands(1, 2)
+	b.tagOrders(commands)
+	for _, c := range commands[:2] {
+		assert.Regexp(t, "^bot1-[0-9a-z]+$", c.add.ClientOrderID)
+	}
+	assert.NotEqual(t, commands[0].add.ClientOrderID, commands[1].add.ClientOrderID)
+
+	// a modified order keeps its client order ID
+	openOrder := x.open["order-bot1-a"]
+	modify := MakeCommandModify(&openOrder, makeTestCommands(0, 1)[0].add)
+	b.tagOrders([]Command{modify})
+	assert.Equal(t, "bot1-a", modify.add.ClientOrderID)
+}
+
+func TestReconcileModifyAmendedInPlace(t *testing.T) {
+	pair := &model.TradingPair{Base: model.XLM, Quote: model.BTC}
+	openOrder := model.OpenOrder{
+		Order: model.Order{
+			Pair:          pair,
+			OrderAction:   model.OrderActionSell,
+			OrderType:     model.OrderTypeLimit,
+			Price:         model.NumberFromFloat(0.00003, 8),
+			Volume:        model.NumberFromFloat(100, 0),
+			ClientOrderID: "bot1-a",
+		},
+		ID: "open0",
+	}
+	x := &testReconcileExchange{open: map[string]model.OpenOrder{}}
+	b := MakeBatchedExchange(x, false, horizon.Asset{Type: utils.Native}, horizon.Asset{Type: "credit_alphanum4", Code: "BTC"}, "", 1, "bot1-")
+	b.orderID2OfferID["open0"] = 0
+
+	amended := openOrder.Order
+	amended.Price = model.NumberFromFloat(0.000031, 8)
+	modify := MakeCommandModify(&openOrder, &amended)
+	b.tagOrders([]Command{modify})
+
+	// the modify timed out but the order was amended in place, so it keeps its ID and client order ID
+	onBook := openOrder
+	onBook.Price = amended.Price
+	x.open["open0"] = onBook
+	e := b.reconcile([]submitResult{{
+		op:      OpModify,
+		command: modify,
+		e:       api.MakeExchangeError(api.ErrorKindTransient, "test", fmt.Errorf("timeout")),
+	}})
+	assert.NoError(t, e)
+	assert.Equal(t, 0, x.numCancels)
+	assert.Equal(t, 0, x.numAdds)
+}
+
+func TestCheckClientOrderIDPrefix(t *testing.T) {
+	s := makeTestStrongholdServer()
+	defer s.Close()
+	assert.NoError(t, CheckClientOrderIDPrefix(makeTestStrongholdExchange(s, false), "bot1-"))
+
+	// kraken rejects orders with a client order ID so a prefix would make every order fail
+	assert.Error(t, CheckClientOrderIDPrefix(testKrakenExchange, "bot1-"))
+	assert.Error(t, CheckClientOrderIDPrefix(&testReconcileExchange{}, "bot1-"))
+
+	// without a prefix the orders are not tagged
+	assert.NoError(t, CheckClientOrderIDPrefix(testKrakenExchange, ""))
+}
+
+func TestBatchedExchangeKeyUsageMetrics(t *testing.T) {
+	s := makeTestStrongholdServer()
+	defer s.Close()
+	b := MakeBatchedExchange(makeTestStrongholdExchange(s, false), false, horizon.Asset{Type: utils.Native}, horizon.Asset{Type: "credit_alphanum4", Code: "USD"}, "", 1, "")
+
+	_, e := b.GetAccountBalances([]interface{}{model.XLM})
+	if !assert.NoError(t, e) {
+		return
+	}
+	m := b.KeyUsageMetrics()
+	assert.Equal(t, uint64(1), m["stronghold_key_0_requests_private"])
+	assert.Equal(t, uint64(0), m["stronghold_key_0_throttles"])
+	assert.Equal(t, false, m["stronghold_key_0_in_backoff"])
+
+	// exchanges that do not rotate keys have no usage to report
+	assert.Equal(t, 0, len(MakeBatchedExchange(&testReconcileExchange{}, false, horizon.Asset{Type: utils.Native}, horizon.Asset{Type: "credit_alphanum4", Code: "USD"}, "", 1, "").KeyUsageMetrics()))
+}
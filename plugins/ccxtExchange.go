@@ -3,12 +3,15 @@ package plugins
 import (
 	"fmt"
 	"log"
+	"math"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/stellar/kelp/api"
 	"github.com/stellar/kelp/model"
+	"github.com/stellar/kelp/support/networking"
 	"github.com/stellar/kelp/support/sdk"
 	"github.com/stellar/kelp/support/utils"
 )
@@ -18,15 +21,51 @@ const ccxtBalancePrecision = 10
 // ensure that ccxtExchange conforms to the Exchange interface
 var _ api.Exchange = ccxtExchange{}
 
+// ensure that ccxtExchange can look up orders
+var _ api.OrderStatusAPI = ccxtExchange{}
+
+// ensure that ccxtExchange can tag orders with client order IDs
+var _ api.ClientOrderIDAPI = ccxtExchange{}
+
 // ccxtExchange is the implementation for the CCXT REST library that supports many exchanges (https://github.com/franz-see/ccxt-rest, https://github.com/ccxt/ccxt/)
 type ccxtExchange struct {
 	assetConverter     *model.AssetConverter
 	delimiter          string
-	ocOverridesHandler *OrderConstraintsOverridesHandler
-	api                *sdk.Ccxt
+	ocOverridesHandler *model.OrderConstraintsOverridesHandler
+	apis               []*sdk.Ccxt
+	keyPool            *networking.KeyPool
 	simMode            bool
 }
 
+// ccxtRateLimits is empty because ccxt-rest already spaces out the requests of each instance to stay within the rate
+// limits of the exchange, the key pool only rotates across the keys and backs off from keys that were throttled
+var ccxtRateLimits = map[networking.EndpointClass]networking.RateLimit{}
+
+// ccxtErrorRules classifies the names of the ccxt error classes that ccxt-rest includes in its error responses,
+// subclasses are listed before their parents (https://github.com/ccxt/ccxt/wiki/Manual#error-hierarchy)
+var ccxtErrorRules = []api.ErrorMessageRule{
+	{Substring: "DDoSProtection", Kind: api.ErrorKindRateLimited},
+	{Substring: "RateLimitExceeded", Kind: api.ErrorKindRateLimited},
+	{Substring: "AuthenticationError", Kind: api.ErrorKindAuth},
+	{Substring: "PermissionDenied", Kind: api.ErrorKindAuth},
+	{Substring: "AccountSuspended", Kind: api.ErrorKindAuth},
+	{Substring: "InsufficientFunds", Kind: api.ErrorKindInsufficientFunds},
+	{Substring: "OrderNotFound", Kind: api.ErrorKindOrderNotFound},
+	{Substring: "InvalidOrder", Kind: api.ErrorKindInvalidOrder},
+	{Substring: "NotSupported", Kind: api.ErrorKindUnsupported},
+	{Substring: "RequestTimeout", Kind: api.ErrorKindTransient},
+	{Substring: "ExchangeNotAvailable", Kind: api.ErrorKindTransient},
+	{Substring: "OnMaintenance", Kind: api.ErrorKindTransient},
+	{Substring: "NetworkError", Kind: api.ErrorKindTransient},
+	// ccxt-rest itself could not be reached
+	{Substring: "could not execute http request", Kind: api.ErrorKindTransient},
+}
+
+// ccxtError classifies the errors returned via ccxt-rest so the trader can react to them, see api.ExchangeError
+func ccxtError(e error) error {
+	return api.ClassifyErrorMessage("ccxt", e, ccxtErrorRules)
+}
+
 // makeCcxtExchange is a factory method to make an exchange using the CCXT interface
 func makeCcxtExchange(
 	exchangeName string,
@@ -40,27 +79,96 @@ func makeCcxtExchange(
 		return nil, fmt.Errorf("need at least 1 ExchangeAPIKey, even if it is an empty key")
 	}
 
-	if len(apiKeys) != 1 {
-		return nil, fmt.Errorf("need exactly 1 ExchangeAPIKey")
+	if len(apiKeys) > math.MaxUint8 {
+		return nil, fmt.Errorf("invalid number of apiKeys: %d", len(apiKeys))
 	}
 
-	c, e := sdk.MakeInitializedCcxtExchange(exchangeName, apiKeys[0], exchangeParams, headers)
+	// each API key gets its own instance on ccxt-rest since the instance name is derived from the key
+	ccxtAPIs := []*sdk.Ccxt{}
+	for i, apiKey := range apiKeys {
+		c, e := sdk.MakeInitializedCcxtExchange(exchangeName, apiKey, exchangeParams, headers)
+		if e != nil {
+			return nil, fmt.Errorf("error making a ccxt exchange for the API key at index %d: %s", i, e)
+		}
+		ccxtAPIs = append(ccxtAPIs, c)
+	}
+
+	keyPool, e := networking.MakeKeyPool("ccxt", len(ccxtAPIs), ccxtRateLimits)
 	if e != nil {
-		return nil, fmt.Errorf("error making a ccxt exchange: %s", e)
+		return nil, fmt.Errorf("could not make key pool: %s", e)
 	}
 
-	ocOverridesHandler := MakeEmptyOrderConstraintsOverridesHandler()
+	ocOverridesHandler := model.MakeEmptyOrderConstraintsOverridesHandler()
 	if orderConstraintOverrides != nil {
-		ocOverridesHandler = MakeOrderConstraintsOverridesHandler(orderConstraintOverrides)
+		ocOverridesHandler = model.MakeOrderConstraintsOverridesHandler(orderConstraintOverrides)
 	}
 
-	return ccxtExchange{
+	c := ccxtExchange{
 		assetConverter:     model.CcxtAssetConverter,
 		delimiter:          "/",
 		ocOverridesHandler: ocOverridesHandler,
-		api:                c,
+		apis:               ccxtAPIs,
+		keyPool:            keyPool,
 		simMode:            simMode,
-	}, nil
+	}
+
+	batchSupported, e := c.supportsBatchTrade()
+	if e != nil {
+		return nil, fmt.Errorf("could not check whether exchange '%s' supports batch orders: %s", exchangeName, e)
+	}
+	if batchSupported {
+		log.Printf("ccxt exchange '%s' supports createOrders and cancelOrders, orders will be submitted in batches\n", exchangeName)
+	}
+
+	// an emulated editOrder is only a cancel followed by a create so we don't use it
+	editSupported, e := c.apis[0].HasNative("editOrder")
+	if e != nil {
+		return nil, fmt.Errorf("could not check whether exchange '%s' supports editing orders: %s", exchangeName, e)
+	}
+	if editSupported {
+		log.Printf("ccxt exchange '%s' supports editOrder, modified orders will be amended in place\n", exchangeName)
+	}
+
+	if batchSupported && editSupported {
+		return ccxtBatchEditExchange{ccxtBatchExchange{ccxtExchange: c}}, nil
+	} else if batchSupported {
+		return ccxtBatchExchange{ccxtExchange: c}, nil
+	} else if editSupported {
+		return ccxtEditExchange{ccxtExchange: c}, nil
+	}
+	return c, nil
+}
+
+// nextAPI returns the ccxt-rest instance for the API key that can call the class of endpoints soonest so we can overcome rate limit issues
+func (c ccxtExchange) nextAPI(class networking.EndpointClass) (*sdk.Ccxt, int) {
+	index := c.keyPool.Acquire(class)
+	log.Printf("returning ccxt API key at index %d for %s endpoint", index, class)
+	return c.apis[index], index
+}
+
+// KeyUsageMetrics impl.
+func (c ccxtExchange) KeyUsageMetrics() map[string]interface{} {
+	return c.keyPool.Metrics()
+}
+
+// ccxtClientOrderIDExchanges are the ccxt exchanges that take the clientOrderId param and report it back on open orders,
+// ccxt does not list this capability and many exchanges silently drop the param
+var ccxtClientOrderIDExchanges = map[string]bool{
+	"binance": true,
+}
+
+// SupportsClientOrderIDs impl. the legacy ccxt-rest API does not report the client order IDs of open orders
+func (c ccxtExchange) SupportsClientOrderIDs() bool {
+	return c.apis[0].APIVersion() != sdk.CcxtAPIVersionLegacy && ccxtClientOrderIDExchanges[c.apis[0].ExchangeName()]
+}
+
+// keyError classifies the error and backs off from the API key when the exchange throttled it
+func (c ccxtExchange) keyError(index int, e error) error {
+	e = ccxtError(e)
+	if api.ErrorKindOf(e) == api.ErrorKindRateLimited {
+		c.keyPool.Throttle(index, api.RetryAfter(e))
+	}
+	return e
 }
 
 // GetTickerPrice impl.
@@ -72,9 +180,10 @@ func (c ccxtExchange) GetTickerPrice(pairs []model.TradingPair) (map[model.Tradi
 
 	priceResult := map[model.TradingPair]api.Ticker{}
 	for _, p := range pairs {
-		tickerMap, e := c.api.FetchTicker(pairsMap[p])
+		ccxtAPI, key := c.nextAPI(networking.EndpointClassPublic)
+		tickerMap, e := ccxtAPI.FetchTicker(pairsMap[p])
 		if e != nil {
-			return nil, fmt.Errorf("error while fetching ticker price for trading pair %s: %s", pairsMap[p], e)
+			return nil, c.keyError(key, fmt.Errorf("error while fetching ticker price for trading pair %s: %s", pairsMap[p], e))
 		}
 
 		askPrice, e := utils.CheckFetchFloat(tickerMap, "ask")
@@ -108,8 +217,8 @@ func (c ccxtExchange) GetOrderConstraints(pair *model.TradingPair) *model.OrderC
 		panic(e)
 	}
 
-	// load from CCXT's cache
-	ccxtMarket := c.api.GetMarket(pairString)
+	// load from CCXT's cache, all instances are of the same exchange so they loaded the same markets
+	ccxtMarket := c.apis[0].GetMarket(pairString)
 	if ccxtMarket == nil {
 		panic(fmt.Errorf("CCXT does not have precision and limit data for the passed in market: %s", pairString))
 	}
@@ -125,9 +234,10 @@ func (c ccxtExchange) OverrideOrderConstraints(pair *model.TradingPair, override
 
 // GetAccountBalances impl
 func (c ccxtExchange) GetAccountBalances(assetList []interface{}) (map[interface{}]model.Number, error) {
-	balanceResponse, e := c.api.FetchBalance()
+	ccxtAPI, key := c.nextAPI(networking.EndpointClassPrivate)
+	balanceResponse, e := ccxtAPI.FetchBalance()
 	if e != nil {
-		return nil, e
+		return nil, c.keyError(key, e)
 	}
 
 	m := map[interface{}]model.Number{}
@@ -161,9 +271,10 @@ func (c ccxtExchange) GetOrderBook(pair *model.TradingPair, maxCount int32) (*mo
 	}
 
 	limit := int(maxCount)
-	ob, e := c.api.FetchOrderBook(pairString, &limit)
+	ccxtAPI, key := c.nextAPI(networking.EndpointClassPublic)
+	ob, e := ccxtAPI.FetchOrderBook(pairString, &limit)
 	if e != nil {
-		return nil, fmt.Errorf("error while fetching orderbook for trading pair '%s': %s", pairString, e)
+		return nil, c.keyError(key, fmt.Errorf("error while fetching orderbook for trading pair '%s': %s", pairString, e))
 	}
 
 	if _, ok := ob["asks"]; !ok {
@@ -205,9 +316,10 @@ func (c ccxtExchange) GetTradeHistory(pair model.TradingPair, maybeCursorStart i
 
 	// TODO fix limit logic to check result so we get full history instead of just 50 trades
 	const limit = 50
-	tradesRaw, e := c.api.FetchMyTrades(pairString, limit, maybeCursorStart)
+	ccxtAPI, key := c.nextAPI(networking.EndpointClassPrivate)
+	tradesRaw, e := ccxtAPI.FetchMyTrades(pairString, limit, maybeCursorStart)
 	if e != nil {
-		return nil, fmt.Errorf("error while fetching trade history for trading pair '%s': %s", pairString, e)
+		return nil, c.keyError(key, fmt.Errorf("error while fetching trade history for trading pair '%s': %s", pairString, e))
 	}
 
 	trades := []model.Trade{}
@@ -249,9 +361,10 @@ func (c ccxtExchange) GetTrades(pair *model.TradingPair, maybeCursor interface{}
 	}
 
 	// TODO use cursor when fetching trades
-	tradesRaw, e := c.api.FetchTrades(pairString)
+	ccxtAPI, key := c.nextAPI(networking.EndpointClassPublic)
+	tradesRaw, e := ccxtAPI.FetchTrades(pairString)
 	if e != nil {
-		return nil, fmt.Errorf("error while fetching trades for trading pair '%s': %s", pairString, e)
+		return nil, c.keyError(key, fmt.Errorf("error while fetching trades for trading pair '%s': %s", pairString, e))
 	}
 
 	trades := []model.Trade{}
@@ -331,9 +444,10 @@ func (c ccxtExchange) GetOpenOrders(pairs []*model.TradingPair) (map[model.Tradi
 		string2Pair[pairString] = *pair
 	}
 
-	openOrdersMap, e := c.api.FetchOpenOrders(pairStrings)
+	ccxtAPI, key := c.nextAPI(networking.EndpointClassPrivate)
+	openOrdersMap, e := ccxtAPI.FetchOpenOrders(pairStrings)
 	if e != nil {
-		return nil, fmt.Errorf("error while fetching open orders for trading pairs '%v': %s", pairStrings, e)
+		return nil, c.keyError(key, fmt.Errorf("error while fetching open orders for trading pairs '%v': %s", pairStrings, e))
 	}
 
 	result := map[model.TradingPair][]model.OpenOrder{}
@@ -367,22 +481,62 @@ func (c ccxtExchange) convertOpenOrderFromCcxt(pair *model.TradingPair, o sdk.Cc
 	}
 	ts := model.MakeTimestamp(o.Timestamp)
 
+	volumePrecision := c.GetOrderConstraints(pair).VolumePrecision
+	var volumeRemaining *model.Number
+	if o.Remaining != nil {
+		volumeRemaining = model.NumberFromFloat(*o.Remaining, volumePrecision)
+	}
+	status := model.OrderStatusOpen
+	if o.Status == "closed" {
+		status = model.OrderStatusFilled
+	} else if o.Status == "canceled" || o.Status == "expired" || o.Status == "rejected" {
+		status = model.OrderStatusCanceled
+	}
+
 	return &model.OpenOrder{
 		Order: model.Order{
-			Pair:        pair,
-			OrderAction: orderAction,
-			OrderType:   model.OrderTypeLimit,
-			Price:       model.NumberFromFloat(o.Price, c.GetOrderConstraints(pair).PricePrecision),
-			Volume:      model.NumberFromFloat(o.Amount, c.GetOrderConstraints(pair).VolumePrecision),
-			Timestamp:   ts,
+			Pair:          pair,
+			OrderAction:   orderAction,
+			OrderType:     model.OrderTypeLimit,
+			Price:         model.NumberFromFloat(o.Price, c.GetOrderConstraints(pair).PricePrecision),
+			Volume:        model.NumberFromFloat(o.Amount, volumePrecision),
+			Timestamp:     ts,
+			ClientOrderID: o.ClientOrderID,
 		},
-		ID:             o.ID,
-		StartTime:      ts,
-		ExpireTime:     nil,
-		VolumeExecuted: model.NumberFromFloat(o.Filled, c.GetOrderConstraints(pair).VolumePrecision),
+		ID:              o.ID,
+		StartTime:       ts,
+		ExpireTime:      nil,
+		VolumeExecuted:  model.NumberFromFloat(o.Filled, volumePrecision),
+		VolumeRemaining: volumeRemaining,
+		Status:          status,
 	}, nil
 }
 
+// GetOrderStatus impl
+func (c ccxtExchange) GetOrderStatus(txID *model.TransactionID, pair model.TradingPair) (*model.OpenOrder, error) {
+	pairString, e := pair.ToString(c.assetConverter, c.delimiter)
+	if e != nil {
+		return nil, fmt.Errorf("error converting pair to string: %s", e)
+	}
+
+	ccxtAPI, key := c.nextAPI(networking.EndpointClassPrivate)
+	o, e := ccxtAPI.FetchOrder(txID.String(), pairString)
+	if e != nil {
+		return nil, c.keyError(key, fmt.Errorf("error while fetching order %s for trading pair '%s': %s", txID.String(), pairString, e))
+	}
+
+	order, e := c.convertOpenOrderFromCcxt(&pair, *o)
+	if e != nil {
+		return nil, fmt.Errorf("cannot convertOpenOrderFromCcxt: %s", e)
+	}
+	return order, nil
+}
+
+// QueryOrders impl
+func (c ccxtExchange) QueryOrders(txIDs []*model.TransactionID, pair model.TradingPair) (map[string]model.OpenOrder, error) {
+	return api.QueryOrdersOneByOne(c.GetOrderStatus, txIDs, pair)
+}
+
 // AddOrder impl
 func (c ccxtExchange) AddOrder(order *model.Order) (*model.TransactionID, error) {
 	pairString, e := order.Pair.ToString(c.assetConverter, c.delimiter)
@@ -394,12 +548,193 @@ func (c ccxtExchange) AddOrder(order *model.Order) (*model.TransactionID, error)
 	if order.OrderAction.IsBuy() {
 		side = "buy"
 	}
+	params := ccxtOrderParams(order)
 
-	log.Printf("ccxt is submitting order: pair=%s, orderAction=%s, orderType=%s, volume=%s, price=%s\n",
-		pairString, order.OrderAction.String(), order.OrderType.String(), order.Volume.AsString(), order.Price.AsString())
-	ccxtOpenOrder, e := c.api.CreateLimitOrder(pairString, side, order.Volume.AsFloat(), order.Price.AsFloat())
+	log.Printf("ccxt is submitting order: pair=%s, orderAction=%s, orderType=%s, volume=%s, price=%s, params=%v\n",
+		pairString, order.OrderAction.String(), order.OrderType.String(), order.Volume.AsString(), order.Price.AsString(), params)
+	ccxtAPI, key := c.nextAPI(networking.EndpointClassTrading)
+	ccxtOpenOrder, e := ccxtAPI.CreateLimitOrderWithParams(pairString, side, order.Volume.AsFloat(), order.Price.AsFloat(), params)
 	if e != nil {
-		return nil, fmt.Errorf("error while creating limit order %s: %s", *order, e)
+		return nil, c.keyError(key, fmt.Errorf("error while creating limit order %s: %s", *order, e))
+	}
+
+	return model.MakeTransactionID(ccxtOpenOrder.ID), nil
+}
+
+// ccxtOrderParams converts the optional order fields to the unified params of ccxt, exchanges that do not support a
+// param reject the order with a NotSupported error
+func ccxtOrderParams(order *model.Order) map[string]interface{} {
+	params := map[string]interface{}{}
+	if order.PostOnly {
+		params["postOnly"] = true
+	}
+	if order.TimeInForce != model.TimeInForceGTC {
+		params["timeInForce"] = strings.ToUpper(order.TimeInForce.String())
+	}
+	if order.ClientOrderID != "" {
+		params["clientOrderId"] = order.ClientOrderID
+	}
+	return params
+}
+
+// ccxtBatchMaxOrders is the number of orders we send in a single createOrders or cancelOrders call, ccxt does not expose
+// the limits of the bulk endpoints so we use the smallest one of the common exchanges (binance takes 5 orders)
+const ccxtBatchMaxOrders = 5
+
+// ccxtBatchExchange is a ccxtExchange for an exchange that has the createOrders and cancelOrders methods in ccxt
+type ccxtBatchExchange struct {
+	ccxtExchange
+}
+
+// ensure that ccxtBatchExchange conforms to the BatchTradeAPI interface
+var _ api.BatchTradeAPI = ccxtBatchExchange{}
+
+// supportsBatchTrade returns true if ccxt can place and cancel many orders of the exchange in a single call, all the
+// instances are for the same exchange so we only need to check one of them
+func (c ccxtExchange) supportsBatchTrade() (bool, error) {
+	for _, method := range []string{"createOrders", "cancelOrders"} {
+		has, e := c.apis[0].Has(method)
+		if e != nil {
+			return false, e
+		}
+		if !has {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// AddOrders impl
+func (c ccxtBatchExchange) AddOrders(orders []*model.Order) ([]api.BatchAddResult, error) {
+	requests := []sdk.CcxtOrderRequest{}
+	for _, order := range orders {
+		pairString, e := order.Pair.ToString(c.assetConverter, c.delimiter)
+		if e != nil {
+			return nil, fmt.Errorf("error converting pair to string: %s", e)
+		}
+
+		side := "sell"
+		if order.OrderAction.IsBuy() {
+			side = "buy"
+		}
+		requests = append(requests, sdk.CcxtOrderRequest{
+			Symbol: pairString,
+			Type:   "limit",
+			Side:   side,
+			Amount: order.Volume.AsFloat(),
+			Price:  order.Price.AsFloat(),
+			Params: ccxtOrderParams(order),
+		})
+	}
+
+	results := []api.BatchAddResult{}
+	for start := 0; start < len(requests); start += ccxtBatchMaxOrders {
+		end := start + ccxtBatchMaxOrders
+		if end > len(requests) {
+			end = len(requests)
+		}
+
+		log.Printf("ccxt is submitting a batch of %d orders\n", end-start)
+		ccxtAPI, key := c.nextAPI(networking.EndpointClassTrading)
+		ccxtOrders, e := ccxtAPI.CreateOrders(requests[start:end])
+		if e != nil {
+			e = c.keyError(key, fmt.Errorf("error while creating a batch of %d orders: %s", end-start, e))
+			for range requests[start:end] {
+				results = append(results, api.BatchAddResult{Error: e})
+			}
+			continue
+		}
+
+		for i, o := range ccxtOrders {
+			if o.ID == "" {
+				e := ccxtError(fmt.Errorf("order %s was rejected with status '%s': %v", *orders[start+i], o.Status, o.Info))
+				results = append(results, api.BatchAddResult{Error: e})
+				continue
+			}
+			results = append(results, api.BatchAddResult{TxID: model.MakeTransactionID(o.ID)})
+		}
+	}
+	return results, nil
+}
+
+// CancelOrders impl, ccxt does not report which of the orders could not be cancelled so an error is set on all of them
+func (c ccxtBatchExchange) CancelOrders(txIDs []*model.TransactionID, pair model.TradingPair) ([]api.BatchCancelResult, error) {
+	pairString, e := pair.ToString(c.assetConverter, c.delimiter)
+	if e != nil {
+		return nil, fmt.Errorf("error converting pair to string: %s", e)
+	}
+
+	results := []api.BatchCancelResult{}
+	for start := 0; start < len(txIDs); start += ccxtBatchMaxOrders {
+		end := start + ccxtBatchMaxOrders
+		if end > len(txIDs) {
+			end = len(txIDs)
+		}
+		orderIDs := []string{}
+		for _, txID := range txIDs[start:end] {
+			orderIDs = append(orderIDs, txID.String())
+		}
+
+		log.Printf("ccxt is canceling a batch of orders: IDs=%v, tradingPair: %s\n", orderIDs, pair.String())
+		ccxtAPI, key := c.nextAPI(networking.EndpointClassTrading)
+		result := model.CancelResultCancelSuccessful
+		e := ccxtAPI.CancelOrders(orderIDs, pairString)
+		if e != nil {
+			e = c.keyError(key, e)
+			result = model.CancelResultFailed
+		}
+		for range orderIDs {
+			results = append(results, api.BatchCancelResult{Result: result, Error: e})
+		}
+	}
+	return results, nil
+}
+
+// ccxtEditExchange is a ccxtExchange for an exchange that has the editOrder method in ccxt
+type ccxtEditExchange struct {
+	ccxtExchange
+}
+
+// ensure that ccxtEditExchange conforms to the ModifyOrder interface
+var _ api.ModifyOrder = ccxtEditExchange{}
+
+// ModifyOrder impl
+func (c ccxtEditExchange) ModifyOrder(txID *model.TransactionID, order *model.Order) (*model.TransactionID, error) {
+	return c.editOrder(txID, order)
+}
+
+// ccxtBatchEditExchange is a ccxtBatchExchange for an exchange that also has the editOrder method in ccxt
+type ccxtBatchEditExchange struct {
+	ccxtBatchExchange
+}
+
+// ensure that ccxtBatchEditExchange conforms to the ModifyOrder interface
+var _ api.ModifyOrder = ccxtBatchEditExchange{}
+
+// ModifyOrder impl
+func (c ccxtBatchEditExchange) ModifyOrder(txID *model.TransactionID, order *model.Order) (*model.TransactionID, error) {
+	return c.editOrder(txID, order)
+}
+
+// editOrder amends the open order with editOrder, the exchange may give the amended order a new ID
+func (c ccxtExchange) editOrder(txID *model.TransactionID, order *model.Order) (*model.TransactionID, error) {
+	pairString, e := order.Pair.ToString(c.assetConverter, c.delimiter)
+	if e != nil {
+		return nil, fmt.Errorf("error converting pair to string: %s", e)
+	}
+
+	side := "sell"
+	if order.OrderAction.IsBuy() {
+		side = "buy"
+	}
+	params := ccxtOrderParams(order)
+
+	log.Printf("ccxt is editing order: ID=%s, pair=%s, orderAction=%s, volume=%s, price=%s, params=%v\n",
+		txID.String(), pairString, order.OrderAction.String(), order.Volume.AsString(), order.Price.AsString(), params)
+	ccxtAPI, key := c.nextAPI(networking.EndpointClassTrading)
+	ccxtOpenOrder, e := ccxtAPI.EditLimitOrder(txID.String(), pairString, side, order.Volume.AsFloat(), order.Price.AsFloat(), params)
+	if e != nil {
+		return nil, c.keyError(key, fmt.Errorf("error while editing order %s to %s: %s", txID.String(), *order, e))
 	}
 
 	return model.MakeTransactionID(ccxtOpenOrder.ID), nil
@@ -409,9 +744,10 @@ func (c ccxtExchange) AddOrder(order *model.Order) (*model.TransactionID, error)
 func (c ccxtExchange) CancelOrder(txID *model.TransactionID, pair model.TradingPair) (model.CancelOrderResult, error) {
 	log.Printf("ccxt is canceling order: ID=%s, tradingPair: %s\n", txID.String(), pair.String())
 
-	resp, e := c.api.CancelOrder(txID.String(), pair.String())
+	ccxtAPI, key := c.nextAPI(networking.EndpointClassTrading)
+	resp, e := ccxtAPI.CancelOrder(txID.String(), pair.String())
 	if e != nil {
-		return model.CancelResultFailed, e
+		return model.CancelResultFailed, c.keyError(key, e)
 	}
 
 	if resp == nil {
@@ -422,14 +758,79 @@ func (c ccxtExchange) CancelOrder(txID *model.TransactionID, pair model.TradingP
 
 // PrepareDeposit impl
 func (c ccxtExchange) PrepareDeposit(asset model.Asset, amount *model.Number) (*api.PrepareDepositResult, error) {
-	// TODO implement
-	return nil, nil
+	ccxtAsset, e := c.assetConverter.ToString(asset)
+	if e != nil {
+		return nil, e
+	}
+
+	ccxtAPI, key := c.nextAPI(networking.EndpointClassFunding)
+	depositAddress, e := ccxtAPI.FetchDepositAddress(ccxtAsset)
+	if e != nil {
+		return nil, c.keyError(key, fmt.Errorf("error while fetching deposit address for asset %s: %s", ccxtAsset, e))
+	}
+	if depositAddress.Address == "" {
+		return nil, fmt.Errorf("exchange did not return a deposit address for asset %s, it may need to be created on the exchange first", ccxtAsset)
+	}
+
+	// the fee is informational so we still return the address when the exchange does not report funding fees
+	fees, e := c.fetchFundingFees()
+	if e != nil {
+		return nil, e
+	}
+	var fee *model.Number
+	if fees != nil {
+		if v, ok := fees.Deposit[ccxtAsset]; ok {
+			fee = model.NumberFromFloat(v, ccxtBalancePrecision)
+		}
+	}
+
+	return &api.PrepareDepositResult{
+		Fee:      fee,
+		Address:  depositAddress.Address,
+		Memo:     depositAddress.Tag,
+		ExpireTs: 0,
+	}, nil
+}
+
+// fetchFundingFees returns nil when the exchange does not support fetching its funding fees
+func (c ccxtExchange) fetchFundingFees() (*sdk.CcxtFundingFees, error) {
+	ccxtAPI, key := c.nextAPI(networking.EndpointClassFunding)
+	fees, e := ccxtAPI.FetchFundingFees()
+	if e != nil {
+		if strings.Contains(e.Error(), "NotSupported") {
+			return nil, nil
+		}
+		return nil, c.keyError(key, fmt.Errorf("error while fetching funding fees: %s", e))
+	}
+	return fees, nil
 }
 
 // GetWithdrawInfo impl
 func (c ccxtExchange) GetWithdrawInfo(asset model.Asset, amountToWithdraw *model.Number, address string) (*api.WithdrawInfo, error) {
-	// TODO implement
-	return nil, nil
+	ccxtAsset, e := c.assetConverter.ToString(asset)
+	if e != nil {
+		return nil, e
+	}
+
+	fees, e := c.fetchFundingFees()
+	if e != nil {
+		return nil, e
+	}
+	if fees == nil {
+		return nil, fmt.Errorf("exchange does not report its withdrawal fees so we cannot compute the amount to receive for asset %s", ccxtAsset)
+	}
+	v, ok := fees.Withdraw[ccxtAsset]
+	if !ok {
+		return nil, fmt.Errorf("exchange did not report the withdrawal fee for asset %s", ccxtAsset)
+	}
+
+	fee := model.NumberFromFloat(v, ccxtBalancePrecision)
+	if fee.AsFloat() >= amountToWithdraw.AsFloat() {
+		return nil, api.MakeErrWithdrawAmountInvalid(amountToWithdraw, fee)
+	}
+	// Subtract would round to the precision of the amount which can be coarser than the fee
+	amountToReceive := model.NumberFromFloat(amountToWithdraw.AsFloat()-fee.AsFloat(), ccxtBalancePrecision)
+	return &api.WithdrawInfo{AmountToReceive: amountToReceive}, nil
 }
 
 // WithdrawFunds impl
@@ -438,6 +839,20 @@ func (c ccxtExchange) WithdrawFunds(
 	amountToWithdraw *model.Number,
 	address string,
 ) (*api.WithdrawFunds, error) {
-	// TODO implement
-	return nil, nil
+	ccxtAsset, e := c.assetConverter.ToString(asset)
+	if e != nil {
+		return nil, e
+	}
+
+	log.Printf("ccxt is withdrawing funds: asset=%s, amount=%s, address=%s\n", ccxtAsset, amountToWithdraw.AsString(), address)
+	// the withdraw API does not take a memo so we never send a tag
+	ccxtAPI, key := c.nextAPI(networking.EndpointClassFunding)
+	withdrawal, e := ccxtAPI.Withdraw(ccxtAsset, amountToWithdraw.AsFloat(), address, "")
+	if e != nil {
+		return nil, c.keyError(key, fmt.Errorf("error while withdrawing %s %s to address %s: %s", amountToWithdraw.AsString(), ccxtAsset, address, e))
+	}
+
+	return &api.WithdrawFunds{
+		WithdrawalID: withdrawal.ID,
+	}, nil
 }
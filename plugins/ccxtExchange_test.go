@@ -9,6 +9,8 @@ import (
 
 	"github.com/stellar/kelp/api"
 	"github.com/stellar/kelp/model"
+	"github.com/stellar/kelp/support/sdk"
+	"github.com/stellar/kelp/support/sdk/ccxttest"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -490,3 +492,499 @@ func TestGetOrderConstraints_Ccxt_Precision(t *testing.T) {
 		})
 	}
 }
+
+func TestCcxtError(t *testing.T) {
+	testCases := []struct {
+		message  string
+		wantKind api.ErrorKind
+	}{
+		{`error in response, bodyString: {"error":"DDoSProtection: binance 429 Too Many Requests"}`, api.ErrorKindRateLimited},
+		{`error in response, bodyString: {"error":"AuthenticationError: binance requires apiKey"}`, api.ErrorKindAuth},
+		{`error in response, bodyString: {"error":"InsufficientFunds: binance Account has insufficient balance"}`, api.ErrorKindInsufficientFunds},
+		{`error in response, bodyString: {"error":"OrderNotFound: binance Unknown order sent."}`, api.ErrorKindOrderNotFound},
+		{`error in response, bodyString: {"error":"InvalidOrder: binance Filter failure: PRICE_FILTER"}`, api.ErrorKindInvalidOrder},
+		{`error in response, bodyString: {"error":"NotSupported: binance createOrder() does not support timeInForce FOK"}`, api.ErrorKindUnsupported},
+		{`could not execute http request: dial tcp 127.0.0.1:3000: connect: connection refused`, api.ErrorKindTransient},
+		{`symbol does not exist: XLM/FOO`, api.ErrorKindUnknown},
+	}
+
+	for _, kase := range testCases {
+		t.Run(kase.message, func(t *testing.T) {
+			e := ccxtError(fmt.Errorf("error when cancelling order: %s", kase.message))
+			assert.Equal(t, kase.wantKind, api.ErrorKindOf(e))
+		})
+	}
+}
+
+func makeTestCcxtExchange(t *testing.T, server *ccxttest.Server, apiKeys []api.ExchangeAPIKey) *ccxtExchange {
+	if e := sdk.SetBaseURL(server.URL); !assert.NoError(t, e) {
+		t.FailNow()
+	}
+
+	exchange, e := makeCcxtExchange("binance", nil, apiKeys, []api.ExchangeParam{}, []api.ExchangeHeader{}, false)
+	if !assert.NoError(t, e) {
+		t.FailNow()
+	}
+	switch c := exchange.(type) {
+	case ccxtBatchEditExchange:
+		return &c.ccxtExchange
+	case ccxtBatchExchange:
+		return &c.ccxtExchange
+	case ccxtEditExchange:
+		return &c.ccxtExchange
+	}
+	c := exchange.(ccxtExchange)
+	return &c
+}
+
+func makeTestCcxtServer() *ccxttest.Server {
+	server := ccxttest.NewServer(ccxttest.VersionV1, "binance")
+	server.AddMarket(ccxttest.Market{Symbol: "XLM/BTC", Base: "XLM", Quote: "BTC", PricePrecision: 8, AmountPrecision: 0, MinAmount: 1})
+	server.SetTicker("XLM/BTC", 0.0000249, 0.0000251)
+	server.SetBalance("XLM", 1000)
+	server.SetBalance("BTC", 1)
+	return server
+}
+
+func TestCcxtExchangeSupportsClientOrderIDs(t *testing.T) {
+	apiKeys := []api.ExchangeAPIKey{{Key: "key0", Secret: "secret0"}}
+	for _, kase := range []struct {
+		version  string
+		exchange string
+		want     bool
+	}{
+		{ccxttest.VersionV1, "binance", true},
+		{ccxttest.VersionLegacy, "binance", false},
+		{ccxttest.VersionV1, "bitfinex", false},
+	} {
+		t.Run(kase.version+"/"+kase.exchange, func(t *testing.T) {
+			server := ccxttest.NewServer(kase.version, kase.exchange)
+			defer server.Close()
+			server.AddMarket(ccxttest.Market{Symbol: "XLM/BTC", Base: "XLM", Quote: "BTC", PricePrecision: 8, AmountPrecision: 0, MinAmount: 1})
+			if e := sdk.SetBaseURL(server.URL); !assert.NoError(t, e) {
+				return
+			}
+
+			exchange, e := makeCcxtExchange(kase.exchange, nil, apiKeys, []api.ExchangeParam{}, []api.ExchangeHeader{}, false)
+			if !assert.NoError(t, e) {
+				return
+			}
+			assert.Equal(t, kase.want, exchange.(api.ClientOrderIDAPI).SupportsClientOrderIDs())
+			e = CheckClientOrderIDPrefix(exchange, "bot1-")
+			assert.Equal(t, !kase.want, e != nil)
+		})
+	}
+}
+
+func TestCcxtExchangeRotatesAPIKeys(t *testing.T) {
+	server := makeTestCcxtServer()
+	defer server.Close()
+	defer sdk.SetBaseURL(sdk.DefaultCcxtBaseURL)
+
+	apiKeys := []api.ExchangeAPIKey{
+		{Key: "key0", Secret: "secret0"},
+		{Key: "key1", Secret: "secret1"},
+		{Key: "key2", Secret: "secret2"},
+	}
+	c := makeTestCcxtExchange(t, server, apiKeys)
+	if !assert.Equal(t, 3, len(server.Instances())) {
+		return
+	}
+
+	pair := model.TradingPair{Base: model.XLM, Quote: model.BTC}
+	for i := 0; i < 3; i++ {
+		_, e := c.GetTickerPrice([]model.TradingPair{pair})
+		if !assert.NoError(t, e) {
+			return
+		}
+	}
+	_, e := c.GetAccountBalances([]interface{}{model.XLM, model.BTC})
+	if !assert.NoError(t, e) {
+		return
+	}
+	txID, e := c.AddOrder(&model.Order{
+		Pair:        &pair,
+		OrderAction: model.OrderActionSell,
+		OrderType:   model.OrderTypeLimit,
+		Price:       model.NumberFromFloat(0.00003, 8),
+		Volume:      model.NumberFromFloat(100, 0),
+	})
+	if !assert.NoError(t, e) {
+		return
+	}
+	result, e := c.CancelOrder(txID, pair)
+	if !assert.NoError(t, e) {
+		return
+	}
+	assert.Equal(t, model.CancelResultCancelSuccessful, result)
+
+	// every call after loading the markets went to the next key
+	usedKeys := []string{}
+	for _, call := range server.Calls() {
+		if call.Method != "loadMarkets" {
+			usedKeys = append(usedKeys, server.APIKey(call.Instance))
+		}
+	}
+	assert.Equal(t, []string{"key0", "key1", "key2", "key0", "key1", "key2"}, usedKeys)
+}
+
+func TestCcxtExchangeBacksOffThrottledKey(t *testing.T) {
+	server := makeTestCcxtServer()
+	defer server.Close()
+	defer sdk.SetBaseURL(sdk.DefaultCcxtBaseURL)
+
+	c := makeTestCcxtExchange(t, server, []api.ExchangeAPIKey{
+		{Key: "key0", Secret: "secret0"},
+		{Key: "key1", Secret: "secret1"},
+	})
+
+	server.FailNext("DDoSProtection: binance 429 Too Many Requests")
+	_, e := c.GetAccountBalances([]interface{}{model.XLM})
+	if !assert.Error(t, e) {
+		return
+	}
+	assert.Equal(t, api.ErrorKindRateLimited, api.ErrorKindOf(e))
+
+	usage := c.keyPool.Usage()
+	assert.Equal(t, uint64(1), usage[0].Throttles)
+	assert.Equal(t, uint64(0), usage[1].Throttles)
+
+	// both of the following calls skip the key that is backing off
+	for i := 0; i < 2; i++ {
+		_, e = c.GetAccountBalances([]interface{}{model.XLM})
+		if !assert.NoError(t, e) {
+			return
+		}
+	}
+	calls := server.Calls()
+	for _, call := range calls[len(calls)-2:] {
+		assert.Equal(t, "key1", server.APIKey(call.Instance))
+	}
+}
+
+func TestMakeCcxtExchangeInvalidKeys(t *testing.T) {
+	_, e := makeCcxtExchange("binance", nil, []api.ExchangeAPIKey{}, []api.ExchangeParam{}, []api.ExchangeHeader{}, false)
+	assert.Error(t, e)
+
+	_, e = makeCcxtExchange("binance", nil, make([]api.ExchangeAPIKey, 256), []api.ExchangeParam{}, []api.ExchangeHeader{}, false)
+	assert.Error(t, e)
+}
+
+func TestCcxtExchangeDepositAndWithdraw(t *testing.T) {
+	server := makeTestCcxtServer()
+	defer server.Close()
+	defer sdk.SetBaseURL(sdk.DefaultCcxtBaseURL)
+	server.SetDepositAddress("XLM", "GBTESTADDRESS", "12345")
+	server.SetFundingFees("XLM", 0.5, 0.01)
+
+	c := makeTestCcxtExchange(t, server, []api.ExchangeAPIKey{{Key: "key0", Secret: "secret0"}})
+
+	deposit, e := c.PrepareDeposit(model.XLM, model.NumberFromFloat(100, 7))
+	if !assert.NoError(t, e) {
+		return
+	}
+	assert.Equal(t, "GBTESTADDRESS", deposit.Address)
+	assert.Equal(t, "12345", deposit.Memo)
+	assert.Equal(t, 0.5, deposit.Fee.AsFloat())
+
+	_, e = c.PrepareDeposit(model.BTC, model.NumberFromFloat(1, 8))
+	assert.Error(t, e)
+
+	info, e := c.GetWithdrawInfo(model.XLM, model.NumberFromFloat(25, 0), "GBDESTINATION")
+	if !assert.NoError(t, e) {
+		return
+	}
+	assert.Equal(t, 24.99, info.AmountToReceive.AsFloat())
+
+	_, e = c.GetWithdrawInfo(model.XLM, model.NumberFromFloat(0.01, 2), "GBDESTINATION")
+	assert.Error(t, e)
+
+	result, e := c.WithdrawFunds(model.XLM, model.NumberFromFloat(25, 0), "GBDESTINATION")
+	if !assert.NoError(t, e) {
+		return
+	}
+	withdrawals := server.Withdrawals()
+	if assert.Equal(t, 1, len(withdrawals)) {
+		assert.Equal(t, withdrawals[0].ID, result.WithdrawalID)
+		assert.Equal(t, 25.0, withdrawals[0].Amount)
+		assert.Equal(t, "GBDESTINATION", withdrawals[0].Address)
+	}
+
+	_, e = c.WithdrawFunds(model.XLM, model.NumberFromFloat(5000, 0), "GBDESTINATION")
+	assert.Equal(t, api.ErrorKindInsufficientFunds, api.ErrorKindOf(e))
+}
+
+func TestCcxtExchangeFundingFeesNotSupported(t *testing.T) {
+	server := makeTestCcxtServer()
+	defer server.Close()
+	defer sdk.SetBaseURL(sdk.DefaultCcxtBaseURL)
+	server.SetDepositAddress("XLM", "GBTESTADDRESS", "")
+	server.Unsupport("fetchFundingFees")
+
+	c := makeTestCcxtExchange(t, server, []api.ExchangeAPIKey{{Key: "key0", Secret: "secret0"}})
+
+	deposit, e := c.PrepareDeposit(model.XLM, model.NumberFromFloat(100, 7))
+	if !assert.NoError(t, e) {
+		return
+	}
+	assert.Equal(t, "GBTESTADDRESS", deposit.Address)
+	assert.Nil(t, deposit.Fee)
+
+	_, e = c.GetWithdrawInfo(model.XLM, model.NumberFromFloat(25, 0), "GBDESTINATION")
+	assert.Error(t, e)
+}
+
+func TestCcxtExchangeOrderStatus(t *testing.T) {
+	server := makeTestCcxtServer()
+	defer server.Close()
+	defer sdk.SetBaseURL(sdk.DefaultCcxtBaseURL)
+
+	c := makeTestCcxtExchange(t, server, []api.ExchangeAPIKey{{Key: "key0", Secret: "secret0"}})
+	pair := model.TradingPair{Base: model.XLM, Quote: model.BTC}
+	txIDs := []*model.TransactionID{}
+	for _, volume := range []float64{100, 50} {
+		txID, e := c.AddOrder(&model.Order{
+			Pair:        &pair,
+			OrderAction: model.OrderActionSell,
+			OrderType:   model.OrderTypeLimit,
+			Price:       model.NumberFromFloat(0.00003, 8),
+			Volume:      model.NumberFromFloat(volume, 0),
+		})
+		if !assert.NoError(t, e) {
+			return
+		}
+		txIDs = append(txIDs, txID)
+	}
+	if !assert.NoError(t, server.FillOrder(txIDs[0].String(), 40)) {
+		return
+	}
+	if !assert.NoError(t, server.FillOrder(txIDs[1].String(), 50)) {
+		return
+	}
+
+	m, e := c.GetOpenOrders([]*model.TradingPair{&pair})
+	if !assert.NoError(t, e) || !assert.Equal(t, 1, len(m[pair])) {
+		return
+	}
+	assert.Equal(t, 100.0, m[pair][0].Volume.AsFloat())
+	assert.Equal(t, 40.0, m[pair][0].VolumeExecuted.AsFloat())
+	assert.Equal(t, 60.0, m[pair][0].RemainingVolume().AsFloat())
+
+	o, e := c.GetOrderStatus(txIDs[1], pair)
+	if !assert.NoError(t, e) {
+		return
+	}
+	assert.Equal(t, model.OrderStatusFilled, o.Status)
+	assert.Equal(t, 0.0, o.RemainingVolume().AsFloat())
+
+	orders, e := c.QueryOrders(append(txIDs, model.MakeTransactionID("999")), pair)
+	if !assert.NoError(t, e) {
+		return
+	}
+	assert.Equal(t, 2, len(orders))
+	assert.Equal(t, model.OrderStatusOpen, orders[txIDs[0].String()].Status)
+
+	_, e = c.GetOrderStatus(model.MakeTransactionID("999"), pair)
+	assert.Equal(t, api.ErrorKindOrderNotFound, api.ErrorKindOf(e))
+}
+
+func TestCcxtExchangeOrderFlags(t *testing.T) {
+	server := makeTestCcxtServer()
+	defer server.Close()
+	defer sdk.SetBaseURL(sdk.DefaultCcxtBaseURL)
+
+	c := makeTestCcxtExchange(t, server, []api.ExchangeAPIKey{{Key: "key0", Secret: "secret0"}})
+	pair := model.TradingPair{Base: model.XLM, Quote: model.BTC}
+	makeOrder := func() *model.Order {
+		return &model.Order{
+			Pair:        &pair,
+			OrderAction: model.OrderActionSell,
+			OrderType:   model.OrderTypeLimit,
+			Price:       model.NumberFromFloat(0.00003, 8),
+			Volume:      model.NumberFromFloat(100, 0),
+		}
+	}
+	lastParams := func() interface{} {
+		calls := server.Calls()
+		args := calls[len(calls)-1].Args
+		if len(args) < 6 {
+			return nil
+		}
+		return args[5]
+	}
+
+	order := makeOrder()
+	order.PostOnly = true
+	order.ClientOrderID = "kelp-1"
+	txID, e := c.AddOrder(order)
+	if !assert.NoError(t, e) {
+		return
+	}
+	assert.Equal(t, map[string]interface{}{"postOnly": true, "clientOrderId": "kelp-1"}, lastParams())
+	o, e := c.GetOrderStatus(txID, pair)
+	if assert.NoError(t, e) {
+		assert.Equal(t, "kelp-1", o.ClientOrderID)
+	}
+
+	// orders without flags are sent without params
+	_, e = c.AddOrder(makeOrder())
+	if assert.NoError(t, e) {
+		assert.Nil(t, lastParams())
+	}
+
+	order = makeOrder()
+	order.TimeInForce = model.TimeInForceIOC
+	txID, e = c.AddOrder(order)
+	if !assert.NoError(t, e) {
+		return
+	}
+	assert.Equal(t, map[string]interface{}{"timeInForce": "IOC"}, lastParams())
+	o, e = c.GetOrderStatus(txID, pair)
+	if assert.NoError(t, e) {
+		assert.Equal(t, model.OrderStatusCanceled, o.Status)
+	}
+
+	server.Unsupport("createOrder")
+	_, e = c.AddOrder(makeOrder())
+	assert.Equal(t, api.ErrorKindUnsupported, api.ErrorKindOf(e))
+}
+
+func TestCcxtExchangeBatchOrders(t *testing.T) {
+	server := makeTestCcxtServer()
+	defer server.Close()
+	defer sdk.SetBaseURL(sdk.DefaultCcxtBaseURL)
+	if e := sdk.SetBaseURL(server.URL); !assert.NoError(t, e) {
+		return
+	}
+
+	// exchanges without the bulk methods do not implement the BatchTradeAPI
+	exchange, e := makeCcxtExchange("binance", nil, []api.ExchangeAPIKey{{Key: "key0", Secret: "secret0"}}, []api.ExchangeParam{}, []api.ExchangeHeader{}, false)
+	if !assert.NoError(t, e) {
+		return
+	}
+	_, ok := exchange.(api.BatchTradeAPI)
+	assert.False(t, ok)
+
+	server.SetHas("createOrders", true)
+	server.SetHas("cancelOrders", "emulated")
+	exchange, e = makeCcxtExchange("binance", nil, []api.ExchangeAPIKey{{Key: "key0", Secret: "secret0"}}, []api.ExchangeParam{}, []api.ExchangeHeader{}, false)
+	if !assert.NoError(t, e) {
+		return
+	}
+	batchAPI, ok := exchange.(api.BatchTradeAPI)
+	if !assert.True(t, ok) {
+		return
+	}
+
+	// the last order needs more XLM than is left in the account
+	pair := model.TradingPair{Base: model.XLM, Quote: model.BTC}
+	orders := []*model.Order{}
+	for _, volume := range []float64{100, 100, 100, 100, 100, 100, 500} {
+		orders = append(orders, &model.Order{
+			Pair:        &pair,
+			OrderAction: model.OrderActionSell,
+			OrderType:   model.OrderTypeLimit,
+			Price:       model.NumberFromFloat(0.00003, 8),
+			Volume:      model.NumberFromFloat(volume, 0),
+		})
+	}
+	addResults, e := batchAPI.AddOrders(orders)
+	if !assert.NoError(t, e) || !assert.Equal(t, len(orders), len(addResults)) {
+		return
+	}
+	txIDs := []*model.TransactionID{}
+	for _, r := range addResults[:6] {
+		if assert.NoError(t, r.Error) {
+			txIDs = append(txIDs, r.TxID)
+		}
+	}
+	assert.Nil(t, addResults[6].TxID)
+	assert.Equal(t, api.ErrorKindInsufficientFunds, api.ErrorKindOf(addResults[6].Error))
+
+	cancelResults, e := batchAPI.CancelOrders(txIDs, pair)
+	if !assert.NoError(t, e) || !assert.Equal(t, len(txIDs), len(cancelResults)) {
+		return
+	}
+	for _, r := range cancelResults {
+		assert.NoError(t, r.Error)
+		assert.Equal(t, model.CancelResultCancelSuccessful, r.Result)
+	}
+
+	// the orders are split into batches of ccxtBatchMaxOrders
+	numCalls := map[string]int{}
+	for _, call := range server.Calls() {
+		numCalls[call.Method]++
+	}
+	assert.Equal(t, 2, numCalls["createOrders"])
+	assert.Equal(t, 2, numCalls["cancelOrders"])
+	assert.Equal(t, 0, numCalls["createOrder"])
+
+	m, e := exchange.GetOpenOrders([]*model.TradingPair{&pair})
+	if assert.NoError(t, e) {
+		assert.Equal(t, 0, len(m[pair]))
+	}
+}
+
+func TestCcxtExchangeModifyOrder(t *testing.T) {
+	server := makeTestCcxtServer()
+	defer server.Close()
+	defer sdk.SetBaseURL(sdk.DefaultCcxtBaseURL)
+	if e := sdk.SetBaseURL(server.URL); !assert.NoError(t, e) {
+		return
+	}
+
+	// an emulated editOrder is a cancel followed by a create so the exchange does not implement ModifyOrder
+	server.SetHas("editOrder", "emulated")
+	exchange, e := makeCcxtExchange("binance", nil, []api.ExchangeAPIKey{{Key: "key0", Secret: "secret0"}}, []api.ExchangeParam{}, []api.ExchangeHeader{}, false)
+	if !assert.NoError(t, e) {
+		return
+	}
+	_, ok := exchange.(api.ModifyOrder)
+	assert.False(t, ok)
+
+	// the exchange keeps the bulk methods when it can also edit orders
+	server.SetHas("editOrder", true)
+	server.SetHas("createOrders", true)
+	server.SetHas("cancelOrders", true)
+	exchange, e = makeCcxtExchange("binance", nil, []api.ExchangeAPIKey{{Key: "key0", Secret: "secret0"}}, []api.ExchangeParam{}, []api.ExchangeHeader{}, false)
+	if !assert.NoError(t, e) {
+		return
+	}
+	_, ok = exchange.(api.BatchTradeAPI)
+	assert.True(t, ok)
+	modifyAPI, ok := exchange.(api.ModifyOrder)
+	if !assert.True(t, ok) {
+		return
+	}
+
+	pair := model.TradingPair{Base: model.XLM, Quote: model.BTC}
+	order := &model.Order{
+		Pair:        &pair,
+		OrderAction: model.OrderActionSell,
+		OrderType:   model.OrderTypeLimit,
+		Price:       model.NumberFromFloat(0.00003, 8),
+		Volume:      model.NumberFromFloat(100, 0),
+	}
+	txID, e := exchange.AddOrder(order)
+	if !assert.NoError(t, e) {
+		return
+	}
+
+	order.Price = model.NumberFromFloat(0.00004, 8)
+	order.Volume = model.NumberFromFloat(80, 0)
+	modifiedTxID, e := modifyAPI.ModifyOrder(txID, order)
+	if !assert.NoError(t, e) {
+		return
+	}
+
+	m, e := exchange.GetOpenOrders([]*model.TradingPair{&pair})
+	if assert.NoError(t, e) && assert.Equal(t, 1, len(m[pair])) {
+		assert.Equal(t, modifiedTxID.String(), m[pair][0].ID)
+		assert.Equal(t, "0.00004000", m[pair][0].Price.AsString())
+		assert.Equal(t, "80", m[pair][0].Volume.AsString())
+	}
+
+	// the original order is gone so it cannot be edited again
+	_, e = modifyAPI.ModifyOrder(txID, order)
+	assert.Equal(t, api.ErrorKindOrderNotFound, api.ErrorKindOf(e))
+}
@@ -200,16 +200,29 @@ func loadExchanges() {
 			TradeEnabled: true,
 			Tested:       true,
 			makeFn: func(exchangeFactoryData exchangeFactoryData) (api.Exchange, error) {
-				return p2pb2b.MakeP2PB2BExchange(exchangeFactoryData.apiKeys, exchangeFactoryData.simMode)
+				return p2pb2b.MakeP2PB2BExchange(exchangeFactoryData.apiKeys, exchangeFactoryData.exchangeParams, exchangeFactoryData.simMode)
 			},
 		},
 		"stronghold": {
-			SortOrder: 5,
-			Description: "P2PB2B is an Estonian cryptocurrency exchange (https://p2pb2b.io/)",
-			TradeEnabled: false,
+			SortOrder:    1,
+			Description:  "Stronghold is a digital asset exchange built on the Stellar network (https://stronghold.co/)",
+			TradeEnabled: true,
 			Tested:       false,
 			makeFn: func(exchangeFactoryData exchangeFactoryData) (api.Exchange, error) {
-				return makeStrongholdExchange(exchangeFactoryData.apiKeys, exchangeFactoryData.simMode)
+				return makeStrongholdExchange(
+					exchangeFactoryData.apiKeys,
+					exchangeFactoryData.exchangeParams,
+					exchangeFactoryData.simMode,
+				)
+			},
+		},
+		"paper": {
+			SortOrder:    2,
+			Description:  "Paper trading against the market data of another exchange, without risking any funds",
+			TradeEnabled: true,
+			Tested:       false,
+			makeFn: func(exchangeFactoryData exchangeFactoryData) (api.Exchange, error) {
+				return makePaperExchange(exchangeFactoryData.exchangeParams, exchangeFactoryData.headers)
 			},
 		},
 	}
@@ -248,11 +261,18 @@ func loadExchanges() {
 
 // MakeExchange is a factory method to make an exchange based on a given type
 func MakeExchange(exchangeType string, simMode bool) (api.Exchange, error) {
+	return makeExchangeWithParams(exchangeType, nil, nil, simMode)
+}
+
+// makeExchangeWithParams makes an exchange without API keys that is configured with the params and headers
+func makeExchangeWithParams(exchangeType string, exchangeParams []api.ExchangeParam, headers []api.ExchangeHeader, simMode bool) (api.Exchange, error) {
 	if exchange, ok := getExchanges()[exchangeType]; ok {
 		exchangeAPIKey := api.ExchangeAPIKey{Key: "", Secret: ""}
 		x, e := exchange.makeFn(exchangeFactoryData{
-			simMode: simMode,
-			apiKeys: []api.ExchangeAPIKey{exchangeAPIKey},
+			simMode:        simMode,
+			apiKeys:        []api.ExchangeAPIKey{exchangeAPIKey},
+			exchangeParams: exchangeParams,
+			headers:        headers,
 		})
 		if e != nil {
 			return nil, fmt.Errorf("error when making the '%s' exchange: %s", exchangeType, e)
@@ -91,12 +91,12 @@ func (f *FillTracker) TrackFills() error {
 
 		tradeHistoryResult, e := f.fillTrackable.GetTradeHistory(*f.GetPair(), lastCursor, nil)
 		if e != nil {
-			eMsg := fmt.Sprintf("error when fetching trades: %s", e)
+			eMsg := fmt.Sprintf("error when fetching trades (kind=%s): %s", api.ErrorKindOf(e), e)
 			if f.countError() {
 				return fmt.Errorf(eMsg)
 			}
 			log.Printf("%s\n", eMsg)
-			f.sleep()
+			f.sleepAfterError(e)
 			continue
 		}
 
@@ -140,6 +140,41 @@ func (f *FillTracker) sleep() {
 	time.Sleep(time.Duration(f.fillTrackerSleepMillis) * time.Millisecond)
 }
 
+// maxRateLimitBackoffShift caps the exponential backoff used when we are rate limited without being told how long to wait
+const maxRateLimitBackoffShift = 5
+
+// sleepAfterError sleeps for longer than usual when the exchange is throttling us so the next request does not fail too
+func (f *FillTracker) sleepAfterError(e error) {
+	sleepTime := f.backoffAfterError(e)
+	if sleepTime != time.Duration(f.fillTrackerSleepMillis)*time.Millisecond {
+		log.Printf("backing off for %s before fetching trades again\n", sleepTime)
+	}
+	time.Sleep(sleepTime)
+}
+
+func (f *FillTracker) backoffAfterError(e error) time.Duration {
+	sleepTime := time.Duration(f.fillTrackerSleepMillis) * time.Millisecond
+	if api.ErrorKindOf(e) != api.ErrorKindRateLimited {
+		return sleepTime
+	}
+
+	if retryAfter := api.RetryAfter(e); retryAfter > 0 {
+		if retryAfter > sleepTime {
+			return retryAfter
+		}
+		return sleepTime
+	}
+
+	shift := f.fillTrackerDeleteCycles
+	if shift > maxRateLimitBackoffShift {
+		shift = maxRateLimitBackoffShift
+	}
+	if shift < 1 {
+		shift = 1
+	}
+	return sleepTime << uint(shift)
+}
+
 func handlePanic(ech chan error) {
 	if r := recover(); r != nil {
 		e := r.(error)
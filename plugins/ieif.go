@@ -237,7 +237,7 @@ func (ieif *IEIF) pairLiabilities(asset horizon.Asset, otherAsset horizon.Asset)
 // liabilities returns the asset liabilities and pairLiabilities (non-nil only if the other asset is specified)
 func (ieif *IEIF) _liabilities(asset horizon.Asset, otherAsset horizon.Asset) (*Liabilities, *Liabilities, error) {
 	// uses all offers for this trading account to accommodate sharing by other bots
-	offers, err := ieif.exchangeShim.LoadOffersHack()
+	offers, err := ieif.exchangeShim.LoadAllOffersHack()
 	if err != nil {
 		assetString := utils.Asset2String(asset)
 		log.Printf("error: cannot load offers to compute liabilities for asset (%s): %s\n", assetString, err)
@@ -1,14 +1,22 @@
 package plugins
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
 	"math"
+	"net/http"
+	"net/url"
 	"reflect"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Beldur/kraken-go-api-client"
@@ -20,6 +28,12 @@ import (
 // ensure that krakenExchange conforms to the Exchange interface
 var _ api.Exchange = &krakenExchange{}
 
+// ensure that krakenExchange can look up orders
+var _ api.OrderStatusAPI = &krakenExchange{}
+
+// ensure that krakenExchange can amend orders
+var _ api.ModifyOrder = &krakenExchange{}
+
 const precisionBalances = 10
 
 // krakenExchange is the implementation for the Kraken Exchange
@@ -27,9 +41,12 @@ type krakenExchange struct {
 	assetConverter           *model.AssetConverter
 	assetConverterOpenOrders *model.AssetConverter // kraken uses different symbols when fetching open orders!
 	apis                     []*krakenapi.KrakenApi
-	apiNextIndex             uint8
+	apiKeys                  []api.ExchangeAPIKey // used to sign the requests to endpoints that the kraken client does not support
+	keyPool                  *networking.KeyPool
+	httpClient               *http.Client // the same client that the kraken client sends its requests over
+	nonces                   []*krakenNonce // one per API key, shared by the kraken client and our signed requests
 	delimiter                string
-	ocOverridesHandler       *OrderConstraintsOverridesHandler
+	ocOverridesHandler       *model.OrderConstraintsOverridesHandler
 	withdrawKeys             asset2Address2Key
 	isSimulated              bool // will simulate add and cancel orders if this is true
 }
@@ -63,25 +80,120 @@ func makeKrakenExchange(apiKeys []api.ExchangeAPIKey, isSimulated bool) (api.Exc
 		krakenAPIs = append(krakenAPIs, krakenAPIClient)
 	}
 
+	keyPool, e := networking.MakeKeyPool("kraken", len(krakenAPIs), krakenRateLimits)
+	if e != nil {
+		return nil, fmt.Errorf("could not make key pool: %s", e)
+	}
+
 	return &krakenExchange{
 		assetConverter:           model.KrakenAssetConverter,
 		assetConverterOpenOrders: model.KrakenAssetConverterOpenOrders,
 		apis:               krakenAPIs,
-		apiNextIndex:       0,
+		apiKeys:            apiKeys,
+		keyPool:            keyPool,
+		httpClient:         http.DefaultClient,
+		nonces:             makeKrakenNonces(len(apiKeys)),
 		delimiter:          "",
-		ocOverridesHandler: MakeEmptyOrderConstraintsOverridesHandler(),
+		ocOverridesHandler: model.MakeEmptyOrderConstraintsOverridesHandler(),
 		withdrawKeys:       asset2Address2Key{},
 		isSimulated:        isSimulated,
 	}, nil
 }
 
-// nextAPI rotates the API key being used so we can overcome rate limit issues
-func (k *krakenExchange) nextAPI() *krakenapi.KrakenApi {
-	log.Printf("returning kraken API key at index %d", k.apiNextIndex)
-	api := k.apis[k.apiNextIndex]
-	// rotate key for the next call
-	k.apiNextIndex = (k.apiNextIndex + 1) % uint8(len(k.apis))
-	return api
+// krakenRateLimits approximates the rate limits of a starter account for each API key, the funding endpoints use the
+// same call counter as the private endpoints (https://support.kraken.com/hc/en-us/articles/206548367)
+var krakenRateLimits = map[networking.EndpointClass]networking.RateLimit{
+	networking.EndpointClassPublic:  {Burst: 3, Interval: time.Second},
+	networking.EndpointClassPrivate: {Burst: 15, Interval: 3 * time.Second},
+	networking.EndpointClassTrading: {Burst: 60, Interval: time.Second},
+}
+
+// nextAPI returns the client for the API key that can call the class of endpoints soonest so we can overcome rate limit issues
+func (k *krakenExchange) nextAPI(class networking.EndpointClass) (*krakenapi.KrakenApi, int) {
+	index := k.keyPool.Acquire(class)
+	log.Printf("returning kraken API key at index %d for %s endpoint", index, class)
+	return k.apis[index], index
+}
+
+// krakenNonce is the nonce of an API key. kraken rejects a nonce that is not larger than the previous nonce of the key,
+// and the kraken client takes its nonce from the clock when it sends a request, so the private requests of a key hold
+// the nonce while they are sent to make sure they reach kraken in the order of their nonces
+type krakenNonce struct {
+	mutex sync.Mutex
+	last  int64
+}
+
+func makeKrakenNonces(numKeys int) []*krakenNonce {
+	nonces := []*krakenNonce{}
+	for i := 0; i < numKeys; i++ {
+		nonces = append(nonces, &krakenNonce{})
+	}
+	return nonces
+}
+
+// hold blocks until the previous private request of the key is done, release has to be called after the request
+func (n *krakenNonce) hold() (release func()) {
+	n.mutex.Lock()
+	return func() {
+		// requests of the kraken client used the time at which they were sent, which is before now
+		if now := time.Now().UnixNano(); now > n.last {
+			n.last = now
+		}
+		n.mutex.Unlock()
+	}
+}
+
+// next returns a nonce on the same nanosecond scale as the kraken client that is larger than all the previous nonces of
+// the key, must be called while the nonce is held
+func (n *krakenNonce) next() int64 {
+	nonce := time.Now().UnixNano()
+	if nonce <= n.last {
+		nonce = n.last + 1
+	}
+	n.last = nonce
+	return nonce
+}
+
+// nextPrivateAPI is nextAPI for the private endpoints, the nonce of the key is held until release is called after the
+// request is sent
+func (k *krakenExchange) nextPrivateAPI(class networking.EndpointClass) (*krakenapi.KrakenApi, int, func()) {
+	krakenAPI, index := k.nextAPI(class)
+	return krakenAPI, index, k.nonces[index].hold()
+}
+
+// KeyUsageMetrics impl.
+func (k *krakenExchange) KeyUsageMetrics() map[string]interface{} {
+	return k.keyPool.Metrics()
+}
+
+// keyError classifies the error and backs off from the API key when kraken throttled it
+func (k *krakenExchange) keyError(index int, e error) error {
+	e = krakenError(e)
+	if api.ErrorKindOf(e) == api.ErrorKindRateLimited {
+		k.keyPool.Throttle(index, api.RetryAfter(e))
+	}
+	return e
+}
+
+// krakenErrorRules classifies the error codes that the kraken API returns in its error messages
+var krakenErrorRules = []api.ErrorMessageRule{
+	{Substring: "Rate limit exceeded", Kind: api.ErrorKindRateLimited},
+	{Substring: "EGeneral:Too many requests", Kind: api.ErrorKindRateLimited},
+	// the nonce is rejected when requests with the same key race each other, sending it again fixes it
+	{Substring: "EAPI:Invalid nonce", Kind: api.ErrorKindTransient},
+	{Substring: "EAPI:Invalid key", Kind: api.ErrorKindAuth},
+	{Substring: "EAPI:Invalid signature", Kind: api.ErrorKindAuth},
+	{Substring: "EGeneral:Permission denied", Kind: api.ErrorKindAuth},
+	{Substring: "EOrder:Insufficient funds", Kind: api.ErrorKindInsufficientFunds},
+	{Substring: "EOrder:Unknown order", Kind: api.ErrorKindOrderNotFound},
+	{Substring: "EOrder:", Kind: api.ErrorKindInvalidOrder},
+	{Substring: "EService:", Kind: api.ErrorKindTransient},
+	{Substring: "EGeneral:Internal error", Kind: api.ErrorKindTransient},
+}
+
+// krakenError classifies the errors returned by the kraken client so the trader can react to them, see api.ExchangeError
+func krakenError(e error) error {
+	return api.ClassifyErrorMessage("kraken", e, krakenErrorRules)
 }
 
 // AddOrder impl.
@@ -90,34 +202,40 @@ func (k *krakenExchange) AddOrder(order *model.Order) (*model.TransactionID, err
 	if e != nil {
 		return nil, e
 	}
+	e = checkKrakenOrderFlags(order)
+	if e != nil {
+		return nil, e
+	}
 
 	if k.isSimulated {
 		log.Printf("not adding order to Kraken in simulation mode, order=%s\n", *order)
 		return model.MakeTransactionID("simulated"), nil
 	}
 
-	orderConstraints := k.GetOrderConstraints(order.Pair)
-	if order.Price.Precision() > orderConstraints.PricePrecision {
-		return nil, fmt.Errorf("kraken price precision can be a maximum of %d, got %d, value = %.12f", orderConstraints.PricePrecision, order.Price.Precision(), order.Price.AsFloat())
-	}
-	if order.Volume.Precision() > orderConstraints.VolumePrecision {
-		return nil, fmt.Errorf("kraken volume precision can be a maximum of %d, got %d, value = %.12f", orderConstraints.VolumePrecision, order.Volume.Precision(), order.Volume.AsFloat())
+	e = k.checkOrderPrecision(order)
+	if e != nil {
+		return nil, e
 	}
 
 	args := map[string]string{
 		"price": order.Price.AsString(),
 	}
+	if order.PostOnly {
+		args["oflags"] = "post"
+	}
 	log.Printf("kraken is submitting order: pair=%s, orderAction=%s, orderType=%s, volume=%s, price=%s\n",
 		pairStr, order.OrderAction.String(), order.OrderType.String(), order.Volume.AsString(), order.Price.AsString())
-	resp, e := k.nextAPI().AddOrder(
+	krakenAPI, key, release := k.nextPrivateAPI(networking.EndpointClassTrading)
+	resp, e := krakenAPI.AddOrder(
 		pairStr,
 		order.OrderAction.String(),
 		order.OrderType.String(),
 		order.Volume.AsString(),
 		args,
 	)
+	release()
 	if e != nil {
-		return nil, e
+		return nil, k.keyError(key, e)
 	}
 
 	// expected case for production orders
@@ -132,6 +250,135 @@ func (k *krakenExchange) AddOrder(order *model.Order) (*model.TransactionID, err
 	return nil, fmt.Errorf("no transactionIds returned from order creation")
 }
 
+// checkKrakenOrderFlags rejects the optional order fields that we cannot send to kraken, the kraken client only sends
+// the oflags from them and drops timeinforce and userref
+func checkKrakenOrderFlags(order *model.Order) error {
+	if order.TimeInForce != model.TimeInForceGTC {
+		return api.MakeErrUnsupported("kraken", fmt.Sprintf("time in force %s", order.TimeInForce))
+	}
+	if order.ClientOrderID != "" {
+		return api.MakeErrUnsupported("kraken", "client order ID")
+	}
+	return nil
+}
+
+func (k *krakenExchange) checkOrderPrecision(order *model.Order) error {
+	orderConstraints := k.GetOrderConstraints(order.Pair)
+	if order.Price.Precision() > orderConstraints.PricePrecision {
+		return fmt.Errorf("kraken price precision can be a maximum of %d, got %d, value = %.12f", orderConstraints.PricePrecision, order.Price.Precision(), order.Price.AsFloat())
+	}
+	if order.Volume.Precision() > orderConstraints.VolumePrecision {
+		return fmt.Errorf("kraken volume precision can be a maximum of %d, got %d, value = %.12f", orderConstraints.VolumePrecision, order.Volume.Precision(), order.Volume.AsFloat())
+	}
+	return nil
+}
+
+// krakenAPIURL is where we send the requests to endpoints that the kraken client does not support, tests point it at a
+// fake server
+var krakenAPIURL = krakenapi.APIURL
+
+// krakenEditOrderResult is the result of the EditOrder endpoint
+type krakenEditOrderResult struct {
+	Status       string `json:"status"`
+	TxID         string `json:"txid"`
+	OriginalTxID string `json:"originaltxid"`
+}
+
+// ModifyOrder impl. kraken replaces the open order with a new order that has a new ID, there is no EditOrder in the
+// kraken client so we sign the request ourselves
+func (k *krakenExchange) ModifyOrder(txID *model.TransactionID, order *model.Order) (*model.TransactionID, error) {
+	pairStr, e := order.Pair.ToString(k.assetConverter, k.delimiter)
+	if e != nil {
+		return nil, e
+	}
+	e = checkKrakenOrderFlags(order)
+	if e != nil {
+		return nil, e
+	}
+
+	if k.isSimulated {
+		log.Printf("not modifying order %s on Kraken in simulation mode, order=%s\n", txID.String(), *order)
+		return txID, nil
+	}
+
+	e = k.checkOrderPrecision(order)
+	if e != nil {
+		return nil, e
+	}
+
+	values := url.Values{}
+	values.Set("txid", txID.String())
+	values.Set("pair", pairStr)
+	values.Set("volume", order.Volume.AsString())
+	values.Set("price", order.Price.AsString())
+	if order.PostOnly {
+		values.Set("oflags", "post")
+	}
+	log.Printf("kraken is modifying order: ID=%s, pair=%s, orderAction=%s, volume=%s, price=%s\n",
+		txID.String(), pairStr, order.OrderAction.String(), order.Volume.AsString(), order.Price.AsString())
+	var result krakenEditOrderResult
+	e = k.privateRequest(networking.EndpointClassTrading, "EditOrder", values, &result)
+	if e != nil {
+		return nil, api.WrapError(e, "error while modifying order %s", txID.String())
+	}
+
+	if result.TxID == "" {
+		return nil, fmt.Errorf("no transactionId returned from modifying order %s, status = %s", txID.String(), result.Status)
+	}
+	return model.MakeTransactionID(result.TxID), nil
+}
+
+// privateRequest calls the private endpoint with the API key that the key pool hands out for the class, the request
+// is signed the same way as the kraken client does it (https://www.kraken.com/features/api#general-usage)
+func (k *krakenExchange) privateRequest(class networking.EndpointClass, method string, values url.Values, result interface{}) error {
+	_, index, release := k.nextPrivateAPI(class)
+	e := k.signedRequest(k.apiKeys[index], k.nonces[index].next(), method, values, result)
+	release()
+	if e != nil {
+		return k.keyError(index, e)
+	}
+	return nil
+}
+
+// signedRequest signs and sends the request to the private endpoint with the API key and nonce
+func (k *krakenExchange) signedRequest(apiKey api.ExchangeAPIKey, nonce int64, method string, values url.Values, result interface{}) error {
+	secret, e := base64.StdEncoding.DecodeString(apiKey.Secret)
+	if e != nil {
+		return fmt.Errorf("could not decode the API secret: %s", e)
+	}
+
+	urlPath := fmt.Sprintf("/%s/private/%s", krakenapi.APIVersion, method)
+	values.Set("nonce", strconv.FormatInt(nonce, 10))
+	shaSum := sha256.Sum256([]byte(values.Get("nonce") + values.Encode()))
+	mac := hmac.New(sha512.New, secret)
+	mac.Write(append([]byte(urlPath), shaSum[:]...))
+	headers := map[string]string{
+		"API-Key":      apiKey.Key,
+		"API-Sign":     base64.StdEncoding.EncodeToString(mac.Sum(nil)),
+		"Content-Type": "application/x-www-form-urlencoded",
+		"User-Agent":   krakenapi.APIUserAgent,
+	}
+
+	var resp struct {
+		Error  []string        `json:"error"`
+		Result json.RawMessage `json:"result"`
+	}
+	e = networking.JSONRequest(k.httpClient, "POST", krakenAPIURL+urlPath, values.Encode(), headers, &resp, "")
+	if e != nil {
+		return e
+	}
+	if len(resp.Error) > 0 {
+		// the error codes are in the same format as the errors from the kraken client so krakenErrorRules apply
+		return fmt.Errorf("could not execute request (%s)", resp.Error)
+	}
+
+	e = json.Unmarshal(resp.Result, result)
+	if e != nil {
+		return fmt.Errorf("could not unmarshal the result of %s: %s", method, e)
+	}
+	return nil
+}
+
 // CancelOrder impl.
 func (k *krakenExchange) CancelOrder(txID *model.TransactionID, pair model.TradingPair) (model.CancelOrderResult, error) {
 	if k.isSimulated {
@@ -140,9 +387,11 @@ func (k *krakenExchange) CancelOrder(txID *model.TransactionID, pair model.Tradi
 	log.Printf("kraken is canceling order: ID=%s, tradingPair=%s\n", txID.String(), pair.String())
 
 	// we don't actually use the pair for kraken
-	resp, e := k.nextAPI().CancelOrder(txID.String())
+	krakenAPI, key, release := k.nextPrivateAPI(networking.EndpointClassTrading)
+	resp, e := krakenAPI.CancelOrder(txID.String())
+	release()
 	if e != nil {
-		return model.CancelResultFailed, e
+		return model.CancelResultFailed, k.keyError(key, e)
 	}
 
 	if resp.Count > 1 {
@@ -163,9 +412,11 @@ func (k *krakenExchange) CancelOrder(txID *model.TransactionID, pair model.Tradi
 
 // GetAccountBalances impl.
 func (k *krakenExchange) GetAccountBalances(assetList []interface{}) (map[interface{}]model.Number, error) {
-	balanceResponse, e := k.nextAPI().Balance()
+	krakenAPI, key, release := k.nextPrivateAPI(networking.EndpointClassPrivate)
+	balanceResponse, e := krakenAPI.Balance()
+	release()
 	if e != nil {
-		return nil, e
+		return nil, k.keyError(key, e)
 	}
 
 	m := map[interface{}]model.Number{}
@@ -220,9 +471,11 @@ func (k *krakenExchange) GetAssetConverter() *model.AssetConverter {
 
 // GetOpenOrders impl.
 func (k *krakenExchange) GetOpenOrders(pairs []*model.TradingPair) (map[model.TradingPair][]model.OpenOrder, error) {
-	openOrdersResponse, e := k.nextAPI().OpenOrders(map[string]string{})
+	krakenAPI, key, release := k.nextPrivateAPI(networking.EndpointClassPrivate)
+	openOrdersResponse, e := krakenAPI.OpenOrders(map[string]string{})
+	release()
 	if e != nil {
-		return nil, fmt.Errorf("cannot load open orders for Kraken: %s", e)
+		return nil, k.keyError(key, e)
 	}
 
 	// convert to a map so we can easily search for the existence of a trading pair
@@ -253,21 +506,79 @@ func (k *krakenExchange) GetOpenOrders(pairs []*model.TradingPair) (map[model.Tr
 			return nil, fmt.Errorf("open orders are listed with repeated base/quote pairs for %s", *pair)
 		}
 
-		orderConstraints := k.GetOrderConstraints(pair)
-		m[*pair] = append(m[*pair], model.OpenOrder{
-			Order: model.Order{
-				Pair:        pair,
-				OrderAction: model.OrderActionFromString(o.Description.Type),
-				OrderType:   model.OrderTypeFromString(o.Description.OrderType),
-				Price:       model.MustNumberFromString(o.Description.PrimaryPrice, orderConstraints.PricePrecision),
-				Volume:      model.MustNumberFromString(o.Volume, orderConstraints.VolumePrecision),
-				Timestamp:   model.MakeTimestamp(int64(o.OpenTime)),
-			},
-			ID:             ID,
-			StartTime:      model.MakeTimestamp(int64(o.StartTime)),
-			ExpireTime:     model.MakeTimestamp(int64(o.ExpireTime)),
-			VolumeExecuted: model.NumberFromFloat(o.VolumeExecuted, orderConstraints.VolumePrecision),
-		})
+		m[*pair] = append(m[*pair], k.convertOrder(ID, o, pair))
+	}
+	return m, nil
+}
+
+func (k *krakenExchange) convertOrder(ID string, o krakenapi.Order, pair *model.TradingPair) model.OpenOrder {
+	orderConstraints := k.GetOrderConstraints(pair)
+	volume := model.MustNumberFromString(o.Volume, orderConstraints.VolumePrecision)
+	volumeExecuted := model.NumberFromFloat(o.VolumeExecuted, orderConstraints.VolumePrecision)
+	status := model.OrderStatusOpen
+	if o.Status == "closed" {
+		status = model.OrderStatusFilled
+	} else if o.Status == "canceled" || o.Status == "expired" {
+		status = model.OrderStatusCanceled
+	}
+
+	return model.OpenOrder{
+		Order: model.Order{
+			Pair:        pair,
+			OrderAction: model.OrderActionFromString(o.Description.Type),
+			OrderType:   model.OrderTypeFromString(o.Description.OrderType),
+			Price:       model.MustNumberFromString(o.Description.PrimaryPrice, orderConstraints.PricePrecision),
+			Volume:      volume,
+			Timestamp:   model.MakeTimestamp(int64(o.OpenTime)),
+		},
+		ID:              ID,
+		StartTime:       model.MakeTimestamp(int64(o.StartTime)),
+		ExpireTime:      model.MakeTimestamp(int64(o.ExpireTime)),
+		VolumeExecuted:  volumeExecuted,
+		VolumeRemaining: volume.Subtract(*volumeExecuted),
+		Status:          status,
+	}
+}
+
+// krakenMaxQueryOrders is the maximum number of transaction IDs that kraken accepts in a single QueryOrders call
+const krakenMaxQueryOrders = 50
+
+// GetOrderStatus impl.
+func (k *krakenExchange) GetOrderStatus(txID *model.TransactionID, pair model.TradingPair) (*model.OpenOrder, error) {
+	m, e := k.QueryOrders([]*model.TransactionID{txID}, pair)
+	if e != nil {
+		return nil, e
+	}
+
+	o, ok := m[txID.String()]
+	if !ok {
+		return nil, api.MakeExchangeError(api.ErrorKindOrderNotFound, "kraken", fmt.Errorf("order %s was not found", txID.String()))
+	}
+	return &o, nil
+}
+
+// QueryOrders impl.
+func (k *krakenExchange) QueryOrders(txIDs []*model.TransactionID, pair model.TradingPair) (map[string]model.OpenOrder, error) {
+	m := map[string]model.OpenOrder{}
+	for start := 0; start < len(txIDs); start += krakenMaxQueryOrders {
+		end := start + krakenMaxQueryOrders
+		if end > len(txIDs) {
+			end = len(txIDs)
+		}
+		ids := []string{}
+		for _, txID := range txIDs[start:end] {
+			ids = append(ids, txID.String())
+		}
+
+		krakenAPI, key, release := k.nextPrivateAPI(networking.EndpointClassPrivate)
+		resp, e := krakenAPI.QueryOrders(strings.Join(ids, ","), map[string]string{})
+		release()
+		if e != nil {
+			return nil, k.keyError(key, e)
+		}
+		for ID, o := range *resp {
+			m[ID] = k.convertOrder(ID, o, &pair)
+		}
 	}
 	return m, nil
 }
@@ -279,9 +590,10 @@ func (k *krakenExchange) GetOrderBook(pair *model.TradingPair, maxCount int32) (
 		return nil, e
 	}
 
-	krakenob, e := k.nextAPI().Depth(pairStr, int(maxCount))
+	krakenAPI, key := k.nextAPI(networking.EndpointClassPublic)
+	krakenob, e := krakenAPI.Depth(pairStr, int(maxCount))
 	if e != nil {
-		return nil, e
+		return nil, k.keyError(key, e)
 	}
 
 	asks := k.readOrders(krakenob.Asks, pair, model.OrderActionSell)
@@ -313,9 +625,10 @@ func (k *krakenExchange) GetTickerPrice(pairs []model.TradingPair) (map[model.Tr
 		return nil, e
 	}
 
-	resp, e := k.nextAPI().Ticker(values(pairsMap)...)
+	krakenAPI, key := k.nextAPI(networking.EndpointClassPublic)
+	resp, e := krakenAPI.Ticker(values(pairsMap)...)
 	if e != nil {
-		return nil, e
+		return nil, k.keyError(key, e)
 	}
 
 	priceResult := map[model.TradingPair]api.Ticker{}
@@ -367,9 +680,11 @@ func (k *krakenExchange) getTradeHistory(tradingPair model.TradingPair, maybeCur
 		input["end"] = *maybeCursorEnd
 	}
 
-	resp, e := k.nextAPI().Query("TradesHistory", input)
+	krakenAPI, key, release := k.nextPrivateAPI(networking.EndpointClassPrivate)
+	resp, e := krakenAPI.Query("TradesHistory", input)
+	release()
 	if e != nil {
-		return nil, e
+		return nil, k.keyError(key, e)
 	}
 	krakenResp := resp.(map[string]interface{})
 	krakenTrades := krakenResp["trades"].(map[string]interface{})
@@ -455,13 +770,14 @@ func (k *krakenExchange) getTrades(pair *model.TradingPair, maybeCursor *int64)
 	}
 
 	var tradesResp *krakenapi.TradesResponse
+	krakenAPI, key := k.nextAPI(networking.EndpointClassPublic)
 	if maybeCursor != nil {
-		tradesResp, e = k.nextAPI().Trades(pairStr, *maybeCursor)
+		tradesResp, e = krakenAPI.Trades(pairStr, *maybeCursor)
 	} else {
-		tradesResp, e = k.nextAPI().Trades(pairStr, -1)
+		tradesResp, e = krakenAPI.Trades(pairStr, -1)
 	}
 	if e != nil {
-		return nil, e
+		return nil, k.keyError(key, e)
 	}
 
 	orderConstraints := k.GetOrderConstraints(pair)
@@ -536,7 +852,8 @@ func (k *krakenExchange) GetWithdrawInfo(
 	if e != nil {
 		return nil, e
 	}
-	resp, e := k.nextAPI().Query(
+	krakenAPI, key, release := k.nextPrivateAPI(networking.EndpointClassPrivate)
+	resp, e := krakenAPI.Query(
 		"WithdrawInfo",
 		map[string]string{
 			"asset":  krakenAsset,
@@ -544,8 +861,9 @@ func (k *krakenExchange) GetWithdrawInfo(
 			"amount": amountToWithdraw.AsString(),
 		},
 	)
+	release()
 	if e != nil {
-		return nil, e
+		return nil, k.keyError(key, e)
 	}
 
 	return parseWithdrawInfoResponse(resp, amountToWithdraw)
@@ -674,12 +992,14 @@ type depositMethod struct {
 }
 
 func (k *krakenExchange) getDepositMethods(asset string) (*depositMethod, error) {
-	resp, e := k.nextAPI().Query(
+	krakenAPI, key, release := k.nextPrivateAPI(networking.EndpointClassPrivate)
+	resp, e := krakenAPI.Query(
 		"DepositMethods",
 		map[string]string{"asset": asset},
 	)
+	release()
 	if e != nil {
-		return nil, e
+		return nil, k.keyError(key, e)
 	}
 
 	switch arr := resp.(type) {
@@ -710,9 +1030,11 @@ func (k *krakenExchange) getDepositAddress(asset string, method string, genAddre
 		// only set "new" if it's supposed to be 'true'. If you set it to 'false' then it will be treated as true by Kraken :(
 		input["new"] = "true"
 	}
-	resp, e := k.nextAPI().Query("DepositAddresses", input)
+	krakenAPI, key, release := k.nextPrivateAPI(networking.EndpointClassPrivate)
+	resp, e := krakenAPI.Query("DepositAddresses", input)
+	release()
 	if e != nil {
-		return []depositAddress{}, e
+		return []depositAddress{}, k.keyError(key, e)
 	}
 
 	addressList := []depositAddress{}
@@ -829,7 +1151,8 @@ func (k *krakenExchange) WithdrawFunds(
 	if e != nil {
 		return nil, e
 	}
-	resp, e := k.nextAPI().Query(
+	krakenAPI, key, release := k.nextPrivateAPI(networking.EndpointClassPrivate)
+	resp, e := krakenAPI.Query(
 		"Withdraw",
 		map[string]string{
 			"asset":  krakenAsset,
@@ -837,8 +1160,9 @@ func (k *krakenExchange) WithdrawFunds(
 			"amount": amountToWithdraw.AsString(),
 		},
 	)
+	release()
 	if e != nil {
-		return nil, e
+		return nil, k.keyError(key, e)
 	}
 
 	return parseWithdrawResponse(resp)
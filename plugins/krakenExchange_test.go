@@ -2,7 +2,10 @@ package plugins
 
 import (
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"strconv"
+	"sync"
 	"testing"
 	"time"
 
@@ -10,6 +13,7 @@ import (
 
 	"github.com/Beldur/kraken-go-api-client"
 	"github.com/stellar/kelp/model"
+	"github.com/stellar/kelp/support/networking"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -17,13 +21,22 @@ var testKrakenExchange api.Exchange = &krakenExchange{
 	assetConverter:           model.KrakenAssetConverter,
 	assetConverterOpenOrders: model.KrakenAssetConverterOpenOrders,
 	apis:               []*krakenapi.KrakenApi{krakenapi.New("", "")},
-	apiNextIndex:       0,
+	keyPool:            makeTestKrakenKeyPool(),
+	nonces:             makeKrakenNonces(1),
 	delimiter:          "",
-	ocOverridesHandler: MakeEmptyOrderConstraintsOverridesHandler(),
+	ocOverridesHandler: model.MakeEmptyOrderConstraintsOverridesHandler(),
 	withdrawKeys:       asset2Address2Key{},
 	isSimulated:        true,
 }
 
+func makeTestKrakenKeyPool() *networking.KeyPool {
+	keyPool, e := networking.MakeKeyPool("kraken", 1, krakenRateLimits)
+	if e != nil {
+		panic(e)
+	}
+	return keyPool
+}
+
 func TestGetTickerPrice(t *testing.T) {
 	pair := model.TradingPair{Base: model.XLM, Quote: model.BTC}
 	pairs := []model.TradingPair{pair}
@@ -234,6 +247,123 @@ func TestAddOrder(t *testing.T) {
 	assert.Fail(t, "force fail")
 }
 
+func TestKrakenUnsupportedOrderFlags(t *testing.T) {
+	tradingPair := &model.TradingPair{Base: model.XLM, Quote: model.USD}
+	makeOrder := func() *model.Order {
+		return &model.Order{
+			Pair:        tradingPair,
+			OrderAction: model.OrderActionSell,
+			OrderType:   model.OrderTypeLimit,
+			Price:       model.NumberFromFloat(0.1, 6),
+			Volume:      model.NumberFromFloat(30, 8),
+		}
+	}
+
+	// post-only is sent as an order flag
+	order := makeOrder()
+	order.PostOnly = true
+	txID, e := testKrakenExchange.AddOrder(order)
+	if assert.NoError(t, e) {
+		assert.Equal(t, "simulated", txID.String())
+	}
+
+	for _, tif := range []model.TimeInForce{model.TimeInForceIOC, model.TimeInForceFOK} {
+		order = makeOrder()
+		order.TimeInForce = tif
+		_, e = testKrakenExchange.AddOrder(order)
+		assert.Equal(t, api.ErrorKindUnsupported, api.ErrorKindOf(e), tif.String())
+	}
+
+	order = makeOrder()
+	order.ClientOrderID = "kelp-1"
+	_, e = testKrakenExchange.AddOrder(order)
+	assert.Equal(t, api.ErrorKindUnsupported, api.ErrorKindOf(e))
+}
+
+func TestKrakenModifyOrder(t *testing.T) {
+	nonces := []int64{}
+	var mutex sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		nonce, e := strconv.ParseInt(r.FormValue("nonce"), 10, 64)
+		if e != nil || (len(nonces) > 0 && nonce <= nonces[len(nonces)-1]) {
+			w.Write([]byte(`{"error":["EAPI:Invalid nonce"]}`))
+			return
+		}
+		nonces = append(nonces, nonce)
+		if r.URL.Path != "/0/private/EditOrder" || r.Header.Get("API-Key") != "key0" || r.Header.Get("API-Sign") == "" {
+			w.Write([]byte(`{"error":["EAPI:Invalid signature"]}`))
+			return
+		}
+		if r.FormValue("txid") != "OPEN-1" {
+			w.Write([]byte(`{"error":["EOrder:Unknown order"]}`))
+			return
+		}
+		if r.FormValue("pair") != "XXLMZUSD" || r.FormValue("volume") != "40.00000000" || r.FormValue("price") != "0.120000" {
+			w.Write([]byte(`{"error":["EGeneral:Invalid arguments"]}`))
+			return
+		}
+		w.Write([]byte(`{"error":[],"result":{"status":"ok","txid":"OPEN-2","originaltxid":"OPEN-1"}}`))
+	}))
+	defer server.Close()
+	defer func(u string) { krakenAPIURL = u }(krakenAPIURL)
+	krakenAPIURL = server.URL
+
+	k := &krakenExchange{
+		assetConverter:           model.KrakenAssetConverter,
+		assetConverterOpenOrders: model.KrakenAssetConverterOpenOrders,
+		apis:                     []*krakenapi.KrakenApi{krakenapi.New("key0", "c2VjcmV0MA==")},
+		apiKeys:                  []api.ExchangeAPIKey{{Key: "key0", Secret: "c2VjcmV0MA=="}},
+		keyPool:                  makeTestKrakenKeyPool(),
+		httpClient:               http.DefaultClient,
+		nonces:                   makeKrakenNonces(1),
+		delimiter:                "",
+		ocOverridesHandler:       model.MakeEmptyOrderConstraintsOverridesHandler(),
+		withdrawKeys:             asset2Address2Key{},
+		isSimulated:              false,
+	}
+	tradingPair := &model.TradingPair{Base: model.XLM, Quote: model.USD}
+	order := &model.Order{
+		Pair:        tradingPair,
+		OrderAction: model.OrderActionSell,
+		OrderType:   model.OrderTypeLimit,
+		Price:       model.NumberFromFloat(0.12, 6),
+		Volume:      model.NumberFromFloat(40, 8),
+	}
+
+	txID, e := k.ModifyOrder(model.MakeTransactionID("OPEN-1"), order)
+	if assert.NoError(t, e) {
+		assert.Equal(t, "OPEN-2", txID.String())
+	}
+
+	_, e = k.ModifyOrder(model.MakeTransactionID("OPEN-0"), order)
+	assert.Equal(t, api.ErrorKindOrderNotFound, api.ErrorKindOf(e))
+
+	k.apiKeys[0].Key = "key1"
+	_, e = k.ModifyOrder(model.MakeTransactionID("OPEN-1"), order)
+	assert.Equal(t, api.ErrorKindAuth, api.ErrorKindOf(e))
+
+	// every request goes through the key pool with a nonce that increases even when sent in quick succession
+	assert.Equal(t, 3, len(nonces))
+	assert.Equal(t, uint64(3), k.keyPool.Usage()[0].Requests[networking.EndpointClassTrading])
+
+	// concurrent requests with the same key reach kraken in the order of their nonces
+	k.apiKeys[0].Key = "key0"
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, e := k.ModifyOrder(model.MakeTransactionID("OPEN-1"), order)
+			assert.NoError(t, e)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 13, len(nonces))
+}
+
 func TestCancelOrder(t *testing.T) {
 	if testing.Short() {
 		return
@@ -296,3 +426,27 @@ func TestWithdrawFunds(t *testing.T) {
 	fmt.Printf("refid=%v\n", result.WithdrawalID)
 	assert.Fail(t, "force fail")
 }
+
+func TestKrakenError(t *testing.T) {
+	testCases := []struct {
+		message  string
+		wantKind api.ErrorKind
+	}{
+		{"EAPI:Rate limit exceeded", api.ErrorKindRateLimited},
+		{"EAPI:Invalid key", api.ErrorKindAuth},
+		{"EOrder:Insufficient funds", api.ErrorKindInsufficientFunds},
+		{"EOrder:Unknown order", api.ErrorKindOrderNotFound},
+		{"EOrder:Invalid price", api.ErrorKindInvalidOrder},
+		{"EService:Unavailable", api.ErrorKindTransient},
+		{"EQuery:Unknown asset pair", api.ErrorKindUnknown},
+	}
+
+	for _, kase := range testCases {
+		t.Run(kase.message, func(t *testing.T) {
+			e := krakenError(fmt.Errorf("Could not execute request! (%s)", kase.message))
+			assert.Equal(t, kase.wantKind, api.ErrorKindOf(e))
+		})
+	}
+
+	assert.Nil(t, krakenError(nil))
+}
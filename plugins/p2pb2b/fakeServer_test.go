@@ -0,0 +1,576 @@
+package p2pb2b
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// credentials accepted by the fake server
+const (
+	testP2BKey    = "4a5f8e2c1d3b7a9e6f0c2d4b8a1e3f5c"
+	testP2BSecret = "9c7e5a3f1b2d4c6e8a0f2b4d6c8e0a1f"
+)
+
+// fakeP2BFailure is an error that the fake server returns instead of handling the request
+type fakeP2BFailure struct {
+	statusCode int
+	message    string
+}
+
+// fakeP2BServer is a local stand-in for the p2pb2b API. Private endpoints check the signature of the request the way
+// p2pb2b does, independently of the client's implementation, and list endpoints are served in pages.
+type fakeP2BServer struct {
+	t      *testing.T
+	server *httptest.Server
+	mutex  *sync.Mutex
+
+	markets  []*Market
+	balances map[string]*GetAccountBalanceItem
+	tickers  map[string]*TickerPriceResult
+	// openOrders maps the market to our open orders
+	openOrders map[string]ExchangeOrders
+	// book maps the side to the orders in the book
+	book  map[string]ExchangeOrders
+	deals map[uint64][]*OrderDeal
+	// history maps the market to our finished orders, most recently finished first
+	history     map[string][]*HistoryOrder
+	nextOrderID uint64
+	nextDealID  uint64
+	failures    []fakeP2BFailure
+
+	// maxPageSize caps the limit that clients ask for, zero leaves it uncapped
+	maxPageSize int
+	// omitTotal leaves the total out of the responses
+	omitTotal bool
+	// numRequests counts the requests per path
+	numRequests map[string]int
+	// lastNonce is the nonce of the last signed request, every request needs a larger one
+	lastNonce int64
+}
+
+// makeFakeP2BServer starts a fake server that lists the XLM_BTC market
+func makeFakeP2BServer(t *testing.T) *fakeP2BServer {
+	s := &fakeP2BServer{
+		t:     t,
+		mutex: &sync.Mutex{},
+		markets: []*Market{{
+			Name:      "XLM_BTC",
+			Stock:     "XLM",
+			Money:     "BTC",
+			Precision: MarketPrecision{Money: "8", Stock: "1", Fee: "4"},
+			Limits:    MarketLimits{MinAmount: "10", MinTotal: "0.0001"},
+		}},
+		balances:    map[string]*GetAccountBalanceItem{},
+		tickers:     map[string]*TickerPriceResult{},
+		openOrders:  map[string]ExchangeOrders{},
+		book:        map[string]ExchangeOrders{},
+		deals:       map[uint64][]*OrderDeal{},
+		history:     map[string][]*HistoryOrder{},
+		nextOrderID: 1000,
+		numRequests: map[string]int{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(p2bApiPrefix+"/account/balances", s.handleBalances)
+	mux.HandleFunc(p2bApiPrefix+"/orders", s.handleOrders)
+	mux.HandleFunc(p2bApiPrefix+"/order/new", s.handleNewOrder)
+	mux.HandleFunc(p2bApiPrefix+"/order/cancel", s.handleCancelOrder)
+	mux.HandleFunc(p2bApiPrefix+"/account/order", s.handleOrderDeals)
+	mux.HandleFunc(p2bApiPrefix+"/account/order_history", s.handleOrderHistory)
+	mux.HandleFunc(p2bApiPrefix+"/public/markets", s.handleMarkets)
+	mux.HandleFunc(p2bApiPrefix+"/public/ticker", s.handleTicker)
+	mux.HandleFunc(p2bApiPrefix+"/public/book", s.handleBook)
+	s.server = httptest.NewServer(mux)
+	return s
+}
+
+// api returns a client that sends its requests to the fake server
+func (s *fakeP2BServer) api() *P2BApi {
+	return &P2BApi{baseURL: s.server.URL, key: testP2BKey, secret: testP2BSecret}
+}
+
+func (s *fakeP2BServer) close() {
+	s.server.Close()
+}
+
+// setBalance sets the available balance of the asset, nothing is frozen
+func (s *fakeP2BServer) setBalance(asset string, available float64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.balances[asset] = &GetAccountBalanceItem{Available: formatAmount(available), Freeze: "0"}
+}
+
+// failNext makes the next request fail, a status code of 200 returns an unsuccessful response with the message
+func (s *fakeP2BServer) failNext(statusCode int, message string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.failures = append(s.failures, fakeP2BFailure{statusCode: statusCode, message: message})
+}
+
+// fillOrder fills the amount of our open order and settles the balances, the order is finished once it is fully filled
+func (s *fakeP2BServer) fillOrder(id uint64, amount float64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for market, orders := range s.openOrders {
+		for i, o := range orders {
+			if o.Id != id {
+				continue
+			}
+
+			left, _ := strconv.ParseFloat(o.Left, 64)
+			dealStock, _ := strconv.ParseFloat(o.DealStock, 64)
+			price, _ := strconv.ParseFloat(o.Price, 64)
+			if amount > left {
+				s.t.Fatalf("cannot fill %f of order %d, only %f is left", amount, id, left)
+			}
+			m := s.findMarket(market)
+			if o.Side == "sell" {
+				s.settle(m.Stock, -amount)
+				s.credit(m.Money, amount*price)
+			} else {
+				s.settle(m.Money, -amount*price)
+				s.credit(m.Stock, amount)
+			}
+
+			o.Left = formatAmount(left - amount)
+			o.DealStock = formatAmount(dealStock + amount)
+			o.DealMoney = formatAmount((dealStock + amount) * price)
+			s.nextDealID++
+			s.deals[id] = append(s.deals[id], &OrderDeal{
+				Id:     s.nextDealID,
+				Time:   float64(time.Now().Unix()),
+				Price:  o.Price,
+				Amount: formatAmount(amount),
+				Deal:   formatAmount(amount * price),
+				Fee:    "0",
+				Role:   1,
+			})
+			if left == amount {
+				s.finish(market, i)
+			}
+			return
+		}
+	}
+	s.t.Fatalf("no open order with id %d", id)
+}
+
+// finish moves the open order to the order history, must be called with the mutex held
+func (s *fakeP2BServer) finish(market string, i int) {
+	orders := s.openOrders[market]
+	o := orders[i]
+	s.openOrders[market] = append(orders[:i:i], orders[i+1:]...)
+	s.history[market] = append([]*HistoryOrder{{
+		Id:        o.Id,
+		Market:    o.Market,
+		Side:      o.Side,
+		Type:      o.Type,
+		Amount:    o.Amount,
+		Price:     o.Price,
+		CTime:     o.Timestamp,
+		FTime:     float64(time.Now().Unix()),
+		DealStock: o.DealStock,
+		DealMoney: o.DealMoney,
+		DealFee:   o.DealFee,
+	}}, s.history[market]...)
+}
+
+// settle releases the amount from the frozen balance of the asset, must be called with the mutex held
+func (s *fakeP2BServer) settle(asset string, amount float64) {
+	b := s.balances[asset]
+	freeze, _ := strconv.ParseFloat(b.Freeze, 64)
+	b.Freeze = formatAmount(freeze + amount)
+}
+
+// credit adds the amount to the available balance of the asset, must be called with the mutex held
+func (s *fakeP2BServer) credit(asset string, amount float64) {
+	b, ok := s.balances[asset]
+	if !ok {
+		b = &GetAccountBalanceItem{Available: "0", Freeze: "0"}
+		s.balances[asset] = b
+	}
+	available, _ := strconv.ParseFloat(b.Available, 64)
+	b.Available = formatAmount(available + amount)
+}
+
+func (s *fakeP2BServer) requests(path string) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.numRequests[p2bApiPrefix+path]
+}
+
+// page returns the bounds of the requested page of n records along with the limit that was applied
+func (s *fakeP2BServer) page(offset int, limit int, n int) (int, int, P2BLimitOffset) {
+	if limit <= 0 {
+		// the default page size of the exchange
+		limit = 50
+	}
+	if s.maxPageSize > 0 && limit > s.maxPageSize {
+		limit = s.maxPageSize
+	}
+	start := offset
+	if start > n {
+		start = n
+	}
+	end := start + limit
+	if end > n {
+		end = n
+	}
+
+	lo := P2BLimitOffset{Offset: offset, Limit: limit}
+	if !s.omitTotal {
+		lo.Total = n
+	}
+	return start, end, lo
+}
+
+func (s *fakeP2BServer) handleBalances(w http.ResponseWriter, r *http.Request) {
+	var request GetAccountBalancesRequest
+	if !s.readRequest(w, r, &request) {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.writeResult(w, s.balances)
+}
+
+func (s *fakeP2BServer) handleOrders(w http.ResponseWriter, r *http.Request) {
+	var request GetOrdersRequest
+	if !s.readRequest(w, r, &request) {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	orders := s.openOrders[request.Market]
+	start, end, lo := s.page(request.Offset, request.Limit, len(orders))
+	records := orders[start:end]
+	s.writeResult(w, &GetOrdersResult{P2BLimitOffset: lo, Records: &records})
+}
+
+func (s *fakeP2BServer) handleNewOrder(w http.ResponseWriter, r *http.Request) {
+	var request CreateOrderRequest
+	if !s.readRequest(w, r, &request) {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	market := s.findMarket(request.Market)
+	if market == nil {
+		s.writeUnsuccessful(w, http.StatusOK, "Market is not available")
+		return
+	}
+	amount, err := strconv.ParseFloat(request.Amount, 64)
+	if err != nil || amount <= 0 {
+		s.writeUnsuccessful(w, http.StatusOK, "Invalid amount")
+		return
+	}
+	price, err := strconv.ParseFloat(request.Price, 64)
+	if err != nil || price <= 0 {
+		s.writeUnsuccessful(w, http.StatusOK, "Invalid price")
+		return
+	}
+
+	// the order freezes the stock when selling and the money when buying
+	asset, frozen := market.Money, amount*price
+	if request.Side == "sell" {
+		asset, frozen = market.Stock, amount
+	} else if request.Side != "buy" {
+		s.writeUnsuccessful(w, http.StatusOK, "Invalid side")
+		return
+	}
+	if !s.freeze(asset, frozen) {
+		s.writeUnsuccessful(w, http.StatusOK, "Balance not enough")
+		return
+	}
+
+	s.nextOrderID++
+	order := &ExchangeOrder{
+		Id:        s.nextOrderID,
+		Left:      request.Amount,
+		Market:    request.Market,
+		Amount:    request.Amount,
+		Type:      "limit",
+		Price:     request.Price,
+		Timestamp: float64(time.Now().Unix()),
+		Side:      request.Side,
+		DealFee:   "0",
+		TakerFee:  "0.002",
+		MakerFee:  "0.002",
+		DealStock: "0",
+		DealMoney: "0",
+	}
+	s.openOrders[request.Market] = append(s.openOrders[request.Market], order)
+	s.writeResult(w, order)
+}
+
+func (s *fakeP2BServer) handleCancelOrder(w http.ResponseWriter, r *http.Request) {
+	var request CancelOrderRequest
+	if !s.readRequest(w, r, &request) {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	orders := s.openOrders[request.Market]
+	for i, o := range orders {
+		if o.Id != request.Id {
+			continue
+		}
+
+		amount, _ := strconv.ParseFloat(o.Left, 64)
+		price, _ := strconv.ParseFloat(o.Price, 64)
+		market := s.findMarket(o.Market)
+		if o.Side == "sell" {
+			s.freeze(market.Stock, -amount)
+		} else {
+			s.freeze(market.Money, -amount*price)
+		}
+
+		s.finish(request.Market, i)
+		s.writeResult(w, o)
+		return
+	}
+	s.writeUnsuccessful(w, http.StatusOK, "Order not found")
+}
+
+func (s *fakeP2BServer) handleOrderDeals(w http.ResponseWriter, r *http.Request) {
+	var request GetOrderDealsRequest
+	if !s.readRequest(w, r, &request) {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	deals := s.deals[request.Id]
+	start, end, lo := s.page(request.Offset, request.Limit, len(deals))
+	s.writeResult(w, &GetOrderDealsResult{P2BLimitOffset: lo, Records: deals[start:end]})
+}
+
+func (s *fakeP2BServer) handleOrderHistory(w http.ResponseWriter, r *http.Request) {
+	var request GetOrderHistoryRequest
+	if !s.readRequest(w, r, &request) {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	result := GetOrderHistoryResult{}
+	for market, orders := range s.history {
+		start, end, _ := s.page(request.Offset, request.Limit, len(orders))
+		if start < end {
+			result[market] = orders[start:end]
+		}
+	}
+	s.writeResult(w, result)
+}
+
+func (s *fakeP2BServer) handleMarkets(w http.ResponseWriter, r *http.Request) {
+	if !s.readPublicRequest(w, r) {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.writeResult(w, s.markets)
+}
+
+func (s *fakeP2BServer) handleTicker(w http.ResponseWriter, r *http.Request) {
+	if !s.readPublicRequest(w, r) {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	ticker, ok := s.tickers[r.URL.Query().Get("market")]
+	if !ok {
+		s.writeUnsuccessful(w, http.StatusOK, "Market is not available")
+		return
+	}
+	s.writeResult(w, ticker)
+}
+
+func (s *fakeP2BServer) handleBook(w http.ResponseWriter, r *http.Request) {
+	if !s.readPublicRequest(w, r) {
+		return
+	}
+	query := r.URL.Query()
+	offset, _ := strconv.Atoi(query.Get("offset"))
+	limit, _ := strconv.Atoi(query.Get("limit"))
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	orders := s.book[query.Get("side")]
+	start, end, lo := s.page(offset, limit, len(orders))
+	page := orders[start:end]
+	s.writeResult(w, &GetOrdersResult{P2BLimitOffset: lo, Orders: &page})
+}
+
+// findMarket must be called with the mutex held
+func (s *fakeP2BServer) findMarket(name string) *Market {
+	for _, m := range s.markets {
+		if m.Name == name {
+			return m
+		}
+	}
+	return nil
+}
+
+// freeze moves the amount from the available balance of the asset to the frozen balance, a negative amount releases it.
+// It returns false when there is not enough available balance and must be called with the mutex held.
+func (s *fakeP2BServer) freeze(asset string, amount float64) bool {
+	b, ok := s.balances[asset]
+	if !ok {
+		return false
+	}
+	available, _ := strconv.ParseFloat(b.Available, 64)
+	freeze, _ := strconv.ParseFloat(b.Freeze, 64)
+	if amount > available {
+		return false
+	}
+	b.Available = formatAmount(available - amount)
+	b.Freeze = formatAmount(freeze + amount)
+	return true
+}
+
+func formatAmount(v float64) string {
+	return strconv.FormatFloat(v, 'f', 8, 64)
+}
+
+// nextFailure returns true after writing the failure when one was queued
+func (s *fakeP2BServer) nextFailure(w http.ResponseWriter, r *http.Request) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.numRequests[r.URL.Path]++
+
+	if len(s.failures) == 0 {
+		return false
+	}
+	f := s.failures[0]
+	s.failures = s.failures[1:]
+	s.writeUnsuccessful(w, f.statusCode, f.message)
+	return true
+}
+
+func (s *fakeP2BServer) readPublicRequest(w http.ResponseWriter, r *http.Request) bool {
+	if s.nextFailure(w, r) {
+		return false
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return false
+	}
+	return true
+}
+
+// readRequest authenticates the signed request and decodes its body, writing the error response when it returns false
+func (s *fakeP2BServer) readRequest(w http.ResponseWriter, r *http.Request, request interface{}) bool {
+	if s.nextFailure(w, r) {
+		return false
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return false
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		s.t.Errorf("could not read the request to %s: %s", r.URL.Path, err)
+		w.WriteHeader(http.StatusBadRequest)
+		return false
+	}
+	if err = s.authenticate(r, body); err != nil {
+		s.writeUnsuccessful(w, http.StatusUnauthorized, fmt.Sprintf("Unauthorized request: %s", err))
+		return false
+	}
+
+	var p2bRequest P2BRequest
+	if err = json.Unmarshal(body, &p2bRequest); err != nil {
+		s.t.Errorf("could not decode the request to %s: %s", r.URL.Path, err)
+		w.WriteHeader(http.StatusBadRequest)
+		return false
+	}
+	if p2bRequest.Request != r.URL.Path || p2bRequest.Nonce <= 0 {
+		s.writeUnsuccessful(w, http.StatusBadRequest, fmt.Sprintf("Invalid request or nonce in the payload: %s, %d", p2bRequest.Request, p2bRequest.Nonce))
+		return false
+	}
+	if !s.useNonce(p2bRequest.Nonce) {
+		s.writeUnsuccessful(w, http.StatusOK, fmt.Sprintf("Nonce %d is not larger than the previous nonce", p2bRequest.Nonce))
+		return false
+	}
+	if err = json.Unmarshal(body, request); err != nil {
+		s.t.Errorf("could not decode the request to %s: %s", r.URL.Path, err)
+		w.WriteHeader(http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// useNonce returns false if the nonce is not larger than the nonce of the previous request
+func (s *fakeP2BServer) useNonce(nonce int64) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if nonce <= s.lastNonce {
+		return false
+	}
+	s.lastNonce = nonce
+	return true
+}
+
+// authenticate checks that the payload header is the base64 encoded body and that the signature is the hex encoded
+// HMAC-SHA512 of the payload with the secret
+func (s *fakeP2BServer) authenticate(r *http.Request, body []byte) error {
+	if r.Header.Get("X-TXC-APIKEY") != testP2BKey {
+		return fmt.Errorf("unknown api key")
+	}
+
+	payload := r.Header.Get("X-TXC-PAYLOAD")
+	if payload != base64.StdEncoding.EncodeToString(bytes.TrimSpace(body)) {
+		return fmt.Errorf("payload does not match the body")
+	}
+
+	h := hmac.New(sha512.New, []byte(testP2BSecret))
+	h.Write([]byte(payload))
+	expected := hex.EncodeToString(h.Sum(nil))
+	if !hmac.Equal([]byte(r.Header.Get("X-TXC-SIGNATURE")), []byte(expected)) {
+		return fmt.Errorf("invalid signature")
+	}
+	return nil
+}
+
+// writeResult must be called with the mutex held since the result may refer to the server's state
+func (s *fakeP2BServer) writeResult(w http.ResponseWriter, result interface{}) {
+	s.write(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "",
+		"result":  result,
+	})
+}
+
+func (s *fakeP2BServer) writeUnsuccessful(w http.ResponseWriter, statusCode int, message string) {
+	s.write(w, statusCode, map[string]interface{}{
+		"success": false,
+		"message": message,
+	})
+}
+
+func (s *fakeP2BServer) write(w http.ResponseWriter, statusCode int, response interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		s.t.Errorf("could not encode the response: %s", err)
+	}
+}
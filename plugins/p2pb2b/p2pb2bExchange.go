@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"github.com/stellar/kelp/api"
 	"github.com/stellar/kelp/model"
+	"github.com/stellar/kelp/support/networking"
 	"log"
 	"math"
-	"os"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -18,63 +20,180 @@ var ErrorNotSupported = errors.New("FUNCTION_NOT_SUPPORTED")
 
 const precisionBalances = 8
 
+// ensure that pbExchange can look up orders
+var _ api.OrderStatusAPI = &pbExchange{}
+
 // pbExchange is the implementation for the p2pb2b Exchange
 type pbExchange struct {
 	assetConverter *model.AssetConverter
 	apis           []*P2BApi
-	apiNextIndex   uint8
+	keyPool        *networking.KeyPool
 	delimiter      string
-	isSimulated    bool // will simulate add and cancel orders if this is true
+	// markets holds the order constraints of the markets listed on p2pb2b, loaded when the exchange is made
+	markets            map[model.TradingPair]model.OrderConstraints
+	ocOverridesHandler *model.OrderConstraintsOverridesHandler
+	isSimulated        bool // will simulate add and cancel orders if this is true
 }
 
+// the exchange params supported by p2pb2b
+const (
+	// pbParamBaseURL points the client at a different host than p2pb2b.io, e.g. a local fake of the API
+	pbParamBaseURL = "baseUrl"
+	// pbParamProxies is a comma-separated list of HTTP or SOCKS5 proxy urls that requests are rotated across
+	pbParamProxies = "proxies"
+	// pbParamProxyHealthCheckInterval is how often in seconds the proxies are checked, 0 disables the health checks
+	pbParamProxyHealthCheckInterval = "proxyHealthCheckInterval"
+)
+
 // makepbExchange is a factory method to make the pb exchange
-func MakeP2PB2BExchange(apiKeys []api.ExchangeAPIKey, isSimulated bool) (api.Exchange, error) {
+func MakeP2PB2BExchange(apiKeys []api.ExchangeAPIKey, exchangeParams []api.ExchangeParam, isSimulated bool) (api.Exchange, error) {
 	if len(apiKeys) == 0 || len(apiKeys) > math.MaxUint8 {
 		return nil, fmt.Errorf("invalid number of apiKeys: %d", len(apiKeys))
 	}
 
-	var proxies *P2BProxies
-	if path := os.Getenv("P2B_VPN_PATH"); path != "" {
-		// Hard-code a couple of proxies
-		proxy_uk := P2BProxy{location: "UK", ovpn: "expressvpn_uk.ovpn", port: "54321"}
-		err := recycleDockerProxy(path, &proxy_uk)
-		if err != nil {
-			return nil, err
+	baseURL := p2bBaseURL
+	proxyURLs := []string{}
+	healthCheckInterval := defaultProxyHealthCheckInterval
+	for _, param := range exchangeParams {
+		switch param.Param {
+		case pbParamBaseURL:
+			baseURL = strings.TrimSuffix(param.Value, "/")
+		case pbParamProxies:
+			var err error
+			proxyURLs, err = parseProxyURLs(param.Value)
+			if err != nil {
+				return nil, fmt.Errorf("could not parse exchange param %s: %s", param.Param, err)
+			}
+		case pbParamProxyHealthCheckInterval:
+			seconds, err := strconv.ParseUint(param.Value, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("could not parse exchange param %s as a number of seconds (%s): %s", param.Param, param.Value, err)
+			}
+			healthCheckInterval = time.Duration(seconds) * time.Second
+		default:
+			return nil, fmt.Errorf("unrecognized exchange param for p2pb2b: '%s' (supported params: %s, %s, %s)", param.Param, pbParamBaseURL, pbParamProxies, pbParamProxyHealthCheckInterval)
 		}
+	}
 
-		proxy_us := P2BProxy{location: "US", ovpn: "expressvpn_us.ovpn", port: "54322"}
-		err = recycleDockerProxy(path, &proxy_us)
+	var proxies *P2BProxyPool
+	if len(proxyURLs) > 0 {
+		var err error
+		proxies, err = MakeP2BProxyPool(proxyURLs, baseURL, healthCheckInterval)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("could not make proxy pool: %s", err)
 		}
-		proxies = &P2BProxies{proxy: []*P2BProxy{
-			&proxy_uk,
-			&proxy_us,
-		}, path: path}
+		log.Printf("sending p2pb2b requests through %d proxies (%d healthy)\n", len(proxyURLs), proxies.numHealthy())
 	}
 
 	pbAPIs := make([]*P2BApi, 0)
 	for _, apiKey := range apiKeys {
-		pbAPIClient := &P2BApi{key: apiKey.Key, secret: apiKey.Secret, proxies: proxies}
+		pbAPIClient := &P2BApi{baseURL: baseURL, key: apiKey.Key, secret: apiKey.Secret, proxies: proxies}
 		pbAPIs = append(pbAPIs, pbAPIClient)
 	}
 
-	return &pbExchange{
-		assetConverter: model.P2PB2BAssetConverter,
-		apis:           pbAPIs,
-		apiNextIndex:   0,
-		delimiter:      "_",
-		isSimulated:    isSimulated,
-	}, nil
+	keyPool, err := networking.MakeKeyPool("p2pb2b", len(pbAPIs), pbRateLimits)
+	if err != nil {
+		return nil, fmt.Errorf("could not make key pool: %s", err)
+	}
+
+	p2b := &pbExchange{
+		assetConverter:     model.P2PB2BAssetConverter,
+		apis:               pbAPIs,
+		keyPool:            keyPool,
+		delimiter:          "_",
+		ocOverridesHandler: model.MakeEmptyOrderConstraintsOverridesHandler(),
+		isSimulated:        isSimulated,
+	}
+
+	err = p2b.loadMarkets()
+	if err != nil {
+		return nil, fmt.Errorf("could not load p2pb2b markets: %s", err)
+	}
+	return p2b, nil
+}
+
+// loadMarkets fetches the markets listed on p2pb2b and builds the order constraints for the ones the bot understands
+func (p2b *pbExchange) loadMarkets() error {
+	pbAPI, key := p2b.nextAPI(networking.EndpointClassPublic)
+	markets, err := pbAPI.getMarkets()
+	if err != nil {
+		return p2b.keyError(key, err)
+	}
+
+	m := map[model.TradingPair]model.OrderConstraints{}
+	for _, market := range markets {
+		base, err := p2b.assetConverter.FromString(market.Stock)
+		if err != nil {
+			// not every asset listed on p2pb2b is understood by the bot
+			continue
+		}
+		quote, err := p2b.assetConverter.FromString(market.Money)
+		if err != nil {
+			continue
+		}
+
+		oc, err := parseOrderConstraints(market)
+		if err != nil {
+			return fmt.Errorf("could not parse order constraints of market %s: %s", market.Name, err)
+		}
+		m[model.TradingPair{Base: base, Quote: quote}] = *oc
+	}
+
+	p2b.markets = m
+	log.Printf("loaded %d p2pb2b markets that are understood by the bot (%d listed)\n", len(m), len(markets))
+	return nil
+}
+
+// parseOrderConstraints converts the precision and limits of the market to order constraints
+func parseOrderConstraints(market *Market) (*model.OrderConstraints, error) {
+	pricePrecision, err := strconv.ParseInt(market.Precision.Money, 10, 8)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse money precision '%s': %s", market.Precision.Money, err)
+	}
+	volumePrecision, err := strconv.ParseInt(market.Precision.Stock, 10, 8)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse stock precision '%s': %s", market.Precision.Stock, err)
+	}
+	minBaseVolume, err := strconv.ParseFloat(market.Limits.MinAmount, 64)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse min_amount '%s': %s", market.Limits.MinAmount, err)
+	}
+
+	if market.Limits.MinTotal == "" {
+		return model.MakeOrderConstraints(int8(pricePrecision), int8(volumePrecision), minBaseVolume), nil
+	}
+	minQuoteVolume, err := strconv.ParseFloat(market.Limits.MinTotal, 64)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse min_total '%s': %s", market.Limits.MinTotal, err)
+	}
+	return model.MakeOrderConstraintsWithCost(int8(pricePrecision), int8(volumePrecision), minBaseVolume, minQuoteVolume), nil
+}
+
+// pbRateLimits keeps each API key within the 10 requests per second that p2pb2b allows
+var pbRateLimits = map[networking.EndpointClass]networking.RateLimit{
+	networking.EndpointClassPublic:  {Burst: 10, Interval: 100 * time.Millisecond},
+	networking.EndpointClassPrivate: {Burst: 10, Interval: 100 * time.Millisecond},
+	networking.EndpointClassTrading: {Burst: 10, Interval: 100 * time.Millisecond},
 }
 
-// nextAPI rotates the API key being used so we can overcome rate limit issues
-func (p2b *pbExchange) nextAPI() *P2BApi {
-	log.Printf("returning pb API key at index %d", p2b.apiNextIndex)
-	api_ := p2b.apis[p2b.apiNextIndex]
-	// rotate key for the next call
-	p2b.apiNextIndex = (p2b.apiNextIndex + 1) % uint8(len(p2b.apis))
-	return api_
+// nextAPI returns the client for the API key that can call the class of endpoints soonest so we can overcome rate limit issues
+func (p2b *pbExchange) nextAPI(class networking.EndpointClass) (*P2BApi, int) {
+	index := p2b.keyPool.Acquire(class)
+	log.Printf("returning pb API key at index %d for %s endpoint", index, class)
+	return p2b.apis[index], index
+}
+
+// KeyUsageMetrics impl.
+func (p2b *pbExchange) KeyUsageMetrics() map[string]interface{} {
+	return p2b.keyPool.Metrics()
+}
+
+// keyError backs off from the API key when p2pb2b throttled it, the error is already classified by the client
+func (p2b *pbExchange) keyError(index int, err error) error {
+	if api.ErrorKindOf(err) == api.ErrorKindRateLimited {
+		p2b.keyPool.Throttle(index, api.RetryAfter(err))
+	}
+	return err
 }
 
 func (p2b *pbExchange) floatFromString(val string) (float64, error) {
@@ -87,6 +206,16 @@ func (p2b *pbExchange) AddOrder(order *model.Order) (*model.TransactionID, error
 	if err != nil {
 		return nil, err
 	}
+	// /order/new only takes the market, side, amount and price
+	if order.TimeInForce != model.TimeInForceGTC {
+		return nil, api.MakeErrUnsupported("p2pb2b", fmt.Sprintf("time in force %s", order.TimeInForce))
+	}
+	if order.PostOnly {
+		return nil, api.MakeErrUnsupported("p2pb2b", "post-only")
+	}
+	if order.ClientOrderID != "" {
+		return nil, api.MakeErrUnsupported("p2pb2b", "client order ID")
+	}
 
 	if p2b.isSimulated {
 		log.Printf("not adding order to pb in simulation mode, order=%s\n", *order)
@@ -101,9 +230,10 @@ func (p2b *pbExchange) AddOrder(order *model.Order) (*model.TransactionID, error
 		return nil, fmt.Errorf("pb volume precision can be a maximum of %d, got %d, value = %.12f", orderConstraints.VolumePrecision, order.Volume.Precision(), order.Volume.AsFloat())
 	}
 
-	resp, err := p2b.nextAPI().createOrder(market, order.Volume.AsString(), order.Price.AsString(), bool(order.OrderAction))
+	pbAPI, key := p2b.nextAPI(networking.EndpointClassTrading)
+	resp, err := pbAPI.createOrder(market, order.Volume.AsString(), order.Price.AsString(), bool(order.OrderAction))
 	if err != nil {
-		return nil, err
+		return nil, p2b.keyError(key, err)
 	}
 
 	return model.MakeTransactionID(fmt.Sprintf("%d", resp.Id)), nil
@@ -125,9 +255,10 @@ func (p2b *pbExchange) CancelOrder(txID_ *model.TransactionID, pair model.Tradin
 		return model.CancelResultFailed, err
 	}
 
-	_, err = p2b.nextAPI().cancelOrder(market, txID)
+	pbAPI, key := p2b.nextAPI(networking.EndpointClassTrading)
+	_, err = pbAPI.cancelOrder(market, txID)
 	if err != nil {
-		return model.CancelResultFailed, err
+		return model.CancelResultFailed, p2b.keyError(key, err)
 	}
 
 	return model.CancelResultCancelSuccessful, nil
@@ -135,9 +266,10 @@ func (p2b *pbExchange) CancelOrder(txID_ *model.TransactionID, pair model.Tradin
 
 // GetAccountBalances impl.
 func (p2b *pbExchange) GetAccountBalances(assetList []interface{}) (map[interface{}]model.Number, error) {
-	balanceResponse, err := p2b.nextAPI().getAccountBalanaces()
+	pbAPI, key := p2b.nextAPI(networking.EndpointClassPrivate)
+	balanceResponse, err := pbAPI.getAccountBalanaces()
 	if err != nil {
-		return nil, err
+		return nil, p2b.keyError(key, err)
 	}
 
 	m := map[interface{}]model.Number{}
@@ -170,13 +302,22 @@ func (p2b *pbExchange) GetAccountBalances(assetList []interface{}) (map[interfac
 }
 
 // GetOrderConstraints impl
-func (*pbExchange) GetOrderConstraints(pair *model.TradingPair) *model.OrderConstraints {
-	constraints, ok := pbPrecisionMatrix[*pair]
-	if !ok {
-		log.Printf("pbExchange could not find orderConstraints for trading pair %v, returning nil\n", pair)
-		return nil
+func (p2b *pbExchange) GetOrderConstraints(pair *model.TradingPair) *model.OrderConstraints {
+	oc, ok := p2b.markets[*pair]
+	if ok {
+		return p2b.ocOverridesHandler.Apply(pair, &oc)
+	}
+
+	if p2b.ocOverridesHandler.IsCompletelyOverriden(pair) {
+		override := p2b.ocOverridesHandler.Get(pair)
+		return model.MakeOrderConstraintsFromOverride(override)
 	}
-	return &constraints
+	panic(fmt.Sprintf("pbExchange could not find orderConstraints for trading pair %v, the market is not listed on p2pb2b. Provide them using the CENTRALIZED_* config values.", pair))
+}
+
+// OverrideOrderConstraints impl, can partially override values for specific pairs
+func (p2b *pbExchange) OverrideOrderConstraints(pair *model.TradingPair, override *model.OrderConstraintsOverride) {
+	p2b.ocOverridesHandler.Upsert(pair, override)
 }
 
 // GetAssetConverter impl.
@@ -203,9 +344,10 @@ func (p2b *pbExchange) getOpenOrders(pair *model.TradingPair) ([]model.OpenOrder
 		return nil, err
 	}
 
-	orders_, err := p2b.nextAPI().getOpenOrders(market)
+	pbAPI, key := p2b.nextAPI(networking.EndpointClassPrivate)
+	orders_, err := pbAPI.getOpenOrders(market)
 	if err != nil {
-		return nil, err
+		return nil, p2b.keyError(key, err)
 	}
 
 	orders := make([]model.OpenOrder, 0)
@@ -214,28 +356,122 @@ func (p2b *pbExchange) getOpenOrders(pair *model.TradingPair) ([]model.OpenOrder
 	}
 
 	for _, o := range *orders_ {
-		o := o
-		orderConstraints := p2b.GetOrderConstraints(pair)
-		order := model.OpenOrder{
-			Order: model.Order{
-				Pair:        pair,
-				OrderAction: model.OrderActionFromString(o.Side),
-				OrderType:   model.OrderTypeFromString(o.Type),
-				Price:       model.MustNumberFromString(o.Price, orderConstraints.PricePrecision),
-				Volume:      model.MustNumberFromString(o.Amount, orderConstraints.VolumePrecision),
-				Timestamp:   model.MakeTimestamp(int64(o.Timestamp)),
-			},
-			ID:             strconv.FormatUint(o.Id, 10),
-			StartTime:      model.MakeTimestamp(int64(o.Timestamp)),
-			ExpireTime:     model.MakeTimestamp(int64(o.Timestamp) + 2500000),
-			VolumeExecuted: model.NumberFromFloat(0.0, orderConstraints.VolumePrecision),
-			// @todo hack 0.0 rather than real figure - is this important for our needs?
-		}
-		orders = append(orders, order)
+		orders = append(orders, p2b.convertOpenOrder(o, pair))
 	}
 	return orders, nil
 }
 
+// pbTimestamp converts the (fractional) seconds since epoch that p2pb2b uses to a model.Timestamp in milliseconds
+func pbTimestamp(secs float64) *model.Timestamp {
+	return model.MakeTimestamp(int64(secs * 1000))
+}
+
+func (p2b *pbExchange) convertOpenOrder(o *ExchangeOrder, pair *model.TradingPair) model.OpenOrder {
+	orderConstraints := p2b.GetOrderConstraints(pair)
+	return model.OpenOrder{
+		Order: model.Order{
+			Pair:        pair,
+			OrderAction: model.OrderActionFromString(o.Side),
+			OrderType:   model.OrderTypeFromString(o.Type),
+			Price:       model.MustNumberFromString(o.Price, orderConstraints.PricePrecision),
+			Volume:      model.MustNumberFromString(o.Amount, orderConstraints.VolumePrecision),
+			Timestamp:   pbTimestamp(o.Timestamp),
+		},
+		ID:              strconv.FormatUint(o.Id, 10),
+		StartTime:       pbTimestamp(o.Timestamp),
+		ExpireTime:      pbTimestamp(o.Timestamp + 2500000),
+		VolumeExecuted:  model.MustNumberFromString(o.DealStock, orderConstraints.VolumePrecision),
+		VolumeRemaining: model.MustNumberFromString(o.Left, orderConstraints.VolumePrecision),
+		Status:          model.OrderStatusOpen,
+	}
+}
+
+// convertHistoryOrder converts an order that is no longer open, it was filled if its full amount was executed
+func (p2b *pbExchange) convertHistoryOrder(o *HistoryOrder, pair *model.TradingPair) model.OpenOrder {
+	orderConstraints := p2b.GetOrderConstraints(pair)
+	volume := model.MustNumberFromString(o.Amount, orderConstraints.VolumePrecision)
+	volumeExecuted := model.MustNumberFromString(o.DealStock, orderConstraints.VolumePrecision)
+	status := model.OrderStatusCanceled
+	if volumeExecuted.AsFloat() >= volume.AsFloat() {
+		status = model.OrderStatusFilled
+	}
+
+	return model.OpenOrder{
+		Order: model.Order{
+			Pair:        pair,
+			OrderAction: model.OrderActionFromString(o.Side),
+			OrderType:   model.OrderTypeFromString(o.Type),
+			Price:       model.MustNumberFromString(o.Price, orderConstraints.PricePrecision),
+			Volume:      volume,
+			Timestamp:   pbTimestamp(o.CTime),
+		},
+		ID:              strconv.FormatUint(o.Id, 10),
+		StartTime:       pbTimestamp(o.CTime),
+		ExpireTime:      nil,
+		VolumeExecuted:  volumeExecuted,
+		VolumeRemaining: volume.Subtract(*volumeExecuted),
+		Status:          status,
+	}
+}
+
+// pbOrderStatusLookback is how far back the order history is searched for orders that are no longer open
+const pbOrderStatusLookback = 7 * 24 * time.Hour
+
+// GetOrderStatus impl.
+func (p2b *pbExchange) GetOrderStatus(txID *model.TransactionID, pair model.TradingPair) (*model.OpenOrder, error) {
+	m, err := p2b.QueryOrders([]*model.TransactionID{txID}, pair)
+	if err != nil {
+		return nil, err
+	}
+
+	o, ok := m[txID.String()]
+	if !ok {
+		return nil, api.MakeExchangeError(api.ErrorKindOrderNotFound, "p2pb2b", fmt.Errorf("order %s was not found", txID.String()))
+	}
+	return &o, nil
+}
+
+// QueryOrders impl, p2pb2b cannot look up an order by ID so the orders are searched among the open orders and then in
+// the order history of the last pbOrderStatusLookback
+func (p2b *pbExchange) QueryOrders(txIDs []*model.TransactionID, pair model.TradingPair) (map[string]model.OpenOrder, error) {
+	wanted := map[string]bool{}
+	for _, txID := range txIDs {
+		wanted[txID.String()] = true
+	}
+
+	openOrders, err := p2b.getOpenOrders(&pair)
+	if err != nil {
+		return nil, err
+	}
+	m := map[string]model.OpenOrder{}
+	for _, o := range openOrders {
+		if wanted[o.ID] {
+			m[o.ID] = o
+		}
+	}
+	if len(m) == len(wanted) {
+		return m, nil
+	}
+
+	market, err := pair.ToString(p2b.assetConverter, p2b.delimiter)
+	if err != nil {
+		return nil, err
+	}
+	since := float64(time.Now().Add(-pbOrderStatusLookback).Unix())
+	pbAPI, key := p2b.nextAPI(networking.EndpointClassPrivate)
+	finishedOrders, err := pbAPI.getOrderHistory(market, since)
+	if err != nil {
+		return nil, p2b.keyError(key, err)
+	}
+	for _, o := range finishedOrders {
+		ID := strconv.FormatUint(o.Id, 10)
+		if wanted[ID] {
+			m[ID] = p2b.convertHistoryOrder(o, &pair)
+		}
+	}
+	return m, nil
+}
+
 // GetOrderBook impl.
 func (p2b *pbExchange) GetOrderBook(pair *model.TradingPair, maxCount int32) (*model.OrderBook, error) {
 	market, err := pair.ToString(p2b.assetConverter, p2b.delimiter)
@@ -243,15 +479,17 @@ func (p2b *pbExchange) GetOrderBook(pair *model.TradingPair, maxCount int32) (*m
 		return nil, err
 	}
 
-	sells_, err := p2b.nextAPI().getOrderBook(market, false, maxCount)
+	pbAPI, key := p2b.nextAPI(networking.EndpointClassPublic)
+	sells_, err := pbAPI.getOrderBook(market, false, maxCount)
 	if err != nil {
-		return nil, err
+		return nil, p2b.keyError(key, err)
 	}
 	sells := p2b.readOrders(sells_, pair, model.OrderActionSell)
 
-	buys_, err := p2b.nextAPI().getOrderBook(market, true, maxCount)
+	pbAPI, key = p2b.nextAPI(networking.EndpointClassPublic)
+	buys_, err := pbAPI.getOrderBook(market, true, maxCount)
 	if err != nil {
-		return nil, err
+		return nil, p2b.keyError(key, err)
 	}
 	buys := p2b.readOrders(buys_, pair, model.OrderActionBuy)
 
@@ -288,9 +526,10 @@ func (p2b *pbExchange) GetTickerPrice(pairs []model.TradingPair) (map[model.Trad
 		if err != nil {
 			return nil, err
 		}
-		ticker, err := p2b.nextAPI().getTicker(market)
+		pbAPI, key := p2b.nextAPI(networking.EndpointClassPublic)
+		ticker, err := pbAPI.getTicker(market)
 		if err != nil {
-			return nil, err
+			return nil, p2b.keyError(key, err)
 		}
 
 		priceResult[p] = api.Ticker{
@@ -303,9 +542,203 @@ func (p2b *pbExchange) GetTickerPrice(pairs []model.TradingPair) (map[model.Trad
 }
 
 // GetTradeHistory impl.
-func (*pbExchange) GetTradeHistory(pair model.TradingPair, maybeCursorStart interface{}, maybeCursorEnd interface{}) (*api.TradeHistoryResult, error) {
-	log.Println("pbExchange does not support GetTradeHistory function")
-	return nil, ErrorNotSupported
+func (p2b *pbExchange) GetTradeHistory(pair model.TradingPair, maybeCursorStart interface{}, maybeCursorEnd interface{}) (*api.TradeHistoryResult, error) {
+	cursorStart, err := toPBTradeCursor(maybeCursorStart)
+	if err != nil {
+		return nil, err
+	}
+	if cursorStart == nil {
+		cursorStart = makePBTradeCursor(0)
+	}
+	cursorEnd, err := toPBTradeCursor(maybeCursorEnd)
+	if err != nil {
+		return nil, err
+	}
+	until := math.Inf(1)
+	if cursorEnd != nil {
+		until = cursorEnd.time
+	}
+
+	market, err := pair.ToString(p2b.assetConverter, p2b.delimiter)
+	if err != nil {
+		return nil, err
+	}
+
+	tradedOrders, dealStocks, err := p2b.getTradedOrders(market, cursorStart)
+	if err != nil {
+		return nil, err
+	}
+
+	orderConstraints := p2b.GetOrderConstraints(&pair)
+	res := api.TradeHistoryResult{Trades: []model.Trade{}}
+	newDeals := []*OrderDeal{}
+	for _, o := range tradedOrders {
+		pbAPI, key := p2b.nextAPI(networking.EndpointClassPrivate)
+		deals, err := pbAPI.getOrderDeals(o.id)
+		if err != nil {
+			return nil, p2b.keyError(key, err)
+		}
+
+		for _, d := range deals {
+			if !cursorStart.isNew(d.Time, d.Id) || d.Time > until {
+				continue
+			}
+			newDeals = append(newDeals, d)
+
+			res.Trades = append(res.Trades, model.Trade{
+				Order: model.Order{
+					Pair:        &pair,
+					OrderAction: model.OrderActionFromString(o.side),
+					OrderType:   model.OrderTypeFromString(o.orderType),
+					Price:       model.MustNumberFromString(d.Price, orderConstraints.PricePrecision),
+					Volume:      model.MustNumberFromString(d.Amount, orderConstraints.VolumePrecision),
+					Timestamp:   pbTimestamp(d.Time),
+				},
+				TransactionID: model.MakeTransactionID(strconv.FormatUint(d.Id, 10)),
+				Cost:          model.MustNumberFromString(d.Deal, orderConstraints.PricePrecision),
+				Fee:           model.MustNumberFromString(d.Fee, orderConstraints.PricePrecision),
+			})
+		}
+	}
+
+	// sort to be in ascending order
+	sort.Sort(model.TradesByTsID(res.Trades))
+
+	sort.Slice(newDeals, func(i int, j int) bool {
+		return newDeals[i].Time < newDeals[j].Time
+	})
+	cursor := cursorStart
+	for _, d := range newDeals {
+		cursor = cursor.advance(d.Time, d.Id)
+	}
+	if cursorEnd == nil {
+		// every deal of the open orders was returned so orders that keep their executed volume can be skipped next time
+		cursor = cursor.withDealStocks(dealStocks)
+	}
+	res.Cursor = cursor
+	return &res, nil
+}
+
+// pbTradeCursor is the cursor used for the trade history of p2pb2b.
+// Several deals can happen in the same second so the cursor remembers the ids of the deals at its time that were already
+// returned. It also remembers the executed volume of our open orders so only the orders that were filled since the
+// cursor are checked for new deals.
+type pbTradeCursor struct {
+	time    float64
+	dealIDs map[uint64]bool
+	// dealStocks maps the ids of our open orders to their executed base volume
+	dealStocks map[uint64]string
+}
+
+func makePBTradeCursor(time float64) *pbTradeCursor {
+	return &pbTradeCursor{
+		time:       time,
+		dealIDs:    map[uint64]bool{},
+		dealStocks: map[uint64]string{},
+	}
+}
+
+// String is the Stringer method
+func (c *pbTradeCursor) String() string {
+	return fmt.Sprintf("pbTradeCursor[time=%s, numDealIDs=%d, numOpenOrders=%d]", strconv.FormatFloat(c.time, 'f', -1, 64), len(c.dealIDs), len(c.dealStocks))
+}
+
+// isNew returns true if the deal comes after this cursor
+func (c *pbTradeCursor) isNew(time float64, dealID uint64) bool {
+	if time != c.time {
+		return time > c.time
+	}
+	return !c.dealIDs[dealID]
+}
+
+// advance returns a new cursor that comes after the deal, the receiver is not modified
+func (c *pbTradeCursor) advance(time float64, dealID uint64) *pbTradeCursor {
+	next := makePBTradeCursor(time)
+	if time == c.time {
+		for id := range c.dealIDs {
+			next.dealIDs[id] = true
+		}
+	}
+	next.dealIDs[dealID] = true
+	next.dealStocks = c.dealStocks
+	return next
+}
+
+// withDealStocks returns a copy of the cursor with the executed volumes of the open orders, the receiver is not modified
+func (c *pbTradeCursor) withDealStocks(dealStocks map[uint64]string) *pbTradeCursor {
+	return &pbTradeCursor{
+		time:       c.time,
+		dealIDs:    c.dealIDs,
+		dealStocks: dealStocks,
+	}
+}
+
+// toPBTradeCursor converts the cursors accepted by GetTradeHistory, a string of (fractional) seconds since epoch is
+// accepted too so the cursor from GetLatestTradeCursor is understood
+func toPBTradeCursor(maybeCursor interface{}) (*pbTradeCursor, error) {
+	switch c := maybeCursor.(type) {
+	case nil:
+		return nil, nil
+	case *pbTradeCursor:
+		return c, nil
+	case string:
+		v, err := strconv.ParseFloat(c, 64)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse cursor '%s' as seconds since epoch: %s", c, err)
+		}
+		return makePBTradeCursor(v), nil
+	default:
+		return nil, fmt.Errorf("invalid type of cursor, expected a string of seconds since epoch: %v (%T)", maybeCursor, maybeCursor)
+	}
+}
+
+// tradedOrder is one of our orders that has at least one fill
+type tradedOrder struct {
+	id        uint64
+	side      string
+	orderType string
+}
+
+// getTradedOrders returns the orders in the market that may have been filled since the cursor, which are the open orders
+// whose executed volume changed and the orders that were finished since then. It also returns the executed volume of
+// every open order.
+func (p2b *pbExchange) getTradedOrders(market string, cursor *pbTradeCursor) ([]tradedOrder, map[uint64]string, error) {
+	pbAPI, key := p2b.nextAPI(networking.EndpointClassPrivate)
+	openOrders, err := pbAPI.getOpenOrders(market)
+	if err != nil {
+		return nil, nil, p2b.keyError(key, err)
+	}
+
+	pbAPI, key = p2b.nextAPI(networking.EndpointClassPrivate)
+	finishedOrders, err := pbAPI.getOrderHistory(market, cursor.time)
+	if err != nil {
+		return nil, nil, p2b.keyError(key, err)
+	}
+
+	orders := []tradedOrder{}
+	dealStocks := map[uint64]string{}
+	for _, o := range *openOrders {
+		dealStocks[o.Id] = o.DealStock
+		if prev, ok := cursor.dealStocks[o.Id]; ok && prev == o.DealStock {
+			continue
+		}
+		if hasDeals(o.DealStock) {
+			orders = append(orders, tradedOrder{id: o.Id, side: o.Side, orderType: o.Type})
+		}
+	}
+	for _, o := range finishedOrders {
+		if hasDeals(o.DealStock) {
+			orders = append(orders, tradedOrder{id: o.Id, side: o.Side, orderType: o.Type})
+		}
+	}
+	return orders, dealStocks, nil
+}
+
+// hasDeals returns true if the order may have been filled given its executed base volume, an unknown volume counts as filled
+// so we do not miss fills
+func hasDeals(dealStock string) bool {
+	v, err := strconv.ParseFloat(dealStock, 64)
+	return err != nil || v > 0
 }
 
 // GetLatestTradeCursor impl.
@@ -315,10 +748,69 @@ func (*pbExchange) GetLatestTradeCursor() (interface{}, error) {
 	return latestTradeCursor, nil
 }
 
-// GetTrades impl.
-func (*pbExchange) GetTrades(pair *model.TradingPair, maybeCursor interface{}) (*api.TradesResult, error) {
-	log.Println("pbExchange does not support GetTrades function")
-	return nil, ErrorNotSupported
+// pbTradesLimit is the maximum number of public trades p2pb2b returns in one call
+const pbTradesLimit = 100
+
+// GetTrades impl., the cursor is the id of the last trade returned
+func (p2b *pbExchange) GetTrades(pair *model.TradingPair, maybeCursor interface{}) (*api.TradesResult, error) {
+	// p2pb2b requires a lastId so start from the first trade when there is no cursor
+	lastID := uint64(1)
+	if maybeCursor != nil {
+		s, ok := maybeCursor.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid type of cursor, expected a string trade id: %v (%T)", maybeCursor, maybeCursor)
+		}
+		var err error
+		lastID, err = strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse cursor '%s' as a trade id: %s", s, err)
+		}
+	}
+
+	market, err := pair.ToString(p2b.assetConverter, p2b.delimiter)
+	if err != nil {
+		return nil, err
+	}
+
+	pbAPI, key := p2b.nextAPI(networking.EndpointClassPublic)
+	deals, err := pbAPI.getMarketHistory(market, lastID, pbTradesLimit)
+	if err != nil {
+		return nil, p2b.keyError(key, err)
+	}
+
+	orderConstraints := p2b.GetOrderConstraints(pair)
+	res := api.TradesResult{
+		Cursor: maybeCursor,
+		Trades: []model.Trade{},
+	}
+	maxID := lastID
+	for _, d := range deals {
+		price := model.MustNumberFromString(d.Price, orderConstraints.PricePrecision)
+		volume := model.MustNumberFromString(d.Amount, orderConstraints.VolumePrecision)
+		res.Trades = append(res.Trades, model.Trade{
+			Order: model.Order{
+				Pair:        pair,
+				OrderAction: model.OrderActionFromString(d.Type),
+				OrderType:   model.OrderTypeLimit,
+				Price:       price,
+				Volume:      volume,
+				Timestamp:   pbTimestamp(d.Time),
+			},
+			TransactionID: model.MakeTransactionID(strconv.FormatUint(d.Id, 10)),
+			Cost:          price.Multiply(*volume),
+		})
+		if d.Id > maxID {
+			maxID = d.Id
+		}
+	}
+
+	// sort to be in ascending order
+	sort.Sort(model.TradesByTsID(res.Trades))
+
+	if len(res.Trades) > 0 {
+		res.Cursor = strconv.FormatUint(maxID, 10)
+	}
+	return &res, nil
 }
 
 // GetWithdrawInfo impl.
@@ -346,15 +838,3 @@ func (*pbExchange) WithdrawFunds(
 	log.Println("pbExchange does not support WithdrawFunds function")
 	return nil, ErrorNotSupported
 }
-
-// pbPrecisionMatrix describes the price and volume precision and min base volume for each trading pair
-// taken from this URL: https://support.pb.com/hc/en-us/articles/360001389366-Price-and-volume-decimal-precision
-var pbPrecisionMatrix = map[model.TradingPair]model.OrderConstraints{
-	*model.MakeTradingPair(model.SHX, model.USD): *model.MakeOrderConstraints(8, 8, 50.0),
-	*model.MakeTradingPair(model.SHX, model.BTC): *model.MakeOrderConstraints(8, 8, 50.0),
-	*model.MakeTradingPair(model.SHX, model.ETH): *model.MakeOrderConstraints(8, 8, 50.0),
-
-	//for tests
-	*model.MakeTradingPair(model.ETH, model.BTC): *model.MakeOrderConstraints(5, 8, 0.02),
-	*model.MakeTradingPair(model.XLM, model.BTC): *model.MakeOrderConstraints(8, 8, 30.0),
-}
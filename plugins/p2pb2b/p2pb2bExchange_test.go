@@ -0,0 +1,443 @@
+package p2pb2b
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stellar/kelp/api"
+	"github.com/stellar/kelp/model"
+)
+
+func TestParseOrderConstraints(t *testing.T) {
+	market := &Market{
+		Name:      "XLM_BTC",
+		Stock:     "XLM",
+		Money:     "BTC",
+		Precision: MarketPrecision{Money: "8", Stock: "1", Fee: "4"},
+		Limits:    MarketLimits{MinAmount: "10", MinTotal: "0.0001"},
+	}
+	oc, err := parseOrderConstraints(market)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if oc.PricePrecision != 8 || oc.VolumePrecision != 1 {
+		t.Errorf("unexpected precision: %s", oc)
+	}
+	if oc.MinBaseVolume.AsFloat() != 10 || oc.MinQuoteVolume == nil || oc.MinQuoteVolume.AsFloat() != 0.0001 {
+		t.Errorf("unexpected min volumes: %s", oc)
+	}
+
+	market.Limits.MinTotal = ""
+	oc, err = parseOrderConstraints(market)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if oc.MinQuoteVolume != nil {
+		t.Errorf("expected no min quote volume, got %s", oc)
+	}
+
+	market.Precision.Money = "eight"
+	if _, err = parseOrderConstraints(market); err == nil {
+		t.Errorf("expected an error for an invalid precision")
+	}
+}
+
+func TestOverrideOrderConstraints(t *testing.T) {
+	pair := model.TradingPair{Base: model.XLM, Quote: model.BTC}
+	p2b := &pbExchange{
+		markets: map[model.TradingPair]model.OrderConstraints{
+			pair: *model.MakeOrderConstraints(8, 1, 10),
+		},
+		ocOverridesHandler: model.MakeEmptyOrderConstraintsOverridesHandler(),
+	}
+
+	p2b.OverrideOrderConstraints(&pair, model.MakeOrderConstraintsOverride(nil, nil, model.NumberFromFloat(25, 1), nil))
+	oc := p2b.GetOrderConstraints(&pair)
+	if oc.PricePrecision != 8 || oc.MinBaseVolume.AsFloat() != 25 {
+		t.Errorf("expected the min base volume to be overridden, got %s", oc)
+	}
+
+	// pairs that are not listed can be used when they are completely overridden
+	unlisted := model.TradingPair{Base: model.SHX, Quote: model.USD}
+	p2b.OverrideOrderConstraints(&unlisted, model.MakeOrderConstraintsOverrideFromConstraints(model.MakeOrderConstraints(4, 2, 50)))
+	oc = p2b.GetOrderConstraints(&unlisted)
+	if oc.PricePrecision != 4 || oc.VolumePrecision != 2 {
+		t.Errorf("unexpected order constraints for the unlisted pair: %s", oc)
+	}
+}
+
+var testPair = model.TradingPair{Base: model.XLM, Quote: model.BTC}
+
+func makeTestP2BExchange(t *testing.T, s *fakeP2BServer, secret string, isSimulated bool) *pbExchange {
+	exchange, err := MakeP2PB2BExchange(
+		[]api.ExchangeAPIKey{{Key: testP2BKey, Secret: secret}},
+		[]api.ExchangeParam{{Param: "baseUrl", Value: s.server.URL + "/"}},
+		isSimulated,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return exchange.(*pbExchange)
+}
+
+func TestP2BAccountBalances(t *testing.T) {
+	s := makeFakeP2BServer(t)
+	defer s.close()
+	s.setBalance("XLM", 100)
+	s.setBalance("BTC", 0.5)
+	exchange := makeTestP2BExchange(t, s, testP2BSecret, false)
+
+	// frozen balances count towards the balance
+	_, err := exchange.AddOrder(&model.Order{
+		Pair:        &testPair,
+		OrderAction: model.OrderActionSell,
+		OrderType:   model.OrderTypeLimit,
+		Price:       model.NumberFromFloat(0.00003, 8),
+		Volume:      model.NumberFromFloat(10, 1),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	balances, err := exchange.GetAccountBalances([]interface{}{model.XLM, model.BTC, model.USD})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(balances) != 2 {
+		t.Errorf("expected balances for the 2 assets held, got %v", balances)
+	}
+	if v := balances[model.XLM]; v.AsFloat() != 100 {
+		t.Errorf("expected an XLM balance of 100, got %s", v.AsString())
+	}
+	if v := balances[model.BTC]; v.AsFloat() != 0.5 {
+		t.Errorf("expected a BTC balance of 0.5, got %s", v.AsString())
+	}
+
+	if _, err = exchange.GetAccountBalances([]interface{}{"XLM"}); err == nil {
+		t.Errorf("expected an error for an asset that is not a model.Asset")
+	}
+}
+
+func TestP2BOrderLifecycle(t *testing.T) {
+	s := makeFakeP2BServer(t)
+	defer s.close()
+	s.setBalance("BTC", 1)
+	exchange := makeTestP2BExchange(t, s, testP2BSecret, false)
+
+	txID, err := exchange.AddOrder(&model.Order{
+		Pair:        &testPair,
+		OrderAction: model.OrderActionBuy,
+		OrderType:   model.OrderTypeLimit,
+		Price:       model.NumberFromFloat(0.00002512, 8),
+		Volume:      model.NumberFromFloat(150.5, 1),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	openOrders, err := exchange.GetOpenOrders([]*model.TradingPair{&testPair})
+	if err != nil {
+		t.Fatal(err)
+	}
+	orders := openOrders[testPair]
+	if len(orders) != 1 {
+		t.Fatalf("expected 1 open order, got %d", len(orders))
+	}
+	o := orders[0]
+	if o.ID != txID.String() || o.OrderAction != model.OrderActionBuy || o.Price.AsString() != "0.00002512" || o.Volume.AsString() != "150.5" {
+		t.Errorf("unexpected open order: %s", o.String())
+	}
+
+	result, err := exchange.CancelOrder(txID, testPair)
+	if err != nil || result != model.CancelResultCancelSuccessful {
+		t.Fatalf("expected the order to be cancelled, got %s: %v", result, err)
+	}
+	openOrders, err = exchange.GetOpenOrders([]*model.TradingPair{&testPair})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(openOrders[testPair]) != 0 {
+		t.Errorf("expected no open orders after the cancel, got %d", len(openOrders[testPair]))
+	}
+
+	// the order is gone
+	result, err = exchange.CancelOrder(txID, testPair)
+	if result != model.CancelResultFailed || api.ErrorKindOf(err) != api.ErrorKindOrderNotFound {
+		t.Errorf("expected an order not found error, got %s: %v", result, err)
+	}
+}
+
+func TestP2BOrderStatus(t *testing.T) {
+	s := makeFakeP2BServer(t)
+	defer s.close()
+	s.setBalance("XLM", 1000)
+	exchange := makeTestP2BExchange(t, s, testP2BSecret, false)
+
+	txIDs := []*model.TransactionID{}
+	for _, volume := range []float64{100, 50, 20} {
+		txID, err := exchange.AddOrder(&model.Order{
+			Pair:        &testPair,
+			OrderAction: model.OrderActionSell,
+			OrderType:   model.OrderTypeLimit,
+			Price:       model.NumberFromFloat(0.00003, 8),
+			Volume:      model.NumberFromFloat(volume, 1),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		txIDs = append(txIDs, txID)
+	}
+	id := func(txID *model.TransactionID) uint64 {
+		v, err := strconv.ParseUint(txID.String(), 10, 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	s.fillOrder(id(txIDs[0]), 40)
+	s.fillOrder(id(txIDs[1]), 50)
+	s.fillOrder(id(txIDs[2]), 5)
+	if _, err := exchange.CancelOrder(txIDs[2], testPair); err != nil {
+		t.Fatal(err)
+	}
+
+	// the open orders report the remaining volume of the partial fill
+	openOrders, err := exchange.GetOpenOrders([]*model.TradingPair{&testPair})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(openOrders[testPair]) != 1 {
+		t.Fatalf("expected 1 open order, got %d", len(openOrders[testPair]))
+	}
+	o := openOrders[testPair][0]
+	if o.Volume.AsFloat() != 100 || o.VolumeExecuted.AsFloat() != 40 || o.RemainingVolume().AsFloat() != 60 {
+		t.Errorf("unexpected partially filled order: %s", o.String())
+	}
+
+	orders, err := exchange.QueryOrders(append(txIDs, model.MakeTransactionID("999")), testPair)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != 3 {
+		t.Fatalf("expected 3 orders, got %d", len(orders))
+	}
+	wantOrders := []struct {
+		status         model.OrderStatus
+		volumeExecuted float64
+		remaining      float64
+	}{
+		{model.OrderStatusOpen, 40, 60},
+		{model.OrderStatusFilled, 50, 0},
+		{model.OrderStatusCanceled, 5, 15},
+	}
+	for i, want := range wantOrders {
+		o := orders[txIDs[i].String()]
+		if o.Status != want.status || o.VolumeExecuted.AsFloat() != want.volumeExecuted || o.RemainingVolume().AsFloat() != want.remaining {
+			t.Errorf("unexpected order at index %d: %s", i, o.String())
+		}
+	}
+
+	o2, err := exchange.GetOrderStatus(txIDs[1], testPair)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o2.Status != model.OrderStatusFilled {
+		t.Errorf("expected the order to be filled, got %s", o2.String())
+	}
+
+	// p2pb2b reports seconds and model timestamps are in milliseconds
+	nowMillis := time.Now().UnixNano() / int64(time.Millisecond)
+	for _, o := range []model.OpenOrder{o, *o2} {
+		if startMillis := o.StartTime.AsInt64(); startMillis < nowMillis-60000 || startMillis > nowMillis {
+			t.Errorf("expected the start time of order %s to be within the last minute in millis, got %d", o.ID, startMillis)
+		}
+	}
+	_, err = exchange.GetOrderStatus(model.MakeTransactionID("999"), testPair)
+	if api.ErrorKindOf(err) != api.ErrorKindOrderNotFound {
+		t.Errorf("expected an order not found error, got %v", err)
+	}
+}
+
+func TestP2BAddOrderErrors(t *testing.T) {
+	s := makeFakeP2BServer(t)
+	defer s.close()
+	s.setBalance("XLM", 5)
+	exchange := makeTestP2BExchange(t, s, testP2BSecret, false)
+
+	order := &model.Order{
+		Pair:        &testPair,
+		OrderAction: model.OrderActionSell,
+		OrderType:   model.OrderTypeLimit,
+		Price:       model.NumberFromFloat(0.00003, 8),
+		Volume:      model.NumberFromFloat(10, 1),
+	}
+	_, err := exchange.AddOrder(order)
+	if api.ErrorKindOf(err) != api.ErrorKindInsufficientFunds {
+		t.Errorf("expected an insufficient funds error, got %v", err)
+	}
+
+	// precision errors are caught before the order is sent
+	numRequests := s.requests("/order/new")
+	order.Price = model.NumberFromFloat(0.000030001, 9)
+	if _, err = exchange.AddOrder(order); err == nil {
+		t.Errorf("expected an error for a price with too many decimals")
+	}
+	if s.requests("/order/new") != numRequests {
+		t.Errorf("expected no order to be sent with an invalid precision")
+	}
+
+	// the api has no order flags so they are rejected instead of being dropped
+	for _, withFlag := range []func(o *model.Order){
+		func(o *model.Order) { o.PostOnly = true },
+		func(o *model.Order) { o.TimeInForce = model.TimeInForceIOC },
+		func(o *model.Order) { o.ClientOrderID = "kelp-1" },
+	} {
+		flagged := *order
+		withFlag(&flagged)
+		if _, err = exchange.AddOrder(&flagged); api.ErrorKindOf(err) != api.ErrorKindUnsupported {
+			t.Errorf("expected an unsupported error for %s, got %v", flagged.String(), err)
+		}
+	}
+	if s.requests("/order/new") != numRequests {
+		t.Errorf("expected no order to be sent with unsupported flags")
+	}
+
+	simulated := makeTestP2BExchange(t, s, testP2BSecret, true)
+	txID, err := simulated.AddOrder(order)
+	if err != nil || txID.String() != "simulated" || s.requests("/order/new") != numRequests {
+		t.Errorf("expected the order to be simulated, got %v: %v", txID, err)
+	}
+}
+
+func TestP2BTradeHistory(t *testing.T) {
+	s := makeFakeP2BServer(t)
+	defer s.close()
+	s.setBalance("XLM", 1000)
+	exchange := makeTestP2BExchange(t, s, testP2BSecret, false)
+
+	orderIDs := []uint64{}
+	for i := 0; i < 2; i++ {
+		txID, err := exchange.AddOrder(&model.Order{
+			Pair:        &testPair,
+			OrderAction: model.OrderActionSell,
+			OrderType:   model.OrderTypeLimit,
+			Price:       model.NumberFromFloat(0.00003, 8),
+			Volume:      model.NumberFromFloat(100, 1),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		id, _ := strconv.ParseUint(txID.String(), 10, 64)
+		orderIDs = append(orderIDs, id)
+	}
+	cursor, err := exchange.GetLatestTradeCursor()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fetch := func(wantVolumes []float64, wantDealRequests int) {
+		numRequests := s.requests("/account/order")
+		history, err := exchange.GetTradeHistory(testPair, cursor, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		volumes := []float64{}
+		for _, trade := range history.Trades {
+			volumes = append(volumes, trade.Volume.AsFloat())
+		}
+		sort.Float64s(volumes)
+		if !reflect.DeepEqual(volumes, wantVolumes) {
+			t.Errorf("expected trades with volumes %v, got %v", wantVolumes, volumes)
+		}
+		if n := s.requests("/account/order") - numRequests; n != wantDealRequests {
+			t.Errorf("expected deals to be fetched for %d orders, got %d", wantDealRequests, n)
+		}
+		cursor = history.Cursor
+	}
+
+	s.fillOrder(orderIDs[0], 10)
+	s.fillOrder(orderIDs[1], 20)
+	fetch([]float64{10, 20}, 2)
+
+	// deals in the same second as the cursor are not lost and only the order that was filled again is checked for deals
+	s.fillOrder(orderIDs[0], 5)
+	fetch([]float64{5}, 1)
+	fetch([]float64{}, 0)
+
+	// the deals of finished orders are fetched once they leave the open orders
+	s.fillOrder(orderIDs[1], 80)
+	fetch([]float64{80}, 1)
+}
+
+func TestP2BTickerPrice(t *testing.T) {
+	s := makeFakeP2BServer(t)
+	defer s.close()
+	s.tickers["XLM_BTC"] = &TickerPriceResult{Bid: "0.00002510", Ask: "0.00002530", Low: "0.000024", High: "0.000026", Last: "0.00002520", Vol: "150000"}
+	exchange := makeTestP2BExchange(t, s, testP2BSecret, false)
+
+	prices, err := exchange.GetTickerPrice([]model.TradingPair{testPair})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ticker := prices[testPair]
+	if ticker.BidPrice.AsString() != "0.00002510" || ticker.AskPrice.AsString() != "0.00002530" {
+		t.Errorf("unexpected ticker: bid=%s, ask=%s", ticker.BidPrice.AsString(), ticker.AskPrice.AsString())
+	}
+}
+
+func TestP2BOrderBook(t *testing.T) {
+	s := makeFakeP2BServer(t)
+	defer s.close()
+	s.book["sell"] = makeTestOrders(30, "sell")
+	s.book["buy"] = makeTestOrders(30, "buy")
+	exchange := makeTestP2BExchange(t, s, testP2BSecret, false)
+
+	book, err := exchange.GetOrderBook(&testPair, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(book.Asks()) != 20 || len(book.Bids()) != 20 {
+		t.Errorf("expected 20 asks and 20 bids, got %d and %d", len(book.Asks()), len(book.Bids()))
+	}
+}
+
+func TestP2BErrors(t *testing.T) {
+	s := makeFakeP2BServer(t)
+	defer s.close()
+	s.setBalance("XLM", 100)
+	s.tickers["XLM_BTC"] = &TickerPriceResult{Bid: "0.0000251", Ask: "0.0000253"}
+
+	// the markets are public so a wrong secret is only noticed on private endpoints
+	wrongSecret := makeTestP2BExchange(t, s, "wrong", false)
+	if _, err := wrongSecret.GetAccountBalances([]interface{}{model.XLM}); api.ErrorKindOf(err) != api.ErrorKindAuth {
+		t.Errorf("expected an auth error for a wrong secret, got %v", err)
+	}
+
+	exchange := makeTestP2BExchange(t, s, testP2BSecret, false)
+	s.failNext(http.StatusUnauthorized, "Unauthorized request")
+	if _, err := exchange.GetTickerPrice([]model.TradingPair{testPair}); api.ErrorKindOf(err) != api.ErrorKindAuth {
+		t.Errorf("expected an auth error, got %v", err)
+	}
+
+	s.failNext(http.StatusOK, "Unexpected error")
+	if _, err := exchange.GetOpenOrders([]*model.TradingPair{&testPair}); err == nil || api.ErrorKindOf(err) != api.ErrorKindUnknown {
+		t.Errorf("expected an unclassified error, got %v", err)
+	}
+
+	// a rate limited key is backed off
+	s.failNext(http.StatusOK, "Too many requests")
+	if _, err := exchange.GetAccountBalances([]interface{}{model.XLM}); api.ErrorKindOf(err) != api.ErrorKindRateLimited {
+		t.Errorf("expected a rate limited error, got %v", err)
+	}
+	if usage := exchange.keyPool.Usage(); usage[0].Throttles != 1 {
+		t.Errorf("expected the key to be throttled, got %d throttles", usage[0].Throttles)
+	}
+
+	if _, err := MakeP2PB2BExchange([]api.ExchangeAPIKey{{Key: testP2BKey, Secret: testP2BSecret}}, []api.ExchangeParam{{Param: "unknown", Value: "1"}}, false); err == nil {
+		t.Errorf("expected an error for an unrecognized exchange param")
+	}
+}
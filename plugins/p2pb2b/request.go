@@ -7,15 +7,16 @@ import (
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io/ioutil"
+	"log"
 	"net/http"
-	"os/exec"
-	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/stellar/kelp/api"
 )
 
 const (
@@ -24,23 +25,24 @@ const (
 	proxyAttempts = 4
 	startSleep    = 5.0
 	multSleep     = 1.5
+	// pageLimit is the maximum number of records requested from a list endpoint at a time
+	pageLimit = 100
 )
 
-// Common structs
-type P2BProxy struct {
-	location, ovpn, port string
-}
-
-type P2BProxies struct {
-	proxy []*P2BProxy
-	index uint64
-	path  string
+// p2bMutatingRequests change the account so they are sent only once. A retry after a timeout could place a second order
+// when the exchange had accepted the first one, the caller decides what to do after checking the open orders.
+var p2bMutatingRequests = map[string]bool{
+	p2bApiPrefix + "/order/new":    true,
+	p2bApiPrefix + "/order/cancel": true,
 }
 
+// Common structs
 type P2BApi struct {
+	baseURL string
 	key     string
 	secret  string
-	proxies *P2BProxies
+	// proxies is nil when requests are sent directly
+	proxies *P2BProxyPool
 }
 
 type P2BRequest struct {
@@ -150,18 +152,174 @@ type TickerPriceResponse struct {
 	Result *TickerPriceResult `json:"result"`
 }
 
-func recycleDockerProxy(path string, proxy *P2BProxy) error {
-	script := filepath.Join(path, "openvpn.sh")
-	// fmt.Println(script, proxy)
-	ovpn := filepath.Join(path, proxy.ovpn)
-	command := exec.Command("bash", script, proxy.location, ovpn, proxy.port)
-	fmt.Println(proxy.location, ovpn, proxy.port)
-	return command.Run()
+// Markets structs
+type MarketPrecision struct {
+	Money string `json:"money"`
+	Stock string `json:"stock"`
+	Fee   string `json:"fee"`
 }
 
-//
-func (p2b *P2BApi) unsuccessful() error {
-	return errors.New("UNSUCCESSFUL_REQUEST")
+type MarketLimits struct {
+	MinAmount string `json:"min_amount"`
+	MaxAmount string `json:"max_amount"`
+	StepSize  string `json:"step_size"`
+	MinPrice  string `json:"min_price"`
+	MaxPrice  string `json:"max_price"`
+	TickSize  string `json:"tick_size"`
+	MinTotal  string `json:"min_total"`
+}
+
+// Market describes a trading pair listed on p2pb2b, Stock is the base asset and Money is the quote asset
+type Market struct {
+	Name      string          `json:"name" binding:"required"`
+	Stock     string          `json:"stock" binding:"required"`
+	Money     string          `json:"money" binding:"required"`
+	Precision MarketPrecision `json:"precision"`
+	Limits    MarketLimits    `json:"limits"`
+}
+
+type GetMarketsResponse struct {
+	P2BResponse
+	Result []*Market `json:"result"`
+}
+
+// Order history structs
+type GetOrderHistoryRequest struct {
+	*P2BRequest
+	P2BLimitOffset
+}
+
+// HistoryOrder is a finished (filled or cancelled) order, times are in seconds since epoch
+type HistoryOrder struct {
+	Id        uint64  `json:"id" binding:"required"`
+	Market    string  `json:"market"`
+	Side      string  `json:"side"`
+	Type      string  `json:"type"`
+	Amount    string  `json:"amount"`
+	Price     string  `json:"price"`
+	CTime     float64 `json:"ctime"`
+	FTime     float64 `json:"ftime"`
+	DealStock string  `json:"dealStock"`
+	DealMoney string  `json:"dealMoney"`
+	DealFee   string  `json:"dealFee"`
+}
+
+// GetOrderHistoryResult maps the market to its finished orders
+type GetOrderHistoryResult map[string][]*HistoryOrder
+
+type GetOrderHistoryResponse struct {
+	P2BResponse
+	Result GetOrderHistoryResult `json:"result"`
+}
+
+// Order deals structs
+type GetOrderDealsRequest struct {
+	*P2BRequest
+	P2BLimitOffset
+	Id uint64 `json:"orderId" binding:"required"`
+}
+
+// OrderDeal is a single fill of one of our orders, the time is in seconds since epoch
+type OrderDeal struct {
+	Id          uint64  `json:"id" binding:"required"`
+	Time        float64 `json:"time"`
+	Price       string  `json:"price"`
+	Amount      string  `json:"amount"`
+	Deal        string  `json:"deal"`
+	Fee         string  `json:"fee"`
+	Role        int     `json:"role"`
+	DealOrderId uint64  `json:"dealOrderId"`
+}
+
+type GetOrderDealsResult struct {
+	P2BLimitOffset
+	Records []*OrderDeal `json:"records"`
+}
+
+type GetOrderDealsResponse struct {
+	P2BResponse
+	Result *GetOrderDealsResult `json:"result"`
+}
+
+// Market history structs
+type MarketDeal struct {
+	Id     uint64  `json:"id" binding:"required"`
+	Type   string  `json:"type"`
+	Time   float64 `json:"time"`
+	Amount string  `json:"amount"`
+	Price  string  `json:"price"`
+}
+
+type GetMarketHistoryResponse struct {
+	P2BResponse
+	Result []*MarketDeal `json:"result"`
+}
+
+// p2bErrorRules classifies the messages of unsuccessful p2pb2b responses
+var p2bErrorRules = []api.ErrorMessageRule{
+	{Substring: "Too many requests", Kind: api.ErrorKindRateLimited},
+	{Substring: "Unauthorized", Kind: api.ErrorKindAuth},
+	{Substring: "Balance not enough", Kind: api.ErrorKindInsufficientFunds},
+	{Substring: "Order not found", Kind: api.ErrorKindOrderNotFound},
+}
+
+// unsuccessful converts a response with success=false to an error, classified by its message when possible
+func (p2b *P2BApi) unsuccessful(response *P2BResponse) error {
+	err := fmt.Errorf("UNSUCCESSFUL_REQUEST: %v", response.Message)
+	return api.ClassifyErrorMessage("p2pb2b", err, p2bErrorRules)
+}
+
+// p2bPage describes a page of records returned by a list endpoint
+type p2bPage struct {
+	count int
+	// total is the number of records across all pages, negative when the endpoint did not report it
+	total int
+	// limit is the page size applied by the exchange, which can be lower than requested, zero when not reported
+	limit int
+	// done stops the pagination before all the records are fetched
+	done bool
+}
+
+// reportedTotal returns the total from a response, which is omitted from the JSON when it is zero so a zero total
+// with records in the page means the endpoint did not report it
+func reportedTotal(total int, count int) int {
+	if total == 0 && count > 0 {
+		return -1
+	}
+	return total
+}
+
+// paginate calls fetchPage with increasing offsets until all the records of a list endpoint are fetched, or maxRecords
+// are fetched when it is positive. When the endpoint reports the total we follow it, otherwise a page shorter than the
+// page size is the last one.
+func paginate(maxRecords int, fetchPage func(offset int, limit int) (*p2bPage, error)) error {
+	offset := 0
+	for maxRecords <= 0 || offset < maxRecords {
+		limit := pageLimit
+		if maxRecords > 0 && maxRecords-offset < limit {
+			limit = maxRecords - offset
+		}
+
+		page, err := fetchPage(offset, limit)
+		if err != nil {
+			return err
+		}
+		offset += page.count
+
+		if page.done || page.count == 0 {
+			return nil
+		}
+		if page.total >= 0 && offset >= page.total {
+			return nil
+		}
+		if page.limit > 0 && page.limit < limit {
+			limit = page.limit
+		}
+		if page.total < 0 && page.count < limit {
+			return nil
+		}
+	}
+	return nil
 }
 
 func (p2b *P2BApi) getAccountBalanaces() (GetAccountBalancesResult, error) {
@@ -178,7 +336,7 @@ func (p2b *P2BApi) getAccountBalanaces() (GetAccountBalancesResult, error) {
 		return nil, err
 	}
 	if !response.Success {
-		return nil, p2b.unsuccessful()
+		return nil, p2b.unsuccessful(&response.P2BResponse)
 	}
 	return response.Result, nil
 }
@@ -187,10 +345,8 @@ func (p2b *P2BApi) getOpenOrders(market string) (*ExchangeOrders, error) {
 	p2bRequest := P2BRequest{
 		Request: fmt.Sprintf("%s/orders", p2bApiPrefix),
 	}
-	offset := 0
-	limit := 100
 	orders := make(ExchangeOrders, 0)
-	for {
+	err := paginate(0, func(offset int, limit int) (*p2bPage, error) {
 		request := GetOrdersRequest{
 			P2BRequest: &p2bRequest,
 			Market:     market,
@@ -206,17 +362,19 @@ func (p2b *P2BApi) getOpenOrders(market string) (*ExchangeOrders, error) {
 			return nil, err
 		}
 		if !response.Success {
-			return nil, p2b.unsuccessful()
-		}
-		//fmt.Println(*response.Result, response.Result.Records)
-		if response.Result.Records != nil && response.Result.Total > 0 {
-			orders = append(orders, *response.Result.Records...)
+			return nil, p2b.unsuccessful(&response.P2BResponse)
 		}
-		if response.Result.Total < limit {
-			return &orders, nil
+		if response.Result == nil || response.Result.Records == nil {
+			return &p2bPage{}, nil
 		}
-		offset += limit
+		orders = append(orders, *response.Result.Records...)
+		count := len(*response.Result.Records)
+		return &p2bPage{count: count, total: reportedTotal(response.Result.Total, count), limit: response.Result.Limit}, nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	return &orders, nil
 }
 
 func (p2b *P2BApi) createOrder(market, amount, price string, sell bool) (*ActionOrderResult, error) {
@@ -243,7 +401,7 @@ func (p2b *P2BApi) createOrder(market, amount, price string, sell bool) (*Action
 		return nil, err
 	}
 	if !response.Success {
-		return nil, p2b.unsuccessful()
+		return nil, p2b.unsuccessful(&response.P2BResponse)
 	}
 	return response.Result, nil
 }
@@ -265,7 +423,7 @@ func (p2b *P2BApi) cancelOrder(market string, id uint64) (*ActionOrderResult, er
 		return nil, err
 	}
 	if !response.Success {
-		return nil, p2b.unsuccessful()
+		return nil, p2b.unsuccessful(&response.P2BResponse)
 	}
 	return response.Result, nil
 }
@@ -281,25 +439,20 @@ func (p2b *P2BApi) getTicker(market string) (*TickerPriceResult, error) {
 		return nil, err
 	}
 	if !response.Success {
-		return nil, p2b.unsuccessful()
+		return nil, p2b.unsuccessful(&response.P2BResponse)
 	}
 	return response.Result, nil
 }
 
+// getOrderBook returns up to maxCount orders of the side of the book, or the whole side when maxCount is not positive
 func (p2b *P2BApi) getOrderBook(market string, sell bool, maxCount int32) (*ExchangeOrders, error) {
-	offset := 0
-	limit := 100
-	orders := make(ExchangeOrders, 0)
-
 	side := "buy"
 	if sell {
 		side = "sell"
 	}
 
-	for {
-		if offset >= int(maxCount) {
-			break
-		}
+	orders := make(ExchangeOrders, 0)
+	err := paginate(int(maxCount), func(offset int, limit int) (*p2bPage, error) {
 		var response GetOrdersResponse
 		paras := map[string]string{
 			"market": market,
@@ -313,22 +466,165 @@ func (p2b *P2BApi) getOrderBook(market string, sell bool, maxCount int32) (*Exch
 			return nil, err
 		}
 		if !response.Success {
-			return nil, p2b.unsuccessful()
+			return nil, p2b.unsuccessful(&response.P2BResponse)
 		}
-		if response.Result.Orders != nil && response.Result.Total > 0 {
-			orders = append(orders, *response.Result.Orders...)
+		if response.Result == nil || response.Result.Orders == nil {
+			return &p2bPage{}, nil
 		}
-		if response.Result.Total < limit {
-			break
+		orders = append(orders, *response.Result.Orders...)
+		count := len(*response.Result.Orders)
+		return &p2bPage{count: count, total: reportedTotal(response.Result.Total, count), limit: response.Result.Limit}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the whole book is fetched when maxCount is not positive
+	if maxCount > 0 && len(orders) > int(maxCount) {
+		orders = orders[:maxCount]
+	}
+	return &orders, nil
+}
+
+func (p2b *P2BApi) getMarkets() ([]*Market, error) {
+	var response GetMarketsResponse
+	request := fmt.Sprintf("%s/public/markets", p2bApiPrefix)
+	err := p2b.get(request, &response, nil)
+	if err != nil {
+		return nil, err
+	}
+	if !response.Success {
+		return nil, p2b.unsuccessful(&response.P2BResponse)
+	}
+	return response.Result, nil
+}
+
+// getOrderHistory returns the finished orders in the market that were finished at or after the since timestamp (seconds),
+// p2pb2b lists the most recently finished orders first so we stop paging once we go past since
+func (p2b *P2BApi) getOrderHistory(market string, since float64) ([]*HistoryOrder, error) {
+	p2bRequest := P2BRequest{
+		Request: fmt.Sprintf("%s/account/order_history", p2bApiPrefix),
+	}
+	orders := make([]*HistoryOrder, 0)
+	err := paginate(0, func(offset int, limit int) (*p2bPage, error) {
+		request := GetOrderHistoryRequest{
+			P2BRequest: &p2bRequest,
+			P2BLimitOffset: P2BLimitOffset{
+				Offset: offset,
+				Limit:  limit,
+			},
 		}
-		offset += limit
+
+		var response GetOrderHistoryResponse
+		err := p2b.post(&p2bRequest, &request, &response)
+		if err != nil {
+			return nil, err
+		}
+		if !response.Success {
+			return nil, p2b.unsuccessful(&response.P2BResponse)
+		}
+
+		// the order history does not report the total
+		page := &p2bPage{count: 0, total: -1}
+		for m, marketOrders := range response.Result {
+			page.count += len(marketOrders)
+			for _, o := range marketOrders {
+				if o.FTime < since {
+					page.done = true
+					continue
+				}
+				if m == market {
+					orders = append(orders, o)
+				}
+			}
+		}
+		return page, nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	result := orders[0:maxCount]
-	return &result, nil
+	return orders, nil
+}
+
+// getOrderDeals returns all the fills of the order
+func (p2b *P2BApi) getOrderDeals(id uint64) ([]*OrderDeal, error) {
+	p2bRequest := P2BRequest{
+		Request: fmt.Sprintf("%s/account/order", p2bApiPrefix),
+	}
+	deals := make([]*OrderDeal, 0)
+	err := paginate(0, func(offset int, limit int) (*p2bPage, error) {
+		request := GetOrderDealsRequest{
+			P2BRequest: &p2bRequest,
+			Id:         id,
+			P2BLimitOffset: P2BLimitOffset{
+				Offset: offset,
+				Limit:  limit,
+			},
+		}
+
+		var response GetOrderDealsResponse
+		err := p2b.post(&p2bRequest, &request, &response)
+		if err != nil {
+			return nil, err
+		}
+		if !response.Success {
+			return nil, p2b.unsuccessful(&response.P2BResponse)
+		}
+		if response.Result == nil {
+			return &p2bPage{}, nil
+		}
+		deals = append(deals, response.Result.Records...)
+		count := len(response.Result.Records)
+		return &p2bPage{count: count, total: reportedTotal(response.Result.Total, count), limit: response.Result.Limit}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deals, nil
+}
+
+// getMarketHistory returns up to limit of the most recent public trades in the market with an id greater than lastId
+func (p2b *P2BApi) getMarketHistory(market string, lastId uint64, limit int) ([]*MarketDeal, error) {
+	var response GetMarketHistoryResponse
+	paras := map[string]string{
+		"market": market,
+		"lastId": strconv.FormatUint(lastId, 10),
+		"limit":  strconv.Itoa(limit),
+	}
+	request := fmt.Sprintf("%s/public/history", p2bApiPrefix)
+	err := p2b.get(request, &response, paras)
+	if err != nil {
+		return nil, err
+	}
+	if !response.Success {
+		return nil, p2b.unsuccessful(&response.P2BResponse)
+	}
+	return response.Result, nil
+}
+
+// p2bNonces has the last nonce of every API key, it is shared by all the clients since clients that use the same key
+// have to send increasing nonces as well
+var p2bNonces = struct {
+	sync.Mutex
+	last map[string]int64
+}{last: map[string]int64{}}
+
+// nextNonce returns the current time in milliseconds, or one more than the previous nonce of the key when requests are
+// sent within the same millisecond, since p2pb2b rejects a nonce that is not larger than the previous one of the key
+func (p2b *P2BApi) nextNonce() int64 {
+	p2bNonces.Lock()
+	defer p2bNonces.Unlock()
+
+	nonce := time.Now().UnixNano() / int64(time.Millisecond)
+	if last := p2bNonces.last[p2b.key]; nonce <= last {
+		nonce = last + 1
+	}
+	p2bNonces.last[p2b.key] = nonce
+	return nonce
 }
 
 func (p2b *P2BApi) post(p2bR *P2BRequest, request_, response interface{}) error {
-	p2bR.Nonce = time.Now().UTC().Unix()
+	p2bR.Nonce = p2b.nextNonce()
 
 	b := new(bytes.Buffer)
 	json.NewEncoder(b).Encode(request_)
@@ -337,12 +633,7 @@ func (p2b *P2BApi) post(p2bR *P2BRequest, request_, response interface{}) error
 	h := hmac.New(sha512.New, []byte(p2b.secret))
 	h.Write([]byte(hex_))
 	sig := hex.EncodeToString(h.Sum(nil))
-	urlPrefix := p2bBaseURL
-	if p2b.proxies != nil {
-		urlPrefix = fmt.Sprintf("http://localhost:%s", p2b.proxies.proxy[p2b.proxies.index].port)
-	}
-	url := fmt.Sprintf("%s%s", urlPrefix, p2bR.Request)
-	fmt.Println(p2b.key, data, hex_, sig)
+	url := fmt.Sprintf("%s%s", p2b.baseURL, p2bR.Request)
 
 	request, err := http.NewRequest("POST", url, bytes.NewBuffer([]byte(data)))
 	if err != nil {
@@ -353,15 +644,11 @@ func (p2b *P2BApi) post(p2bR *P2BRequest, request_, response interface{}) error
 	request.Header.Set("X-TXC-SIGNATURE", sig)
 	request.Header.Set("Content-Type", "application/json")
 
-	return p2b.submit(request, response)
+	return p2b.submit(request, response, !p2bMutatingRequests[p2bR.Request])
 }
 
 func (p2b *P2BApi) get(request_ string, response interface{}, paras map[string]string) error {
-	urlPrefix := p2bBaseURL
-	if p2b.proxies != nil {
-		urlPrefix = fmt.Sprintf("http://localhost:%s", p2b.proxies.proxy[p2b.proxies.index].port)
-	}
-	url := fmt.Sprintf("%s%s", urlPrefix, request_)
+	url := fmt.Sprintf("%s%s", p2b.baseURL, request_)
 	request, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return err
@@ -373,52 +660,65 @@ func (p2b *P2BApi) get(request_ string, response interface{}, paras map[string]s
 		}
 	}
 	request.URL.RawQuery = query.Encode()
-	return p2b.submit(request, response)
+	return p2b.submit(request, response, true)
 }
 
-func (p2b *P2BApi) submit(request *http.Request, response interface{}) error {
-	fmt.Println(request.URL.String())
-
+// submit sends the request, idempotent requests are retried through the next proxy when they fail with a transient error
+func (p2b *P2BApi) submit(request *http.Request, response interface{}, idempotent bool) error {
 	attempts := 1
 	sleep := startSleep
-	if p2b.proxies != nil {
+	if p2b.proxies != nil && idempotent {
 		attempts = proxyAttempts
 	}
 	var err error
 	for i := 0; i < attempts; i++ {
-		if i > 0 {
-			err = recycleDockerProxy(p2b.proxies.path, p2b.proxies.proxy[p2b.proxies.index])
+		if i > 0 && request.GetBody != nil {
+			// the body was consumed by the previous attempt
+			request.Body, err = request.GetBody()
 			if err != nil {
 				return err
 			}
 		}
 
-		err = p2b.submit_(request, response)
-		if err == nil {
+		client := &http.Client{}
+		var proxy *P2BProxy
+		if p2b.proxies != nil {
+			proxy = p2b.proxies.next()
+			client = proxy.client
+		}
+
+		err = p2b.submit_(client, request, response)
+		if err == nil || !api.IsRetryable(err) {
+			break // from for
+		}
+		if i < attempts-1 {
+			log.Printf("retrying p2pb2b request %s after error: %s\n", request.URL.Path, err)
+		}
+		if proxy != nil {
+			// rotate to the next proxy straight away, only back off when none of them are left
+			p2b.proxies.markFailed(proxy, err)
+			if p2b.proxies.numHealthy() > 0 {
+				continue
+			}
+		}
+		if i == attempts-1 {
 			break // from for
 		}
+		if retryAfter := api.RetryAfter(err); retryAfter > time.Duration(sleep)*time.Second {
+			sleep = retryAfter.Seconds()
+		}
 		time.Sleep(time.Duration(sleep) * time.Second)
-		fmt.Print(".")
 		sleep *= multSleep
 	}
-	if p2b.proxies != nil {
-		p2b.proxies.index = (p2b.proxies.index + 1) % uint64(len(p2b.proxies.proxy))
-	}
 	return err
 }
 
-func (p2b *P2BApi) submit_(request *http.Request, response interface{}) error {
-	if p2b.proxies != nil {
-		fmt.Printf("TRYING proxy: %s\n", p2b.proxies.proxy[p2b.proxies.index].location)
-	}
-
-	client := &http.Client{}
+func (p2b *P2BApi) submit_(client *http.Client, request *http.Request, response interface{}) error {
 	httpResponse, err := client.Do(request)
 	if err != nil {
-		return err
+		return api.MakeExchangeError(api.ErrorKindTransient, "p2pb2b", err)
 	}
 	defer httpResponse.Body.Close()
-	fmt.Println("response Status Code:", httpResponse.StatusCode)
 
 	if httpResponse.StatusCode >= 200 && httpResponse.StatusCode < 300 {
 		body, _ := ioutil.ReadAll(httpResponse.Body)
@@ -428,7 +728,13 @@ func (p2b *P2BApi) submit_(request *http.Request, response interface{}) error {
 		decoder := json.NewDecoder(bodyReader)
 		return decoder.Decode(&response)
 	}
-	return errors.New(fmt.Sprintf("BAD_STATUS_CODE: %d", httpResponse.StatusCode))
+	err = fmt.Errorf("BAD_STATUS_CODE: %d", httpResponse.StatusCode)
+	kind := api.ErrorKindFromStatusCode(httpResponse.StatusCode)
+	if kind == api.ErrorKindRateLimited {
+		retryAfter, _ := strconv.Atoi(httpResponse.Header.Get("Retry-After"))
+		return api.MakeErrRateLimited("p2pb2b", time.Duration(retryAfter)*time.Second, err)
+	}
+	return api.MakeExchangeError(kind, "p2pb2b", err)
 }
 
 //func main() {
@@ -0,0 +1,227 @@
+package p2pb2b
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func makeTestOrders(n int, side string) ExchangeOrders {
+	orders := make(ExchangeOrders, 0, n)
+	for i := 0; i < n; i++ {
+		orders = append(orders, &ExchangeOrder{
+			Id:     uint64(i + 1),
+			Market: "XLM_BTC",
+			Side:   side,
+			Price:  fmt.Sprintf("0.%08d", i+1),
+			Amount: "10",
+			Left:   "10",
+		})
+	}
+	return orders
+}
+
+func checkOrderIds(t *testing.T, orders ExchangeOrders, n int) {
+	if len(orders) != n {
+		t.Fatalf("expected %d orders, got %d", n, len(orders))
+	}
+	for i, o := range orders {
+		if o.Id != uint64(i+1) {
+			t.Fatalf("expected order %d at index %d, got %d", i+1, i, o.Id)
+		}
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	testCases := []struct {
+		name       string
+		maxRecords int
+		pages      []p2bPage
+		wantCalls  int
+		wantLimits []int
+	}{
+		{
+			name:       "follows the total",
+			pages:      []p2bPage{{count: 100, total: 250}, {count: 100, total: 250}, {count: 50, total: 250}},
+			wantCalls:  3,
+			wantLimits: []int{100, 100, 100},
+		}, {
+			// the exchange may return fewer records than we asked for
+			name:       "short pages with a total",
+			pages:      []p2bPage{{count: 50, total: 120}, {count: 50, total: 120}, {count: 20, total: 120}},
+			wantCalls:  3,
+			wantLimits: []int{100, 100, 100},
+		}, {
+			name:       "stops at a short page without a total",
+			pages:      []p2bPage{{count: 100, total: -1}, {count: 30, total: -1}},
+			wantCalls:  2,
+			wantLimits: []int{100, 100},
+		}, {
+			name:       "short pages without a total",
+			pages:      []p2bPage{{count: 40, total: -1, limit: 40}, {count: 40, total: -1, limit: 40}, {count: 10, total: -1, limit: 40}},
+			wantCalls:  3,
+			wantLimits: []int{100, 100, 100},
+		}, {
+			name:       "stops at max records",
+			maxRecords: 150,
+			pages:      []p2bPage{{count: 100, total: 1000}, {count: 50, total: 1000}},
+			wantCalls:  2,
+			wantLimits: []int{100, 50},
+		}, {
+			name:       "stops when done",
+			pages:      []p2bPage{{count: 100, total: -1, done: true}},
+			wantCalls:  1,
+			wantLimits: []int{100},
+		}, {
+			name:       "stops at an empty page",
+			pages:      []p2bPage{{count: 100, total: 1000}, {count: 0, total: 1000}},
+			wantCalls:  2,
+			wantLimits: []int{100, 100},
+		},
+	}
+
+	for _, kase := range testCases {
+		t.Run(kase.name, func(t *testing.T) {
+			limits := []int{}
+			err := paginate(kase.maxRecords, func(offset int, limit int) (*p2bPage, error) {
+				if len(limits) >= len(kase.pages) {
+					return nil, fmt.Errorf("unexpected request for offset %d", offset)
+				}
+				limits = append(limits, limit)
+				page := kase.pages[len(limits)-1]
+				return &page, nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(limits) != kase.wantCalls || fmt.Sprint(limits) != fmt.Sprint(kase.wantLimits) {
+				t.Errorf("expected limits %v, got %v", kase.wantLimits, limits)
+			}
+		})
+	}
+
+	expected := errors.New("page failed")
+	err := paginate(0, func(offset int, limit int) (*p2bPage, error) {
+		return nil, expected
+	})
+	if err != expected {
+		t.Errorf("expected the error from the page to be returned, got %v", err)
+	}
+}
+
+func TestGetOpenOrdersPaginates(t *testing.T) {
+	server := makeFakeP2BServer(t)
+	defer server.close()
+	server.openOrders["XLM_BTC"] = makeTestOrders(250, "sell")
+
+	orders, err := server.api().getOpenOrders("XLM_BTC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkOrderIds(t, *orders, 250)
+	if n := server.requests("/orders"); n != 3 {
+		t.Errorf("expected 3 requests, got %d", n)
+	}
+
+	// the exchange caps the page size and leaves out the total
+	server.maxPageSize = 40
+	server.omitTotal = true
+	orders, err = server.api().getOpenOrders("XLM_BTC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkOrderIds(t, *orders, 250)
+
+	orders, err = server.api().getOpenOrders("SHX_BTC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkOrderIds(t, *orders, 0)
+}
+
+func TestGetOrderBookPaginates(t *testing.T) {
+	server := makeFakeP2BServer(t)
+	defer server.close()
+	server.book["sell"] = makeTestOrders(230, "sell")
+	server.book["buy"] = makeTestOrders(20, "buy")
+
+	asks, err := server.api().getOrderBook("XLM_BTC", true, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkOrderIds(t, *asks, 230)
+
+	asks, err = server.api().getOrderBook("XLM_BTC", true, 150)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkOrderIds(t, *asks, 150)
+
+	// the whole book when there is no max count
+	asks, err = server.api().getOrderBook("XLM_BTC", true, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkOrderIds(t, *asks, 230)
+
+	// a book with fewer orders than the max count
+	bids, err := server.api().getOrderBook("XLM_BTC", false, 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkOrderIds(t, *bids, 20)
+}
+
+func TestGetOrderDealsPaginates(t *testing.T) {
+	server := makeFakeP2BServer(t)
+	defer server.close()
+	deals := []*OrderDeal{}
+	for i := 0; i < 120; i++ {
+		deals = append(deals, &OrderDeal{Id: uint64(i + 1), Price: "0.00001", Amount: "1"})
+	}
+	server.deals[7] = deals
+
+	result, err := server.api().getOrderDeals(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 120 || result[119].Id != 120 {
+		t.Errorf("expected all 120 deals, got %d", len(result))
+	}
+}
+
+func TestNonceIncreasesAcrossPages(t *testing.T) {
+	server := makeFakeP2BServer(t)
+	defer server.close()
+	server.openOrders["XLM_BTC"] = makeTestOrders(500, "sell")
+	p2b := server.api()
+
+	// the pages are fetched back to back within the same second
+	start := time.Now()
+	orders, err := p2b.getOpenOrders("XLM_BTC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkOrderIds(t, *orders, 500)
+	if elapsed := time.Since(start); elapsed >= time.Second {
+		t.Errorf("expected the pages to be fetched in under a second, took %s", elapsed)
+	}
+	if n := server.requests("/orders"); n != 5 {
+		t.Errorf("expected 5 requests, got %d", n)
+	}
+
+	// clients that share the API key share the nonce as well
+	other := server.api()
+	previous := p2b.nextNonce()
+	for i := 0; i < 1000; i++ {
+		nonce := p2b.nextNonce()
+		if i%2 == 1 {
+			nonce = other.nextNonce()
+		}
+		if nonce <= previous {
+			t.Fatalf("expected the nonce to increase, got %d after %d", nonce, previous)
+		}
+		previous = nonce
+	}
+}
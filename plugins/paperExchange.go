@@ -0,0 +1,782 @@
+package plugins
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stellar/kelp/api"
+	"github.com/stellar/kelp/model"
+)
+
+// ensure that paperExchange conforms to the Exchange interface
+var _ api.Exchange = &paperExchange{}
+
+// ensure that paperExchange can look up orders
+var _ api.OrderStatusAPI = &paperExchange{}
+
+// paperMarketData is the market that the paper exchange trades against, every api.Exchange is one
+type paperMarketData interface {
+	api.Constrainable
+	api.OrderbookFetcher
+	GetAssetConverter() *model.AssetConverter
+	GetTrades(pair *model.TradingPair, maybeCursor interface{}) (*api.TradesResult, error)
+}
+
+// paperExchange keeps a simulated account that trades against the order book and the trades of another exchange. New
+// orders that cross the order book are filled against it right away, and resting orders are filled by the trades of the
+// other exchange that reach their price. Every fill is reported in the trade history so it reaches the FillTracker.
+type paperExchange struct {
+	market  paperMarketData
+	feeRate float64 // charged in the quote asset on every fill
+
+	mutex    *sync.Mutex
+	balances map[model.Asset]*paperBalance
+	orders   map[string]*paperOrder
+	fills    []model.Trade
+	feeds    map[model.TradingPair]*paperFeed
+	nextID   int
+}
+
+type paperBalance struct {
+	free   float64
+	locked float64
+}
+
+type paperOrder struct {
+	model.Order
+	ID       string
+	seq      int
+	executed float64
+	status   model.OrderStatus
+}
+
+func (o *paperOrder) remaining() float64 {
+	return o.Volume.AsFloat() - o.executed
+}
+
+// paperFeed tracks how far we have read the trades of the market for a trading pair
+type paperFeed struct {
+	cursor interface{}
+	lastTs int64
+	// IDs of the trades at lastTs, the next page of trades can repeat them
+	lastTsIDs map[string]bool
+}
+
+// paperSourceParamPrefix is the prefix of the params that are passed on to the source exchange without the prefix
+const paperSourceParamPrefix = "source."
+
+// makePaperExchange is a factory method, the market is another exchange (param "source") or a recording of one (param
+// "recording"). The starting balances are set with "balance_<ASSET>" params and the fee rate with "fee". Params that
+// start with "source." and all the headers are passed on to the source exchange.
+func makePaperExchange(exchangeParams []api.ExchangeParam, headers []api.ExchangeHeader) (api.Exchange, error) {
+	var market paperMarketData
+	source := ""
+	feeRate := 0.0
+	balances := map[model.Asset]*paperBalance{}
+	sourceParams := []api.ExchangeParam{}
+	for _, param := range exchangeParams {
+		switch {
+		case param.Param == "source":
+			if market != nil || source != "" {
+				return nil, fmt.Errorf("the paper exchange takes only one of the 'source' and 'recording' params")
+			}
+			if param.Value == "paper" {
+				return nil, fmt.Errorf("the paper exchange cannot use itself as the source")
+			}
+			source = param.Value
+		case strings.HasPrefix(param.Param, paperSourceParamPrefix):
+			sourceParams = append(sourceParams, api.ExchangeParam{
+				Param: strings.TrimPrefix(param.Param, paperSourceParamPrefix),
+				Value: param.Value,
+			})
+		case param.Param == "recording":
+			if market != nil || source != "" {
+				return nil, fmt.Errorf("the paper exchange takes only one of the 'source' and 'recording' params")
+			}
+			r, e := loadPaperRecording(param.Value)
+			if e != nil {
+				return nil, fmt.Errorf("could not load the recording of the paper exchange: %s", e)
+			}
+			market = r
+		case param.Param == "fee":
+			f, e := strconv.ParseFloat(param.Value, 64)
+			if e != nil || f < 0 || f >= 1 {
+				return nil, fmt.Errorf("invalid fee for the paper exchange: %s", param.Value)
+			}
+			feeRate = f
+		case strings.HasPrefix(param.Param, "balance_"):
+			b, e := strconv.ParseFloat(param.Value, 64)
+			if e != nil || b < 0 {
+				return nil, fmt.Errorf("invalid balance for param '%s' of the paper exchange: %s", param.Param, param.Value)
+			}
+			balances[model.Asset(strings.TrimPrefix(param.Param, "balance_"))] = &paperBalance{free: b}
+		default:
+			return nil, fmt.Errorf("unrecognized param for the paper exchange: %s", param.Param)
+		}
+	}
+	if source != "" {
+		// the source is only used for its market data so it is made without API keys
+		x, e := makeExchangeWithParams(source, sourceParams, headers, true)
+		if e != nil {
+			return nil, fmt.Errorf("could not make the source exchange of the paper exchange: %s", e)
+		}
+		market = x
+	} else if len(sourceParams) > 0 || len(headers) > 0 {
+		return nil, fmt.Errorf("the paper exchange only takes '%s' params and headers together with a 'source' exchange", paperSourceParamPrefix)
+	}
+	if market == nil {
+		return nil, fmt.Errorf("the paper exchange needs a 'source' exchange or a 'recording' to trade against")
+	}
+
+	return &paperExchange{
+		market:   market,
+		feeRate:  feeRate,
+		mutex:    &sync.Mutex{},
+		balances: balances,
+		orders:   map[string]*paperOrder{},
+		fills:    []model.Trade{},
+		feeds:    map[model.TradingPair]*paperFeed{},
+		nextID:   1,
+	}, nil
+}
+
+func (p *paperExchange) balance(asset model.Asset) *paperBalance {
+	b, ok := p.balances[asset]
+	if !ok {
+		b = &paperBalance{}
+		p.balances[asset] = b
+	}
+	return b
+}
+
+// reservation returns the asset and the amount that the remaining volume of the order holds
+func (p *paperExchange) reservation(order *model.Order, volume float64) (model.Asset, float64) {
+	if order.OrderAction.IsSell() {
+		return order.Pair.Base, volume
+	}
+	return order.Pair.Quote, volume * order.Price.AsFloat() * (1 + p.feeRate)
+}
+
+// sync fills the resting orders of the pair with the trades of the market since the last sync, must be called with the
+// mutex held
+func (p *paperExchange) sync(pair model.TradingPair) error {
+	feed, ok := p.feeds[pair]
+	result, e := p.market.GetTrades(&pair, feedCursor(feed))
+	if e != nil {
+		return fmt.Errorf("could not fetch the trades of the market for pair %s: %s", pair.String(), e)
+	}
+	trades := result.Trades
+	sort.Sort(model.TradesByTsID(trades))
+
+	if !ok {
+		// the trades before the first sync happened before any of our orders were placed
+		feed = &paperFeed{lastTsIDs: map[string]bool{}}
+		p.feeds[pair] = feed
+		for _, t := range trades {
+			feed.see(t)
+		}
+		feed.cursor = result.Cursor
+		return nil
+	}
+
+	for _, t := range trades {
+		if !feed.see(t) {
+			continue
+		}
+		p.fillRestingOrders(pair, t)
+	}
+	if result.Cursor != nil {
+		feed.cursor = result.Cursor
+	}
+	return nil
+}
+
+func feedCursor(feed *paperFeed) interface{} {
+	if feed == nil {
+		return nil
+	}
+	return feed.cursor
+}
+
+// see returns true if the trade was not seen before
+func (f *paperFeed) see(t model.Trade) bool {
+	ts := int64(0)
+	if t.Timestamp != nil {
+		ts = t.Timestamp.AsInt64()
+	}
+	ID := ""
+	if t.TransactionID != nil {
+		ID = t.TransactionID.String()
+	}
+
+	if ts < f.lastTs || (ts == f.lastTs && f.lastTsIDs[ID]) {
+		return false
+	}
+	if ts > f.lastTs {
+		f.lastTs = ts
+		f.lastTsIDs = map[string]bool{}
+	}
+	f.lastTsIDs[ID] = true
+	return true
+}
+
+// fillRestingOrders fills our orders that the market trade reached, the best priced orders are filled first at the price
+// of the order since they were resting on the book
+func (p *paperExchange) fillRestingOrders(pair model.TradingPair, t model.Trade) {
+	tradePrice := t.Price.AsFloat()
+	left := t.Volume.AsFloat()
+
+	candidates := []*paperOrder{}
+	for _, o := range p.orders {
+		if o.status != model.OrderStatusOpen || *o.Pair != pair {
+			continue
+		}
+		// a buyer lifting the asks reaches our sell orders and a seller hitting the bids reaches our buy orders
+		if t.OrderAction.IsBuy() && o.OrderAction.IsSell() && o.Price.AsFloat() <= tradePrice {
+			candidates = append(candidates, o)
+		} else if t.OrderAction.IsSell() && o.OrderAction.IsBuy() && o.Price.AsFloat() >= tradePrice {
+			candidates = append(candidates, o)
+		}
+	}
+	sort.Slice(candidates, func(i int, j int) bool {
+		if candidates[i].OrderAction.IsSell() {
+			return candidates[i].Price.AsFloat() < candidates[j].Price.AsFloat()
+		}
+		return candidates[i].Price.AsFloat() > candidates[j].Price.AsFloat()
+	})
+
+	for _, o := range candidates {
+		if left <= 0 {
+			return
+		}
+		volume := o.remaining()
+		if volume > left {
+			volume = left
+		}
+		left -= volume
+		p.fill(o, volume, o.Price.AsFloat())
+	}
+}
+
+// fill executes the volume of the order at the price and records it as one of our trades
+func (p *paperExchange) fill(o *paperOrder, volume float64, price float64) {
+	base := p.balance(o.Pair.Base)
+	quote := p.balance(o.Pair.Quote)
+	cost := volume * price
+	fee := cost * p.feeRate
+	if o.OrderAction.IsSell() {
+		base.locked -= volume
+		quote.free += cost - fee
+	} else {
+		// the order reserved the quote asset at its own price, a taker fill at a better price releases the difference
+		quote.locked -= volume * o.Price.AsFloat() * (1 + p.feeRate)
+		quote.free += volume*o.Price.AsFloat()*(1+p.feeRate) - cost - fee
+		base.free += volume
+	}
+	o.executed += volume
+	if o.remaining() <= 0 {
+		o.status = model.OrderStatusFilled
+	}
+
+	oc := p.GetOrderConstraints(o.Pair)
+	trade := model.Trade{
+		Order: model.Order{
+			Pair:        o.Pair,
+			OrderAction: o.OrderAction,
+			OrderType:   model.OrderTypeLimit,
+			Price:       model.NumberFromFloat(price, oc.PricePrecision),
+			Volume:      model.NumberFromFloat(volume, oc.VolumePrecision),
+			Timestamp:   model.MakeTimestamp(time.Now().UnixNano() / int64(time.Millisecond)),
+		},
+		TransactionID: model.MakeTransactionID(fmt.Sprintf("%s-%d", o.ID, len(p.fills)+1)),
+		Cost:          model.NumberFromFloat(cost, largePrecision),
+		Fee:           model.NumberFromFloat(fee, largePrecision),
+	}
+	p.fills = append(p.fills, trade)
+	log.Printf("paper exchange filled order %s: %s\n", o.ID, trade)
+}
+
+// AddOrder impl.
+func (p *paperExchange) AddOrder(order *model.Order) (*model.TransactionID, error) {
+	if order.Price.AsFloat() <= 0 || order.Volume.AsFloat() <= 0 {
+		return nil, api.MakeExchangeError(api.ErrorKindInvalidOrder, "paper", fmt.Errorf("price and volume of order %s need to be positive", *order))
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	e := p.sync(*order.Pair)
+	if e != nil {
+		return nil, e
+	}
+
+	asset, amount := p.reservation(order, order.Volume.AsFloat())
+	b := p.balance(asset)
+	if b.free < amount {
+		return nil, api.MakeExchangeError(api.ErrorKindInsufficientFunds, "paper", fmt.Errorf("order %s needs %f %s but only %f is available", *order, amount, asset, b.free))
+	}
+
+	// the levels of the order book that the order crosses, best first
+	ob, e := p.market.GetOrderBook(order.Pair, 50)
+	if e != nil {
+		return nil, fmt.Errorf("could not fetch the order book of the market: %s", e)
+	}
+	levels := []model.Order{}
+	if order.OrderAction.IsBuy() {
+		for _, ask := range ob.Asks() {
+			if ask.Price.AsFloat() <= order.Price.AsFloat() {
+				levels = append(levels, ask)
+			}
+		}
+	} else {
+		for _, bid := range ob.Bids() {
+			if bid.Price.AsFloat() >= order.Price.AsFloat() {
+				levels = append(levels, bid)
+			}
+		}
+	}
+
+	if order.PostOnly && len(levels) > 0 {
+		return nil, api.MakeExchangeError(api.ErrorKindInvalidOrder, "paper", fmt.Errorf("post-only order %s would cross the order book", *order))
+	}
+	crossingVolume := 0.0
+	for _, level := range levels {
+		crossingVolume += level.Volume.AsFloat()
+	}
+	if order.TimeInForce == model.TimeInForceFOK && crossingVolume < order.Volume.AsFloat() {
+		return nil, api.MakeExchangeError(api.ErrorKindInvalidOrder, "paper", fmt.Errorf("fill-or-kill order %s cannot be filled by the order book", *order))
+	}
+
+	b.free -= amount
+	b.locked += amount
+	o := &paperOrder{
+		Order:  *order,
+		ID:     strconv.Itoa(p.nextID),
+		seq:    p.nextID,
+		status: model.OrderStatusOpen,
+	}
+	if o.Timestamp == nil {
+		o.Timestamp = model.MakeTimestamp(time.Now().UnixNano() / int64(time.Millisecond))
+	}
+	p.nextID++
+	p.orders[o.ID] = o
+
+	// the order takes the liquidity of the levels it crosses at their prices
+	for _, level := range levels {
+		if o.remaining() <= 0 {
+			break
+		}
+		volume := level.Volume.AsFloat()
+		if volume > o.remaining() {
+			volume = o.remaining()
+		}
+		p.fill(o, volume, level.Price.AsFloat())
+	}
+
+	if o.status == model.OrderStatusOpen && order.TimeInForce != model.TimeInForceGTC {
+		p.cancel(o)
+	}
+	log.Printf("paper exchange added order %s: %s\n", o.ID, *order)
+	return model.MakeTransactionID(o.ID), nil
+}
+
+// cancel releases what the remaining volume of the order holds, must be called with the mutex held
+func (p *paperExchange) cancel(o *paperOrder) {
+	asset, amount := p.reservation(&o.Order, o.remaining())
+	b := p.balance(asset)
+	b.locked -= amount
+	b.free += amount
+	o.status = model.OrderStatusCanceled
+}
+
+// CancelOrder impl.
+func (p *paperExchange) CancelOrder(txID *model.TransactionID, pair model.TradingPair) (model.CancelOrderResult, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	// fills that happened before the cancel still count
+	e := p.sync(pair)
+	if e != nil {
+		return model.CancelResultFailed, e
+	}
+
+	o, ok := p.orders[txID.String()]
+	if !ok || o.status != model.OrderStatusOpen {
+		return model.CancelResultFailed, api.MakeExchangeError(api.ErrorKindOrderNotFound, "paper", fmt.Errorf("order %s is not open", txID.String()))
+	}
+	p.cancel(o)
+	log.Printf("paper exchange cancelled order %s\n", txID.String())
+	return model.CancelResultCancelSuccessful, nil
+}
+
+func (p *paperExchange) openOrder(o *paperOrder) model.OpenOrder {
+	oc := p.GetOrderConstraints(o.Pair)
+	return model.OpenOrder{
+		Order:           o.Order,
+		ID:              o.ID,
+		StartTime:       o.Timestamp,
+		VolumeExecuted:  model.NumberFromFloat(o.executed, oc.VolumePrecision),
+		VolumeRemaining: model.NumberFromFloat(o.remaining(), oc.VolumePrecision),
+		Status:          o.status,
+	}
+}
+
+// GetOpenOrders impl.
+func (p *paperExchange) GetOpenOrders(pairs []*model.TradingPair) (map[model.TradingPair][]model.OpenOrder, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	m := map[model.TradingPair][]model.OpenOrder{}
+	for _, pair := range pairs {
+		e := p.sync(*pair)
+		if e != nil {
+			return nil, e
+		}
+
+		open := []*paperOrder{}
+		for _, o := range p.orders {
+			if o.status == model.OrderStatusOpen && *o.Pair == *pair {
+				open = append(open, o)
+			}
+		}
+		sort.Slice(open, func(i int, j int) bool {
+			return open[i].seq < open[j].seq
+		})
+		m[*pair] = []model.OpenOrder{}
+		for _, o := range open {
+			m[*pair] = append(m[*pair], p.openOrder(o))
+		}
+	}
+	return m, nil
+}
+
+// GetOrderStatus impl.
+func (p *paperExchange) GetOrderStatus(txID *model.TransactionID, pair model.TradingPair) (*model.OpenOrder, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	e := p.sync(pair)
+	if e != nil {
+		return nil, e
+	}
+
+	o, ok := p.orders[txID.String()]
+	if !ok {
+		return nil, api.MakeExchangeError(api.ErrorKindOrderNotFound, "paper", fmt.Errorf("unknown order %s", txID.String()))
+	}
+	openOrder := p.openOrder(o)
+	return &openOrder, nil
+}
+
+// QueryOrders impl.
+func (p *paperExchange) QueryOrders(txIDs []*model.TransactionID, pair model.TradingPair) (map[string]model.OpenOrder, error) {
+	return api.QueryOrdersOneByOne(p.GetOrderStatus, txIDs, pair)
+}
+
+// GetAccountBalances impl.
+func (p *paperExchange) GetAccountBalances(assetList []interface{}) (map[interface{}]model.Number, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	// balances change when the resting orders are filled
+	for pair := range p.feeds {
+		e := p.sync(pair)
+		if e != nil {
+			return nil, e
+		}
+	}
+
+	m := map[interface{}]model.Number{}
+	for _, elem := range assetList {
+		asset, ok := elem.(model.Asset)
+		if !ok {
+			return nil, fmt.Errorf("invalid type of asset passed in, only model.Asset accepted")
+		}
+		b := p.balance(asset)
+		m[asset] = *model.NumberFromFloat(b.free+b.locked, precisionBalances)
+	}
+	return m, nil
+}
+
+// GetTradeHistory impl. the cursor is the number of our trades that were already returned
+func (p *paperExchange) GetTradeHistory(pair model.TradingPair, maybeCursorStart interface{}, maybeCursorEnd interface{}) (*api.TradeHistoryResult, error) {
+	start, e := toPaperCursor(maybeCursorStart, 0)
+	if e != nil {
+		return nil, e
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	e = p.sync(pair)
+	if e != nil {
+		return nil, e
+	}
+
+	end, e := toPaperCursor(maybeCursorEnd, int64(len(p.fills)))
+	if e != nil {
+		return nil, e
+	}
+	if end > int64(len(p.fills)) {
+		end = int64(len(p.fills))
+	}
+
+	trades := []model.Trade{}
+	for i := start; i < end; i++ {
+		if *p.fills[i].Pair == pair {
+			trades = append(trades, p.fills[i])
+		}
+	}
+	cursor := start
+	if end > cursor {
+		cursor = end
+	}
+	return &api.TradeHistoryResult{
+		Cursor: cursor,
+		Trades: trades,
+	}, nil
+}
+
+func toPaperCursor(maybeCursor interface{}, defaultValue int64) (int64, error) {
+	switch c := maybeCursor.(type) {
+	case nil:
+		return defaultValue, nil
+	case int64:
+		return c, nil
+	case string:
+		return strconv.ParseInt(c, 10, 64)
+	default:
+		return 0, fmt.Errorf("unsupported cursor type for paper trade history: %T", maybeCursor)
+	}
+}
+
+// GetLatestTradeCursor impl.
+func (p *paperExchange) GetLatestTradeCursor() (interface{}, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return int64(len(p.fills)), nil
+}
+
+// GetTrades impl. returns the trades of the market
+func (p *paperExchange) GetTrades(pair *model.TradingPair, maybeCursor interface{}) (*api.TradesResult, error) {
+	return p.market.GetTrades(pair, maybeCursor)
+}
+
+// GetOrderBook impl. returns the order book of the market, which does not have our orders in it
+func (p *paperExchange) GetOrderBook(pair *model.TradingPair, maxCount int32) (*model.OrderBook, error) {
+	return p.market.GetOrderBook(pair, maxCount)
+}
+
+// GetTickerPrice impl.
+func (p *paperExchange) GetTickerPrice(pairs []model.TradingPair) (map[model.TradingPair]api.Ticker, error) {
+	m := map[model.TradingPair]api.Ticker{}
+	for _, pair := range pairs {
+		ob, e := p.market.GetOrderBook(&pair, 1)
+		if e != nil {
+			return nil, fmt.Errorf("could not fetch the order book of the market for pair %s: %s", pair.String(), e)
+		}
+		if ob.TopAsk() == nil || ob.TopBid() == nil {
+			return nil, fmt.Errorf("the order book of the market for pair %s is empty on one side", pair.String())
+		}
+		m[pair] = api.Ticker{
+			AskPrice: ob.TopAsk().Price,
+			BidPrice: ob.TopBid().Price,
+		}
+	}
+	return m, nil
+}
+
+// GetAssetConverter impl.
+func (p *paperExchange) GetAssetConverter() *model.AssetConverter {
+	return p.market.GetAssetConverter()
+}
+
+// GetOrderConstraints impl.
+func (p *paperExchange) GetOrderConstraints(pair *model.TradingPair) *model.OrderConstraints {
+	return p.market.GetOrderConstraints(pair)
+}
+
+// OverrideOrderConstraints impl, can partially override values for specific pairs
+func (p *paperExchange) OverrideOrderConstraints(pair *model.TradingPair, override *model.OrderConstraintsOverride) {
+	p.market.OverrideOrderConstraints(pair, override)
+}
+
+// PrepareDeposit impl.
+func (p *paperExchange) PrepareDeposit(asset model.Asset, amount *model.Number) (*api.PrepareDepositResult, error) {
+	return nil, api.MakeErrUnsupported("paper", "deposits")
+}
+
+// GetWithdrawInfo impl.
+func (p *paperExchange) GetWithdrawInfo(asset model.Asset, amountToWithdraw *model.Number, address string) (*api.WithdrawInfo, error) {
+	return nil, api.MakeErrUnsupported("paper", "withdrawals")
+}
+
+// WithdrawFunds impl.
+func (p *paperExchange) WithdrawFunds(asset model.Asset, amountToWithdraw *model.Number, address string) (*api.WithdrawFunds, error) {
+	return nil, api.MakeErrUnsupported("paper", "withdrawals")
+}
+
+// paperRecording is market data that was recorded from an exchange for a single trading pair. It is replayed one frame
+// at a time, reading the trades from the cursor of a frame moves the market to the frame after it so the market moves
+// as the bot reads its trades, and reading from the same cursor again does not move it further.
+//
+// The file is JSON:
+//
+//	{
+//	  "base": "XLM", "quote": "USD",
+//	  "pricePrecision": 7, "volumePrecision": 1, "minBaseVolume": 1,
+//	  "frames": [{
+//	    "timestamp": 1554200000000,
+//	    "bids": [[0.1005, 300]], "asks": [[0.1010, 250]],
+//	    "trades": [{"id": "t1", "side": "buy", "price": 0.1010, "volume": 50}]
+//	  }]
+//	}
+type paperRecording struct {
+	Base            model.Asset           `json:"base"`
+	Quote           model.Asset           `json:"quote"`
+	PricePrecision  int8                  `json:"pricePrecision"`
+	VolumePrecision int8                  `json:"volumePrecision"`
+	MinBaseVolume   float64               `json:"minBaseVolume"`
+	Frames          []paperRecordingFrame `json:"frames"`
+
+	ocOverridesHandler *model.OrderConstraintsOverridesHandler
+	frame              int // the latest frame that the trades were read up to, the order book is of this frame
+}
+
+type paperRecordingFrame struct {
+	Timestamp int64                 `json:"timestamp"`
+	Bids      [][2]float64          `json:"bids"`
+	Asks      [][2]float64          `json:"asks"`
+	Trades    []paperRecordingTrade `json:"trades"`
+}
+
+type paperRecordingTrade struct {
+	ID     string  `json:"id"`
+	Side   string  `json:"side"`
+	Price  float64 `json:"price"`
+	Volume float64 `json:"volume"`
+}
+
+func loadPaperRecording(filename string) (*paperRecording, error) {
+	data, e := ioutil.ReadFile(filename)
+	if e != nil {
+		return nil, fmt.Errorf("could not read file: %s", e)
+	}
+
+	r := &paperRecording{}
+	e = json.Unmarshal(data, r)
+	if e != nil {
+		return nil, fmt.Errorf("could not parse file '%s': %s", filename, e)
+	}
+	if r.Base == "" || r.Quote == "" {
+		return nil, fmt.Errorf("the recording in file '%s' does not have the base and quote assets of its trading pair", filename)
+	}
+	if len(r.Frames) == 0 {
+		return nil, fmt.Errorf("the recording in file '%s' does not have any frames", filename)
+	}
+	r.ocOverridesHandler = model.MakeEmptyOrderConstraintsOverridesHandler()
+	return r, nil
+}
+
+// checkPair returns an error if the pair is not the one that was recorded
+func (r *paperRecording) checkPair(pair *model.TradingPair) error {
+	if pair.Base != r.Base || pair.Quote != r.Quote {
+		return fmt.Errorf("the recording is of trading pair %s, not %s", model.TradingPair{Base: r.Base, Quote: r.Quote}.String(), pair.String())
+	}
+	return nil
+}
+
+// GetTrades returns the trades of the frame after the cursor and moves the market to that frame, the cursor is the index
+// of a frame. Without a cursor it returns the trades up to the current frame.
+func (r *paperRecording) GetTrades(pair *model.TradingPair, maybeCursor interface{}) (*api.TradesResult, error) {
+	e := r.checkPair(pair)
+	if e != nil {
+		return nil, e
+	}
+
+	from := -1
+	to := r.frame
+	if maybeCursor != nil {
+		c, ok := maybeCursor.(int)
+		if !ok || c < 0 || c >= len(r.Frames) {
+			return nil, fmt.Errorf("unsupported cursor for a recording: %v (%T)", maybeCursor, maybeCursor)
+		}
+		from = c
+		to = c
+		if to < len(r.Frames)-1 {
+			to++
+		}
+		// the market does not move back when an older cursor is read again
+		if to > r.frame {
+			r.frame = to
+		}
+	}
+
+	trades := []model.Trade{}
+	for i := from + 1; i <= to; i++ {
+		f := r.Frames[i]
+		for _, t := range f.Trades {
+			trades = append(trades, model.Trade{
+				Order: model.Order{
+					Pair:        pair,
+					OrderAction: model.OrderActionFromString(t.Side),
+					OrderType:   model.OrderTypeLimit,
+					Price:       model.NumberFromFloat(t.Price, r.PricePrecision),
+					Volume:      model.NumberFromFloat(t.Volume, r.VolumePrecision),
+					Timestamp:   model.MakeTimestamp(f.Timestamp),
+				},
+				TransactionID: model.MakeTransactionID(t.ID),
+			})
+		}
+	}
+	return &api.TradesResult{
+		Cursor: to,
+		Trades: trades,
+	}, nil
+}
+
+// GetOrderBook returns the order book of the current frame
+func (r *paperRecording) GetOrderBook(pair *model.TradingPair, maxCount int32) (*model.OrderBook, error) {
+	e := r.checkPair(pair)
+	if e != nil {
+		return nil, e
+	}
+
+	f := r.Frames[r.frame]
+	readLevels := func(levels [][2]float64, orderAction model.OrderAction) []model.Order {
+		orders := []model.Order{}
+		for i, level := range levels {
+			if int32(i) >= maxCount {
+				break
+			}
+			orders = append(orders, model.Order{
+				Pair:        pair,
+				OrderAction: orderAction,
+				OrderType:   model.OrderTypeLimit,
+				Price:       model.NumberFromFloat(level[0], r.PricePrecision),
+				Volume:      model.NumberFromFloat(level[1], r.VolumePrecision),
+				Timestamp:   model.MakeTimestamp(f.Timestamp),
+			})
+		}
+		return orders
+	}
+	return model.MakeOrderBook(pair, readLevels(f.Asks, model.OrderActionSell), readLevels(f.Bids, model.OrderActionBuy)), nil
+}
+
+// GetAssetConverter impl.
+func (r *paperRecording) GetAssetConverter() *model.AssetConverter {
+	return model.Display
+}
+
+// GetOrderConstraints impl.
+func (r *paperRecording) GetOrderConstraints(pair *model.TradingPair) *model.OrderConstraints {
+	oc := model.MakeOrderConstraints(r.PricePrecision, r.VolumePrecision, r.MinBaseVolume)
+	return r.ocOverridesHandler.Apply(pair, oc)
+}
+
+// OverrideOrderConstraints impl, can partially override values for specific pairs
+func (r *paperRecording) OverrideOrderConstraints(pair *model.TradingPair, override *model.OrderConstraintsOverride) {
+	r.ocOverridesHandler.Upsert(pair, override)
+}
@@ -0,0 +1,251 @@
+package plugins
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stellar/kelp/api"
+	"github.com/stellar/kelp/model"
+	"github.com/stretchr/testify/assert"
+)
+
+const testPaperRecording = `{
+	"base": "XLM",
+	"quote": "USD",
+	"pricePrecision": 4,
+	"volumePrecision": 1,
+	"minBaseVolume": 1,
+	"frames": [
+		{"timestamp": 1, "bids": [[0.10, 100]], "asks": [[0.11, 100]], "trades": [{"id": "t0", "side": "buy", "price": 0.11, "volume": 10}]},
+		{"timestamp": 2, "bids": [[0.10, 100]], "asks": [[0.11, 100]], "trades": [{"id": "t1", "side": "sell", "price": 0.095, "volume": 30}]},
+		{"timestamp": 3, "bids": [[0.10, 25]], "asks": [[0.12, 100]], "trades": [{"id": "t2", "side": "buy", "price": 0.12, "volume": 500}]}
+	]
+}`
+
+func writeTestPaperRecording(t *testing.T) string {
+	f, e := ioutil.TempFile("", "paper_recording")
+	if !assert.NoError(t, e) {
+		t.FailNow()
+	}
+	_, e = f.WriteString(testPaperRecording)
+	f.Close()
+	if !assert.NoError(t, e) {
+		t.FailNow()
+	}
+	return f.Name()
+}
+
+func makeTestPaperExchange(t *testing.T) (api.Exchange, func()) {
+	filename := writeTestPaperRecording(t)
+	x, e := makePaperExchange([]api.ExchangeParam{
+		{Param: "recording", Value: filename},
+		{Param: "balance_XLM", Value: "1000"},
+		{Param: "balance_USD", Value: "100"},
+	}, nil)
+	if !assert.NoError(t, e) {
+		t.FailNow()
+	}
+	return x, func() { os.Remove(filename) }
+}
+
+func TestPaperExchange(t *testing.T) {
+	x, cleanup := makeTestPaperExchange(t)
+	defer cleanup()
+	pair := model.TradingPair{Base: model.XLM, Quote: model.USD}
+	makeOrder := func(action model.OrderAction, price float64, volume float64, postOnly bool) *model.Order {
+		return &model.Order{
+			Pair:        &pair,
+			OrderAction: action,
+			OrderType:   model.OrderTypeLimit,
+			Price:       model.NumberFromFloat(price, 4),
+			Volume:      model.NumberFromFloat(volume, 1),
+			PostOnly:    postOnly,
+		}
+	}
+
+	// the buy rests below the asks
+	txID, e := x.AddOrder(makeOrder(model.OrderActionBuy, 0.10, 50, true))
+	if !assert.NoError(t, e) {
+		return
+	}
+	assert.Equal(t, "1", txID.String())
+
+	// the market moves to the next frame, where a seller fills 30 of the resting buy, and the post-only sell would cross
+	_, e = x.AddOrder(makeOrder(model.OrderActionSell, 0.10, 10, true))
+	assert.Equal(t, api.ErrorKindInvalidOrder, api.ErrorKindOf(e))
+
+	openOrders, e := x.GetOpenOrders([]*model.TradingPair{&pair})
+	if !assert.NoError(t, e) {
+		return
+	}
+	if !assert.Equal(t, 1, len(openOrders[pair])) {
+		return
+	}
+	assert.Equal(t, "1", openOrders[pair][0].ID)
+	assert.Equal(t, 30.0, openOrders[pair][0].VolumeExecuted.AsFloat())
+	assert.Equal(t, 20.0, openOrders[pair][0].VolumeRemaining.AsFloat())
+
+	// the sell takes the 25 on the bid and rests with the rest
+	txID, e = x.AddOrder(makeOrder(model.OrderActionSell, 0.10, 40, false))
+	if !assert.NoError(t, e) {
+		return
+	}
+	status, e := x.(api.OrderStatusAPI).GetOrderStatus(txID, pair)
+	if !assert.NoError(t, e) {
+		return
+	}
+	assert.Equal(t, model.OrderStatusOpen, status.Status)
+	assert.Equal(t, 15.0, status.VolumeRemaining.AsFloat())
+
+	_, e = x.AddOrder(makeOrder(model.OrderActionBuy, 0.10, 10000, false))
+	assert.Equal(t, api.ErrorKindInsufficientFunds, api.ErrorKindOf(e))
+
+	result, e := x.CancelOrder(txID, pair)
+	if !assert.NoError(t, e) {
+		return
+	}
+	assert.Equal(t, model.CancelResultCancelSuccessful, result)
+	_, e = x.CancelOrder(txID, pair)
+	assert.Equal(t, api.ErrorKindOrderNotFound, api.ErrorKindOf(e))
+
+	balances, e := x.GetAccountBalances([]interface{}{model.XLM, model.USD})
+	if !assert.NoError(t, e) {
+		return
+	}
+	assert.Equal(t, 1005.0, balances[model.XLM].AsFloat())
+	assert.Equal(t, 99.5, balances[model.USD].AsFloat())
+
+	history, e := x.GetTradeHistory(pair, int64(0), nil)
+	if !assert.NoError(t, e) {
+		return
+	}
+	if !assert.Equal(t, 2, len(history.Trades)) {
+		return
+	}
+	assert.Equal(t, model.OrderActionBuy, history.Trades[0].OrderAction)
+	assert.Equal(t, 30.0, history.Trades[0].Volume.AsFloat())
+	assert.Equal(t, model.OrderActionSell, history.Trades[1].OrderAction)
+	assert.Equal(t, 25.0, history.Trades[1].Volume.AsFloat())
+	assert.Equal(t, 0.1, history.Trades[1].Price.AsFloat())
+
+	cursor, e := x.GetLatestTradeCursor()
+	if !assert.NoError(t, e) {
+		return
+	}
+	assert.Equal(t, history.Cursor, cursor)
+	history, e = x.GetTradeHistory(pair, cursor, nil)
+	if !assert.NoError(t, e) {
+		return
+	}
+	assert.Equal(t, 0, len(history.Trades))
+}
+
+func TestPaperRecording(t *testing.T) {
+	filename := writeTestPaperRecording(t)
+	defer os.Remove(filename)
+	r, e := loadPaperRecording(filename)
+	if !assert.NoError(t, e) {
+		return
+	}
+	pair := model.TradingPair{Base: model.XLM, Quote: model.USD}
+
+	result, e := r.GetTrades(&pair, nil)
+	if !assert.NoError(t, e) {
+		return
+	}
+	assert.Equal(t, 0, result.Cursor)
+	assert.Equal(t, 1, len(result.Trades))
+
+	// reading from the same cursor again does not move the market any further
+	for i := 0; i < 2; i++ {
+		result, e = r.GetTrades(&pair, 0)
+		if !assert.NoError(t, e) {
+			return
+		}
+		assert.Equal(t, 1, result.Cursor)
+		if assert.Equal(t, 1, len(result.Trades)) {
+			assert.Equal(t, "t1", result.Trades[0].TransactionID.String())
+		}
+	}
+
+	// the market stays on the last frame once it is reached
+	result, e = r.GetTrades(&pair, 1)
+	if !assert.NoError(t, e) {
+		return
+	}
+	result, e = r.GetTrades(&pair, 2)
+	if !assert.NoError(t, e) {
+		return
+	}
+	assert.Equal(t, 2, result.Cursor)
+	assert.Equal(t, 0, len(result.Trades))
+	ob, e := r.GetOrderBook(&pair, 10)
+	if !assert.NoError(t, e) {
+		return
+	}
+	assert.Equal(t, 25.0, ob.Bids()[0].Volume.AsFloat())
+
+	_, e = r.GetTrades(&pair, "1")
+	assert.Error(t, e)
+	otherPair := model.TradingPair{Base: model.XLM, Quote: model.BTC}
+	_, e = r.GetTrades(&otherPair, 0)
+	assert.Error(t, e)
+	_, e = r.GetOrderBook(&otherPair, 10)
+	assert.Error(t, e)
+}
+
+func TestPaperExchangeParams(t *testing.T) {
+	_, e := makePaperExchange([]api.ExchangeParam{{Param: "balance_XLM", Value: "1000"}}, nil)
+	assert.Error(t, e)
+
+	_, e = makePaperExchange([]api.ExchangeParam{{Param: "source", Value: "paper"}}, nil)
+	assert.Error(t, e)
+
+	_, e = makePaperExchange([]api.ExchangeParam{{Param: "fee", Value: "-1"}}, nil)
+	assert.Error(t, e)
+
+	filename := writeTestPaperRecording(t)
+	defer os.Remove(filename)
+	_, e = makePaperExchange([]api.ExchangeParam{
+		{Param: "recording", Value: filename},
+		{Param: "source.baseUrl", Value: "http://localhost"},
+	}, nil)
+	assert.Error(t, e)
+}
+
+func TestPaperExchangeSourceParams(t *testing.T) {
+	s := makeTestStrongholdServer()
+	defer s.Close()
+	// loading all the exchanges needs ccxt-rest so the source is the only exchange here
+	var sourceData exchangeFactoryData
+	defer func(x *map[string]ExchangeContainer) { exchanges = x }(exchanges)
+	exchanges = &map[string]ExchangeContainer{
+		"stronghold": {
+			makeFn: func(exchangeFactoryData exchangeFactoryData) (api.Exchange, error) {
+				sourceData = exchangeFactoryData
+				return makeStrongholdExchange(exchangeFactoryData.apiKeys, exchangeFactoryData.exchangeParams, exchangeFactoryData.simMode)
+			},
+		},
+	}
+
+	// the source exchange is configured with the params that have the source prefix and with the headers
+	headers := []api.ExchangeHeader{{Header: "X-Test", Value: "1"}}
+	x, e := makePaperExchange([]api.ExchangeParam{
+		{Param: "source", Value: "stronghold"},
+		{Param: "source.baseUrl", Value: s.URL},
+		{Param: "balance_XLM", Value: "1000"},
+	}, headers)
+	if !assert.NoError(t, e) {
+		return
+	}
+	assert.Equal(t, []api.ExchangeParam{{Param: "baseUrl", Value: s.URL}}, sourceData.exchangeParams)
+	assert.Equal(t, headers, sourceData.headers)
+	assert.True(t, sourceData.simMode)
+	ob, e := x.GetOrderBook(&testStrongholdPair, 10)
+	if !assert.NoError(t, e) {
+		return
+	}
+	assert.NotNil(t, ob)
+	assert.Equal(t, 1, countTestStrongholdRequests(s, "GET /v1/venues/test-venue/markets"))
+}
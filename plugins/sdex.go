@@ -49,7 +49,7 @@ type SDEX struct {
 	seqNum             uint64
 	reloadSeqNum       bool
 	ieif               *IEIF
-	ocOverridesHandler *OrderConstraintsOverridesHandler
+	ocOverridesHandler *model.OrderConstraintsOverridesHandler
 }
 
 // enforce SDEX implements api.Constrainable
@@ -99,7 +99,7 @@ func MakeSDEX(
 		assetMap:           assetMap,
 		opFeeStroopsFn:     opFeeStroopsFn,
 		tradingOnSdex:      exchangeShim == nil,
-		ocOverridesHandler: MakeEmptyOrderConstraintsOverridesHandler(),
+		ocOverridesHandler: model.MakeEmptyOrderConstraintsOverridesHandler(),
 	}
 
 	if exchangeShim == nil {
@@ -265,6 +265,11 @@ func (sdex *SDEX) LoadOffersHack() ([]horizon.Offer, error) {
 	return sdex._loadOffers()
 }
 
+// LoadAllOffersHack impl, the offers of all the bots are on the trading account so this is the same as LoadOffersHack
+func (sdex *SDEX) LoadAllOffersHack() ([]horizon.Offer, error) {
+	return sdex._loadOffers()
+}
+
 func (sdex *SDEX) _loadOffers() ([]horizon.Offer, error) {
 	return utils.LoadAllOffers(sdex.TradingAccount, sdex.API)
 }
@@ -1,16 +1,16 @@
 package plugins
 
 import (
-	"errors"
 	"fmt"
 	"log"
 	"math"
-	"reflect"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/pkg/errors"
 	"github.com/stellar/kelp/api"
 	"github.com/stellar/kelp/model"
 	"github.com/stellar/kelp/support/networking"
@@ -20,114 +20,408 @@ import (
 // ensure that strongholdExchange conforms to the Exchange interface
 var _ api.Exchange = &strongholdExchange{}
 
-const strongholdprecisionBalances = 10
+// ensure that strongholdExchange can look up orders
+var _ api.OrderStatusAPI = &strongholdExchange{}
 
-// strongholdExchange is the implementation for the Kraken Exchange
-type strongholdExchange struct {
-	assetConverter           *model.AssetConverter
-	assetConverterOpenOrders *model.AssetConverter // kraken uses different symbols when fetching open orders!
-	apis                     []*strongholdapi.StrongholdApi
-	apiNextIndex             uint8
-	delimiter                string
-	withdrawKeys             strongholdAsset2Address2Key
-	isSimulated              bool // will simulate add and cancel orders if this is true
-}
+// ensure that strongholdExchange can tag orders with client order IDs
+var _ api.ClientOrderIDAPI = &strongholdExchange{}
 
-type strongholdAsset2Address2Key map[model.Asset]map[string]string
+const strongholdprecisionBalances = 10
 
-func (m strongholdAsset2Address2Key) getKey(asset model.Asset, address string) (string, error) {
-	address2Key, ok := m[asset]
-	if !ok {
-		return "", fmt.Errorf("asset (%v) is not registered in strongholdAsset2Address2Key: %v", asset, m)
-	}
+// strongholdDefaultMarketsRefreshInterval is how often the market metadata is reloaded when not configured
+const strongholdDefaultMarketsRefreshInterval = 1 * time.Hour
 
-	key, ok := address2Key[address]
-	if !ok {
-		return "", fmt.Errorf("address is not registered in strongholdAsset2Address2Key: %v (asset = %v)", address, asset)
-	}
+// strongholdMarketsRetryDelay is how long we wait before refreshing the markets again after a refresh failed
+const strongholdMarketsRetryDelay = 1 * time.Minute
+
+// strongholdExchange is the implementation for the Stronghold Exchange
+type strongholdExchange struct {
+	assetConverter         *model.AssetConverter
+	apis                   []*strongholdapi.StrongholdApi
+	keyPool                *networking.KeyPool
+	delimiter              string
+	ocOverridesHandler     *model.OrderConstraintsOverridesHandler
+	marketsRefreshInterval time.Duration
+	isSimulated            bool // will simulate add and cancel orders if this is true
+	// stream is nil unless streaming is enabled, when synced it serves the order books and our own fills
+	stream *strongholdapi.Stream
+
+	// initialized runtime vars
+	marketsMutex    *sync.Mutex
+	markets         map[model.TradingPair]strongholdMarket
+	marketsLoadedAt time.Time
+	// marketsLoadMutex is held while the markets are refreshed so only one refresh is in flight, marketsLoads counts
+	// the refreshes so callers that waited for one use its result instead of sending another request
+	marketsLoadMutex *sync.Mutex
+	marketsLoads     uint64
+	marketsFailedAt  time.Time // time of the last refresh that failed, we back off from refreshing after it
+}
 
-	return key, nil
+// strongholdMarket is the market metadata we need for a trading pair
+type strongholdMarket struct {
+	id          string
+	constraints model.OrderConstraints
 }
 
-// makeKrakenExchange is a factory method to make the kraken exchange
-// TODO 2, should take in config file for withdrawalKeys mapping
-func makeStrongholdExchange(apiKeys []api.ExchangeAPIKey, isSimulated bool) (api.Exchange, error) {
+// params that can be specified for the stronghold exchange using EXCHANGE_PARAMS
+const (
+	strongholdParamBaseURL                = "baseUrl"
+	strongholdParamVenueID                = "venueId"
+	strongholdParamAccountID              = "accountId"
+	strongholdParamMarketsRefreshInterval = "marketsRefreshSeconds"
+	strongholdParamStream                 = "stream"
+)
+
+// makeStrongholdExchange is a factory method to make the stronghold exchange
+func makeStrongholdExchange(apiKeys []api.ExchangeAPIKey, exchangeParams []api.ExchangeParam, isSimulated bool) (api.Exchange, error) {
 	if len(apiKeys) == 0 || len(apiKeys) > math.MaxUint8 {
 		return nil, fmt.Errorf("invalid number of apiKeys: %d", len(apiKeys))
 	}
 
+	baseURL := strongholdapi.APIURL
+	venueID := ""
+	accountID := ""
+	marketsRefreshInterval := strongholdDefaultMarketsRefreshInterval
+	useStream := false
+	for _, param := range exchangeParams {
+		switch param.Param {
+		case strongholdParamBaseURL:
+			baseURL = strings.TrimSuffix(param.Value, "/")
+		case strongholdParamVenueID:
+			venueID = param.Value
+		case strongholdParamAccountID:
+			accountID = param.Value
+		case strongholdParamMarketsRefreshInterval:
+			seconds, e := strconv.ParseUint(param.Value, 10, 32)
+			if e != nil {
+				return nil, fmt.Errorf("could not parse exchange param %s as a number of seconds (%s): %s", param.Param, param.Value, e)
+			}
+			marketsRefreshInterval = time.Duration(seconds) * time.Second
+		case strongholdParamStream:
+			b, e := strconv.ParseBool(param.Value)
+			if e != nil {
+				return nil, fmt.Errorf("could not parse exchange param %s as a boolean (%s): %s", param.Param, param.Value, e)
+			}
+			useStream = b
+		default:
+			return nil, fmt.Errorf("unrecognized exchange param for stronghold: '%s' (supported params: %s, %s, %s, %s, %s)",
+				param.Param, strongholdParamBaseURL, strongholdParamVenueID, strongholdParamAccountID, strongholdParamMarketsRefreshInterval, strongholdParamStream)
+		}
+	}
+	log.Printf("making stronghold exchange with baseURL=%s, venueID=%s, accountID=%s, stream=%v (venueID and accountID are resolved from the API when empty)\n", baseURL, venueID, accountID, useStream)
+
 	strongholdAPIs := []*strongholdapi.StrongholdApi{}
 	for _, apiKey := range apiKeys {
 		strongholdAPIClient := strongholdapi.New(apiKey.Key, apiKey.Secret)
+		strongholdAPIClient.SetBaseURL(baseURL)
+		if venueID != "" {
+			strongholdAPIClient.SetVenueID(venueID)
+		}
+		if accountID != "" {
+			strongholdAPIClient.SetAccountID(accountID)
+		}
 		strongholdAPIs = append(strongholdAPIs, strongholdAPIClient)
 	}
 
-	return &strongholdExchange{
-		assetConverter:           model.StrongholdAssetConverter,
-		assetConverterOpenOrders: model.StrongholdAssetConverterOpenOrders,
-		apis:         strongholdAPIs,
-		apiNextIndex: 0,
-		delimiter:    "",
-		withdrawKeys: strongholdAsset2Address2Key{},
-		isSimulated:  isSimulated,
-	}, nil
+	keyPool, e := networking.MakeKeyPool("stronghold", len(strongholdAPIs), strongholdRateLimits)
+	if e != nil {
+		return nil, fmt.Errorf("could not make key pool: %s", e)
+	}
+
+	k := &strongholdExchange{
+		assetConverter:         model.StrongholdAssetConverter,
+		apis:                   strongholdAPIs,
+		keyPool:                keyPool,
+		delimiter:              "",
+		ocOverridesHandler:     model.MakeEmptyOrderConstraintsOverridesHandler(),
+		marketsRefreshInterval: marketsRefreshInterval,
+		isSimulated:            isSimulated,
+		marketsMutex:           &sync.Mutex{},
+		markets:                map[model.TradingPair]strongholdMarket{},
+		marketsLoadMutex:       &sync.Mutex{},
+	}
+
+	e = k.loadMarkets()
+	if e != nil {
+		return nil, fmt.Errorf("could not load stronghold markets: %s", e)
+	}
+
+	if useStream {
+		k.stream, e = strongholdAPIs[0].NewStream()
+		if e != nil {
+			return nil, fmt.Errorf("could not make stronghold stream: %s", e)
+		}
+		// exchanges that are only used for market data are made without credentials so cannot subscribe to fills
+		if apiKeys[0].Key != "" {
+			e = k.stream.SubscribeFills()
+			if e != nil {
+				log.Printf("could not subscribe to stronghold fills, trade history will be fetched over REST until the stream reconnects: %s\n", e)
+			}
+		}
+	}
+	return k, nil
 }
 
-// nextAPI rotates the API key being used so we can overcome rate limit issues
-func (k *strongholdExchange) nextAPI() *strongholdapi.StrongholdApi {
-	log.Printf("returning stronghold API key at index %d", k.apiNextIndex)
-	api := k.apis[k.apiNextIndex]
-	// rotate key for the next call
-	k.apiNextIndex = (k.apiNextIndex + 1) % uint8(len(k.apis))
-	return api
+// loadMarkets fetches the market metadata and replaces the cached markets
+func (k *strongholdExchange) loadMarkets() error {
+	strongholdAPI, key := k.nextAPI(networking.EndpointClassPublic)
+	markets, e := strongholdAPI.Markets()
+	if e != nil {
+		return k.keyError(key, e)
+	}
+
+	m := map[model.TradingPair]strongholdMarket{}
+	for _, market := range markets {
+		base, e := k.assetConverter.FromString(market.BaseAssetID)
+		if e != nil {
+			// not every asset listed on stronghold is understood by the bot
+			continue
+		}
+		quote, e := k.assetConverter.FromString(market.CounterAssetID)
+		if e != nil {
+			continue
+		}
+
+		oc, e := strongholdParseOrderConstraints(market)
+		if e != nil {
+			return fmt.Errorf("could not parse order constraints of market %s: %s", market.ID, e)
+		}
+		m[model.TradingPair{Base: base, Quote: quote}] = strongholdMarket{
+			id:          market.ID,
+			constraints: *oc,
+		}
+	}
+
+	k.marketsMutex.Lock()
+	defer k.marketsMutex.Unlock()
+	k.markets = m
+	k.marketsLoadedAt = time.Now()
+	log.Printf("loaded %d stronghold markets that are understood by the bot (%d listed)\n", len(m), len(markets))
+	return nil
+}
+
+// getMarket returns the market for the pair, refreshing the markets when they are stale
+func (k *strongholdExchange) getMarket(pair *model.TradingPair) (strongholdMarket, bool) {
+	k.marketsMutex.Lock()
+	loadedAt := k.marketsLoadedAt
+	loads := k.marketsLoads
+	failedAt := k.marketsFailedAt
+	k.marketsMutex.Unlock()
+
+	if time.Since(loadedAt) > k.marketsRefreshInterval && time.Since(failedAt) > strongholdMarketsRetryDelay {
+		k.refreshMarkets(loads)
+	}
+
+	k.marketsMutex.Lock()
+	defer k.marketsMutex.Unlock()
+	market, ok := k.markets[*pair]
+	return market, ok
+}
+
+// refreshMarkets reloads the stale markets unless another caller refreshed them after we saw the markets as stale
+func (k *strongholdExchange) refreshMarkets(seenLoads uint64) {
+	k.marketsLoadMutex.Lock()
+	defer k.marketsLoadMutex.Unlock()
+
+	k.marketsMutex.Lock()
+	loads := k.marketsLoads
+	loadedAt := k.marketsLoadedAt
+	k.marketsMutex.Unlock()
+	if loads != seenLoads {
+		// we waited for the refresh of another caller, it has the same result as ours would have
+		return
+	}
+
+	e := k.loadMarkets()
+	k.marketsMutex.Lock()
+	k.marketsLoads++
+	if e != nil {
+		k.marketsFailedAt = time.Now()
+	}
+	k.marketsMutex.Unlock()
+	if e != nil {
+		// keep using the markets we have, they will be refreshed again after the retry delay
+		log.Printf("could not refresh stronghold markets, continuing with markets loaded at %s and retrying in %s: %s\n",
+			loadedAt.Format(time.RFC3339), strongholdMarketsRetryDelay, e)
+	}
+}
+
+// marketID returns the stronghold market id for the pair
+func (k *strongholdExchange) marketID(pair *model.TradingPair) (string, error) {
+	market, ok := k.getMarket(pair)
+	if ok {
+		return market.id, nil
+	}
+	// fall back to the conventional market id for pairs that were not loaded, e.g. pairs with overridden constraints
+	return pair.ToString(k.assetConverter, k.delimiter)
+}
+
+func strongholdParseOrderConstraints(market strongholdapi.Market) (*model.OrderConstraints, error) {
+	pricePrecision, e := strongholdPrecisionFromIncrement(market.MinimumPriceIncrement)
+	if e != nil {
+		return nil, fmt.Errorf("invalid minimumPriceIncrement: %s", e)
+	}
+	volumePrecision, e := strongholdPrecisionFromIncrement(market.MinimumSizeIncrement)
+	if e != nil {
+		return nil, fmt.Errorf("invalid minimumSizeIncrement: %s", e)
+	}
+
+	minBaseVolume := 0.0
+	if market.MinimumSize != "" {
+		minBaseVolume, e = strconv.ParseFloat(market.MinimumSize, 64)
+		if e != nil {
+			return nil, fmt.Errorf("invalid minimumSize (%s): %s", market.MinimumSize, e)
+		}
+	}
+
+	if market.MinimumValue == "" {
+		return model.MakeOrderConstraints(pricePrecision, volumePrecision, minBaseVolume), nil
+	}
+	minQuoteVolume, e := strconv.ParseFloat(market.MinimumValue, 64)
+	if e != nil {
+		return nil, fmt.Errorf("invalid minimumValue (%s): %s", market.MinimumValue, e)
+	}
+	if minQuoteVolume == 0 {
+		return model.MakeOrderConstraints(pricePrecision, volumePrecision, minBaseVolume), nil
+	}
+	return model.MakeOrderConstraintsWithCost(pricePrecision, volumePrecision, minBaseVolume, minQuoteVolume), nil
+}
+
+// strongholdPrecisionFromIncrement converts an increment such as "0.001" to the number of decimal places, 3 in this case
+func strongholdPrecisionFromIncrement(increment string) (int8, error) {
+	f, e := strconv.ParseFloat(increment, 64)
+	if e != nil {
+		return 0, fmt.Errorf("could not parse increment (%s): %s", increment, e)
+	}
+	if f <= 0 {
+		return 0, fmt.Errorf("increment should be positive: %s", increment)
+	}
+
+	parts := strings.SplitN(increment, ".", 2)
+	if len(parts) == 1 {
+		return 0, nil
+	}
+	return int8(len(strings.TrimRight(parts[1], "0"))), nil
+}
+
+// strongholdRateLimits keeps each API key well within the request rate that stronghold allows
+var strongholdRateLimits = map[networking.EndpointClass]networking.RateLimit{
+	networking.EndpointClassPublic:  {Burst: 20, Interval: 50 * time.Millisecond},
+	networking.EndpointClassPrivate: {Burst: 20, Interval: 50 * time.Millisecond},
+	networking.EndpointClassTrading: {Burst: 20, Interval: 50 * time.Millisecond},
+	networking.EndpointClassFunding: {Burst: 5, Interval: time.Second},
+}
+
+// nextAPI returns the client for the API key that can call the class of endpoints soonest so we can overcome rate limit issues
+func (k *strongholdExchange) nextAPI(class networking.EndpointClass) (*strongholdapi.StrongholdApi, int) {
+	index := k.keyPool.Acquire(class)
+	log.Printf("returning stronghold API key at index %d for %s endpoint", index, class)
+	return k.apis[index], index
+}
+
+// KeyUsageMetrics impl.
+func (k *strongholdExchange) KeyUsageMetrics() map[string]interface{} {
+	return k.keyPool.Metrics()
+}
+
+// SupportsClientOrderIDs impl.
+func (k *strongholdExchange) SupportsClientOrderIDs() bool {
+	return true
+}
+
+// keyError classifies the error and backs off from the API key when stronghold throttled it
+func (k *strongholdExchange) keyError(index int, e error) error {
+	e = strongholdError(e)
+	if api.ErrorKindOf(e) == api.ErrorKindRateLimited {
+		k.keyPool.Throttle(index, api.RetryAfter(e))
+	}
+	return e
+}
+
+// strongholdError classifies the errors returned by the stronghold client so the trader can react to them, see api.ExchangeError
+func strongholdError(e error) error {
+	// the client wraps the errors from resolving the venue and account
+	switch err := errors.Cause(e).(type) {
+	case *strongholdapi.NetworkError:
+		return api.MakeExchangeError(api.ErrorKindTransient, "stronghold", e)
+	case *strongholdapi.APIError:
+		switch err.Code {
+		case strongholdapi.ErrorCodeRateLimited:
+			return api.MakeErrRateLimited("stronghold", err.RetryAfter, e)
+		case strongholdapi.ErrorCodeUnauthorized:
+			return api.MakeExchangeError(api.ErrorKindAuth, "stronghold", e)
+		case strongholdapi.ErrorCodeInsufficientFunds:
+			return api.MakeExchangeError(api.ErrorKindInsufficientFunds, "stronghold", e)
+		case strongholdapi.ErrorCodeOrderNotFound:
+			return api.MakeExchangeError(api.ErrorKindOrderNotFound, "stronghold", e)
+		case strongholdapi.ErrorCodePostOnlyRejected:
+			return api.MakeExchangeError(api.ErrorKindInvalidOrder, "stronghold", e)
+		}
+
+		kind := api.ErrorKindFromStatusCode(err.StatusCode)
+		if kind == api.ErrorKindRateLimited {
+			return api.MakeErrRateLimited("stronghold", err.RetryAfter, e)
+		}
+		return api.MakeExchangeError(kind, "stronghold", e)
+	default:
+		return e
+	}
+}
+
+// strongholdTimeInForce maps to the time in force values of the stronghold API, GTC is the default so it is not sent
+var strongholdTimeInForce = map[model.TimeInForce]string{
+	model.TimeInForceGTC: "",
+	model.TimeInForceIOC: strongholdapi.TimeInForceIOC,
+	model.TimeInForceFOK: strongholdapi.TimeInForceFOK,
 }
 
 // AddOrder impl.
 func (k *strongholdExchange) AddOrder(order *model.Order) (*model.TransactionID, error) {
-	pairStr, e := order.Pair.ToString(k.assetConverter, k.delimiter)
+	pairStr, e := k.marketID(order.Pair)
 	if e != nil {
 		return nil, e
 	}
 
 	if k.isSimulated {
-		log.Printf("not adding order to Kraken in simulation mode, order=%s\n", *order)
+		log.Printf("not adding order to Stronghold in simulation mode, order=%s\n", *order)
 		return model.MakeTransactionID("simulated"), nil
 	}
 
-	orderConstraints := k.GetOrderConstraints(order.Pair)
+	if !order.OrderType.IsLimit() {
+		return nil, fmt.Errorf("stronghold only supports limit orders, got orderType = %s", order.OrderType.String())
+	}
+	orderConstraints, e := k.orderConstraints(order.Pair)
+	if e != nil {
+		return nil, e
+	}
 	if order.Price.Precision() > orderConstraints.PricePrecision {
-		return nil, fmt.Errorf("kraken price precision can be a maximum of %d, got %d, value = %.12f", orderConstraints.PricePrecision, order.Price.Precision(), order.Price.AsFloat())
+		return nil, fmt.Errorf("stronghold price precision can be a maximum of %d, got %d, value = %.12f", orderConstraints.PricePrecision, order.Price.Precision(), order.Price.AsFloat())
 	}
 	if order.Volume.Precision() > orderConstraints.VolumePrecision {
-		return nil, fmt.Errorf("kraken volume precision can be a maximum of %d, got %d, value = %.12f", orderConstraints.VolumePrecision, order.Volume.Precision(), order.Volume.AsFloat())
+		return nil, fmt.Errorf("stronghold volume precision can be a maximum of %d, got %d, value = %.12f", orderConstraints.VolumePrecision, order.Volume.Precision(), order.Volume.AsFloat())
 	}
 
-	args := map[string]string{
-		"price": order.Price.AsString(),
-	}
-	log.Printf("kraken is submitting order: pair=%s, orderAction=%s, orderType=%s, volume=%s, price=%s\n",
+	log.Printf("stronghold is submitting order: pair=%s, orderAction=%s, orderType=%s, volume=%s, price=%s\n",
 		pairStr, order.OrderAction.String(), order.OrderType.String(), order.Volume.AsString(), order.Price.AsString())
-	resp, e := k.nextAPI().AddOrder(
-		pairStr,
-		order.OrderAction.String(),
-		order.OrderType.String(),
-		order.Volume.AsString(),
-		args,
-	)
+	strongholdAPI, key := k.nextAPI(networking.EndpointClassTrading)
+	resp, e := strongholdAPI.AddOrder(strongholdapi.OrderRequest{
+		MarketID:      pairStr,
+		Type:          order.OrderType.String(),
+		Side:          order.OrderAction.String(),
+		Price:         order.Price.AsString(),
+		Size:          order.Volume.AsString(),
+		TimeInForce:   strongholdTimeInForce[order.TimeInForce],
+		PostOnly:      order.PostOnly,
+		ClientOrderID: order.ClientOrderID,
+	})
 	if e != nil {
-		return nil, e
+		return nil, k.keyError(key, e)
 	}
 
-	// expected case for production orders
-	if len(resp.TransactionIds) == 1 {
-		return model.MakeTransactionID(resp.TransactionIds[0]), nil
+	if resp.ID == "" {
+		return nil, fmt.Errorf("no order id returned from order creation")
 	}
-
-	if len(resp.TransactionIds) > 1 {
-		return nil, fmt.Errorf("there was more than 1 transctionId: %s", resp.TransactionIds)
-	}
-
-	return nil, fmt.Errorf("no transactionIds returned from order creation")
+	return model.MakeTransactionID(resp.ID), nil
 }
 
 // CancelOrder impl.
@@ -135,35 +429,28 @@ func (k *strongholdExchange) CancelOrder(txID *model.TransactionID, pair model.T
 	if k.isSimulated {
 		return model.CancelResultCancelSuccessful, nil
 	}
-	log.Printf("kraken is canceling order: ID=%s, tradingPair=%s\n", txID.String(), pair.String())
+	log.Printf("stronghold is canceling order: ID=%s, tradingPair=%s\n", txID.String(), pair.String())
 
-	// we don't actually use the pair for kraken
-	resp, e := k.nextAPI().CancelOrder(txID.String())
+	// order ids are unique across markets so we don't need the pair
+	strongholdAPI, key := k.nextAPI(networking.EndpointClassTrading)
+	e := strongholdAPI.CancelOrder(txID.String())
 	if e != nil {
-		return model.CancelResultFailed, e
-	}
-
-	if resp.Count > 1 {
-		log.Printf("warning: count from a cancelled order is greater than 1: %d\n", resp.Count)
-	}
-
-	// TODO 2 - need to figure out whether count = 0 could also mean that it is pending cancellation
-	if resp.Count == 0 {
-		return model.CancelResultFailed, nil
-	}
-	// resp.Count == 1 here
-
-	if resp.Pending {
-		return model.CancelResultPending, nil
+		return model.CancelResultFailed, k.keyError(key, e)
 	}
 	return model.CancelResultCancelSuccessful, nil
 }
 
 // GetAccountBalances impl.
 func (k *strongholdExchange) GetAccountBalances(assetList []interface{}) (map[interface{}]model.Number, error) {
-	balanceResponse, e := k.nextAPI().Balance()
+	strongholdAPI, key := k.nextAPI(networking.EndpointClassPrivate)
+	account, e := strongholdAPI.Account()
 	if e != nil {
-		return nil, e
+		return nil, k.keyError(key, e)
+	}
+
+	balances := map[string]string{}
+	for _, b := range account.Balances {
+		balances[b.AssetID] = b.Amount
 	}
 
 	m := map[interface{}]model.Number{}
@@ -175,31 +462,55 @@ func (k *strongholdExchange) GetAccountBalances(assetList []interface{}) (map[in
 			return nil, fmt.Errorf("invalid type of asset passed in, only model.Asset accepted")
 		}
 
-		krakenAssetString, e := k.assetConverter.ToString(asset)
+		strongholdAssetString, e := k.assetConverter.ToString(asset)
 		if e != nil {
 			// discard partially built map for now
 			return nil, e
 		}
-		bal := strongholdGetFieldValue(*balanceResponse, krakenAssetString)
-		m[asset] = *model.NumberFromFloat(bal, strongholdprecisionBalances)
+
+		bal, ok := balances[strongholdAssetString]
+		if !ok {
+			// assets that were never funded are not listed in the account
+			m[asset] = *model.NumberConstants.Zero
+			continue
+		}
+		balNumber, e := model.NumberFromString(bal, strongholdprecisionBalances)
+		if e != nil {
+			return nil, fmt.Errorf("could not parse balance for asset %s: %s", strongholdAssetString, e)
+		}
+		m[asset] = *balNumber
 	}
 	return m, nil
 }
 
-func strongholdGetFieldValue(object strongholdapi.BalanceResponse, fieldName string) float64 {
-	r := reflect.ValueOf(object)
-	f := reflect.Indirect(r).FieldByName(fieldName)
-	return f.Interface().(float64)
-}
-
-// GetOrderConstraints impl
+// GetOrderConstraints impl, returns nil for pairs that are not listed on stronghold and are not completely overridden
 func (k *strongholdExchange) GetOrderConstraints(pair *model.TradingPair) *model.OrderConstraints {
-	constraints, ok := krakenPrecisionMatrix[*pair]
-	if !ok {
-		log.Printf("strongholdExchange could not find orderConstraints for trading pair %v, returning nil\n", pair)
+	oc, e := k.orderConstraints(pair)
+	if e != nil {
+		log.Printf("%s\n", e)
 		return nil
 	}
-	return &constraints
+	return oc
+}
+
+// orderConstraints returns the constraints of the pair or an error if stronghold does not list the pair
+func (k *strongholdExchange) orderConstraints(pair *model.TradingPair) (*model.OrderConstraints, error) {
+	market, ok := k.getMarket(pair)
+	if ok {
+		oc := market.constraints
+		return k.ocOverridesHandler.Apply(pair, &oc), nil
+	}
+
+	if k.ocOverridesHandler.IsCompletelyOverriden(pair) {
+		override := k.ocOverridesHandler.Get(pair)
+		return model.MakeOrderConstraintsFromOverride(override), nil
+	}
+	return nil, fmt.Errorf("strongholdExchange could not find orderConstraints for trading pair %v, the market is not listed on stronghold. Provide them using the CENTRALIZED_* config values", pair)
+}
+
+// OverrideOrderConstraints impl, can partially override values for specific pairs
+func (k *strongholdExchange) OverrideOrderConstraints(pair *model.TradingPair, override *model.OrderConstraintsOverride) {
+	k.ocOverridesHandler.Upsert(pair, override)
 }
 
 // GetAssetConverter impl.
@@ -209,27 +520,26 @@ func (k *strongholdExchange) GetAssetConverter() *model.AssetConverter {
 
 // GetOpenOrders impl.
 func (k *strongholdExchange) GetOpenOrders(pairs []*model.TradingPair) (map[model.TradingPair][]model.OpenOrder, error) {
-	openOrdersResponse, e := k.nextAPI().OpenOrders(map[string]string{})
-	if e != nil {
-		return nil, fmt.Errorf("cannot load open orders for Kraken: %s", e)
-	}
-
-	// convert to a map so we can easily search for the existence of a trading pair
-	// kraken uses different symbols when fetching open orders!
-	pairsMap, e := model.TradingPairs2Strings2(k.assetConverterOpenOrders, "", pairs)
+	strongholdAPI, key := k.nextAPI(networking.EndpointClassPrivate)
+	openOrders, e := strongholdAPI.OpenOrders("")
 	if e != nil {
-		return nil, e
+		return nil, k.keyError(key, e)
 	}
 
-	m := map[model.TradingPair][]model.OpenOrder{}
-	for ID, o := range openOrdersResponse.Open {
-		// kraken uses different symbols when fetching open orders!
-		pair, e := model.TradingPairFromString(3, k.assetConverterOpenOrders, o.Description.AssetPair)
+	// convert to a map keyed by market id so we can easily look up the trading pair of an open order
+	marketID2Pair := map[string]*model.TradingPair{}
+	for _, pair := range pairs {
+		marketID, e := k.marketID(pair)
 		if e != nil {
 			return nil, e
 		}
+		marketID2Pair[marketID] = pair
+	}
 
-		if _, ok := pairsMap[*pair]; !ok {
+	m := map[model.TradingPair][]model.OpenOrder{}
+	for _, o := range openOrders {
+		pair, ok := marketID2Pair[o.MarketID]
+		if !ok {
 			// skip open orders for pairs that were not requested
 			continue
 		}
@@ -237,276 +547,454 @@ func (k *strongholdExchange) GetOpenOrders(pairs []*model.TradingPair) (map[mode
 		if _, ok := m[*pair]; !ok {
 			m[*pair] = []model.OpenOrder{}
 		}
-		if _, ok := m[model.TradingPair{Base: pair.Quote, Quote: pair.Base}]; ok {
-			return nil, fmt.Errorf("open orders are listed with repeated base/quote pairs for %s", *pair)
-		}
 
-		orderConstraints := k.GetOrderConstraints(pair)
-		m[*pair] = append(m[*pair], model.OpenOrder{
-			Order: model.Order{
-				Pair:        pair,
-				OrderAction: model.OrderActionFromString(o.Description.Type),
-				OrderType:   model.OrderTypeFromString(o.Description.OrderType),
-				Price:       model.MustNumberFromString(o.Description.PrimaryPrice, orderConstraints.PricePrecision),
-				Volume:      model.MustNumberFromString(o.Volume, orderConstraints.VolumePrecision),
-				Timestamp:   model.MakeTimestamp(int64(o.OpenTime)),
-			},
-			ID:             ID,
-			StartTime:      model.MakeTimestamp(int64(o.StartTime)),
-			ExpireTime:     model.MakeTimestamp(int64(o.ExpireTime)),
-			VolumeExecuted: model.NumberFromFloat(o.VolumeExecuted, orderConstraints.VolumePrecision),
-		})
+		openOrder, e := k.convertOrder(o, pair)
+		if e != nil {
+			return nil, e
+		}
+		m[*pair] = append(m[*pair], *openOrder)
 	}
 	return m, nil
 }
 
+func (k *strongholdExchange) convertOrder(o strongholdapi.Order, pair *model.TradingPair) (*model.OpenOrder, error) {
+	orderConstraints, e := k.orderConstraints(pair)
+	if e != nil {
+		return nil, e
+	}
+	volume := model.MustNumberFromString(o.Size, orderConstraints.VolumePrecision)
+	volumeExecuted := model.MustNumberFromString(o.SizeFilled, orderConstraints.VolumePrecision)
+	status := model.OrderStatusOpen
+	if o.Status == strongholdapi.StatusFilled {
+		status = model.OrderStatusFilled
+	} else if o.Status == strongholdapi.StatusCancelled {
+		status = model.OrderStatusCanceled
+	}
+
+	return &model.OpenOrder{
+		Order: model.Order{
+			Pair:          pair,
+			OrderAction:   model.OrderActionFromString(o.Side),
+			OrderType:     model.OrderTypeFromString(o.Type),
+			Price:         model.MustNumberFromString(o.Price, orderConstraints.PricePrecision),
+			Volume:        volume,
+			Timestamp:     model.MakeTimestampFromTime(o.PlacedAt),
+			ClientOrderID: o.ClientOrderID,
+		},
+		ID:              o.ID,
+		StartTime:       model.MakeTimestampFromTime(o.PlacedAt),
+		ExpireTime:      nil,
+		VolumeExecuted:  volumeExecuted,
+		VolumeRemaining: volume.Subtract(*volumeExecuted),
+		Status:          status,
+	}, nil
+}
+
+// GetOrderStatus impl.
+func (k *strongholdExchange) GetOrderStatus(txID *model.TransactionID, pair model.TradingPair) (*model.OpenOrder, error) {
+	marketID, e := k.marketID(&pair)
+	if e != nil {
+		return nil, e
+	}
+
+	// order ids are unique across markets so we only use the pair to check the order
+	strongholdAPI, key := k.nextAPI(networking.EndpointClassPrivate)
+	o, e := strongholdAPI.Order(txID.String())
+	if e != nil {
+		return nil, k.keyError(key, e)
+	}
+	if o.MarketID != marketID {
+		return nil, fmt.Errorf("order %s is in market %s and not in market %s", txID.String(), o.MarketID, marketID)
+	}
+
+	return k.convertOrder(*o, &pair)
+}
+
+// QueryOrders impl.
+func (k *strongholdExchange) QueryOrders(txIDs []*model.TransactionID, pair model.TradingPair) (map[string]model.OpenOrder, error) {
+	return api.QueryOrdersOneByOne(k.GetOrderStatus, txIDs, pair)
+}
+
 // GetOrderBook impl.
 func (k *strongholdExchange) GetOrderBook(pair *model.TradingPair, maxCount int32) (*model.OrderBook, error) {
-	pairStr, e := pair.ToString(k.assetConverter, k.delimiter)
+	pairStr, e := k.marketID(pair)
 	if e != nil {
 		return nil, e
 	}
 
-	krakenob, e := k.nextAPI().Depth(pairStr, int(maxCount))
+	strongholdob, e := k.fetchOrderBook(pairStr)
 	if e != nil {
 		return nil, e
 	}
 
-	asks := k.readOrders(krakenob.Asks, pair, model.OrderActionSell)
-	bids := k.readOrders(krakenob.Bids, pair, model.OrderActionBuy)
+	asks, e := k.readOrders(strongholdob.Asks, pair, model.OrderActionSell, maxCount)
+	if e != nil {
+		return nil, e
+	}
+	bids, e := k.readOrders(strongholdob.Bids, pair, model.OrderActionBuy, maxCount)
+	if e != nil {
+		return nil, e
+	}
 	ob := model.MakeOrderBook(pair, asks, bids)
 	return ob, nil
 }
 
-func (k *strongholdExchange) readOrders(obi []strongholdapi.OrderBookItem, pair *model.TradingPair, orderAction model.OrderAction) []model.Order {
-	orderConstraints := k.GetOrderConstraints(pair)
+// fetchOrderBook returns the streamed order book when it is synced, otherwise it is fetched over REST
+func (k *strongholdExchange) fetchOrderBook(marketID string) (*strongholdapi.OrderBook, error) {
+	if k.stream != nil {
+		// the first call subscribes, so the book is served over REST until the snapshot arrives
+		e := k.stream.SubscribeOrderBook(marketID)
+		if e != nil {
+			log.Printf("could not subscribe to the stronghold order book of market %s, falling back to REST: %s\n", marketID, e)
+		}
+		if ob, ok := k.stream.OrderBook(marketID); ok {
+			return ob, nil
+		}
+	}
+	strongholdAPI, key := k.nextAPI(networking.EndpointClassPublic)
+	ob, e := strongholdAPI.OrderBook(marketID)
+	if e != nil {
+		return nil, k.keyError(key, e)
+	}
+	return ob, nil
+}
+
+func (k *strongholdExchange) readOrders(obi []strongholdapi.OrderBookItem, pair *model.TradingPair, orderAction model.OrderAction, maxCount int32) ([]model.Order, error) {
+	orderConstraints, e := k.orderConstraints(pair)
+	if e != nil {
+		return nil, e
+	}
 	orders := []model.Order{}
-	for _, item := range obi {
+	for i, item := range obi {
+		if maxCount > 0 && int32(i) >= maxCount {
+			break
+		}
+
+		price, e := model.NumberFromString(item.Price, orderConstraints.PricePrecision)
+		if e != nil {
+			return nil, fmt.Errorf("could not parse price of order book item (%s): %s", item.Price, e)
+		}
+		volume, e := model.NumberFromString(item.Size, orderConstraints.VolumePrecision)
+		if e != nil {
+			return nil, fmt.Errorf("could not parse size of order book item (%s): %s", item.Size, e)
+		}
+
 		orders = append(orders, model.Order{
 			Pair:        pair,
 			OrderAction: orderAction,
 			OrderType:   model.OrderTypeLimit,
-			Price:       model.NumberFromFloat(item.Price, orderConstraints.PricePrecision),
-			Volume:      model.NumberFromFloat(item.Amount, orderConstraints.VolumePrecision),
-			Timestamp:   model.MakeTimestamp(item.Ts),
+			Price:       price,
+			Volume:      volume,
+			Timestamp:   nil,
 		})
 	}
-	return orders
+	return orders, nil
 }
 
 // GetTickerPrice impl.
 func (k *strongholdExchange) GetTickerPrice(pairs []model.TradingPair) (map[model.TradingPair]api.Ticker, error) {
-	pairsMap, e := model.TradingPairs2Strings(k.assetConverter, k.delimiter, pairs)
-	if e != nil {
-		return nil, e
-	}
-
-	resp, e := k.nextAPI().Ticker(strongholdValues(pairsMap)...)
-	if e != nil {
-		return nil, e
-	}
-
 	priceResult := map[model.TradingPair]api.Ticker{}
 	for _, p := range pairs {
-		orderConstraints := k.GetOrderConstraints(&p)
-		pairTickerInfo := resp.GetPairTickerInfo(pairsMap[p])
+		// stronghold does not have a ticker endpoint so we use the top of the order book
+		ob, e := k.GetOrderBook(&p, 1)
+		if e != nil {
+			return nil, fmt.Errorf("could not fetch order book for ticker of trading pair %s: %s", p.String(), e)
+		}
+
+		topAsk := ob.TopAsk()
+		topBid := ob.TopBid()
+		if topAsk == nil || topBid == nil {
+			return nil, fmt.Errorf("order book for trading pair %s is missing asks or bids", p.String())
+		}
 		priceResult[p] = api.Ticker{
-			AskPrice: model.MustNumberFromString(pairTickerInfo.Ask[0], orderConstraints.PricePrecision),
-			BidPrice: model.MustNumberFromString(pairTickerInfo.Bid[0], orderConstraints.PricePrecision),
+			AskPrice: topAsk.Price,
+			BidPrice: topBid.Price,
 		}
 	}
 
 	return priceResult, nil
 }
 
-// values gives you the values of a map
-// TODO 2 - move to autogenerated generic function
-func strongholdValues(m map[model.TradingPair]string) []string {
-	values := []string{}
-	for _, v := range m {
-		values = append(values, v)
+// strongholdTradesPageSize is the number of trades requested per page when fetching the trade history
+const strongholdTradesPageSize = 100
+
+// strongholdTradeCursor is the cursor used for the trade history of stronghold.
+// Multiple trades can execute in the same millisecond so the cursor remembers the ids of the trades at its timestamp
+// that were already returned, which keeps it stable when new trades arrive at the same timestamp later.
+type strongholdTradeCursor struct {
+	timestampMillis int64
+	tradeIDs        map[string]bool
+}
+
+func makeStrongholdTradeCursor(timestampMillis int64) *strongholdTradeCursor {
+	return &strongholdTradeCursor{
+		timestampMillis: timestampMillis,
+		tradeIDs:        map[string]bool{},
 	}
-	return values
 }
 
-// GetTradeHistory impl.
-func (k *strongholdExchange) GetTradeHistory(pair model.TradingPair, maybeCursorStart interface{}, maybeCursorEnd interface{}) (*api.TradeHistoryResult, error) {
-	var mcs *string
-	if maybeCursorStart != nil {
-		i := maybeCursorStart.(string)
-		mcs = &i
+// String is the Stringer method
+func (c *strongholdTradeCursor) String() string {
+	return fmt.Sprintf("strongholdTradeCursor[timestampMillis=%d, numTradeIDs=%d]", c.timestampMillis, len(c.tradeIDs))
+}
+
+// isNew returns true if the trade comes after this cursor
+func (c *strongholdTradeCursor) isNew(ts int64, tradeID string) bool {
+	if ts != c.timestampMillis {
+		return ts > c.timestampMillis
 	}
+	return !c.tradeIDs[tradeID]
+}
 
-	var mce *string
-	if maybeCursorEnd != nil {
-		i := maybeCursorEnd.(string)
-		mce = &i
+// advance returns a new cursor that comes after the trade, the receiver is not modified
+func (c *strongholdTradeCursor) advance(ts int64, tradeID string) *strongholdTradeCursor {
+	next := makeStrongholdTradeCursor(ts)
+	if ts == c.timestampMillis {
+		for id := range c.tradeIDs {
+			next.tradeIDs[id] = true
+		}
 	}
+	next.tradeIDs[tradeID] = true
+	return next
+}
 
-	return k.getTradeHistory(pair, mcs, mce)
+// toStrongholdTradeCursor converts the cursors accepted by GetTradeHistory and GetTrades, unix millis can be passed in as a string or int64
+func toStrongholdTradeCursor(maybeCursor interface{}) (*strongholdTradeCursor, error) {
+	switch c := maybeCursor.(type) {
+	case nil:
+		return nil, nil
+	case *strongholdTradeCursor:
+		return c, nil
+	case int64:
+		return makeStrongholdTradeCursor(c), nil
+	case string:
+		ts, e := strconv.ParseInt(c, 10, 64)
+		if e != nil {
+			return nil, fmt.Errorf("could not parse string cursor as unix millis (%s): %s", c, e)
+		}
+		return makeStrongholdTradeCursor(ts), nil
+	default:
+		return nil, fmt.Errorf("unsupported cursor type for stronghold trade history: %T", maybeCursor)
+	}
 }
 
-func (k *strongholdExchange) getTradeHistory(tradingPair model.TradingPair, maybeCursorStart *string, maybeCursorEnd *string) (*api.TradeHistoryResult, error) {
-	input := map[string]string{}
-	if maybeCursorStart != nil {
-		input["start"] = *maybeCursorStart
+// GetTradeHistory impl.
+func (k *strongholdExchange) GetTradeHistory(pair model.TradingPair, maybeCursorStart interface{}, maybeCursorEnd interface{}) (*api.TradeHistoryResult, error) {
+	cursorStart, e := toStrongholdTradeCursor(maybeCursorStart)
+	if e != nil {
+		return nil, e
+	}
+	cursorEnd, e := toStrongholdTradeCursor(maybeCursorEnd)
+	if e != nil {
+		return nil, e
 	}
-	if maybeCursorEnd != nil {
-		input["end"] = *maybeCursorEnd
+
+	return k.getTradeHistory(pair, cursorStart, cursorEnd)
+}
+
+func (k *strongholdExchange) getTradeHistory(tradingPair model.TradingPair, maybeCursorStart *strongholdTradeCursor, maybeCursorEnd *strongholdTradeCursor) (*api.TradeHistoryResult, error) {
+	pairStr, e := k.marketID(&tradingPair)
+	if e != nil {
+		return nil, e
 	}
 
-	resp, e := k.nextAPI().Query("TradesHistory", input)
+	orderConstraints, e := k.orderConstraints(&tradingPair)
 	if e != nil {
 		return nil, e
 	}
-	krakenResp := resp.(map[string]interface{})
-	krakenTrades := krakenResp["trades"].(map[string]interface{})
+	// for now use the max precision between price and volume for fee and cost
+	feeCostPrecision := orderConstraints.PricePrecision
+	if orderConstraints.VolumePrecision > feeCostPrecision {
+		feeCostPrecision = orderConstraints.VolumePrecision
+	}
 
+	cursor := maybeCursorStart
+	if cursor == nil {
+		cursor = makeStrongholdTradeCursor(0)
+	}
 	res := api.TradeHistoryResult{Trades: []model.Trade{}}
-	for _txid, v := range krakenTrades {
-		m := v.(map[string]interface{})
-		_time := m["time"].(float64)
-		ts := model.MakeTimestamp(int64(_time))
-		_type := m["type"].(string)
-		_ordertype := m["ordertype"].(string)
-		_price := m["price"].(string)
-		_vol := m["vol"].(string)
-		_cost := m["cost"].(string)
-		_fee := m["fee"].(string)
-		_pair := m["pair"].(string)
-		var pair *model.TradingPair
-		pair, e = model.TradingPairFromString(4, k.assetConverter, _pair)
-		if e != nil {
-			return nil, e
-		}
-		orderConstraints := k.GetOrderConstraints(pair)
-		// for now use the max precision between price and volume for fee and cost
-		feeCostPrecision := orderConstraints.PricePrecision
-		if orderConstraints.VolumePrecision > feeCostPrecision {
-			feeCostPrecision = orderConstraints.VolumePrecision
-		}
+	// appendTrades adds the trades that come after the cursor to the result and advances the cursor past them
+	appendTrades := func(trades []strongholdapi.Trade) error {
+		for _, t := range trades {
+			ts := model.MakeTimestampFromTime(t.ExecutedAt)
+			if !cursor.isNew(ts.AsInt64(), t.ID) {
+				continue
+			}
+
+			price, e := model.NumberFromString(t.Price, orderConstraints.PricePrecision)
+			if e != nil {
+				return fmt.Errorf("could not parse price of trade %s: %s", t.ID, e)
+			}
+			volume, e := model.NumberFromString(t.Size, orderConstraints.VolumePrecision)
+			if e != nil {
+				return fmt.Errorf("could not parse size of trade %s: %s", t.ID, e)
+			}
+			fee, e := model.NumberFromString(t.Fee, feeCostPrecision)
+			if e != nil {
+				return fmt.Errorf("could not parse fee of trade %s: %s", t.ID, e)
+			}
 
-		if *pair == tradingPair {
 			res.Trades = append(res.Trades, model.Trade{
 				Order: model.Order{
-					Pair:        pair,
-					OrderAction: model.OrderActionFromString(_type),
-					OrderType:   model.OrderTypeFromString(_ordertype),
-					Price:       model.MustNumberFromString(_price, orderConstraints.PricePrecision),
-					Volume:      model.MustNumberFromString(_vol, orderConstraints.VolumePrecision),
+					Pair:        &tradingPair,
+					OrderAction: model.OrderActionFromString(t.Side),
+					OrderType:   model.OrderTypeLimit,
+					Price:       price,
+					Volume:      volume,
 					Timestamp:   ts,
 				},
-				TransactionID: model.MakeTransactionID(_txid),
-				Cost:          model.MustNumberFromString(_cost, feeCostPrecision),
-				Fee:           model.MustNumberFromString(_fee, feeCostPrecision),
+				TransactionID: model.MakeTransactionID(t.ID),
+				Cost:          model.NumberFromFloat(price.AsFloat()*volume.AsFloat(), feeCostPrecision),
+				Fee:           fee,
 			})
+			cursor = cursor.advance(ts.AsInt64(), t.ID)
+		}
+		return nil
+	}
+
+	if fills, ok := k.streamedFills(pairStr, cursor, maybeCursorEnd); ok {
+		e = appendTrades(fills)
+		if e != nil {
+			return nil, e
+		}
+	} else {
+		for {
+			params := strongholdapi.TradesParams{
+				MarketID:  pairStr,
+				StartTime: cursor.timestampMillis,
+				Limit:     strongholdTradesPageSize,
+			}
+			if maybeCursorEnd != nil {
+				params.EndTime = maybeCursorEnd.timestampMillis
+			}
+			strongholdAPI, key := k.nextAPI(networking.EndpointClassPrivate)
+			trades, e := strongholdAPI.Trades(params)
+			if e != nil {
+				return nil, k.keyError(key, e)
+			}
+
+			pageStart := cursor.timestampMillis
+			e = appendTrades(trades)
+			if e != nil {
+				return nil, e
+			}
+
+			if len(trades) < strongholdTradesPageSize {
+				break
+			}
+			if cursor.timestampMillis == pageStart {
+				// the next page would start at the same timestamp and return the same trades
+				return nil, fmt.Errorf("more than %d trades were executed at timestamp %d, cannot page through them", strongholdTradesPageSize, pageStart)
+			}
 		}
 	}
 
 	// sort to be in ascending order
 	sort.Sort(model.TradesByTsID(res.Trades))
 
-	// set correct value for cursor
 	if len(res.Trades) > 0 {
-		lastCursor := res.Trades[len(res.Trades)-1].Order.Timestamp.AsInt64()
-		// add 1 to lastCursor so we don't repeat the same cursor on the next run
-		res.Cursor = strconv.FormatInt(lastCursor+1, 10)
+		res.Cursor = cursor
 	} else if maybeCursorStart != nil {
-		res.Cursor = *maybeCursorStart
+		res.Cursor = maybeCursorStart
 	} else {
 		res.Cursor = nil
 	}
-
 	return &res, nil
 }
 
+// streamedFills returns our fills between the cursors from the stream, ok is false when the stream cannot vouch for
+// having all of them, e.g. when the start cursor is older than the current subscription
+func (k *strongholdExchange) streamedFills(marketID string, cursorStart *strongholdTradeCursor, maybeCursorEnd *strongholdTradeCursor) ([]strongholdapi.Trade, bool) {
+	if k.stream == nil {
+		return nil, false
+	}
+
+	fills, ok := k.stream.Fills(marketID, cursorStart.timestampMillis)
+	if !ok {
+		return nil, false
+	}
+	if maybeCursorEnd == nil {
+		return fills, true
+	}
+
+	// the end cursor is exclusive, same as the endTime of the REST API
+	filtered := []strongholdapi.Trade{}
+	for _, t := range fills {
+		if model.MakeTimestampFromTime(t.ExecutedAt).AsInt64() < maybeCursorEnd.timestampMillis {
+			filtered = append(filtered, t)
+		}
+	}
+	return filtered, true
+}
+
 // GetLatestTradeCursor impl.
 func (k *strongholdExchange) GetLatestTradeCursor() (interface{}, error) {
-	timeNowSecs := time.Now().Unix()
-	latestTradeCursor := fmt.Sprintf("%d", timeNowSecs)
-	return latestTradeCursor, nil
+	timeNowMillis := time.Now().UnixNano() / int64(time.Millisecond)
+	return makeStrongholdTradeCursor(timeNowMillis), nil
 }
 
 // GetTrades impl.
 func (k *strongholdExchange) GetTrades(pair *model.TradingPair, maybeCursor interface{}) (*api.TradesResult, error) {
-	if maybeCursor != nil {
-		mc := maybeCursor.(int64)
-		return k.getTrades(pair, &mc)
+	pairStr, e := k.marketID(pair)
+	if e != nil {
+		return nil, e
 	}
-	return k.getTrades(pair, nil)
-}
 
-func (k *strongholdExchange) getTrades(pair *model.TradingPair, maybeCursor *int64) (*api.TradesResult, error) {
-	pairStr, e := pair.ToString(k.assetConverter, k.delimiter)
+	strongholdAPI, key := k.nextAPI(networking.EndpointClassPublic)
+	trades, e := strongholdAPI.MarketTrades(pairStr, 0)
 	if e != nil {
-		return nil, e
+		return nil, k.keyError(key, e)
 	}
 
-	var tradesResp *strongholdapi.TradesResponse
-	if maybeCursor != nil {
-		tradesResp, e = k.nextAPI().Trades(pairStr, *maybeCursor)
-	} else {
-		tradesResp, e = k.nextAPI().Trades(pairStr, -1)
+	// the cursor is exclusive like the one of the trade history, so a trade is only returned once
+	cursor, e := toStrongholdTradeCursor(maybeCursor)
+	if e != nil {
+		return nil, fmt.Errorf("unsupported cursor for stronghold trades: %s", e)
 	}
+
+	orderConstraints, e := k.orderConstraints(pair)
 	if e != nil {
 		return nil, e
 	}
-
-	orderConstraints := k.GetOrderConstraints(pair)
+	sort.SliceStable(trades, func(i int, j int) bool {
+		if trades[i].ExecutedAt.Equal(trades[j].ExecutedAt) {
+			return trades[i].ID < trades[j].ID
+		}
+		return trades[i].ExecutedAt.Before(trades[j].ExecutedAt)
+	})
 	tradesResult := &api.TradesResult{
-		Cursor: tradesResp.Last,
+		Cursor: maybeCursor,
 		Trades: []model.Trade{},
 	}
-	for _, tInfo := range tradesResp.Trades {
-		action, e := strongholdGetAction(tInfo)
-		if e != nil {
-			return nil, e
-		}
-		orderType, e := strongholdGetOrderType(tInfo)
-		if e != nil {
-			return nil, e
+	if cursor == nil {
+		cursor = makeStrongholdTradeCursor(0)
+	}
+	for _, t := range trades {
+		ts := model.MakeTimestampFromTime(t.ExecutedAt)
+		if !cursor.isNew(ts.AsInt64(), t.ID) {
+			continue
 		}
+		cursor = cursor.advance(ts.AsInt64(), t.ID)
 
 		tradesResult.Trades = append(tradesResult.Trades, model.Trade{
 			Order: model.Order{
 				Pair:        pair,
-				OrderAction: action,
-				OrderType:   orderType,
-				Price:       model.NumberFromFloat(tInfo.PriceFloat, orderConstraints.PricePrecision),
-				Volume:      model.NumberFromFloat(tInfo.VolumeFloat, orderConstraints.VolumePrecision),
-				Timestamp:   model.MakeTimestamp(tInfo.Time),
+				OrderAction: model.OrderActionFromString(t.Side),
+				OrderType:   model.OrderTypeLimit,
+				Price:       model.MustNumberFromString(t.Price, orderConstraints.PricePrecision),
+				Volume:      model.MustNumberFromString(t.Size, orderConstraints.VolumePrecision),
+				Timestamp:   ts,
 			},
-			// TransactionID unavailable
+			TransactionID: model.MakeTransactionID(t.ID),
 			// Cost unavailable
 			// Fee unavailable
 		})
 	}
 
-	// sort to be in ascending order
-	sort.Sort(model.TradesByTsID(tradesResult.Trades))
-	// cursor is already set using the result from the kraken go sdk, so no need to set again here
-
-	return tradesResult, nil
-}
-
-func strongholdGetAction(tInfo strongholdapi.TradeInfo) (model.OrderAction, error) {
-	if tInfo.Buy {
-		return model.OrderActionBuy, nil
-	} else if tInfo.Sell {
-		return model.OrderActionSell, nil
-	}
-
-	// return OrderActionBuy as nil value
-	return model.OrderActionBuy, errors.New("unidentified trade action")
-}
-
-func strongholdGetOrderType(tInfo strongholdapi.TradeInfo) (model.OrderType, error) {
-	if tInfo.Market {
-		return model.OrderTypeMarket, nil
-	} else if tInfo.Limit {
-		return model.OrderTypeLimit, nil
+	if len(tradesResult.Trades) > 0 {
+		tradesResult.Cursor = cursor
 	}
-	return -1, errors.New("unidentified trade action")
+	return tradesResult, nil
 }
 
 // GetWithdrawInfo impl.
@@ -515,290 +1003,96 @@ func (k *strongholdExchange) GetWithdrawInfo(
 	amountToWithdraw *model.Number,
 	address string,
 ) (*api.WithdrawInfo, error) {
-	krakenAsset, e := k.assetConverter.ToString(asset)
+	strongholdAsset, e := k.assetConverter.ToString(asset)
 	if e != nil {
 		return nil, e
 	}
 
-	withdrawKey, e := k.withdrawKeys.getKey(asset, address)
-	if e != nil {
-		return nil, e
-	}
-	resp, e := k.nextAPI().Query(
-		"WithdrawInfo",
-		map[string]string{
-			"asset":  krakenAsset,
-			"key":    withdrawKey,
-			"amount": amountToWithdraw.AsString(),
-		},
-	)
-	if e != nil {
-		return nil, e
-	}
-
-	return strongholdParseWithdrawInfoResponse(resp, amountToWithdraw)
-}
-
-func strongholdParseWithdrawInfoResponse(resp interface{}, amountToWithdraw *model.Number) (*api.WithdrawInfo, error) {
-	switch m := resp.(type) {
-	case map[string]interface{}:
-		info, e := strongholdParseWithdrawInfo(m)
-		if e != nil {
-			return nil, e
-		}
-		if info.limit != nil && info.limit.AsFloat() < amountToWithdraw.AsFloat() {
-			return nil, api.MakeErrWithdrawAmountAboveLimit(amountToWithdraw, info.limit)
-		}
-		if info.fee != nil && info.fee.AsFloat() >= amountToWithdraw.AsFloat() {
-			return nil, api.MakeErrWithdrawAmountInvalid(amountToWithdraw, info.fee)
-		}
-
-		return &api.WithdrawInfo{AmountToReceive: info.amount}, nil
-	default:
-		return nil, fmt.Errorf("could not parse response type from WithdrawInfo: %s", reflect.TypeOf(m))
-	}
-}
-
-type strongholdWithdrawInfo struct {
-	limit  *model.Number
-	fee    *model.Number
-	amount *model.Number
-}
-
-func strongholdParseWithdrawInfo(m map[string]interface{}) (*strongholdWithdrawInfo, error) {
-	// limit
-	limit, e := networking.ParseNumber(m, "limit", "WithdrawInfo")
+	strongholdAPI, key := k.nextAPI(networking.EndpointClassPublic)
+	assets, e := strongholdAPI.Assets()
 	if e != nil {
-		return nil, e
+		return nil, api.WrapError(k.keyError(key, e), "could not fetch assets to get withdraw info")
 	}
-
-	// fee
-	fee, e := networking.ParseNumber(m, "fee", "WithdrawInfo")
-	if e != nil {
-		if !strings.HasPrefix(e.Error(), networking.PrefixFieldNotFound) {
-			return nil, e
+	for _, a := range assets {
+		if a.ID == strongholdAsset {
+			return strongholdParseWithdrawInfo(a, amountToWithdraw)
 		}
-		// fee may be missing in which case it's null
-		fee = nil
 	}
-
-	// amount
-	amount, e := networking.ParseNumber(m, "amount", "WithdrawInfo")
-	if e != nil {
-		return nil, e
-	}
-
-	return &strongholdWithdrawInfo{
-		limit:  limit,
-		fee:    fee,
-		amount: amount,
-	}, nil
+	return nil, fmt.Errorf("asset %s (%s) is not listed on stronghold", asset, strongholdAsset)
 }
 
-// PrepareDeposit impl.
-func (k *strongholdExchange) PrepareDeposit(asset model.Asset, amount *model.Number) (*api.PrepareDepositResult, error) {
-	krakenAsset, e := k.assetConverter.ToString(asset)
-	if e != nil {
-		return nil, e
-	}
-
-	dm, e := k.getDepositMethods(krakenAsset)
-	if e != nil {
-		return nil, e
-	}
-
-	if dm.limit != nil && dm.limit.AsFloat() < amount.AsFloat() {
-		return nil, api.MakeErrDepositAmountAboveLimit(amount, dm.limit)
+func strongholdParseWithdrawInfo(a strongholdapi.Asset, amountToWithdraw *model.Number) (*api.WithdrawInfo, error) {
+	precision := amountToWithdraw.Precision()
+	if a.Precision > precision {
+		precision = a.Precision
 	}
 
-	// get any unused address on the account or generate a new address if no existing unused address
-	generateNewAddress := false
-	for {
-		addressList, e := k.getDepositAddress(krakenAsset, dm.method, generateNewAddress)
+	if a.MaximumWithdrawal != "" {
+		limit, e := model.NumberFromString(a.MaximumWithdrawal, precision)
 		if e != nil {
-			if strings.Contains(e.Error(), "EFunding:Too many addresses") {
-				return nil, api.MakeErrTooManyDepositAddresses()
-			}
-			return nil, e
-		}
-		// TODO 2 - filter addresses that may be "in progress" - save suggested address on account before using and filter using that list
-		// discard addresses that have been used up
-		addressList = strongholdKeepOnlyNew(addressList)
-
-		if len(addressList) > 0 {
-			earliestAddress := addressList[len(addressList)-1]
-			return &api.PrepareDepositResult{
-				Fee:      dm.fee,
-				Address:  earliestAddress.address,
-				ExpireTs: earliestAddress.expireTs,
-			}, nil
+			return nil, fmt.Errorf("could not parse maximumWithdrawal (%s) for asset %s: %s", a.MaximumWithdrawal, a.ID, e)
 		}
-
-		// error if we just tried to generate a new address which failed
-		if generateNewAddress {
-			return nil, fmt.Errorf("attempt to generate a new address failed")
+		if limit.AsFloat() < amountToWithdraw.AsFloat() {
+			return nil, api.MakeErrWithdrawAmountAboveLimit(amountToWithdraw, limit)
 		}
-
-		// retry the loop by attempting to generate a new address
-		generateNewAddress = true
 	}
-}
 
-func strongholdKeepOnlyNew(addressList []strongholdDepositAddress) []strongholdDepositAddress {
-	ret := []strongholdDepositAddress{}
-	for _, a := range addressList {
-		if a.isNew {
-			ret = append(ret, a)
+	if a.MinimumWithdrawal != "" {
+		minimum, e := model.NumberFromString(a.MinimumWithdrawal, precision)
+		if e != nil {
+			return nil, fmt.Errorf("could not parse minimumWithdrawal (%s) for asset %s: %s", a.MinimumWithdrawal, a.ID, e)
 		}
-	}
-	return ret
-}
-
-type strongholdDepositMethod struct {
-	method     string
-	limit      *model.Number
-	fee        *model.Number
-	genAddress bool
-}
-
-func (k *strongholdExchange) getDepositMethods(asset string) (*strongholdDepositMethod, error) {
-	resp, e := k.nextAPI().Query(
-		"DepositMethods",
-		map[string]string{"asset": asset},
-	)
-	if e != nil {
-		return nil, e
-	}
-
-	switch arr := resp.(type) {
-	case []interface{}:
-		switch m := arr[0].(type) {
-		case map[string]interface{}:
-			return strongholdParseDepositMethods(m)
-		default:
-			return nil, fmt.Errorf("could not parse inner response type of returned []interface{} from DepositMethods: %s", reflect.TypeOf(m))
+		if minimum.AsFloat() > amountToWithdraw.AsFloat() {
+			return nil, fmt.Errorf("withdraw amount (%s) is less than the minimum withdrawal (%s) for asset %s", amountToWithdraw.AsString(), minimum.AsString(), a.ID)
 		}
-	default:
-		return nil, fmt.Errorf("could not parse response type from DepositMethods: %s", reflect.TypeOf(arr))
 	}
-}
-
-type strongholdDepositAddress struct {
-	address  string
-	expireTs int64
-	isNew    bool
-}
 
-func (k *strongholdExchange) getDepositAddress(asset string, method string, genAddress bool) ([]strongholdDepositAddress, error) {
-	input := map[string]string{
-		"asset":  asset,
-		"method": method,
-	}
-	if genAddress {
-		// only set "new" if it's supposed to be 'true'. If you set it to 'false' then it will be treated as true by Kraken :(
-		input["new"] = "true"
-	}
-	resp, e := k.nextAPI().Query("DepositAddresses", input)
-	if e != nil {
-		return []strongholdDepositAddress{}, e
-	}
-
-	addressList := []strongholdDepositAddress{}
-	switch arr := resp.(type) {
-	case []interface{}:
-		for _, elem := range arr {
-			switch m := elem.(type) {
-			case map[string]interface{}:
-				da, e := strongholdParseDepositAddress(m)
-				if e != nil {
-					return []strongholdDepositAddress{}, e
-				}
-				addressList = append(addressList, *da)
-			default:
-				return []strongholdDepositAddress{}, fmt.Errorf("could not parse inner response type of returned []interface{} from DepositAddresses: %s", reflect.TypeOf(m))
-			}
+	fee := model.NumberConstants.Zero
+	if a.WithdrawalFee != "" {
+		var e error
+		fee, e = model.NumberFromString(a.WithdrawalFee, precision)
+		if e != nil {
+			return nil, fmt.Errorf("could not parse withdrawalFee (%s) for asset %s: %s", a.WithdrawalFee, a.ID, e)
 		}
-	default:
-		return []strongholdDepositAddress{}, fmt.Errorf("could not parse response type from DepositAddresses: %s", reflect.TypeOf(arr))
-	}
-	return addressList, nil
-}
-
-func strongholdParseDepositAddress(m map[string]interface{}) (*strongholdDepositAddress, error) {
-	// address
-	address, e := networking.ParseString(m, "address", "DepositAddresses")
-	if e != nil {
-		return nil, e
 	}
-
-	// expiretm
-	expireN, e := networking.ParseNumber(m, "expiretm", "DepositAddresses")
-	if e != nil {
-		return nil, e
+	if fee.AsFloat() >= amountToWithdraw.AsFloat() {
+		return nil, api.MakeErrWithdrawAmountInvalid(amountToWithdraw, fee)
 	}
-	expireTs := int64(expireN.AsFloat())
 
-	// new
-	isNew, e := networking.ParseBool(m, "new", "DepositAddresses")
-	if e != nil {
-		if !strings.HasPrefix(e.Error(), networking.PrefixFieldNotFound) {
-			return nil, e
-		}
-		// new may be missing in which case it's false
-		isNew = false
-	}
-
-	return &strongholdDepositAddress{
-		address:  address,
-		expireTs: expireTs,
-		isNew:    isNew,
-	}, nil
+	return &api.WithdrawInfo{AmountToReceive: amountToWithdraw.Subtract(*fee)}, nil
 }
 
-func strongholdParseDepositMethods(m map[string]interface{}) (*strongholdDepositMethod, error) {
-	// method
-	method, e := networking.ParseString(m, "method", "DepositMethods")
+// PrepareDeposit impl.
+func (k *strongholdExchange) PrepareDeposit(asset model.Asset, amount *model.Number) (*api.PrepareDepositResult, error) {
+	strongholdAsset, e := k.assetConverter.ToString(asset)
 	if e != nil {
 		return nil, e
 	}
 
-	// limit
-	var limit *model.Number
-	limB, e := networking.ParseBool(m, "limit", "DepositMethods")
+	strongholdAPI, key := k.nextAPI(networking.EndpointClassFunding)
+	instructions, e := strongholdAPI.DepositAddress(strongholdAsset, strongholdapi.PaymentMethodStellar)
 	if e != nil {
-		// limit is special as it can be a boolean or a number
-		limit, e = networking.ParseNumber(m, "limit", "DepositMethods")
-		if e != nil {
-			return nil, e
-		}
-	} else {
-		if limB {
-			return nil, fmt.Errorf("invalid value for 'limit' as a response from DepositMethods: boolean value of 'limit' should never be 'true' as it should be a number in that case")
-		}
-		limit = nil
+		return nil, api.WrapError(k.keyError(key, e), "could not fetch deposit address for asset %s", strongholdAsset)
 	}
 
-	// fee
-	fee, e := networking.ParseNumber(m, "fee", "DepositMethods")
-	if e != nil {
-		if !strings.HasPrefix(e.Error(), networking.PrefixFieldNotFound) {
-			return nil, e
+	var fee *model.Number
+	if instructions.Fee != "" {
+		fee, e = model.NumberFromString(instructions.Fee, amount.Precision())
+		if e != nil {
+			return nil, fmt.Errorf("could not parse deposit fee (%s): %s", instructions.Fee, e)
 		}
-		// fee may be missing in which case it's null
-		fee = nil
 	}
 
-	// gen-address
-	genAddress, e := networking.ParseBool(m, "gen-address", "DepositMethods")
-	if e != nil {
-		return nil, e
+	var expireTs int64
+	if instructions.ExpiresAt != nil {
+		expireTs = instructions.ExpiresAt.Unix()
 	}
 
-	return &strongholdDepositMethod{
-		method:     method,
-		limit:      limit,
-		fee:        fee,
-		genAddress: genAddress,
+	return &api.PrepareDepositResult{
+		Fee:      fee,
+		Address:  instructions.Address,
+		Memo:     instructions.Memo,
+		ExpireTs: expireTs,
 	}, nil
 }
 
@@ -808,53 +1102,23 @@ func (k *strongholdExchange) WithdrawFunds(
 	amountToWithdraw *model.Number,
 	address string,
 ) (*api.WithdrawFunds, error) {
-	krakenAsset, e := k.assetConverter.ToString(asset)
+	strongholdAsset, e := k.assetConverter.ToString(asset)
 	if e != nil {
 		return nil, e
 	}
 
-	withdrawKey, e := k.withdrawKeys.getKey(asset, address)
-	if e != nil {
-		return nil, e
-	}
-	resp, e := k.nextAPI().Query(
-		"Withdraw",
-		map[string]string{
-			"asset":  krakenAsset,
-			"key":    withdrawKey,
-			"amount": amountToWithdraw.AsString(),
-		},
-	)
+	strongholdAPI, key := k.nextAPI(networking.EndpointClassFunding)
+	withdrawal, e := strongholdAPI.Withdraw(strongholdapi.WithdrawalRequest{
+		AssetID:       strongholdAsset,
+		Amount:        amountToWithdraw.AsString(),
+		PaymentMethod: strongholdapi.PaymentMethodStellar,
+		Address:       address,
+	})
 	if e != nil {
-		return nil, e
+		return nil, api.WrapError(k.keyError(key, e), "could not withdraw %s %s to address %s", amountToWithdraw.AsString(), strongholdAsset, address)
 	}
 
-	return strongholdParseWithdrawResponse(resp)
-}
-
-func strongholdParseWithdrawResponse(resp interface{}) (*api.WithdrawFunds, error) {
-	switch m := resp.(type) {
-	case map[string]interface{}:
-		refid, e := networking.ParseString(m, "refid", "Withdraw")
-		if e != nil {
-			return nil, e
-		}
-		return &api.WithdrawFunds{
-			WithdrawalID: refid,
-		}, nil
-	default:
-		return nil, fmt.Errorf("could not parse response type from Withdraw: %s", reflect.TypeOf(m))
-	}
-}
-
-// krakenPrecisionMatrix describes the price and volume precision and min base volume for each trading pair
-// taken from this URL: https://support.kraken.com/hc/en-us/articles/360001389366-Price-and-volume-decimal-precision
-var strongholdPrecisionMatrix = map[model.TradingPair]model.OrderConstraints{
-	*model.MakeTradingPair(model.XLM, model.USD): *model.MakeOrderConstraints(6, 8, 30.0),
-	*model.MakeTradingPair(model.XLM, model.BTC): *model.MakeOrderConstraints(8, 8, 30.0),
-	*model.MakeTradingPair(model.BTC, model.USD): *model.MakeOrderConstraints(1, 8, 0.002),
-	*model.MakeTradingPair(model.ETH, model.USD): *model.MakeOrderConstraints(2, 8, 0.02),
-	*model.MakeTradingPair(model.ETH, model.BTC): *model.MakeOrderConstraints(5, 8, 0.02),
-	*model.MakeTradingPair(model.XRP, model.USD): *model.MakeOrderConstraints(5, 8, 30.0),
-	*model.MakeTradingPair(model.XRP, model.BTC): *model.MakeOrderConstraints(8, 8, 30.0),
+	return &api.WithdrawFunds{
+		WithdrawalID: withdrawal.ID,
+	}, nil
 }
@@ -0,0 +1,871 @@
+package plugins
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stellar/kelp/api"
+	"github.com/stellar/kelp/model"
+	"github.com/stellar/kelp/support/stronghold-go-api-client"
+	"github.com/stellar/kelp/support/stronghold-go-api-client/strongholdtest"
+	"github.com/stretchr/testify/assert"
+)
+
+const testStrongholdKey = "test-key"
+
+// base64 encoded secret
+const testStrongholdSecret = "c2VjcmV0LWZvci10ZXN0aW5nLXN0cm9uZ2hvbGQtZXhjaGFuZ2U="
+
+var testStrongholdPair = model.TradingPair{Base: model.XLM, Quote: model.USD}
+
+func makeTestStrongholdServer() *strongholdtest.Server {
+	s := strongholdtest.NewServer(testStrongholdKey, testStrongholdSecret)
+	s.AddAsset(strongholdapi.Asset{ID: "XLM", Precision: 7, WithdrawalFee: "0.01", MinimumWithdrawal: "1", MaximumWithdrawal: "100000"})
+	s.AddAsset(strongholdapi.Asset{ID: "USD", Precision: 2, WithdrawalFee: "1", MinimumWithdrawal: "10", MaximumWithdrawal: "10000"})
+	s.AddMarket(strongholdapi.Market{
+		ID:                    "XLMUSD",
+		BaseAssetID:           "XLM",
+		CounterAssetID:        "USD",
+		MinimumPriceIncrement: "0.00001",
+		MinimumSizeIncrement:  "0.0000001",
+		MinimumSize:           "1",
+		MinimumValue:          "0.1",
+	})
+	s.SetOrderBook(strongholdapi.OrderBook{
+		MarketID: "XLMUSD",
+		Asks:     []strongholdapi.OrderBookItem{{Price: "0.10100", Size: "50"}, {Price: "0.10200", Size: "75"}},
+		Bids:     []strongholdapi.OrderBookItem{{Price: "0.09900", Size: "20"}, {Price: "0.09800", Size: "30"}},
+	})
+	s.SetBalance("XLM", 1000)
+	s.SetBalance("USD", 100)
+	return s
+}
+
+func makeTestStrongholdExchange(s *strongholdtest.Server, isSimulated bool) *strongholdExchange {
+	apiKeys := []api.ExchangeAPIKey{{Key: testStrongholdKey, Secret: testStrongholdSecret}}
+	exchange, e := makeStrongholdExchange(apiKeys, []api.ExchangeParam{{Param: "baseUrl", Value: s.URL}}, isSimulated)
+	if e != nil {
+		panic(e)
+	}
+	return exchange.(*strongholdExchange)
+}
+
+func TestStrongholdOrderLifecycle(t *testing.T) {
+	s := makeTestStrongholdServer()
+	defer s.Close()
+	exchange := makeTestStrongholdExchange(s, false)
+
+	txID, e := exchange.AddOrder(&model.Order{
+		Pair:        &testStrongholdPair,
+		OrderAction: model.OrderActionSell,
+		OrderType:   model.OrderTypeLimit,
+		Price:       model.NumberFromFloat(0.105, 5),
+		Volume:      model.NumberFromFloat(100, 7),
+	})
+	if !assert.NoError(t, e) {
+		return
+	}
+
+	m, e := exchange.GetOpenOrders([]*model.TradingPair{&testStrongholdPair})
+	if !assert.NoError(t, e) {
+		return
+	}
+	if !assert.Equal(t, 1, len(m[testStrongholdPair])) {
+		return
+	}
+	openOrder := m[testStrongholdPair][0]
+	assert.Equal(t, txID.String(), openOrder.ID)
+	assert.Equal(t, model.OrderActionSell, openOrder.OrderAction)
+	assert.Equal(t, model.OrderTypeLimit, openOrder.OrderType)
+	assert.Equal(t, "0.10500", openOrder.Price.AsString())
+	assert.Equal(t, "100.0000000", openOrder.Volume.AsString())
+
+	balances, e := exchange.GetAccountBalances([]interface{}{model.XLM, model.USD, model.BTC})
+	if !assert.NoError(t, e) {
+		return
+	}
+	assert.Equal(t, 1000.0, balances[model.XLM].AsFloat())
+	assert.Equal(t, 100.0, balances[model.USD].AsFloat())
+	assert.Equal(t, 0.0, balances[model.BTC].AsFloat())
+
+	result, e := exchange.CancelOrder(txID, testStrongholdPair)
+	if !assert.NoError(t, e) {
+		return
+	}
+	assert.Equal(t, model.CancelResultCancelSuccessful, result)
+
+	m, e = exchange.GetOpenOrders([]*model.TradingPair{&testStrongholdPair})
+	if !assert.NoError(t, e) {
+		return
+	}
+	assert.Equal(t, 0, len(m[testStrongholdPair]))
+}
+
+func TestStrongholdOrderStatus(t *testing.T) {
+	s := makeTestStrongholdServer()
+	defer s.Close()
+	exchange := makeTestStrongholdExchange(s, false)
+
+	txIDs := []*model.TransactionID{}
+	for _, volume := range []float64{100, 50} {
+		txID, e := exchange.AddOrder(&model.Order{
+			Pair:        &testStrongholdPair,
+			OrderAction: model.OrderActionSell,
+			OrderType:   model.OrderTypeLimit,
+			Price:       model.NumberFromFloat(0.105, 5),
+			Volume:      model.NumberFromFloat(volume, 7),
+		})
+		if !assert.NoError(t, e) {
+			return
+		}
+		txIDs = append(txIDs, txID)
+	}
+	if !assert.NoError(t, s.FillOrder(txIDs[0].String(), 40)) {
+		return
+	}
+	if !assert.NoError(t, s.FillOrder(txIDs[1].String(), 50)) {
+		return
+	}
+
+	// the open orders report the remaining volume of the partial fill
+	m, e := exchange.GetOpenOrders([]*model.TradingPair{&testStrongholdPair})
+	if !assert.NoError(t, e) || !assert.Equal(t, 1, len(m[testStrongholdPair])) {
+		return
+	}
+	openOrder := m[testStrongholdPair][0]
+	assert.Equal(t, 100.0, openOrder.Volume.AsFloat())
+	assert.Equal(t, 40.0, openOrder.VolumeExecuted.AsFloat())
+	assert.Equal(t, 60.0, openOrder.RemainingVolume().AsFloat())
+
+	o, e := exchange.GetOrderStatus(txIDs[1], testStrongholdPair)
+	if !assert.NoError(t, e) {
+		return
+	}
+	assert.Equal(t, model.OrderStatusFilled, o.Status)
+	assert.Equal(t, 0.0, o.RemainingVolume().AsFloat())
+
+	_, e = exchange.CancelOrder(txIDs[0], testStrongholdPair)
+	if !assert.NoError(t, e) {
+		return
+	}
+	orders, e := exchange.QueryOrders(append(txIDs, model.MakeTransactionID("unknown")), testStrongholdPair)
+	if !assert.NoError(t, e) {
+		return
+	}
+	assert.Equal(t, 2, len(orders))
+	assert.Equal(t, model.OrderStatusCanceled, orders[txIDs[0].String()].Status)
+	assert.Equal(t, 40.0, orders[txIDs[0].String()].VolumeExecuted.AsFloat())
+	assert.Equal(t, model.OrderStatusFilled, orders[txIDs[1].String()].Status)
+
+	_, e = exchange.GetOrderStatus(model.MakeTransactionID("unknown"), testStrongholdPair)
+	assert.Equal(t, api.ErrorKindOrderNotFound, api.ErrorKindOf(e))
+}
+
+func TestStrongholdOrderFlags(t *testing.T) {
+	s := makeTestStrongholdServer()
+	defer s.Close()
+	exchange := makeTestStrongholdExchange(s, false)
+	makeOrder := func(price float64) *model.Order {
+		return &model.Order{
+			Pair:        &testStrongholdPair,
+			OrderAction: model.OrderActionSell,
+			OrderType:   model.OrderTypeLimit,
+			Price:       model.NumberFromFloat(price, 5),
+			Volume:      model.NumberFromFloat(10, 7),
+		}
+	}
+
+	// a post-only sell at the best bid would take liquidity
+	order := makeOrder(0.099)
+	order.PostOnly = true
+	_, e := exchange.AddOrder(order)
+	assert.Equal(t, api.ErrorKindInvalidOrder, api.ErrorKindOf(e))
+
+	order = makeOrder(0.105)
+	order.PostOnly = true
+	order.ClientOrderID = "kelp-1"
+	_, e = exchange.AddOrder(order)
+	if !assert.NoError(t, e) {
+		return
+	}
+	m, e := exchange.GetOpenOrders([]*model.TradingPair{&testStrongholdPair})
+	if !assert.NoError(t, e) || !assert.Equal(t, 1, len(m[testStrongholdPair])) {
+		return
+	}
+	assert.Equal(t, "kelp-1", m[testStrongholdPair][0].ClientOrderID)
+
+	// the fake does not match incoming orders so an immediate-or-cancel order never rests on the book
+	order = makeOrder(0.105)
+	order.TimeInForce = model.TimeInForceIOC
+	txID, e := exchange.AddOrder(order)
+	if !assert.NoError(t, e) {
+		return
+	}
+	o, e := exchange.GetOrderStatus(txID, testStrongholdPair)
+	if assert.NoError(t, e) {
+		assert.Equal(t, model.OrderStatusCanceled, o.Status)
+	}
+}
+func TestStrongholdAddOrderValidation(t *testing.T) {
+	s := makeTestStrongholdServer()
+	defer s.Close()
+	exchange := makeTestStrongholdExchange(s, false)
+
+	_, e := exchange.AddOrder(&model.Order{
+		Pair:        &testStrongholdPair,
+		OrderAction: model.OrderActionBuy,
+		OrderType:   model.OrderTypeLimit,
+		Price:       model.NumberFromFloat(0.1, 8),
+		Volume:      model.NumberFromFloat(100, 7),
+	})
+	assert.Error(t, e)
+
+	_, e = exchange.AddOrder(&model.Order{
+		Pair:        &testStrongholdPair,
+		OrderAction: model.OrderActionBuy,
+		OrderType:   model.OrderTypeMarket,
+		Price:       model.NumberFromFloat(0.1, 5),
+		Volume:      model.NumberFromFloat(100, 7),
+	})
+	assert.Error(t, e)
+
+	// nothing should have reached the exchange
+	assert.Equal(t, 0, len(s.Orders()))
+}
+
+func TestStrongholdSimulatedOrders(t *testing.T) {
+	s := makeTestStrongholdServer()
+	defer s.Close()
+	exchange := makeTestStrongholdExchange(s, true)
+
+	txID, e := exchange.AddOrder(&model.Order{
+		Pair:        &testStrongholdPair,
+		OrderAction: model.OrderActionBuy,
+		OrderType:   model.OrderTypeLimit,
+		Price:       model.NumberFromFloat(0.1, 5),
+		Volume:      model.NumberFromFloat(100, 7),
+	})
+	if !assert.NoError(t, e) {
+		return
+	}
+	assert.Equal(t, "simulated", txID.String())
+	assert.Equal(t, 0, len(s.Orders()))
+}
+
+func TestStrongholdGetOrderBookAndTicker(t *testing.T) {
+	s := makeTestStrongholdServer()
+	defer s.Close()
+	exchange := makeTestStrongholdExchange(s, false)
+
+	ob, e := exchange.GetOrderBook(&testStrongholdPair, 1)
+	if !assert.NoError(t, e) {
+		return
+	}
+	assert.Equal(t, 1, len(ob.Asks()))
+	assert.Equal(t, 1, len(ob.Bids()))
+	assert.Equal(t, 0.101, ob.TopAsk().Price.AsFloat())
+	assert.Equal(t, 0.099, ob.TopBid().Price.AsFloat())
+
+	tickers, e := exchange.GetTickerPrice([]model.TradingPair{testStrongholdPair})
+	if !assert.NoError(t, e) {
+		return
+	}
+	assert.Equal(t, 0.101, tickers[testStrongholdPair].AskPrice.AsFloat())
+	assert.Equal(t, 0.099, tickers[testStrongholdPair].BidPrice.AsFloat())
+}
+
+func TestStrongholdOverrideOrderConstraints(t *testing.T) {
+	s := makeTestStrongholdServer()
+	defer s.Close()
+	exchange := makeTestStrongholdExchange(s, false)
+
+	oc := exchange.GetOrderConstraints(&testStrongholdPair)
+	assert.Equal(t, int8(5), oc.PricePrecision)
+
+	pricePrecision := int8(3)
+	exchange.OverrideOrderConstraints(&testStrongholdPair, model.MakeOrderConstraintsOverride(&pricePrecision, nil, nil, nil))
+	oc = exchange.GetOrderConstraints(&testStrongholdPair)
+	assert.Equal(t, int8(3), oc.PricePrecision)
+	assert.Equal(t, int8(7), oc.VolumePrecision)
+}
+
+func TestStrongholdUnlistedPair(t *testing.T) {
+	s := makeTestStrongholdServer()
+	defer s.Close()
+	exchange := makeTestStrongholdExchange(s, false)
+	pair := model.TradingPair{Base: model.BTC, Quote: model.USD}
+
+	assert.Nil(t, exchange.GetOrderConstraints(&pair))
+	_, e := exchange.AddOrder(&model.Order{
+		Pair:        &pair,
+		OrderAction: model.OrderActionBuy,
+		OrderType:   model.OrderTypeLimit,
+		Price:       model.NumberFromFloat(5000, 2),
+		Volume:      model.NumberFromFloat(1, 8),
+	})
+	assert.Error(t, e)
+}
+
+func TestStrongholdGetTradesCursorType(t *testing.T) {
+	s := makeTestStrongholdServer()
+	defer s.Close()
+	exchange := makeTestStrongholdExchange(s, false)
+
+	_, e := exchange.GetTrades(&testStrongholdPair, 1.5)
+	assert.Error(t, e)
+	_, e = exchange.GetTrades(&testStrongholdPair, int64(0))
+	assert.NoError(t, e)
+	_, e = exchange.GetTrades(&testStrongholdPair, makeStrongholdTradeCursor(0))
+	assert.NoError(t, e)
+}
+
+func TestStrongholdGetTradesCursor(t *testing.T) {
+	s := makeTestStrongholdServer()
+	defer s.Close()
+	exchange := makeTestStrongholdExchange(s, false)
+	executedAt := time.Unix(1554200000, 0)
+	addTestStrongholdTrade(s, "t1", executedAt)
+	addTestStrongholdTrade(s, "t2", executedAt)
+
+	result, e := exchange.GetTrades(&testStrongholdPair, nil)
+	if !assert.NoError(t, e) {
+		return
+	}
+	assert.Equal(t, 2, len(result.Trades))
+
+	// polling again does not return the trades at the timestamp of the cursor twice
+	result, e = exchange.GetTrades(&testStrongholdPair, result.Cursor)
+	if !assert.NoError(t, e) {
+		return
+	}
+	assert.Equal(t, 0, len(result.Trades))
+	assert.NotNil(t, result.Cursor)
+
+	// a trade that arrives later at the same timestamp is still returned
+	addTestStrongholdTrade(s, "t3", executedAt)
+	result, e = exchange.GetTrades(&testStrongholdPair, result.Cursor)
+	if !assert.NoError(t, e) {
+		return
+	}
+	if assert.Equal(t, 1, len(result.Trades)) {
+		assert.Equal(t, "t3", result.Trades[0].TransactionID.String())
+	}
+}
+
+func addTestStrongholdTrade(s *strongholdtest.Server, id string, executedAt time.Time) {
+	s.AddTrade(strongholdapi.Trade{
+		ID:         id,
+		OrderID:    "order-1",
+		MarketID:   "XLMUSD",
+		Side:       strongholdapi.SideSell,
+		Price:      "0.1",
+		Size:       "10",
+		Fee:        "0.001",
+		FeeAssetID: "USD",
+		ExecutedAt: executedAt,
+	})
+}
+
+func TestStrongholdGetTradeHistory(t *testing.T) {
+	s := makeTestStrongholdServer()
+	defer s.Close()
+	exchange := makeTestStrongholdExchange(s, false)
+
+	cursor, e := exchange.GetLatestTradeCursor()
+	if !assert.NoError(t, e) {
+		return
+	}
+
+	// more trades than fit in a single page, with several trades in the same millisecond
+	base := time.Now().Add(time.Second)
+	numTrades := strongholdTradesPageSize + 20
+	for i := 0; i < numTrades; i++ {
+		addTestStrongholdTrade(s, fmt.Sprintf("trade-%03d", i), base.Add(time.Duration(i/3)*time.Millisecond))
+	}
+
+	res, e := exchange.GetTradeHistory(testStrongholdPair, cursor, nil)
+	if !assert.NoError(t, e) {
+		return
+	}
+	if !assert.Equal(t, numTrades, len(res.Trades)) {
+		return
+	}
+	trade := res.Trades[0]
+	assert.Equal(t, "trade-000", trade.TransactionID.String())
+	assert.Equal(t, model.OrderActionSell, trade.OrderAction)
+	assert.Equal(t, 0.1, trade.Price.AsFloat())
+	assert.Equal(t, 10.0, trade.Volume.AsFloat())
+	assert.Equal(t, 1.0, trade.Cost.AsFloat())
+	assert.Equal(t, 0.001, trade.Fee.AsFloat())
+
+	// a trade executed in the same millisecond as the last trade is returned exactly once
+	lastTs := res.Trades[numTrades-1].Timestamp.AsInt64()
+	addTestStrongholdTrade(s, "trade-late", time.Unix(0, lastTs*int64(time.Millisecond)))
+	res, e = exchange.GetTradeHistory(testStrongholdPair, res.Cursor, nil)
+	if !assert.NoError(t, e) {
+		return
+	}
+	if !assert.Equal(t, 1, len(res.Trades)) {
+		return
+	}
+	assert.Equal(t, "trade-late", res.Trades[0].TransactionID.String())
+
+	// no new trades keeps the cursor as-is
+	res2, e := exchange.GetTradeHistory(testStrongholdPair, res.Cursor, nil)
+	if !assert.NoError(t, e) {
+		return
+	}
+	assert.Equal(t, 0, len(res2.Trades))
+	assert.Equal(t, res.Cursor, res2.Cursor)
+}
+
+func TestStrongholdGetTradeHistoryStringCursors(t *testing.T) {
+	s := makeTestStrongholdServer()
+	defer s.Close()
+	exchange := makeTestStrongholdExchange(s, false)
+
+	base := time.Unix(1555000000, 0)
+	for i := 0; i < 5; i++ {
+		addTestStrongholdTrade(s, fmt.Sprintf("trade-%d", i), base.Add(time.Duration(i)*time.Second))
+	}
+
+	startMillis := base.Add(time.Second).UnixNano() / int64(time.Millisecond)
+	endMillis := base.Add(3*time.Second).UnixNano() / int64(time.Millisecond)
+	res, e := exchange.GetTradeHistory(testStrongholdPair, fmt.Sprintf("%d", startMillis), endMillis)
+	if !assert.NoError(t, e) {
+		return
+	}
+	if !assert.Equal(t, 2, len(res.Trades)) {
+		return
+	}
+	assert.Equal(t, "trade-1", res.Trades[0].TransactionID.String())
+	assert.Equal(t, "trade-2", res.Trades[1].TransactionID.String())
+
+	_, e = exchange.GetTradeHistory(testStrongholdPair, 1.5, nil)
+	assert.Error(t, e)
+}
+
+func makeTestStrongholdStreamingExchange(s *strongholdtest.Server) *strongholdExchange {
+	apiKeys := []api.ExchangeAPIKey{{Key: testStrongholdKey, Secret: testStrongholdSecret}}
+	exchange, e := makeStrongholdExchange(apiKeys, []api.ExchangeParam{
+		{Param: "baseUrl", Value: s.URL},
+		{Param: "stream", Value: "true"},
+	}, false)
+	if e != nil {
+		panic(e)
+	}
+	return exchange.(*strongholdExchange)
+}
+
+// countTestStrongholdRequests counts the requests received by the server for the "METHOD path"
+func countTestStrongholdRequests(s *strongholdtest.Server, request string) int {
+	count := 0
+	for _, r := range s.Requests() {
+		if r == request {
+			count++
+		}
+	}
+	return count
+}
+
+func TestStrongholdStreamOrderBook(t *testing.T) {
+	s := makeTestStrongholdServer()
+	defer s.Close()
+	exchange := makeTestStrongholdStreamingExchange(s)
+	defer exchange.stream.Close()
+
+	// the first call subscribes and is served over REST
+	ob, e := exchange.GetOrderBook(&testStrongholdPair, 0)
+	if !assert.NoError(t, e) {
+		return
+	}
+	assert.Equal(t, 0.101, ob.TopAsk().Price.AsFloat())
+	assert.True(t, waitForTestCondition(func() bool {
+		_, ok := exchange.stream.OrderBook("XLMUSD")
+		return ok
+	}))
+
+	s.UpdateOrderBook("XLMUSD", []strongholdapi.OrderBookItem{{Price: "0.10000", Size: "5"}}, []strongholdapi.OrderBookItem{{Price: "0.10100", Size: "0"}})
+	assert.True(t, waitForTestCondition(func() bool {
+		ob, e = exchange.GetOrderBook(&testStrongholdPair, 0)
+		return e == nil && len(ob.Asks()) == 1 && len(ob.Bids()) == 3
+	}))
+	assert.Equal(t, 0.102, ob.TopAsk().Price.AsFloat())
+	assert.Equal(t, 0.1, ob.TopBid().Price.AsFloat())
+	assert.Equal(t, 5.0, ob.TopBid().Volume.AsFloat())
+	assert.Equal(t, 1, countTestStrongholdRequests(s, "GET /v1/venues/test-venue/markets/XLMUSD/orderbook"))
+
+	// maxCount is applied to the streamed book
+	ob, e = exchange.GetOrderBook(&testStrongholdPair, 1)
+	if !assert.NoError(t, e) {
+		return
+	}
+	assert.Equal(t, 1, len(ob.Bids()))
+}
+
+func TestStrongholdStreamTradeHistory(t *testing.T) {
+	s := makeTestStrongholdServer()
+	defer s.Close()
+	exchange := makeTestStrongholdStreamingExchange(s)
+	defer exchange.stream.Close()
+
+	// a cursor from before the fills subscription is served over REST
+	addTestStrongholdTrade(s, "trade-old", time.Now().Add(-time.Hour))
+	res, e := exchange.GetTradeHistory(testStrongholdPair, nil, nil)
+	if !assert.NoError(t, e) {
+		return
+	}
+	assert.Equal(t, 1, len(res.Trades))
+	assert.Equal(t, 1, countTestStrongholdRequests(s, "GET /v1/venues/test-venue/accounts/test-account/trades"))
+
+	var cursor interface{}
+	assert.True(t, waitForTestCondition(func() bool {
+		cursor, e = exchange.GetLatestTradeCursor()
+		_, ok := exchange.stream.Fills("XLMUSD", cursor.(*strongholdTradeCursor).timestampMillis)
+		return e == nil && ok
+	}))
+
+	executedAt := time.Now().Add(10 * time.Millisecond)
+	addTestStrongholdTrade(s, "trade-1", executedAt)
+	addTestStrongholdTrade(s, "trade-2", executedAt)
+	assert.True(t, waitForTestCondition(func() bool {
+		res, e = exchange.GetTradeHistory(testStrongholdPair, cursor, nil)
+		return e == nil && len(res.Trades) == 2
+	}))
+	assert.Equal(t, "trade-1", res.Trades[0].TransactionID.String())
+	assert.Equal(t, "trade-2", res.Trades[1].TransactionID.String())
+	assert.Equal(t, 0.001, res.Trades[0].Fee.AsFloat())
+
+	// the returned cursor continues from the cache without repeating trades
+	res, e = exchange.GetTradeHistory(testStrongholdPair, res.Cursor, nil)
+	if !assert.NoError(t, e) {
+		return
+	}
+	assert.Equal(t, 0, len(res.Trades))
+	assert.Equal(t, 1, countTestStrongholdRequests(s, "GET /v1/venues/test-venue/accounts/test-account/trades"))
+}
+
+// waitForTestCondition polls the condition until it holds or the timeout expires
+func waitForTestCondition(condition func() bool) bool {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if condition() {
+			return true
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return false
+}
+
+func TestMakeStrongholdExchangeParams(t *testing.T) {
+	s := makeTestStrongholdServer()
+	defer s.Close()
+
+	apiKeys := []api.ExchangeAPIKey{{Key: testStrongholdKey, Secret: testStrongholdSecret}}
+	exchange, e := makeStrongholdExchange(apiKeys, []api.ExchangeParam{
+		{Param: "baseUrl", Value: s.URL + "/"},
+		{Param: "venueId", Value: strongholdtest.VenueID},
+		{Param: "accountId", Value: strongholdtest.AccountID},
+	}, false)
+	if !assert.NoError(t, e) {
+		return
+	}
+
+	_, e = exchange.GetAccountBalances([]interface{}{model.XLM})
+	if !assert.NoError(t, e) {
+		return
+	}
+	// the venue and account were not looked up because they were configured
+	assert.Equal(t, []string{
+		"GET /v1/venues/test-venue/markets",
+		"GET /v1/venues/test-venue/accounts/test-account",
+	}, s.Requests())
+
+	_, e = makeStrongholdExchange(apiKeys, []api.ExchangeParam{{Param: "venue", Value: "x"}}, false)
+	assert.Error(t, e)
+}
+
+func TestStrongholdPrepareDeposit(t *testing.T) {
+	s := makeTestStrongholdServer()
+	defer s.Close()
+	exchange := makeTestStrongholdExchange(s, false)
+
+	res, e := exchange.PrepareDeposit(model.XLM, model.NumberFromFloat(100, 7))
+	if !assert.NoError(t, e) {
+		return
+	}
+	assert.Equal(t, strongholdtest.DepositAddress, res.Address)
+	assert.Equal(t, strongholdtest.AccountID, res.Memo)
+	assert.Equal(t, 0.0, res.Fee.AsFloat())
+	assert.Equal(t, int64(0), res.ExpireTs)
+
+	_, e = exchange.PrepareDeposit(model.BTC, model.NumberFromFloat(1, 8))
+	assert.Error(t, e)
+}
+
+func TestStrongholdWithdraw(t *testing.T) {
+	s := makeTestStrongholdServer()
+	defer s.Close()
+	exchange := makeTestStrongholdExchange(s, false)
+	address := "GBQ5HSO4C3BTAPHXMBRRHDN3WHJKH7GC6VPOQ5DQ3BFFN7G3LKYAK6AK"
+
+	info, e := exchange.GetWithdrawInfo(model.XLM, model.NumberFromFloat(100, 7), address)
+	if !assert.NoError(t, e) {
+		return
+	}
+	assert.Equal(t, 99.99, info.AmountToReceive.AsFloat())
+
+	// above the maximum
+	_, e = exchange.GetWithdrawInfo(model.USD, model.NumberFromFloat(20000, 2), address)
+	assert.Error(t, e)
+	// below the minimum
+	_, e = exchange.GetWithdrawInfo(model.USD, model.NumberFromFloat(5, 2), address)
+	assert.Error(t, e)
+
+	res, e := exchange.WithdrawFunds(model.XLM, model.NumberFromFloat(100, 7), address)
+	if !assert.NoError(t, e) {
+		return
+	}
+	withdrawals := s.Withdrawals()
+	if !assert.Equal(t, 1, len(withdrawals)) {
+		return
+	}
+	assert.Equal(t, withdrawals[0].ID, res.WithdrawalID)
+	assert.Equal(t, address, withdrawals[0].Address)
+	assert.Equal(t, "100.0000000", withdrawals[0].Amount)
+
+	_, e = exchange.WithdrawFunds(model.XLM, model.NumberFromFloat(5000, 7), address)
+	assert.Error(t, e)
+}
+
+func TestStrongholdLoadMarkets(t *testing.T) {
+	s := makeTestStrongholdServer()
+	defer s.Close()
+	s.AddAsset(strongholdapi.Asset{ID: "BTC", Precision: 8})
+	s.AddAsset(strongholdapi.Asset{ID: "DOGE", Precision: 8})
+	s.AddMarket(strongholdapi.Market{
+		ID:                    "btc-usd",
+		BaseAssetID:           "BTC",
+		CounterAssetID:        "USD",
+		MinimumPriceIncrement: "0.01",
+		MinimumSizeIncrement:  "0.00000001",
+		MinimumSize:           "0.0001",
+	})
+	// markets with assets that are not understood by the bot are skipped
+	s.AddMarket(strongholdapi.Market{
+		ID:                    "DOGEUSD",
+		BaseAssetID:           "DOGE",
+		CounterAssetID:        "USD",
+		MinimumPriceIncrement: "0.0001",
+		MinimumSizeIncrement:  "1",
+	})
+
+	apiKeys := []api.ExchangeAPIKey{{Key: testStrongholdKey, Secret: testStrongholdSecret}}
+	exchange, e := makeStrongholdExchange(apiKeys, []api.ExchangeParam{
+		{Param: "baseUrl", Value: s.URL},
+		{Param: "marketsRefreshSeconds", Value: "0"},
+	}, false)
+	if !assert.NoError(t, e) {
+		return
+	}
+	k := exchange.(*strongholdExchange)
+	assert.Equal(t, 2, len(k.markets))
+
+	oc := k.GetOrderConstraints(&testStrongholdPair)
+	assert.Equal(t, int8(5), oc.PricePrecision)
+	assert.Equal(t, int8(7), oc.VolumePrecision)
+	assert.Equal(t, 1.0, oc.MinBaseVolume.AsFloat())
+	if assert.NotNil(t, oc.MinQuoteVolume) {
+		assert.Equal(t, 0.1, oc.MinQuoteVolume.AsFloat())
+	}
+	btcPair := model.TradingPair{Base: model.BTC, Quote: model.USD}
+	oc = k.GetOrderConstraints(&btcPair)
+	assert.Equal(t, int8(2), oc.PricePrecision)
+	assert.Equal(t, int8(8), oc.VolumePrecision)
+	assert.Equal(t, 0.0001, oc.MinBaseVolume.AsFloat())
+	assert.Nil(t, oc.MinQuoteVolume)
+	marketID, e := k.marketID(&btcPair)
+	if !assert.NoError(t, e) {
+		return
+	}
+	assert.Equal(t, "btc-usd", marketID)
+
+	// markets listed later are picked up on refresh
+	s.AddMarket(strongholdapi.Market{
+		ID:                    "XLMBTC",
+		BaseAssetID:           "XLM",
+		CounterAssetID:        "BTC",
+		MinimumPriceIncrement: "0.00000001",
+		MinimumSizeIncrement:  "0.1",
+		MinimumSize:           "10",
+	})
+	oc = k.GetOrderConstraints(&model.TradingPair{Base: model.XLM, Quote: model.BTC})
+	assert.Equal(t, int8(8), oc.PricePrecision)
+	assert.Equal(t, int8(1), oc.VolumePrecision)
+
+	// overrides are still applied on top of the loaded constraints
+	volumePrecision := int8(4)
+	k.OverrideOrderConstraints(&btcPair, model.MakeOrderConstraintsOverride(nil, &volumePrecision, nil, nil))
+	oc = k.GetOrderConstraints(&btcPair)
+	assert.Equal(t, int8(2), oc.PricePrecision)
+	assert.Equal(t, int8(4), oc.VolumePrecision)
+}
+
+func TestStrongholdConcurrentMarketsRefresh(t *testing.T) {
+	s := makeTestStrongholdServer()
+	defer s.Close()
+	k := makeTestStrongholdExchange(s, false)
+	marketsRequest := "GET /v1/venues/test-venue/markets"
+	requests := countTestStrongholdRequests(s, marketsRequest)
+
+	// the markets are stale so every caller wants to refresh them, only one request should be sent
+	k.marketsMutex.Lock()
+	k.marketsLoadedAt = time.Time{}
+	k.marketsMutex.Unlock()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, ok := k.getMarket(&testStrongholdPair)
+			assert.True(t, ok)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, requests+1, countTestStrongholdRequests(s, marketsRequest))
+}
+
+func TestStrongholdMarketsRefreshBacksOff(t *testing.T) {
+	s := makeTestStrongholdServer()
+	defer s.Close()
+	k := makeTestStrongholdExchange(s, false)
+	marketsRequest := "GET /v1/venues/test-venue/markets"
+	requests := countTestStrongholdRequests(s, marketsRequest)
+
+	// the markets are always stale, after a refresh fails we keep using the loaded markets without asking again
+	k.marketsRefreshInterval = 0
+	s.FailNext(http.StatusServiceUnavailable, "INTERNAL_ERROR", "down")
+	for i := 0; i < 3; i++ {
+		_, ok := k.getMarket(&testStrongholdPair)
+		assert.True(t, ok)
+	}
+	assert.Equal(t, requests+1, countTestStrongholdRequests(s, marketsRequest))
+
+	// the markets are refreshed again once the retry delay has passed
+	k.marketsMutex.Lock()
+	k.marketsFailedAt = time.Now().Add(-strongholdMarketsRetryDelay)
+	k.marketsMutex.Unlock()
+	_, ok := k.getMarket(&testStrongholdPair)
+	assert.True(t, ok)
+	assert.Equal(t, requests+2, countTestStrongholdRequests(s, marketsRequest))
+}
+
+func TestStrongholdPrecisionFromIncrement(t *testing.T) {
+	testCases := []struct {
+		increment string
+		want      int8
+		wantErr   bool
+	}{
+		{"0.00001", 5, false},
+		{"0.0100", 2, false},
+		{"1", 0, false},
+		{"10.0", 0, false},
+		{"0", 0, true},
+		{"abc", 0, true},
+	}
+
+	for _, kase := range testCases {
+		t.Run(kase.increment, func(t *testing.T) {
+			precision, e := strongholdPrecisionFromIncrement(kase.increment)
+			if kase.wantErr {
+				assert.Error(t, e)
+				return
+			}
+			if !assert.NoError(t, e) {
+				return
+			}
+			assert.Equal(t, kase.want, precision)
+		})
+	}
+}
+
+func TestStrongholdErrorKinds(t *testing.T) {
+	s := makeTestStrongholdServer()
+	defer s.Close()
+	exchange := makeTestStrongholdExchange(s, false)
+
+	testCases := []struct {
+		statusCode int
+		code       string
+		wantKind   api.ErrorKind
+	}{
+		{http.StatusTooManyRequests, strongholdtest.ErrorCodeRateLimited, api.ErrorKindRateLimited},
+		{http.StatusUnauthorized, strongholdtest.ErrorCodeUnauthorized, api.ErrorKindAuth},
+		{http.StatusBadRequest, strongholdtest.ErrorCodeInsufficientFunds, api.ErrorKindInsufficientFunds},
+		{http.StatusNotFound, strongholdtest.ErrorCodeOrderNotFound, api.ErrorKindOrderNotFound},
+		{http.StatusServiceUnavailable, "UNAVAILABLE", api.ErrorKindTransient},
+	}
+
+	for _, kase := range testCases {
+		t.Run(kase.code, func(t *testing.T) {
+			s.FailNext(kase.statusCode, kase.code, "injected")
+			_, e := exchange.GetAccountBalances([]interface{}{model.XLM})
+			if !assert.Error(t, e) {
+				return
+			}
+			assert.Equal(t, kase.wantKind, api.ErrorKindOf(e))
+		})
+	}
+
+	s.FailNext(http.StatusTooManyRequests, strongholdtest.ErrorCodeRateLimited, "slow down")
+	_, e := exchange.GetAccountBalances([]interface{}{model.XLM})
+	assert.True(t, api.IsRetryable(e))
+	assert.Equal(t, time.Second, api.RetryAfter(e))
+}
+
+func TestStrongholdErrorKindsAfterWrap(t *testing.T) {
+	s := makeTestStrongholdServer()
+	defer s.Close()
+	exchange := makeTestStrongholdExchange(s, false)
+	address := "GBQ5HSO4C3BTAPHXMBRRHDN3WHJKH7GC6VPOQ5DQ3BFFN7G3LKYAK6AK"
+
+	s.FailNext(http.StatusTooManyRequests, strongholdtest.ErrorCodeRateLimited, "slow down")
+	_, e := exchange.GetWithdrawInfo(model.XLM, model.NumberFromFloat(100, 7), address)
+	assert.Contains(t, e.Error(), "could not fetch assets to get withdraw info")
+	assert.Equal(t, api.ErrorKindRateLimited, api.ErrorKindOf(e))
+	assert.Equal(t, time.Second, api.RetryAfter(e))
+
+	s.FailNext(http.StatusUnauthorized, strongholdtest.ErrorCodeUnauthorized, "bad key")
+	_, e = exchange.PrepareDeposit(model.XLM, model.NumberFromFloat(100, 7))
+	assert.Equal(t, api.ErrorKindAuth, api.ErrorKindOf(e))
+
+	s.FailNext(http.StatusBadRequest, strongholdtest.ErrorCodeInsufficientFunds, "too much")
+	_, e = exchange.WithdrawFunds(model.XLM, model.NumberFromFloat(100, 7), address)
+	assert.Equal(t, api.ErrorKindInsufficientFunds, api.ErrorKindOf(e))
+}
+
+func TestStrongholdErrorKindNetwork(t *testing.T) {
+	s := makeTestStrongholdServer()
+	exchange := makeTestStrongholdExchange(s, false)
+	s.Close()
+
+	_, e := exchange.GetAccountBalances([]interface{}{model.XLM})
+	assert.Equal(t, api.ErrorKindTransient, api.ErrorKindOf(e))
+}
+
+func TestStrongholdThrottledKeyBacksOff(t *testing.T) {
+	s := makeTestStrongholdServer()
+	defer s.Close()
+	exchange := makeTestStrongholdExchange(s, false)
+
+	s.FailNext(http.StatusTooManyRequests, strongholdtest.ErrorCodeRateLimited, "slow down")
+	_, e := exchange.GetAccountBalances([]interface{}{model.XLM})
+	assert.Error(t, e)
+
+	usage := exchange.keyPool.Usage()
+	assert.Equal(t, uint64(1), usage[0].Throttles)
+	assert.True(t, usage[0].BackoffUntil.After(time.Now()))
+}
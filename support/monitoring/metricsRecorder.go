@@ -1,9 +1,13 @@
 package monitoring
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"sync"
+)
 
 // MetricsRecorder uses a map to store metrics and implements the api.Metrics interface.
 type metricsRecorder struct {
+	mutex   sync.Mutex
 	records map[string]interface{}
 }
 
@@ -25,6 +29,8 @@ func MakeMetricsRecorder(records map[string]interface{}) (Metrics, error) {
 // UpdateMetrics updates (or adds if non-existent) metrics in the records for all key-value
 // pairs in the provided map of metrics.
 func (m *metricsRecorder) UpdateMetrics(metrics map[string]interface{}) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 	for k, v := range metrics {
 		m.records[k] = v
 	}
@@ -32,5 +38,7 @@ func (m *metricsRecorder) UpdateMetrics(metrics map[string]interface{}) {
 
 // MarshalJSON gives the JSON representation of the records.
 func (m *metricsRecorder) MarshalJSON() ([]byte, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 	return json.Marshal(m.records)
 }
@@ -0,0 +1,234 @@
+package networking
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// EndpointClass groups the endpoints of an exchange that share a rate limit
+type EndpointClass string
+
+// endpoint classes shared by the exchange adapters
+const (
+	// EndpointClassPublic is for market data that does not need a key
+	EndpointClassPublic EndpointClass = "public"
+	// EndpointClassPrivate is for account data such as balances, open orders and trade history
+	EndpointClassPrivate EndpointClass = "private"
+	// EndpointClassTrading is for placing and cancelling orders
+	EndpointClassTrading EndpointClass = "trading"
+	// EndpointClassFunding is for deposits and withdrawals
+	EndpointClassFunding EndpointClass = "funding"
+)
+
+// DefaultKeyBackoff is how long a throttled key is skipped when the exchange does not say how long to wait
+const DefaultKeyBackoff = 5 * time.Second
+
+// RateLimit is a token bucket that holds up to Burst requests and refills one request every Interval
+type RateLimit struct {
+	Burst    int
+	Interval time.Duration
+}
+
+// KeyUsage is a snapshot of how a key in the pool has been used
+type KeyUsage struct {
+	Index int
+	// Requests is the number of requests made with the key by endpoint class
+	Requests map[EndpointClass]uint64
+	// Throttles is the number of times the exchange throttled the key
+	Throttles uint64
+	// Waited is the total time callers waited for the key's rate limit or backoff
+	Waited       time.Duration
+	BackoffUntil time.Time
+}
+
+// KeyPool hands out API keys in rotation while keeping each key within its rate limits and skipping keys that
+// are backing off after being throttled by the exchange. It is safe for concurrent use.
+type KeyPool struct {
+	name   string
+	limits map[EndpointClass]RateLimit
+
+	mutex     *sync.Mutex
+	keys      []*poolKey
+	nextIndex int
+
+	// overridden in tests
+	now   func() time.Time
+	sleep func(time.Duration)
+}
+
+type poolKey struct {
+	buckets      map[EndpointClass]*tokenBucket
+	backoffUntil time.Time
+	usage        KeyUsage
+}
+
+// tokenBucket allows the number of tokens to go negative so a caller can reserve a token and wait for it outside the lock
+type tokenBucket struct {
+	limit     RateLimit
+	tokens    float64
+	updatedAt time.Time
+}
+
+// MakeKeyPool is a factory method, endpoint classes that are not in limits are not rate limited
+func MakeKeyPool(name string, numKeys int, limits map[EndpointClass]RateLimit) (*KeyPool, error) {
+	if numKeys <= 0 {
+		return nil, fmt.Errorf("key pool '%s' needs at least one key, got %d", name, numKeys)
+	}
+	for class, limit := range limits {
+		if limit.Burst <= 0 || limit.Interval <= 0 {
+			return nil, fmt.Errorf("invalid rate limit for endpoint class '%s' in key pool '%s': %+v", class, name, limit)
+		}
+	}
+
+	p := &KeyPool{
+		name:   name,
+		limits: limits,
+		mutex:  &sync.Mutex{},
+		keys:   []*poolKey{},
+		now:    time.Now,
+		sleep:  time.Sleep,
+	}
+	start := p.now()
+	for i := 0; i < numKeys; i++ {
+		buckets := map[EndpointClass]*tokenBucket{}
+		for class, limit := range limits {
+			buckets[class] = &tokenBucket{
+				limit:     limit,
+				tokens:    float64(limit.Burst),
+				updatedAt: start,
+			}
+		}
+		p.keys = append(p.keys, &poolKey{
+			buckets: buckets,
+			usage: KeyUsage{
+				Index:    i,
+				Requests: map[EndpointClass]uint64{},
+			},
+		})
+	}
+	return p, nil
+}
+
+// Acquire returns the index of the key to use for a request to an endpoint of the given class. Keys are rotated and
+// the key that can be used soonest is picked, blocking until its rate limit or backoff allows the request.
+func (p *KeyPool) Acquire(class EndpointClass) int {
+	p.mutex.Lock()
+	now := p.now()
+	bestIndex := -1
+	var bestWait time.Duration
+	for i := 0; i < len(p.keys); i++ {
+		index := (p.nextIndex + i) % len(p.keys)
+		wait := p.keys[index].waitFor(class, now)
+		if bestIndex == -1 || wait < bestWait {
+			bestIndex = index
+			bestWait = wait
+		}
+		if wait == 0 {
+			break
+		}
+	}
+
+	k := p.keys[bestIndex]
+	k.reserve(class, now)
+	k.usage.Requests[class]++
+	k.usage.Waited += bestWait
+	p.nextIndex = (bestIndex + 1) % len(p.keys)
+	p.mutex.Unlock()
+
+	if bestWait > 0 {
+		p.sleep(bestWait)
+	}
+	return bestIndex
+}
+
+// Throttle puts the key in backoff after the exchange throttled it, a zero retryAfter uses DefaultKeyBackoff
+func (p *KeyPool) Throttle(index int, retryAfter time.Duration) {
+	if retryAfter <= 0 {
+		retryAfter = DefaultKeyBackoff
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	k := p.keys[index]
+	until := p.now().Add(retryAfter)
+	if until.After(k.backoffUntil) {
+		k.backoffUntil = until
+	}
+	k.usage.Throttles++
+}
+
+// Usage returns a snapshot of the usage of every key in the pool
+func (p *KeyPool) Usage() []KeyUsage {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	usages := []KeyUsage{}
+	for _, k := range p.keys {
+		u := k.usage
+		u.Requests = map[EndpointClass]uint64{}
+		for class, n := range k.usage.Requests {
+			u.Requests[class] = n
+		}
+		u.BackoffUntil = k.backoffUntil
+		usages = append(usages, u)
+	}
+	return usages
+}
+
+// Metrics returns the usage of the keys in a form that can be passed to monitoring.Metrics.UpdateMetrics
+func (p *KeyPool) Metrics() map[string]interface{} {
+	now := p.now()
+	m := map[string]interface{}{}
+	for _, u := range p.Usage() {
+		prefix := fmt.Sprintf("%s_key_%d", p.name, u.Index)
+		for class, n := range u.Requests {
+			m[fmt.Sprintf("%s_requests_%s", prefix, class)] = n
+		}
+		m[prefix+"_throttles"] = u.Throttles
+		m[prefix+"_waited_millis"] = int64(u.Waited / time.Millisecond)
+		m[prefix+"_in_backoff"] = u.BackoffUntil.After(now)
+	}
+	return m
+}
+
+// waitFor returns how long a request of the class would have to wait for this key
+func (k *poolKey) waitFor(class EndpointClass, now time.Time) time.Duration {
+	var wait time.Duration
+	if k.backoffUntil.After(now) {
+		wait = k.backoffUntil.Sub(now)
+	}
+	if b, ok := k.buckets[class]; ok {
+		if bucketWait := b.waitFor(now); bucketWait > wait {
+			wait = bucketWait
+		}
+	}
+	return wait
+}
+
+func (k *poolKey) reserve(class EndpointClass, now time.Time) {
+	if b, ok := k.buckets[class]; ok {
+		b.refill(now)
+		b.tokens--
+	}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	elapsed := now.Sub(b.updatedAt)
+	if elapsed <= 0 {
+		return
+	}
+	b.tokens += float64(elapsed) / float64(b.limit.Interval)
+	if b.tokens > float64(b.limit.Burst) {
+		b.tokens = float64(b.limit.Burst)
+	}
+	b.updatedAt = now
+}
+
+func (b *tokenBucket) waitFor(now time.Time) time.Duration {
+	b.refill(now)
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) * float64(b.limit.Interval))
+}
@@ -0,0 +1,105 @@
+package networking
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// makeTestKeyPool returns a pool with a fake clock that advances when the pool sleeps
+func makeTestKeyPool(t *testing.T, numKeys int, limits map[EndpointClass]RateLimit) (*KeyPool, *time.Time) {
+	p, e := MakeKeyPool("test", numKeys, limits)
+	if !assert.NoError(t, e) {
+		t.FailNow()
+	}
+
+	now := time.Unix(1500000000, 0)
+	p.now = func() time.Time { return now }
+	p.sleep = func(d time.Duration) { now = now.Add(d) }
+	return p, &now
+}
+
+func TestKeyPoolRotates(t *testing.T) {
+	p, _ := makeTestKeyPool(t, 3, nil)
+
+	indices := []int{}
+	for i := 0; i < 6; i++ {
+		indices = append(indices, p.Acquire(EndpointClassPrivate))
+	}
+	assert.Equal(t, []int{0, 1, 2, 0, 1, 2}, indices)
+}
+
+func TestKeyPoolRateLimit(t *testing.T) {
+	p, now := makeTestKeyPool(t, 2, map[EndpointClass]RateLimit{
+		EndpointClassTrading: {Burst: 2, Interval: time.Second},
+	})
+	start := *now
+
+	// 2 keys with a burst of 2 each allow 4 requests without waiting
+	for i := 0; i < 4; i++ {
+		p.Acquire(EndpointClassTrading)
+	}
+	assert.Equal(t, start, *now)
+
+	// the next request waits for a token to be refilled
+	p.Acquire(EndpointClassTrading)
+	assert.Equal(t, start.Add(time.Second), *now)
+
+	// classes without a limit never wait
+	for i := 0; i < 10; i++ {
+		p.Acquire(EndpointClassPublic)
+	}
+	assert.Equal(t, start.Add(time.Second), *now)
+}
+
+func TestKeyPoolSkipsThrottledKeys(t *testing.T) {
+	p, now := makeTestKeyPool(t, 2, nil)
+	start := *now
+
+	p.Throttle(0, 10*time.Second)
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, 1, p.Acquire(EndpointClassPrivate))
+	}
+	assert.Equal(t, start, *now)
+
+	// when every key is backing off we wait for the one that recovers first
+	p.Throttle(1, 0)
+	assert.Equal(t, 1, p.Acquire(EndpointClassPrivate))
+	assert.Equal(t, start.Add(DefaultKeyBackoff), *now)
+}
+
+func TestKeyPoolUsage(t *testing.T) {
+	p, _ := makeTestKeyPool(t, 2, map[EndpointClass]RateLimit{
+		EndpointClassPrivate: {Burst: 1, Interval: time.Second},
+	})
+
+	for i := 0; i < 3; i++ {
+		p.Acquire(EndpointClassPrivate)
+	}
+	p.Acquire(EndpointClassPublic)
+	p.Throttle(1, time.Minute)
+
+	usage := p.Usage()
+	if !assert.Equal(t, 2, len(usage)) {
+		return
+	}
+	assert.Equal(t, map[EndpointClass]uint64{EndpointClassPrivate: 2}, usage[0].Requests)
+	assert.Equal(t, time.Second, usage[0].Waited)
+	assert.Equal(t, map[EndpointClass]uint64{EndpointClassPrivate: 1, EndpointClassPublic: 1}, usage[1].Requests)
+	assert.Equal(t, uint64(1), usage[1].Throttles)
+
+	m := p.Metrics()
+	assert.Equal(t, uint64(2), m["test_key_0_requests_private"])
+	assert.Equal(t, int64(1000), m["test_key_0_waited_millis"])
+	assert.Equal(t, false, m["test_key_0_in_backoff"])
+	assert.Equal(t, true, m["test_key_1_in_backoff"])
+}
+
+func TestMakeKeyPoolValidation(t *testing.T) {
+	_, e := MakeKeyPool("test", 0, nil)
+	assert.Error(t, e)
+
+	_, e = MakeKeyPool("test", 1, map[EndpointClass]RateLimit{EndpointClassPublic: {Burst: 0, Interval: time.Second}})
+	assert.Error(t, e)
+}
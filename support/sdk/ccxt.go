@@ -6,6 +6,7 @@ import (
 	"hash/fnv"
 	"log"
 	"net/http"
+	"net/url"
 	"reflect"
 	"strconv"
 	"strings"
@@ -15,12 +16,31 @@ import (
 	"github.com/stellar/kelp/support/networking"
 )
 
-// ccxtBaseURL should not have suffix of '/'
-const ccxtBaseURL = "http://localhost:3000"
+// DefaultCcxtBaseURL is where ccxt-rest is expected to run when no base URL is configured
+const DefaultCcxtBaseURL = "http://localhost:3000"
+
+// ccxtBaseURL should not have suffix of '/', it is changed with SetBaseURL
+var ccxtBaseURL = DefaultCcxtBaseURL
+
+// CcxtAPIVersion is the flavour of the ccxt-rest API that a server speaks
+type CcxtAPIVersion string
+
+// ccxt-rest versions that are supported
+const (
+	// CcxtAPIVersionLegacy is ccxt-rest v0.0.x, every ccxt method is a route under the exchange instance
+	CcxtAPIVersionLegacy CcxtAPIVersion = "legacy"
+	// CcxtAPIVersionV1 is ccxt-rest v1, ccxt methods are called directly via the "_" route of the exchange instance
+	CcxtAPIVersionV1 CcxtAPIVersion = "v1"
+)
+
+// pathVersion is only served by ccxt-rest v1 and later, legacy servers respond with a 404
+const pathVersion = "/version"
 
 // Ccxt Rest SDK (https://github.com/franz-see/ccxt-rest, https://github.com/ccxt/ccxt/)
 type Ccxt struct {
 	httpClient   *http.Client
+	baseURL      string
+	apiVersion   CcxtAPIVersion
 	exchangeName string
 	instanceName string
 	markets      map[string]CcxtMarket
@@ -62,8 +82,14 @@ func MakeInitializedCcxtExchange(exchangeName string, apiKey api.ExchangeAPIKey,
 	if e != nil {
 		return nil, fmt.Errorf("cannot make instance name: %s", e)
 	}
+	version, e := GetAPIVersion()
+	if e != nil {
+		return nil, fmt.Errorf("cannot determine the version of ccxt-rest: %s", e)
+	}
 	c := &Ccxt{
 		httpClient:   http.DefaultClient,
+		baseURL:      ccxtBaseURL,
+		apiVersion:   version,
 		exchangeName: exchangeName,
 		instanceName: instanceName,
 	}
@@ -76,6 +102,99 @@ func MakeInitializedCcxtExchange(exchangeName string, apiKey api.ExchangeAPIKey,
 	return c, nil
 }
 
+// SetBaseURL points the sdk at the ccxt-rest server running at baseURL, it needs to be called before any exchanges are
+// loaded since the list of exchanges and the API version of the server are cached
+func SetBaseURL(baseURL string) error {
+	u, e := url.Parse(baseURL)
+	if e != nil {
+		return fmt.Errorf("could not parse ccxt base URL '%s': %s", baseURL, e)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid ccxt base URL '%s', expected a URL such as %s", baseURL, DefaultCcxtBaseURL)
+	}
+
+	ccxtBaseURL = strings.TrimSuffix(baseURL, "/")
+	exchangeList = nil
+	apiVersion = nil
+	return nil
+}
+
+// apiVersion is the cached version of the ccxt-rest server at ccxtBaseURL
+var apiVersion *CcxtAPIVersion
+
+// GetAPIVersion returns the version of the ccxt-rest API served at the base URL, probing the server the first time
+func GetAPIVersion() (CcxtAPIVersion, error) {
+	if apiVersion == nil {
+		v, e := probeAPIVersion(http.DefaultClient, ccxtBaseURL)
+		if e != nil {
+			return "", e
+		}
+		log.Printf("ccxt-rest at %s speaks the %s API\n", ccxtBaseURL, v)
+		apiVersion = &v
+	}
+	return *apiVersion, nil
+}
+
+// probeAPIVersion asks the server for its version, only ccxt-rest v1 and later report one
+func probeAPIVersion(httpClient *http.Client, baseURL string) (CcxtAPIVersion, error) {
+	resp, e := httpClient.Get(baseURL + pathVersion)
+	if e != nil {
+		return "", fmt.Errorf("could not execute http request: %s", e)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return CcxtAPIVersionLegacy, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code when probing the version of ccxt-rest: %d", resp.StatusCode)
+	}
+
+	var output struct {
+		Version string `json:"version"`
+	}
+	e = json.NewDecoder(resp.Body).Decode(&output)
+	if e != nil {
+		return "", fmt.Errorf("could not decode the version of ccxt-rest: %s", e)
+	}
+	major, e := strconv.Atoi(strings.SplitN(strings.TrimPrefix(output.Version, "v"), ".", 2)[0])
+	if e != nil {
+		return "", fmt.Errorf("could not parse the version of ccxt-rest '%s': %s", output.Version, e)
+	}
+	if major < 1 {
+		return CcxtAPIVersionLegacy, nil
+	}
+	return CcxtAPIVersionV1, nil
+}
+
+// exchangeURL is the route that lists and creates the instances of the exchange
+func (c *Ccxt) exchangeURL() string {
+	return c.baseURL + pathExchanges + "/" + c.exchangeName
+}
+
+// instanceURL is the route that returns the details of the exchange instance
+func (c *Ccxt) instanceURL() string {
+	return c.exchangeURL() + "/" + c.instanceName
+}
+
+// methodURL is the route that calls the ccxt method on the exchange instance
+func (c *Ccxt) methodURL(method string) string {
+	if c.apiVersion == CcxtAPIVersionV1 {
+		return c.instanceURL() + "/_/" + method
+	}
+	return c.instanceURL() + "/" + method
+}
+
+// ExchangeName returns the name of the ccxt exchange of the instance
+func (c *Ccxt) ExchangeName() string {
+	return c.exchangeName
+}
+
+// APIVersion returns the version of the ccxt-rest API that the instance is called with
+func (c *Ccxt) APIVersion() CcxtAPIVersion {
+	return c.apiVersion
+}
+
 // exchangeList contains a list of supported exchanges
 var exchangeList *[]string
 
@@ -92,10 +211,10 @@ func loadExchangeList() {
 	e := networking.JSONRequest(http.DefaultClient, "GET", ccxtBaseURL+pathExchanges, "", map[string]string{}, &output, "error")
 	if e != nil {
 		eMsg1 := strings.Contains(e.Error(), "could not execute http request")
-		eMsg2 := strings.Contains(e.Error(), "http://localhost:3000/exchanges: dial tcp")
+		eMsg2 := strings.Contains(e.Error(), ccxtBaseURL+pathExchanges+": dial tcp")
 		eMsg3 := strings.Contains(e.Error(), "connection refused")
 		if eMsg1 && eMsg2 && eMsg3 {
-			log.Printf("ccxt-rest is not running on port 3000 so we cannot include those exchanges")
+			log.Printf("ccxt-rest is not running at %s so we cannot include those exchanges", ccxtBaseURL)
 		} else {
 			panic(fmt.Errorf("error getting list of supported exchanges by CCXT: %s", e))
 		}
@@ -119,7 +238,7 @@ func (c *Ccxt) initialize(apiKey api.ExchangeAPIKey, params []api.ExchangeParam,
 
 	// list all the instances of the exchange
 	var instanceList []string
-	e := networking.JSONRequest(c.httpClient, "GET", ccxtBaseURL+pathExchanges+"/"+c.exchangeName, "", map[string]string{}, &instanceList, "error")
+	e := networking.JSONRequest(c.httpClient, "GET", c.exchangeURL(), "", map[string]string{}, &instanceList, "error")
 	if e != nil {
 		return fmt.Errorf("error getting list of exchange instances for exchange '%s': %s", c.exchangeName, e)
 	}
@@ -137,7 +256,7 @@ func (c *Ccxt) initialize(apiKey api.ExchangeAPIKey, params []api.ExchangeParam,
 
 	// load markets to populate fields related to markets
 	var marketsResponse interface{}
-	url := ccxtBaseURL + pathExchanges + "/" + c.exchangeName + "/" + c.instanceName + "/loadMarkets"
+	url := c.methodURL("loadMarkets")
 	e = networking.JSONRequest(c.httpClient, "POST", url, "", map[string]string{}, &marketsResponse, "error")
 	if e != nil {
 		return fmt.Errorf("error loading markets for exchange instance (exchange=%s, instanceName=%s): %s", c.exchangeName, c.instanceName, e)
@@ -204,7 +323,7 @@ func (c *Ccxt) newInstance(apiKey api.ExchangeAPIKey, params []api.ExchangeParam
 	}
 
 	var newInstance map[string]interface{}
-	e = networking.JSONRequest(c.httpClient, "POST", ccxtBaseURL+pathExchanges+"/"+c.exchangeName, string(jsonData), c.headersMap, &newInstance, "error")
+	e = networking.JSONRequest(c.httpClient, "POST", c.exchangeURL(), string(jsonData), c.headersMap, &newInstance, "error")
 	if e != nil {
 		return fmt.Errorf("error in web request when creating new exchange instance for exchange '%s': %s", c.exchangeName, e)
 	}
@@ -218,7 +337,7 @@ func (c *Ccxt) newInstance(apiKey api.ExchangeAPIKey, params []api.ExchangeParam
 // symbolExists returns an error if the symbol does not exist
 func (c *Ccxt) symbolExists(tradingPair string) error {
 	// get list of symbols available on exchange
-	url := ccxtBaseURL + pathExchanges + "/" + c.exchangeName + "/" + c.instanceName
+	url := c.instanceURL()
 	// decode generic data (see "https://blog.golang.org/json-and-go#TOC_4.")
 	var exchangeOutput interface{}
 	e := networking.JSONRequest(c.httpClient, "GET", url, "", c.headersMap, &exchangeOutput, "error")
@@ -242,6 +361,38 @@ func (c *Ccxt) symbolExists(tradingPair string) error {
 	return fmt.Errorf("trading pair '%s' does not exist in the list of %d symbols on exchange '%s'", tradingPair, len(symbolsList), c.exchangeName)
 }
 
+// Has returns true if the exchange implements the ccxt method or emulates it, from the "has" field of the details of
+// the exchange instance (https://github.com/ccxt/ccxt/wiki/Manual#exchange-properties)
+func (c *Ccxt) Has(method string) (bool, error) {
+	v, e := c.hasValue(method)
+	if e != nil {
+		return false, e
+	}
+	return v == true || v == "emulated", nil
+}
+
+// HasNative returns true if the exchange implements the ccxt method, methods that ccxt emulates with other calls are
+// excluded
+func (c *Ccxt) HasNative(method string) (bool, error) {
+	v, e := c.hasValue(method)
+	if e != nil {
+		return false, e
+	}
+	return v == true, nil
+}
+
+// hasValue returns the value of the method in the "has" field of the details of the exchange instance
+func (c *Ccxt) hasValue(method string) (interface{}, error) {
+	var exchangeOutput struct {
+		Has map[string]interface{} `json:"has"`
+	}
+	e := networking.JSONRequest(c.httpClient, "GET", c.instanceURL(), "", c.headersMap, &exchangeOutput, "error")
+	if e != nil {
+		return nil, fmt.Errorf("error fetching details of exchange instance (exchange=%s, instanceName=%s): %s", c.exchangeName, c.instanceName, e)
+	}
+	return exchangeOutput.Has[method], nil
+}
+
 // GetMarket returns the CcxtMarket instance
 func (c *Ccxt) GetMarket(tradingPair string) *CcxtMarket {
 	if v, ok := c.markets[tradingPair]; ok {
@@ -264,7 +415,7 @@ func (c *Ccxt) FetchTicker(tradingPair string) (map[string]interface{}, error) {
 	}
 
 	// fetch ticker for symbol
-	url := ccxtBaseURL + pathExchanges + "/" + c.exchangeName + "/" + c.instanceName + "/fetchTicker"
+	url := c.methodURL("fetchTicker")
 	// decode generic data (see "https://blog.golang.org/json-and-go#TOC_4.")
 	var output interface{}
 	e = networking.JSONRequest(c.httpClient, "POST", url, string(data), c.headersMap, &output, "error")
@@ -304,7 +455,7 @@ func (c *Ccxt) FetchOrderBook(tradingPair string, limit *int) (map[string][]Ccxt
 	}
 
 	// fetch orderbook for symbol
-	url := ccxtBaseURL + pathExchanges + "/" + c.exchangeName + "/" + c.instanceName + "/fetchOrderBook"
+	url := c.methodURL("fetchOrderBook")
 	// decode generic data (see "https://blog.golang.org/json-and-go#TOC_4.")
 	var output interface{}
 	e = networking.JSONRequest(c.httpClient, "POST", url, string(data), c.headersMap, &output, "error")
@@ -365,7 +516,7 @@ func (c *Ccxt) FetchTrades(tradingPair string) ([]CcxtTrade, error) {
 	}
 
 	// fetch trades for symbol
-	url := ccxtBaseURL + pathExchanges + "/" + c.exchangeName + "/" + c.instanceName + "/fetchTrades"
+	url := c.methodURL("fetchTrades")
 	// decode generic data (see "https://blog.golang.org/json-and-go#TOC_4.")
 	output := []CcxtTrade{}
 	e = networking.JSONRequest(c.httpClient, "POST", url, string(data), c.headersMap, &output, "error")
@@ -398,7 +549,7 @@ func (c *Ccxt) FetchMyTrades(tradingPair string, limit int, maybeCursorStart int
 	}
 
 	// fetch trades for symbol
-	url := ccxtBaseURL + pathExchanges + "/" + c.exchangeName + "/" + c.instanceName + "/fetchMyTrades"
+	url := c.methodURL("fetchMyTrades")
 	// decode generic data (see "https://blog.golang.org/json-and-go#TOC_4.")
 	output := []CcxtTrade{}
 	e = networking.JSONRequest(c.httpClient, "POST", url, string(data), c.headersMap, &output, "error")
@@ -417,7 +568,7 @@ type CcxtBalance struct {
 
 // FetchBalance calls the /fetchBalance endpoint on CCXT
 func (c *Ccxt) FetchBalance() (map[string]CcxtBalance, error) {
-	url := ccxtBaseURL + pathExchanges + "/" + c.exchangeName + "/" + c.instanceName + "/fetchBalance"
+	url := c.methodURL("fetchBalance")
 	// decode generic data (see "https://blog.golang.org/json-and-go#TOC_4.")
 	var output interface{}
 	e := networking.JSONRequest(c.httpClient, "POST", url, "", c.headersMap, &output, "error")
@@ -456,9 +607,11 @@ func (c *Ccxt) FetchBalance() (map[string]CcxtBalance, error) {
 
 // CcxtOpenOrder represents an open order
 type CcxtOpenOrder struct {
-	Amount    float64
-	Cost      float64
-	Filled    float64
+	Amount float64
+	Cost   float64
+	Filled float64
+	// Remaining is nil when the exchange does not report it
+	Remaining *float64
 	ID        string
 	Price     float64
 	Side      string
@@ -466,6 +619,10 @@ type CcxtOpenOrder struct {
 	Symbol    string
 	Type      string
 	Timestamp int64
+	// ClientOrderID is empty when the order was placed without one or when the exchange does not report it
+	ClientOrderID string
+	// Info is the raw response of the exchange for the order
+	Info interface{}
 }
 
 // FetchOpenOrders calls the /fetchOpenOrders endpoint on CCXT
@@ -483,7 +640,7 @@ func (c *Ccxt) FetchOpenOrders(tradingPairs []string) (map[string][]CcxtOpenOrde
 		return nil, fmt.Errorf("error marshaling input (tradingPairs=%v) for exchange '%s': %s", tradingPairs, c.exchangeName, e)
 	}
 
-	url := ccxtBaseURL + pathExchanges + "/" + c.exchangeName + "/" + c.instanceName + "/fetchOpenOrders"
+	url := c.methodURL("fetchOpenOrders")
 	// decode generic data (see "https://blog.golang.org/json-and-go#TOC_4.")
 	var output interface{}
 	e = networking.JSONRequest(c.httpClient, "POST", url, string(data), c.headersMap, &output, "error")
@@ -520,6 +677,12 @@ func (c *Ccxt) FetchOpenOrders(tradingPairs []string) (map[string][]CcxtOpenOrde
 
 // CreateLimitOrder calls the /createOrder endpoint on CCXT with a limit price and the order type set to "limit"
 func (c *Ccxt) CreateLimitOrder(tradingPair string, side string, amount float64, price float64) (*CcxtOpenOrder, error) {
+	return c.CreateLimitOrderWithParams(tradingPair, side, amount, price, nil)
+}
+
+// CreateLimitOrderWithParams is CreateLimitOrder with the params argument of createOrder, e.g. the unified "postOnly",
+// "timeInForce" and "clientOrderId" params, which ccxt translates for the exchange (https://github.com/ccxt/ccxt/wiki/Manual#placing-orders)
+func (c *Ccxt) CreateLimitOrderWithParams(tradingPair string, side string, amount float64, price float64, params map[string]interface{}) (*CcxtOpenOrder, error) {
 	orderType := "limit"
 	e := c.symbolExists(tradingPair)
 	if e != nil {
@@ -534,12 +697,15 @@ func (c *Ccxt) CreateLimitOrder(tradingPair string, side string, amount float64,
 		amount,
 		price,
 	}
+	if len(params) > 0 {
+		inputData = append(inputData, params)
+	}
 	data, e := json.Marshal(&inputData)
 	if e != nil {
 		return nil, fmt.Errorf("error marshaling input (%v) for exchange '%s': %s", inputData, c.exchangeName, e)
 	}
 
-	url := ccxtBaseURL + pathExchanges + "/" + c.exchangeName + "/" + c.instanceName + "/createOrder"
+	url := c.methodURL("createOrder")
 	// decode generic data (see "https://blog.golang.org/json-and-go#TOC_4.")
 	var output interface{}
 	e = networking.JSONRequest(c.httpClient, "POST", url, string(data), c.headersMap, &output, "error")
@@ -578,7 +744,7 @@ func (c *Ccxt) CancelOrder(orderID string, tradingPair string) (*CcxtOpenOrder,
 		return nil, fmt.Errorf("error marshaling input (%v) for exchange '%s': %s", inputData, c.exchangeName, e)
 	}
 
-	url := ccxtBaseURL + pathExchanges + "/" + c.exchangeName + "/" + c.instanceName + "/cancelOrder"
+	url := c.methodURL("cancelOrder")
 	// decode generic data (see "https://blog.golang.org/json-and-go#TOC_4.")
 	var output interface{}
 	e = networking.JSONRequest(c.httpClient, "POST", url, string(data), c.headersMap, &output, "error")
@@ -599,3 +765,301 @@ func (c *Ccxt) CancelOrder(orderID string, tradingPair string) (*CcxtOpenOrder,
 
 	return &openOrder, nil
 }
+
+// EditLimitOrder calls the /editOrder endpoint on CCXT to amend the amount and price of an open limit order, the
+// returned order has a new ID on exchanges that replace the order instead of amending it
+func (c *Ccxt) EditLimitOrder(orderID string, tradingPair string, side string, amount float64, price float64, params map[string]interface{}) (*CcxtOpenOrder, error) {
+	orderType := "limit"
+	e := c.symbolExists(tradingPair)
+	if e != nil {
+		return nil, fmt.Errorf("symbol does not exist: %s", e)
+	}
+
+	// marshal input data
+	inputData := []interface{}{
+		orderID,
+		tradingPair,
+		orderType,
+		side,
+		amount,
+		price,
+	}
+	if len(params) > 0 {
+		inputData = append(inputData, params)
+	}
+	data, e := json.Marshal(&inputData)
+	if e != nil {
+		return nil, fmt.Errorf("error marshaling input (%v) for exchange '%s': %s", inputData, c.exchangeName, e)
+	}
+
+	url := c.methodURL("editOrder")
+	// decode generic data (see "https://blog.golang.org/json-and-go#TOC_4.")
+	var output interface{}
+	e = networking.JSONRequest(c.httpClient, "POST", url, string(data), c.headersMap, &output, "error")
+	if e != nil {
+		return nil, fmt.Errorf("error editing order: %s", e)
+	}
+
+	outputMap, ok := output.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("could not convert the output to a map[string]interface{}, type = %s", reflect.TypeOf(output))
+	}
+
+	var openOrder CcxtOpenOrder
+	e = mapstructure.Decode(outputMap, &openOrder)
+	if e != nil {
+		return nil, fmt.Errorf("could not decode outputMap to openOrder (%v): %s", outputMap, e)
+	}
+
+	return &openOrder, nil
+}
+
+// CcxtOrderRequest is a single order of a CreateOrders call
+type CcxtOrderRequest struct {
+	Symbol string                 `json:"symbol"`
+	Type   string                 `json:"type"`
+	Side   string                 `json:"side"`
+	Amount float64                `json:"amount"`
+	Price  float64                `json:"price"`
+	Params map[string]interface{} `json:"params,omitempty"`
+}
+
+// CreateOrders calls the /createOrders endpoint on CCXT, the orders in the result are in the same order as the requests
+// and the orders that were rejected by the exchange do not have an ID
+func (c *Ccxt) CreateOrders(requests []CcxtOrderRequest) ([]CcxtOpenOrder, error) {
+	checked := map[string]bool{}
+	for _, r := range requests {
+		if checked[r.Symbol] {
+			continue
+		}
+		e := c.symbolExists(r.Symbol)
+		if e != nil {
+			return nil, fmt.Errorf("symbol does not exist: %s", e)
+		}
+		checked[r.Symbol] = true
+	}
+
+	// marshal input data
+	inputData := []interface{}{requests}
+	data, e := json.Marshal(&inputData)
+	if e != nil {
+		return nil, fmt.Errorf("error marshaling input (%v) for exchange '%s': %s", inputData, c.exchangeName, e)
+	}
+
+	url := c.methodURL("createOrders")
+	// decode generic data (see "https://blog.golang.org/json-and-go#TOC_4.")
+	var output interface{}
+	e = networking.JSONRequest(c.httpClient, "POST", url, string(data), c.headersMap, &output, "error")
+	if e != nil {
+		return nil, fmt.Errorf("error creating orders: %s", e)
+	}
+
+	var orders []CcxtOpenOrder
+	e = mapstructure.Decode(output, &orders)
+	if e != nil {
+		return nil, fmt.Errorf("could not decode output to a list of orders (%v): %s", output, e)
+	}
+	if len(orders) != len(requests) {
+		return nil, fmt.Errorf("expected %d orders in the result of createOrders but got %d", len(requests), len(orders))
+	}
+
+	return orders, nil
+}
+
+// CancelOrders calls the /cancelOrders endpoint on CCXT with the orderIDs and tradingPair, the result is not parsed since
+// it is specific to the exchange
+func (c *Ccxt) CancelOrders(orderIDs []string, tradingPair string) error {
+	e := c.symbolExists(tradingPair)
+	if e != nil {
+		return fmt.Errorf("symbol does not exist: %s", e)
+	}
+
+	// marshal input data
+	inputData := []interface{}{
+		orderIDs,
+		tradingPair,
+	}
+	data, e := json.Marshal(&inputData)
+	if e != nil {
+		return fmt.Errorf("error marshaling input (%v) for exchange '%s': %s", inputData, c.exchangeName, e)
+	}
+
+	url := c.methodURL("cancelOrders")
+	var output interface{}
+	e = networking.JSONRequest(c.httpClient, "POST", url, string(data), c.headersMap, &output, "error")
+	if e != nil {
+		return fmt.Errorf("error canceling orders: %s", e)
+	}
+	return nil
+}
+
+// FetchOrder calls the /fetchOrder endpoint on CCXT with the orderID and tradingPair, closed orders are returned as well
+func (c *Ccxt) FetchOrder(orderID string, tradingPair string) (*CcxtOpenOrder, error) {
+	e := c.symbolExists(tradingPair)
+	if e != nil {
+		return nil, fmt.Errorf("symbol does not exist: %s", e)
+	}
+
+	// marshal input data
+	inputData := []interface{}{
+		orderID,
+		tradingPair,
+	}
+	data, e := json.Marshal(&inputData)
+	if e != nil {
+		return nil, fmt.Errorf("error marshaling input (%v) for exchange '%s': %s", inputData, c.exchangeName, e)
+	}
+
+	url := c.methodURL("fetchOrder")
+	// decode generic data (see "https://blog.golang.org/json-and-go#TOC_4.")
+	var output interface{}
+	e = networking.JSONRequest(c.httpClient, "POST", url, string(data), c.headersMap, &output, "error")
+	if e != nil {
+		return nil, fmt.Errorf("error fetching order: %s", e)
+	}
+
+	outputMap, ok := output.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("could not convert the output to a map[string]interface{}, type = %s", reflect.TypeOf(output))
+	}
+
+	var order CcxtOpenOrder
+	e = mapstructure.Decode(outputMap, &order)
+	if e != nil {
+		return nil, fmt.Errorf("could not decode outputMap to order (%v): %s", outputMap, e)
+	}
+
+	return &order, nil
+}
+
+// CcxtDepositAddress is the address where an asset can be deposited
+type CcxtDepositAddress struct {
+	Currency string
+	Address  string
+	// Tag is the memo, destination tag or payment id that needs to be attached to the deposit, empty if not needed
+	Tag string
+}
+
+// FetchDepositAddress calls the /fetchDepositAddress endpoint on CCXT, currency is the CCXT code of the asset
+func (c *Ccxt) FetchDepositAddress(currency string) (*CcxtDepositAddress, error) {
+	// marshal input data
+	inputData := []interface{}{currency}
+	data, e := json.Marshal(&inputData)
+	if e != nil {
+		return nil, fmt.Errorf("error marshaling input (%v) for exchange '%s': %s", inputData, c.exchangeName, e)
+	}
+
+	url := c.methodURL("fetchDepositAddress")
+	// decode generic data (see "https://blog.golang.org/json-and-go#TOC_4.")
+	var output interface{}
+	e = networking.JSONRequest(c.httpClient, "POST", url, string(data), c.headersMap, &output, "error")
+	if e != nil {
+		return nil, fmt.Errorf("error fetching deposit address: %s", e)
+	}
+
+	outputMap, ok := output.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("could not convert the output to a map[string]interface{}, type = %s", reflect.TypeOf(output))
+	}
+
+	var depositAddress CcxtDepositAddress
+	e = mapstructure.Decode(outputMap, &depositAddress)
+	if e != nil {
+		return nil, fmt.Errorf("could not decode outputMap to depositAddress (%v): %s", outputMap, e)
+	}
+
+	return &depositAddress, nil
+}
+
+// CcxtWithdrawal is the result of a withdrawal
+type CcxtWithdrawal struct {
+	ID string
+}
+
+// Withdraw calls the /withdraw endpoint on CCXT, the tag is only sent when it is not empty
+func (c *Ccxt) Withdraw(currency string, amount float64, address string, tag string) (*CcxtWithdrawal, error) {
+	// marshal input data
+	inputData := []interface{}{
+		currency,
+		amount,
+		address,
+	}
+	if tag != "" {
+		inputData = append(inputData, tag)
+	}
+	data, e := json.Marshal(&inputData)
+	if e != nil {
+		return nil, fmt.Errorf("error marshaling input (%v) for exchange '%s': %s", inputData, c.exchangeName, e)
+	}
+
+	url := c.methodURL("withdraw")
+	// decode generic data (see "https://blog.golang.org/json-and-go#TOC_4.")
+	var output interface{}
+	e = networking.JSONRequest(c.httpClient, "POST", url, string(data), c.headersMap, &output, "error")
+	if e != nil {
+		return nil, fmt.Errorf("error withdrawing: %s", e)
+	}
+
+	outputMap, ok := output.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("could not convert the output to a map[string]interface{}, type = %s", reflect.TypeOf(output))
+	}
+
+	// some exchanges return a numeric id
+	id, ok := outputMap["id"]
+	if !ok || id == nil {
+		return nil, fmt.Errorf("result from call to withdraw did not contain 'id' field: %v", outputMap)
+	}
+	switch v := id.(type) {
+	case string:
+		return &CcxtWithdrawal{ID: v}, nil
+	case float64:
+		return &CcxtWithdrawal{ID: strconv.FormatFloat(v, 'f', -1, 64)}, nil
+	default:
+		return nil, fmt.Errorf("could not convert the 'id' field of the withdrawal to a string, type = %s", reflect.TypeOf(id))
+	}
+}
+
+// CcxtFundingFees are the fixed fees charged by the exchange for deposits and withdrawals by currency, a currency
+// is missing when the exchange does not report its fee
+type CcxtFundingFees struct {
+	Deposit  map[string]float64
+	Withdraw map[string]float64
+}
+
+// FetchFundingFees calls the /fetchFundingFees endpoint on CCXT
+func (c *Ccxt) FetchFundingFees() (*CcxtFundingFees, error) {
+	url := c.methodURL("fetchFundingFees")
+	// decode generic data (see "https://blog.golang.org/json-and-go#TOC_4.")
+	var output interface{}
+	e := networking.JSONRequest(c.httpClient, "POST", url, "", c.headersMap, &output, "error")
+	if e != nil {
+		return nil, fmt.Errorf("error fetching funding fees: %s", e)
+	}
+
+	outputMap, ok := output.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("could not convert the output to a map[string]interface{}, type = %s", reflect.TypeOf(output))
+	}
+
+	return &CcxtFundingFees{
+		Deposit:  readFees(outputMap["deposit"]),
+		Withdraw: readFees(outputMap["withdraw"]),
+	}, nil
+}
+
+// readFees skips the fees that are null or not numbers since ccxt uses them for unknown fees
+func readFees(raw interface{}) map[string]float64 {
+	fees := map[string]float64{}
+	rawMap, ok := raw.(map[string]interface{})
+	if !ok {
+		return fees
+	}
+
+	for currency, v := range rawMap {
+		if fee, ok := v.(float64); ok {
+			fees[currency] = fee
+		}
+	}
+	return fees
+}
@@ -1,13 +1,17 @@
 package sdk
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 
 	"github.com/stellar/kelp/api"
 	"github.com/stellar/kelp/model"
+	"github.com/stellar/kelp/support/sdk/ccxttest"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -670,3 +674,227 @@ func validateCanceledOrder(t *testing.T, openOrder *CcxtOpenOrder, tradingPair s
 
 	return true
 }
+
+// makeFakeCcxtRest returns a ccxt-rest server for binance with the XLM/USDT market that speaks the API version,
+// calls to the ccxt methods are recorded in methods
+func makeFakeCcxtRest(version CcxtAPIVersion, methods *[]string) *httptest.Server {
+	instancePath := pathExchanges + "/binance/binance"
+	methodPrefix := instancePath + "/"
+	if version == CcxtAPIVersionV1 {
+		methodPrefix = instancePath + "/_/"
+	}
+	writeJSON := func(w http.ResponseWriter, v interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(v)
+	}
+
+	instances := []string{}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == pathVersion && version == CcxtAPIVersionV1:
+			writeJSON(w, map[string]string{"version": "1.0.0"})
+		case r.URL.Path == pathExchanges:
+			writeJSON(w, []string{"binance", "kraken"})
+		case r.URL.Path == pathExchanges+"/binance" && r.Method == "GET":
+			writeJSON(w, instances)
+		case r.URL.Path == pathExchanges+"/binance" && r.Method == "POST":
+			instances = append(instances, "binance")
+			writeJSON(w, map[string]interface{}{"urls": map[string]string{}})
+		case r.URL.Path == instancePath:
+			writeJSON(w, map[string]interface{}{"symbols": []string{"XLM/USDT"}})
+		case strings.HasPrefix(r.URL.Path, methodPrefix) && r.Method == "POST":
+			method := strings.TrimPrefix(r.URL.Path, methodPrefix)
+			*methods = append(*methods, method)
+			switch method {
+			case "loadMarkets":
+				writeJSON(w, map[string]interface{}{"XLM/USDT": map[string]interface{}{"symbol": "XLM/USDT", "base": "XLM", "quote": "USDT"}})
+			case "fetchTicker":
+				writeJSON(w, map[string]interface{}{"symbol": "XLM/USDT", "bid": 0.1, "ask": 0.11})
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestCcxtAPIVersions(t *testing.T) {
+	defer SetBaseURL(DefaultCcxtBaseURL)
+
+	for _, version := range []CcxtAPIVersion{CcxtAPIVersionLegacy, CcxtAPIVersionV1} {
+		t.Run(string(version), func(t *testing.T) {
+			methods := []string{}
+			s := makeFakeCcxtRest(version, &methods)
+			defer s.Close()
+			if !assert.NoError(t, SetBaseURL(s.URL+"/")) {
+				return
+			}
+
+			v, e := GetAPIVersion()
+			if !assert.NoError(t, e) {
+				return
+			}
+			assert.Equal(t, version, v)
+
+			c, e := MakeInitializedCcxtExchange("binance", api.ExchangeAPIKey{}, []api.ExchangeParam{}, []api.ExchangeHeader{})
+			if !assert.NoError(t, e) {
+				return
+			}
+			assert.Equal(t, "XLM", c.GetMarket("XLM/USDT").Base)
+
+			ticker, e := c.FetchTicker("XLM/USDT")
+			if !assert.NoError(t, e) {
+				return
+			}
+			assert.Equal(t, 0.11, ticker["ask"])
+			assert.Equal(t, []string{"loadMarkets", "fetchTicker"}, methods)
+		})
+	}
+}
+
+func TestSetBaseURL(t *testing.T) {
+	defer SetBaseURL(DefaultCcxtBaseURL)
+
+	assert.Error(t, SetBaseURL("localhost:3000"))
+	assert.Error(t, SetBaseURL("ftp://localhost:3000"))
+	if assert.NoError(t, SetBaseURL("http://10.0.0.5:3000/")) {
+		assert.Equal(t, "http://10.0.0.5:3000", ccxtBaseURL)
+	}
+}
+
+func TestFundingMethods(t *testing.T) {
+	defer SetBaseURL(DefaultCcxtBaseURL)
+	s := ccxttest.NewServer(ccxttest.VersionV1, "binance")
+	defer s.Close()
+	s.SetBalance("XLM", 100)
+	s.SetDepositAddress("XLM", "GBTESTADDRESS", "12345")
+	s.SetDepositAddress("BTC", "1TestAddress", "")
+	s.SetFundingFees("XLM", 0, 0.01)
+	s.SetFundingFees("BTC", -1, 0.0005)
+	if !assert.NoError(t, SetBaseURL(s.URL)) {
+		return
+	}
+
+	c, e := MakeInitializedCcxtExchange("binance", api.ExchangeAPIKey{}, []api.ExchangeParam{}, []api.ExchangeHeader{})
+	if !assert.NoError(t, e) {
+		return
+	}
+
+	address, e := c.FetchDepositAddress("XLM")
+	if !assert.NoError(t, e) {
+		return
+	}
+	assert.Equal(t, CcxtDepositAddress{Currency: "XLM", Address: "GBTESTADDRESS", Tag: "12345"}, *address)
+	address, e = c.FetchDepositAddress("BTC")
+	if !assert.NoError(t, e) {
+		return
+	}
+	assert.Equal(t, "", address.Tag)
+
+	fees, e := c.FetchFundingFees()
+	if !assert.NoError(t, e) {
+		return
+	}
+	assert.Equal(t, map[string]float64{"XLM": 0}, fees.Deposit)
+	assert.Equal(t, map[string]float64{"XLM": 0.01, "BTC": 0.0005}, fees.Withdraw)
+
+	withdrawal, e := c.Withdraw("XLM", 25, "GBDESTINATION", "")
+	if !assert.NoError(t, e) {
+		return
+	}
+	assert.Equal(t, []ccxttest.Withdrawal{{ID: withdrawal.ID, Currency: "XLM", Amount: 25, Address: "GBDESTINATION"}}, s.Withdrawals())
+
+	_, e = c.Withdraw("XLM", 1000, "GBDESTINATION", "memo")
+	assert.Contains(t, fmt.Sprintf("%s", e), "InsufficientFunds")
+}
+
+func TestBatchOrderMethods(t *testing.T) {
+	defer SetBaseURL(DefaultCcxtBaseURL)
+	s := ccxttest.NewServer(ccxttest.VersionV1, "binance")
+	defer s.Close()
+	s.AddMarket(ccxttest.Market{Symbol: "XLM/BTC", Base: "XLM", Quote: "BTC", PricePrecision: 8, AmountPrecision: 0, MinAmount: 1})
+	s.SetBalance("XLM", 100)
+	s.SetHas("createOrders", true)
+	s.SetHas("cancelOrders", "emulated")
+	s.SetHas("editOrder", false)
+	if !assert.NoError(t, SetBaseURL(s.URL)) {
+		return
+	}
+
+	c, e := MakeInitializedCcxtExchange("binance", api.ExchangeAPIKey{}, []api.ExchangeParam{}, []api.ExchangeHeader{})
+	if !assert.NoError(t, e) {
+		return
+	}
+
+	for method, want := range map[string]bool{"createOrders": true, "cancelOrders": true, "editOrder": false, "fetchOrders": false} {
+		has, e := c.Has(method)
+		if assert.NoError(t, e) {
+			assert.Equal(t, want, has, method)
+		}
+	}
+
+	orders, e := c.CreateOrders([]CcxtOrderRequest{
+		{Symbol: "XLM/BTC", Type: "limit", Side: "sell", Amount: 60, Price: 0.00003, Params: map[string]interface{}{"clientOrderId": "kelp-1"}},
+		{Symbol: "XLM/BTC", Type: "limit", Side: "sell", Amount: 60, Price: 0.00003},
+	})
+	if !assert.NoError(t, e) || !assert.Equal(t, 2, len(orders)) {
+		return
+	}
+	assert.NotEqual(t, "", orders[0].ID)
+	assert.Equal(t, "kelp-1", orders[0].ClientOrderID)
+	// the second order is rejected since the first one holds most of the balance
+	assert.Equal(t, "", orders[1].ID)
+	assert.Equal(t, "rejected", orders[1].Status)
+
+	_, e = c.CreateOrders([]CcxtOrderRequest{{Symbol: "XLM/FOO", Type: "limit", Side: "sell", Amount: 1, Price: 1}})
+	assert.Error(t, e)
+
+	if !assert.NoError(t, c.CancelOrders([]string{orders[0].ID}, "XLM/BTC")) {
+		return
+	}
+	assert.Error(t, c.CancelOrders([]string{orders[0].ID}, "XLM/BTC"))
+}
+
+func TestEditLimitOrder(t *testing.T) {
+	defer SetBaseURL(DefaultCcxtBaseURL)
+	s := ccxttest.NewServer(ccxttest.VersionV1, "binance")
+	defer s.Close()
+	s.AddMarket(ccxttest.Market{Symbol: "XLM/BTC", Base: "XLM", Quote: "BTC", PricePrecision: 8, AmountPrecision: 0, MinAmount: 1})
+	s.SetBalance("XLM", 100)
+	s.SetHas("editOrder", "emulated")
+	if !assert.NoError(t, SetBaseURL(s.URL)) {
+		return
+	}
+
+	c, e := MakeInitializedCcxtExchange("binance", api.ExchangeAPIKey{}, []api.ExchangeParam{}, []api.ExchangeHeader{})
+	if !assert.NoError(t, e) {
+		return
+	}
+
+	// an emulated editOrder is a cancel followed by a create
+	native, e := c.HasNative("editOrder")
+	if assert.NoError(t, e) {
+		assert.False(t, native)
+	}
+	s.SetHas("editOrder", true)
+	native, e = c.HasNative("editOrder")
+	if assert.NoError(t, e) {
+		assert.True(t, native)
+	}
+
+	order, e := c.CreateLimitOrder("XLM/BTC", "sell", 60, 0.00003)
+	if !assert.NoError(t, e) {
+		return
+	}
+	edited, e := c.EditLimitOrder(order.ID, "XLM/BTC", "sell", 80, 0.00004, nil)
+	if !assert.NoError(t, e) {
+		return
+	}
+	assert.NotEqual(t, order.ID, edited.ID)
+	assert.Equal(t, 80.0, edited.Amount)
+	assert.Equal(t, 0.00004, edited.Price)
+
+	_, e = c.EditLimitOrder(order.ID, "XLM/BTC", "sell", 80, 0.00004, nil)
+	assert.Contains(t, fmt.Sprintf("%s", e), "OrderNotFound")
+}
@@ -0,0 +1,699 @@
+// Package ccxttest provides an in-memory fake of a ccxt-rest server with a single exchange so the ccxt sdk and the
+// exchange adapter can be tested without running ccxt-rest.
+package ccxttest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// API versions of ccxt-rest that the fake can speak
+const (
+	VersionLegacy = "legacy"
+	VersionV1     = "v1"
+)
+
+// Call is a ccxt method called on an exchange instance
+type Call struct {
+	Instance string
+	Method   string
+	Args     []interface{}
+}
+
+// Market is a market listed on the exchange
+type Market struct {
+	Symbol          string
+	Base            string
+	Quote           string
+	PricePrecision  int8
+	AmountPrecision int8
+	MinAmount       float64
+	MinCost         float64
+}
+
+// Withdrawal is a withdrawal made from the account
+type Withdrawal struct {
+	ID       string
+	Currency string
+	Amount   float64
+	Address  string
+	Tag      string
+}
+
+type balance struct {
+	free float64
+	used float64
+}
+
+// Server is a fake ccxt-rest server backed by httptest.Server, all instances of the exchange share one account
+type Server struct {
+	*httptest.Server
+
+	version  string
+	exchange string
+
+	mutex       *sync.Mutex
+	instances   map[string]map[string]string
+	markets     map[string]Market
+	tickers     map[string]map[string]interface{}
+	books       map[string]map[string]interface{}
+	balances    map[string]*balance
+	orders      map[string]map[string]interface{}
+	trades      []map[string]interface{}
+	addresses   map[string]map[string]interface{}
+	fees        map[string]map[string]interface{}
+	withdrawals []Withdrawal
+	unsupported map[string]bool
+	has         map[string]interface{}
+	nextID      int
+	nextErrors  []string
+	calls       []Call
+}
+
+// NewServer starts a fake ccxt-rest server for the exchange that speaks the API version
+func NewServer(version string, exchange string) *Server {
+	if version != VersionLegacy && version != VersionV1 {
+		panic(fmt.Sprintf("unsupported ccxt-rest version: %s", version))
+	}
+
+	s := &Server{
+		version:   version,
+		exchange:  exchange,
+		mutex:     &sync.Mutex{},
+		instances: map[string]map[string]string{},
+		markets:   map[string]Market{},
+		tickers:   map[string]map[string]interface{}{},
+		books:     map[string]map[string]interface{}{},
+		balances:  map[string]*balance{},
+		orders:    map[string]map[string]interface{}{},
+		trades:    []map[string]interface{}{},
+		addresses: map[string]map[string]interface{}{},
+		fees: map[string]map[string]interface{}{
+			"deposit":  {},
+			"withdraw": {},
+		},
+		withdrawals: []Withdrawal{},
+		unsupported: map[string]bool{},
+		has:         map[string]interface{}{},
+		nextID:      1,
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// AddMarket lists a market on the exchange
+func (s *Server) AddMarket(market Market) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.markets[market.Symbol] = market
+}
+
+// SetTicker sets the best bid and ask of the market
+func (s *Server) SetTicker(symbol string, bid float64, ask float64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.tickers[symbol] = map[string]interface{}{"symbol": symbol, "bid": bid, "ask": ask}
+}
+
+// SetOrderBook sets the order book of the market, levels are [price, amount] pairs
+func (s *Server) SetOrderBook(symbol string, bids [][2]float64, asks [][2]float64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.books[symbol] = map[string]interface{}{"symbol": symbol, "bids": bids, "asks": asks}
+}
+
+// SetBalance sets the free balance of the asset, nothing is used by orders
+func (s *Server) SetBalance(asset string, free float64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.balances[asset] = &balance{free: free}
+}
+
+// AddTrade records a trade of the account
+func (s *Server) AddTrade(symbol string, id string, side string, price float64, amount float64, timestamp int64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.trades = append(s.trades, map[string]interface{}{
+		"id":        id,
+		"symbol":    symbol,
+		"side":      side,
+		"price":     price,
+		"amount":    amount,
+		"cost":      price * amount,
+		"timestamp": timestamp,
+		"datetime":  time.Unix(0, timestamp*int64(time.Millisecond)).UTC().Format(time.RFC3339),
+		"fee":       map[string]interface{}{"cost": 0.0, "currency": ""},
+	})
+}
+
+// SetDepositAddress sets the address where the currency can be deposited, an empty tag is sent as null like ccxt does
+func (s *Server) SetDepositAddress(currency string, address string, tag string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var t interface{}
+	if tag != "" {
+		t = tag
+	}
+	s.addresses[currency] = map[string]interface{}{"currency": currency, "address": address, "tag": t, "info": map[string]interface{}{}}
+}
+
+// SetFundingFees sets the fixed deposit and withdrawal fees of the currency, a negative fee is reported as unknown (null)
+func (s *Server) SetFundingFees(currency string, depositFee float64, withdrawFee float64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.fees["deposit"][currency] = feeValue(depositFee)
+	s.fees["withdraw"][currency] = feeValue(withdrawFee)
+}
+
+func feeValue(fee float64) interface{} {
+	if fee < 0 {
+		return nil
+	}
+	return fee
+}
+
+// Withdrawals returns the withdrawals made from the account, in order
+func (s *Server) Withdrawals() []Withdrawal {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]Withdrawal{}, s.withdrawals...)
+}
+
+// Unsupport makes the ccxt method fail with a NotSupported error like it does on exchanges that do not have the method
+func (s *Server) Unsupport(method string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.unsupported[method] = true
+}
+
+// SetHas sets the value of the method in the "has" field of the exchange details, ccxt uses true, false and "emulated"
+func (s *Server) SetHas(method string, value interface{}) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.has[method] = value
+}
+
+// FillOrder fills the amount of the open order and settles the balances, the order is closed once it is fully filled
+func (s *Server) FillOrder(id string, amount float64) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	o, ok := s.orders[id]
+	if !ok || o["status"] != "open" {
+		return fmt.Errorf("no open order with id %s", id)
+	}
+	remaining := o["remaining"].(float64)
+	if amount > remaining {
+		return fmt.Errorf("cannot fill %f of order %s, only %f remains", amount, id, remaining)
+	}
+
+	market := s.markets[o["symbol"].(string)]
+	price := o["price"].(float64)
+	if o["side"] == "sell" {
+		s.balances[market.Base].used -= amount
+		s.credit(market.Quote, amount*price)
+	} else {
+		s.balances[market.Quote].used -= amount * price
+		s.credit(market.Base, amount)
+	}
+
+	o["filled"] = o["filled"].(float64) + amount
+	o["remaining"] = remaining - amount
+	o["cost"] = o["filled"].(float64) * price
+	if o["remaining"].(float64) == 0 {
+		o["status"] = "closed"
+	}
+	return nil
+}
+
+// credit must be called with the mutex held
+func (s *Server) credit(asset string, amount float64) {
+	if _, ok := s.balances[asset]; !ok {
+		s.balances[asset] = &balance{}
+	}
+	s.balances[asset].free += amount
+}
+
+// Instances returns the ids of the exchange instances that were created, sorted
+func (s *Server) Instances() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	ids := []string{}
+	for id := range s.instances {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// APIKey returns the api key that the instance was created with
+func (s *Server) APIKey(instance string) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.instances[instance]["apiKey"]
+}
+
+// Calls returns the ccxt methods called on the exchange instances, in order
+func (s *Server) Calls() []Call {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]Call{}, s.calls...)
+}
+
+// FailNext makes the next ccxt method call fail with the message, which should start with the name of a ccxt error class
+// such as "InsufficientFunds: ...", calls are queued
+func (s *Server) FailNext(message string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.nextErrors = append(s.nextErrors, message)
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	body, e := ioutil.ReadAll(r.Body)
+	if e != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("could not read body: %s", e)})
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case r.Method == "GET" && r.URL.Path == "/version":
+		if s.version == VersionLegacy {
+			http.NotFound(w, r)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"version": "1.0.0"})
+	case r.Method == "GET" && r.URL.Path == "/exchanges":
+		writeJSON(w, http.StatusOK, []string{s.exchange})
+	case len(segments) < 2 || segments[0] != "exchanges" || segments[1] != s.exchange:
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown path: " + r.URL.Path})
+	case len(segments) == 2 && r.Method == "GET":
+		ids := []string{}
+		for id := range s.instances {
+			ids = append(ids, id)
+		}
+		writeJSON(w, http.StatusOK, ids)
+	case len(segments) == 2 && r.Method == "POST":
+		s.createInstance(w, body)
+	case len(segments) == 3 && r.Method == "GET":
+		s.handleDetails(w, segments[2])
+	case s.version == VersionLegacy && len(segments) == 4 && r.Method == "POST":
+		s.handleMethod(w, segments[2], segments[3], body)
+	case s.version == VersionV1 && len(segments) == 5 && segments[3] == "_" && r.Method == "POST":
+		s.handleMethod(w, segments[2], segments[4], body)
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown path: " + r.URL.Path})
+	}
+}
+
+func (s *Server) createInstance(w http.ResponseWriter, body []byte) {
+	var config map[string]string
+	if e := json.Unmarshal(body, &config); e != nil || config["id"] == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid exchange config"})
+		return
+	}
+	s.instances[config["id"]] = config
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"id":   config["id"],
+		"name": s.exchange,
+		"urls": map[string]string{"www": "https://" + s.exchange + ".test"},
+	})
+}
+
+func (s *Server) handleDetails(w http.ResponseWriter, instance string) {
+	if _, ok := s.instances[instance]; !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown exchange instance: " + instance})
+		return
+	}
+
+	symbols := []string{}
+	for symbol := range s.markets {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"id": instance, "name": s.exchange, "symbols": symbols, "has": s.has})
+}
+
+func (s *Server) handleMethod(w http.ResponseWriter, instance string, method string, body []byte) {
+	if _, ok := s.instances[instance]; !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown exchange instance: " + instance})
+		return
+	}
+
+	args := []interface{}{}
+	if len(body) > 0 {
+		if e := json.Unmarshal(body, &args); e != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("arguments should be a json array: %s", e)})
+			return
+		}
+	}
+	s.calls = append(s.calls, Call{Instance: instance, Method: method, Args: args})
+
+	if len(s.nextErrors) > 0 {
+		message := s.nextErrors[0]
+		s.nextErrors = s.nextErrors[1:]
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": message})
+		return
+	}
+
+	if s.unsupported[method] {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("NotSupported: %s %s() is not supported yet", s.exchange, method)})
+		return
+	}
+
+	result, e := s.call(method, args)
+	if e != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": e.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// call runs the ccxt method, must be called with the mutex held
+func (s *Server) call(method string, args []interface{}) (interface{}, error) {
+	switch method {
+	case "loadMarkets":
+		return s.loadMarkets(), nil
+	case "fetchTicker":
+		symbol := stringArg(args, 0)
+		ticker, ok := s.tickers[symbol]
+		if !ok {
+			return nil, fmt.Errorf("BadSymbol: %s does not have market symbol %s", s.exchange, symbol)
+		}
+		return ticker, nil
+	case "fetchOrderBook":
+		symbol := stringArg(args, 0)
+		book, ok := s.books[symbol]
+		if !ok {
+			return map[string]interface{}{"symbol": symbol, "bids": [][2]float64{}, "asks": [][2]float64{}}, nil
+		}
+		return book, nil
+	case "fetchBalance":
+		return s.fetchBalance(), nil
+	case "fetchOpenOrders":
+		return s.fetchOpenOrders(stringArg(args, 0)), nil
+	case "createOrder":
+		return s.createOrder(args)
+	case "cancelOrder":
+		return s.cancelOrder(stringArg(args, 0))
+	case "editOrder":
+		return s.editOrder(args)
+	case "createOrders":
+		return s.createOrders(args)
+	case "cancelOrders":
+		return s.cancelOrders(args)
+	case "fetchOrder":
+		o, ok := s.orders[stringArg(args, 0)]
+		if !ok {
+			return nil, fmt.Errorf("OrderNotFound: %s Order does not exist", s.exchange)
+		}
+		return o, nil
+	case "fetchMyTrades", "fetchTrades":
+		return s.fetchTrades(stringArg(args, 0)), nil
+	case "fetchDepositAddress":
+		currency := stringArg(args, 0)
+		address, ok := s.addresses[currency]
+		if !ok {
+			return nil, fmt.Errorf("InvalidAddress: %s fetchDepositAddress() could not find an address for %s", s.exchange, currency)
+		}
+		return address, nil
+	case "fetchFundingFees":
+		return map[string]interface{}{"deposit": s.fees["deposit"], "withdraw": s.fees["withdraw"], "info": map[string]interface{}{}}, nil
+	case "withdraw":
+		return s.withdraw(args)
+	default:
+		return nil, fmt.Errorf("NotSupported: %s %s() is not supported by the fake", s.exchange, method)
+	}
+}
+
+func (s *Server) loadMarkets() map[string]interface{} {
+	markets := map[string]interface{}{}
+	for symbol, m := range s.markets {
+		markets[symbol] = map[string]interface{}{
+			"symbol": symbol,
+			"base":   m.Base,
+			"quote":  m.Quote,
+			"limits": map[string]interface{}{
+				"amount": map[string]interface{}{"min": m.MinAmount},
+				"price":  map[string]interface{}{"min": 0},
+				"cost":   map[string]interface{}{"min": m.MinCost},
+			},
+			"precision": map[string]interface{}{"amount": m.AmountPrecision, "price": m.PricePrecision},
+		}
+	}
+	return markets
+}
+
+func (s *Server) fetchBalance() map[string]interface{} {
+	result := map[string]interface{}{}
+	free := map[string]interface{}{}
+	used := map[string]interface{}{}
+	total := map[string]interface{}{}
+	for asset, b := range s.balances {
+		result[asset] = map[string]interface{}{"free": b.free, "used": b.used, "total": b.free + b.used}
+		free[asset] = b.free
+		used[asset] = b.used
+		total[asset] = b.free + b.used
+	}
+	result["free"] = free
+	result["used"] = used
+	result["total"] = total
+	return result
+}
+
+func (s *Server) fetchOpenOrders(symbol string) []map[string]interface{} {
+	orders := []map[string]interface{}{}
+	for _, o := range s.sortedOrders() {
+		if o["status"] == "open" && (symbol == "" || o["symbol"] == symbol) {
+			orders = append(orders, o)
+		}
+	}
+	return orders
+}
+
+func (s *Server) createOrder(args []interface{}) (interface{}, error) {
+	symbol, orderType, side := stringArg(args, 0), stringArg(args, 1), stringArg(args, 2)
+	amount, price := floatArg(args, 3), floatArg(args, 4)
+	params := mapArg(args, 5)
+	market, ok := s.markets[symbol]
+	if !ok {
+		return nil, fmt.Errorf("BadSymbol: %s does not have market symbol %s", s.exchange, symbol)
+	}
+	if amount < market.MinAmount || amount <= 0 || price <= 0 {
+		return nil, fmt.Errorf("InvalidOrder: %s amount %f or price %f is invalid", s.exchange, amount, price)
+	}
+
+	// the order reserves the base asset when selling and the quote asset when buying
+	asset, reserved := market.Quote, amount*price
+	if side == "sell" {
+		asset, reserved = market.Base, amount
+	}
+	if !s.reserve(asset, reserved) {
+		return nil, fmt.Errorf("InsufficientFunds: %s Account has insufficient balance for requested action", s.exchange)
+	}
+
+	id := strconv.Itoa(s.nextID)
+	s.nextID++
+	order := map[string]interface{}{
+		"id":        id,
+		"symbol":    symbol,
+		"type":      orderType,
+		"side":      side,
+		"amount":    amount,
+		"price":     price,
+		"cost":      0.0,
+		"filled":    0.0,
+		"remaining": amount,
+		"status":    "open",
+		"timestamp": time.Now().UnixNano() / int64(time.Millisecond),
+	}
+	if clientOrderID, ok := params["clientOrderId"]; ok {
+		order["clientOrderId"] = clientOrderID
+	}
+	s.orders[id] = order
+
+	// incoming orders are never matched, so orders that cannot rest on the book are canceled without a fill
+	if tif := params["timeInForce"]; tif == "IOC" || tif == "FOK" {
+		return s.cancelOrder(id)
+	}
+	return order, nil
+}
+
+// createOrders places every order on its own, like the exchanges that report each rejected order without an id
+func (s *Server) createOrders(args []interface{}) (interface{}, error) {
+	requests, ok := argAt(args, 0).([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("ArgumentsRequired: %s createOrders() requires a list of orders", s.exchange)
+	}
+
+	orders := []interface{}{}
+	for _, r := range requests {
+		request, _ := r.(map[string]interface{})
+		o, e := s.createOrder([]interface{}{request["symbol"], request["type"], request["side"], request["amount"], request["price"], request["params"]})
+		if e != nil {
+			o = map[string]interface{}{"id": nil, "status": "rejected", "info": map[string]interface{}{"error": e.Error()}}
+		}
+		orders = append(orders, o)
+	}
+	return orders, nil
+}
+
+// cancelOrders cancels the orders that it finds and fails if any of them was not found
+func (s *Server) cancelOrders(args []interface{}) (interface{}, error) {
+	ids, _ := argAt(args, 0).([]interface{})
+	orders := []interface{}{}
+	var notFound error
+	for _, id := range ids {
+		o, e := s.cancelOrder(stringArg([]interface{}{id}, 0))
+		if e != nil {
+			notFound = e
+			continue
+		}
+		orders = append(orders, o)
+	}
+	if notFound != nil {
+		return nil, notFound
+	}
+	return orders, nil
+}
+
+func (s *Server) cancelOrder(id string) (interface{}, error) {
+	o, ok := s.orders[id]
+	if !ok || o["status"] != "open" {
+		return nil, fmt.Errorf("OrderNotFound: %s Unknown order sent", s.exchange)
+	}
+
+	market := s.markets[o["symbol"].(string)]
+	remaining := o["remaining"].(float64)
+	if o["side"] == "sell" {
+		s.reserve(market.Base, -remaining)
+	} else {
+		s.reserve(market.Quote, -remaining*o["price"].(float64))
+	}
+	o["status"] = "canceled"
+	return o, nil
+}
+
+// editOrder replaces the order with a new one like the ccxt emulation of editOrder, so the edited order has a new id
+func (s *Server) editOrder(args []interface{}) (interface{}, error) {
+	if _, e := s.cancelOrder(stringArg(args, 0)); e != nil {
+		return nil, e
+	}
+	return s.createOrder(args[1:])
+}
+
+func (s *Server) withdraw(args []interface{}) (interface{}, error) {
+	currency, amount, address, tag := stringArg(args, 0), floatArg(args, 1), stringArg(args, 2), stringArg(args, 3)
+	if address == "" || amount <= 0 {
+		return nil, fmt.Errorf("InvalidAddress: %s withdraw() requires an address and a positive amount", s.exchange)
+	}
+	b, ok := s.balances[currency]
+	if !ok || amount > b.free {
+		return nil, fmt.Errorf("InsufficientFunds: %s Account has insufficient balance for requested action", s.exchange)
+	}
+	b.free -= amount
+
+	w := Withdrawal{
+		ID:       fmt.Sprintf("w%d", s.nextID),
+		Currency: currency,
+		Amount:   amount,
+		Address:  address,
+		Tag:      tag,
+	}
+	s.nextID++
+	s.withdrawals = append(s.withdrawals, w)
+	return map[string]interface{}{"id": w.ID, "info": map[string]interface{}{}}, nil
+}
+
+func (s *Server) fetchTrades(symbol string) []map[string]interface{} {
+	trades := []map[string]interface{}{}
+	for _, t := range s.trades {
+		if symbol == "" || t["symbol"] == symbol {
+			trades = append(trades, t)
+		}
+	}
+	return trades
+}
+
+// reserve moves the amount from the free balance to the used balance, a negative amount releases it
+func (s *Server) reserve(asset string, amount float64) bool {
+	b, ok := s.balances[asset]
+	if !ok || amount > b.free {
+		return false
+	}
+	b.free -= amount
+	b.used += amount
+	return true
+}
+
+func (s *Server) sortedOrders() []map[string]interface{} {
+	ids := []int{}
+	for id := range s.orders {
+		n, _ := strconv.Atoi(id)
+		ids = append(ids, n)
+	}
+	sort.Ints(ids)
+
+	orders := []map[string]interface{}{}
+	for _, id := range ids {
+		orders = append(orders, s.orders[strconv.Itoa(id)])
+	}
+	return orders
+}
+
+func stringArg(args []interface{}, i int) string {
+	if i >= len(args) {
+		return ""
+	}
+	if v, ok := args[i].(string); ok {
+		return v
+	}
+	return fmt.Sprintf("%v", args[i])
+}
+
+func argAt(args []interface{}, i int) interface{} {
+	if i >= len(args) {
+		return nil
+	}
+	return args[i]
+}
+
+func mapArg(args []interface{}, i int) map[string]interface{} {
+	if i >= len(args) {
+		return map[string]interface{}{}
+	}
+	if v, ok := args[i].(map[string]interface{}); ok {
+		return v
+	}
+	return map[string]interface{}{}
+}
+
+func floatArg(args []interface{}, i int) float64 {
+	if i >= len(args) {
+		return 0
+	}
+	switch v := args[i].(type) {
+	case float64:
+		return v
+	case string:
+		f, _ := strconv.ParseFloat(v, 64)
+		return f
+	}
+	return 0
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,478 @@
+package strongholdapi
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// operations that can be sent to the venue stream
+const (
+	StreamOpAuth      = "auth"
+	StreamOpSubscribe = "subscribe"
+)
+
+// channels that can be subscribed to on the venue stream
+const (
+	StreamChannelOrderBook = "orderbook"
+	StreamChannelFills     = "fills"
+)
+
+// types of messages received from the venue stream
+const (
+	StreamTypeAuthenticated = "authenticated"
+	StreamTypeSubscribed    = "subscribed"
+	StreamTypeSnapshot      = "snapshot"
+	StreamTypeUpdate        = "update"
+	StreamTypeFill          = "fill"
+	StreamTypeError         = "error"
+)
+
+const (
+	defaultStreamMinReconnectDelay = time.Second
+	defaultStreamMaxReconnectDelay = 30 * time.Second
+	// maxCachedFills bounds the number of fills kept in memory, older fills are dropped first
+	maxCachedFills = 10000
+)
+
+// Stream maintains a websocket connection to the venue that keeps local copies of the subscribed order books
+// and of the account's own fills. The connection is re-established automatically, and state is only reported
+// as synced once it has been rebuilt from a fresh snapshot or subscription after a reconnect.
+type Stream struct {
+	api  *StrongholdApi
+	url  string
+	path string
+
+	mutex             *sync.Mutex
+	conn              *websocket.Conn
+	writeMutex        *sync.Mutex
+	books             map[string]*streamBook
+	fillsWanted       bool
+	fills             []Trade
+	fillsSyncedAt     int64
+	fillsTrimmedUntil int64
+	fillHandlers      []func(Trade)
+	minReconnectDelay time.Duration
+	maxReconnectDelay time.Duration
+	closed            bool
+	done              chan struct{}
+}
+
+// streamBook is the local copy of a single market's order book, keyed by price so updates can replace levels
+type streamBook struct {
+	synced   bool
+	sequence int64
+	bids     map[float64]OrderBookItem
+	asks     map[float64]OrderBookItem
+}
+
+// NewStream connects to the venue stream in the background, nothing is subscribed until requested
+func (api *StrongholdApi) NewStream() (*Stream, error) {
+	venuePath, err := api.venuePath()
+	if err != nil {
+		return nil, err
+	}
+
+	streamURL, err := url.Parse(api.baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL %s: %s", api.baseURL, err)
+	}
+	switch streamURL.Scheme {
+	case "https":
+		streamURL.Scheme = "wss"
+	case "http":
+		streamURL.Scheme = "ws"
+	default:
+		return nil, fmt.Errorf("unsupported scheme in base URL %s", api.baseURL)
+	}
+	path := "/" + APIVersion + venuePath + "/stream"
+	streamURL.Path = strings.TrimRight(streamURL.Path, "/") + path
+
+	s := &Stream{
+		api:               api,
+		url:               streamURL.String(),
+		path:              path,
+		mutex:             &sync.Mutex{},
+		writeMutex:        &sync.Mutex{},
+		books:             map[string]*streamBook{},
+		minReconnectDelay: defaultStreamMinReconnectDelay,
+		maxReconnectDelay: defaultStreamMaxReconnectDelay,
+		done:              make(chan struct{}),
+	}
+	go s.run()
+	return s, nil
+}
+
+// SetReconnectDelay sets the bounds of the exponential backoff used between reconnection attempts
+func (s *Stream) SetReconnectDelay(minDelay time.Duration, maxDelay time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.minReconnectDelay = minDelay
+	s.maxReconnectDelay = maxDelay
+}
+
+// SubscribeOrderBook starts maintaining a local copy of the market's order book
+func (s *Stream) SubscribeOrderBook(marketID string) error {
+	s.mutex.Lock()
+	if _, ok := s.books[marketID]; ok {
+		s.mutex.Unlock()
+		return nil
+	}
+	s.books[marketID] = newStreamBook()
+	conn := s.conn
+	s.mutex.Unlock()
+
+	if conn == nil {
+		// subscribed once connected
+		return nil
+	}
+	return s.send(conn, StreamRequest{Op: StreamOpSubscribe, Channel: StreamChannelOrderBook, MarketID: marketID})
+}
+
+// SubscribeFills starts collecting the account's own fills, this requires credentials
+func (s *Stream) SubscribeFills() error {
+	s.mutex.Lock()
+	if s.fillsWanted {
+		s.mutex.Unlock()
+		return nil
+	}
+	s.fillsWanted = true
+	conn := s.conn
+	s.mutex.Unlock()
+
+	if conn == nil {
+		// subscribed once connected
+		return nil
+	}
+	return s.subscribeFills(conn)
+}
+
+// OnFill registers a handler that is invoked with every fill received, in order
+func (s *Stream) OnFill(handler func(Trade)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.fillHandlers = append(s.fillHandlers, handler)
+}
+
+// OrderBook returns the local copy of the market's order book, ok is false when the book is not subscribed or not synced
+func (s *Stream) OrderBook(marketID string) (book *OrderBook, ok bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	b, exists := s.books[marketID]
+	if !exists || !b.synced {
+		return nil, false
+	}
+	return &OrderBook{
+		MarketID: marketID,
+		Asks:     sortedLevels(b.asks, true),
+		Bids:     sortedLevels(b.bids, false),
+	}, true
+}
+
+// Fills returns the cached fills of the market (all markets when marketID is empty) executed at or after sinceMillis,
+// sorted by execution time. ok is false when the cache cannot guarantee that no fill since then is missing.
+func (s *Stream) Fills(marketID string, sinceMillis int64) (fills []Trade, ok bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// fills executed in the same millisecond as the subscription may have been missed, so that millisecond is excluded
+	if s.fillsSyncedAt == 0 || sinceMillis <= s.fillsSyncedAt || sinceMillis <= s.fillsTrimmedUntil {
+		return nil, false
+	}
+
+	fills = []Trade{}
+	for _, t := range s.fills {
+		if marketID != "" && t.MarketID != marketID {
+			continue
+		}
+		if toMillis(t.ExecutedAt) < sinceMillis {
+			continue
+		}
+		fills = append(fills, t)
+	}
+	sort.SliceStable(fills, func(i int, j int) bool {
+		return fills[i].ExecutedAt.Before(fills[j].ExecutedAt)
+	})
+	return fills, true
+}
+
+// Close disconnects from the stream and stops reconnecting
+func (s *Stream) Close() {
+	s.mutex.Lock()
+	if s.closed {
+		s.mutex.Unlock()
+		return
+	}
+	s.closed = true
+	close(s.done)
+	conn := s.conn
+	s.mutex.Unlock()
+
+	if conn != nil {
+		conn.Close()
+	}
+}
+
+func (s *Stream) isClosed() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.closed
+}
+
+// run keeps the connection alive until the stream is closed
+func (s *Stream) run() {
+	s.mutex.Lock()
+	delay := s.minReconnectDelay
+	s.mutex.Unlock()
+
+	for {
+		connected, err := s.connectAndServe()
+		if s.isClosed() {
+			return
+		}
+
+		s.mutex.Lock()
+		if connected {
+			delay = s.minReconnectDelay
+		}
+		maxDelay := s.maxReconnectDelay
+		s.mutex.Unlock()
+
+		log.Printf("stronghold stream disconnected, reconnecting in %s: %s\n", delay, err)
+		select {
+		case <-s.done:
+			return
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+		if delay > maxDelay {
+			delay = maxDelay
+		}
+	}
+}
+
+// connectAndServe dials the stream, restores the subscriptions and processes messages until the connection fails
+func (s *Stream) connectAndServe() (bool, error) {
+	conn, _, err := websocket.DefaultDialer.Dial(s.url, nil)
+	if err != nil {
+		return false, fmt.Errorf("could not connect to %s: %s", s.url, err)
+	}
+
+	s.mutex.Lock()
+	if s.closed {
+		s.mutex.Unlock()
+		conn.Close()
+		return false, nil
+	}
+	s.conn = conn
+	marketIDs := []string{}
+	for marketID := range s.books {
+		marketIDs = append(marketIDs, marketID)
+	}
+	fillsWanted := s.fillsWanted
+	s.mutex.Unlock()
+
+	defer func() {
+		s.mutex.Lock()
+		s.conn = nil
+		// anything received from now on may have gaps so nothing is synced until it is rebuilt
+		for _, b := range s.books {
+			b.synced = false
+		}
+		s.fillsSyncedAt = 0
+		s.mutex.Unlock()
+		conn.Close()
+	}()
+
+	if fillsWanted {
+		err = s.subscribeFills(conn)
+		if err != nil {
+			return true, err
+		}
+	}
+	for _, marketID := range marketIDs {
+		err = s.send(conn, StreamRequest{Op: StreamOpSubscribe, Channel: StreamChannelOrderBook, MarketID: marketID})
+		if err != nil {
+			return true, err
+		}
+	}
+
+	for {
+		var msg StreamMessage
+		err = conn.ReadJSON(&msg)
+		if err != nil {
+			return true, fmt.Errorf("could not read from stream: %s", err)
+		}
+
+		err = s.handleMessage(conn, &msg)
+		if err != nil {
+			return true, err
+		}
+	}
+}
+
+func (s *Stream) handleMessage(conn *websocket.Conn, msg *StreamMessage) error {
+	switch msg.Type {
+	case StreamTypeSnapshot:
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		b, ok := s.books[msg.MarketID]
+		if !ok {
+			return nil
+		}
+		b.bids = map[float64]OrderBookItem{}
+		b.asks = map[float64]OrderBookItem{}
+		applyLevels(b.bids, msg.Bids)
+		applyLevels(b.asks, msg.Asks)
+		b.sequence = msg.Sequence
+		b.synced = true
+		return nil
+	case StreamTypeUpdate:
+		s.mutex.Lock()
+		b, ok := s.books[msg.MarketID]
+		if !ok || !b.synced {
+			s.mutex.Unlock()
+			return nil
+		}
+		if msg.Sequence != b.sequence+1 {
+			log.Printf("stronghold stream missed an update on the %s order book (expected sequence %d, got %d), requesting a new snapshot\n", msg.MarketID, b.sequence+1, msg.Sequence)
+			b.synced = false
+			s.mutex.Unlock()
+			return s.send(conn, StreamRequest{Op: StreamOpSubscribe, Channel: StreamChannelOrderBook, MarketID: msg.MarketID})
+		}
+		applyLevels(b.bids, msg.Bids)
+		applyLevels(b.asks, msg.Asks)
+		b.sequence = msg.Sequence
+		s.mutex.Unlock()
+		return nil
+	case StreamTypeSubscribed:
+		if msg.Channel == StreamChannelFills {
+			syncedAt := msg.Timestamp
+			if syncedAt == 0 {
+				syncedAt = toMillis(time.Now())
+			}
+			s.mutex.Lock()
+			s.fillsSyncedAt = syncedAt
+			s.mutex.Unlock()
+		}
+		return nil
+	case StreamTypeFill:
+		if msg.Trade == nil {
+			return nil
+		}
+		s.mutex.Lock()
+		s.fills = append(s.fills, *msg.Trade)
+		if len(s.fills) > maxCachedFills {
+			dropped := s.fills[:len(s.fills)-maxCachedFills]
+			for _, t := range dropped {
+				if m := toMillis(t.ExecutedAt); m > s.fillsTrimmedUntil {
+					s.fillsTrimmedUntil = m
+				}
+			}
+			s.fills = append([]Trade{}, s.fills[len(dropped):]...)
+		}
+		handlers := append([]func(Trade){}, s.fillHandlers...)
+		s.mutex.Unlock()
+
+		for _, h := range handlers {
+			h(*msg.Trade)
+		}
+		return nil
+	case StreamTypeError:
+		return fmt.Errorf("stream error: %s", msg.Message)
+	default:
+		// authentication acks and unknown message types carry no state
+		return nil
+	}
+}
+
+func (s *Stream) subscribeFills(conn *websocket.Conn) error {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	signature, err := createSignature(s.api.secret, timestamp, "GET", s.path, "")
+	if err != nil {
+		return fmt.Errorf("could not sign stream authentication: %s", err)
+	}
+
+	err = s.send(conn, StreamRequest{
+		Op:        StreamOpAuth,
+		Key:       s.api.key,
+		Timestamp: timestamp,
+		Signature: signature,
+	})
+	if err != nil {
+		return err
+	}
+	return s.send(conn, StreamRequest{Op: StreamOpSubscribe, Channel: StreamChannelFills})
+}
+
+// send writes a request to the connection, websocket connections only support a single concurrent writer
+func (s *Stream) send(conn *websocket.Conn, req StreamRequest) error {
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+
+	err := conn.WriteJSON(req)
+	if err != nil {
+		return fmt.Errorf("could not send %s request to stream: %s", req.Op, err)
+	}
+	return nil
+}
+
+func newStreamBook() *streamBook {
+	return &streamBook{
+		bids: map[float64]OrderBookItem{},
+		asks: map[float64]OrderBookItem{},
+	}
+}
+
+// applyLevels sets the levels in the book side, a level with a zero size is removed
+func applyLevels(side map[float64]OrderBookItem, levels []OrderBookItem) {
+	for _, level := range levels {
+		price, err := strconv.ParseFloat(level.Price, 64)
+		if err != nil {
+			log.Printf("stronghold stream ignoring order book level with invalid price: %s\n", level.Price)
+			continue
+		}
+		size, err := strconv.ParseFloat(level.Size, 64)
+		if err != nil {
+			log.Printf("stronghold stream ignoring order book level with invalid size: %s\n", level.Size)
+			continue
+		}
+
+		if size == 0 {
+			delete(side, price)
+		} else {
+			side[price] = level
+		}
+	}
+}
+
+func sortedLevels(side map[float64]OrderBookItem, ascending bool) []OrderBookItem {
+	prices := []float64{}
+	for price := range side {
+		prices = append(prices, price)
+	}
+	if ascending {
+		sort.Float64s(prices)
+	} else {
+		sort.Sort(sort.Reverse(sort.Float64Slice(prices)))
+	}
+
+	levels := []OrderBookItem{}
+	for _, price := range prices {
+		levels = append(levels, side[price])
+	}
+	return levels
+}
+
+func toMillis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
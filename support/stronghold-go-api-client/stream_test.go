@@ -0,0 +1,222 @@
+package strongholdapi_test
+
+import (
+	"testing"
+	"time"
+
+	strongholdapi "github.com/stellar/kelp/support/stronghold-go-api-client"
+	"github.com/stellar/kelp/support/stronghold-go-api-client/strongholdtest"
+)
+
+// waitFor polls the condition until it holds or the timeout expires
+func waitFor(condition func() bool) bool {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if condition() {
+			return true
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return false
+}
+
+func nowMillis() int64 {
+	return time.Now().UnixNano() / int64(time.Millisecond)
+}
+
+func makeTestStream(t *testing.T, s *strongholdtest.Server) *strongholdapi.Stream {
+	stream, err := makeTestAPI(s).NewStream()
+	if err != nil {
+		t.Fatalf("NewStream() should not return an error, got %s", err)
+	}
+	stream.SetReconnectDelay(10*time.Millisecond, 50*time.Millisecond)
+	return stream
+}
+
+func bookMatches(stream *strongholdapi.Stream, marketID string, askPrices []string, bidPrices []string) func() bool {
+	return func() bool {
+		book, ok := stream.OrderBook(marketID)
+		if !ok || len(book.Asks) != len(askPrices) || len(book.Bids) != len(bidPrices) {
+			return false
+		}
+		for i, p := range askPrices {
+			if book.Asks[i].Price != p {
+				return false
+			}
+		}
+		for i, p := range bidPrices {
+			if book.Bids[i].Price != p {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func TestStreamOrderBook(t *testing.T) {
+	s := makeTestServer()
+	defer s.Close()
+	s.SetOrderBook(strongholdapi.OrderBook{
+		MarketID: "XLMUSD",
+		Asks:     []strongholdapi.OrderBookItem{{Price: "0.1010", Size: "50"}},
+		Bids:     []strongholdapi.OrderBookItem{{Price: "0.0990", Size: "20"}},
+	})
+
+	stream := makeTestStream(t, s)
+	defer stream.Close()
+	if _, ok := stream.OrderBook("XLMUSD"); ok {
+		t.Errorf("OrderBook() should not be synced before subscribing")
+	}
+
+	err := stream.SubscribeOrderBook("XLMUSD")
+	if err != nil {
+		t.Fatalf("SubscribeOrderBook() should not return an error, got %s", err)
+	}
+	if !waitFor(bookMatches(stream, "XLMUSD", []string{"0.1010"}, []string{"0.0990"})) {
+		t.Fatalf("OrderBook() should be synced from the snapshot")
+	}
+
+	s.UpdateOrderBook("XLMUSD",
+		[]strongholdapi.OrderBookItem{{Price: "0.0995", Size: "5"}, {Price: "0.0990", Size: "0"}},
+		[]strongholdapi.OrderBookItem{{Price: "0.1020", Size: "7"}, {Price: "0.1005", Size: "3"}},
+	)
+	if !waitFor(bookMatches(stream, "XLMUSD", []string{"0.1005", "0.1010", "0.1020"}, []string{"0.0995"})) {
+		book, _ := stream.OrderBook("XLMUSD")
+		t.Errorf("OrderBook() should apply the update, got %+v", book)
+	}
+}
+
+func TestStreamOrderBookSequenceGap(t *testing.T) {
+	s := makeTestServer()
+	defer s.Close()
+
+	stream := makeTestStream(t, s)
+	defer stream.Close()
+	stream.SubscribeOrderBook("XLMUSD")
+	if !waitFor(bookMatches(stream, "XLMUSD", []string{}, []string{})) {
+		t.Fatalf("OrderBook() should be synced from the snapshot")
+	}
+
+	// the update after the skipped sequence cannot be applied so the stream resubscribes and gets a new snapshot
+	s.SkipStreamSequence("XLMUSD")
+	s.UpdateOrderBook("XLMUSD", nil, []strongholdapi.OrderBookItem{{Price: "0.1010", Size: "50"}})
+	if !waitFor(bookMatches(stream, "XLMUSD", []string{"0.1010"}, []string{})) {
+		book, _ := stream.OrderBook("XLMUSD")
+		t.Errorf("OrderBook() should resync after a sequence gap, got %+v", book)
+	}
+
+	subscriptions := 0
+	for _, r := range s.Requests() {
+		if r == "GET "+strongholdtest.StreamPath {
+			subscriptions++
+		}
+	}
+	if subscriptions != 1 {
+		t.Errorf("the stream should resync without reconnecting, got %d connections", subscriptions)
+	}
+}
+
+func TestStreamFills(t *testing.T) {
+	s := makeTestServer()
+	defer s.Close()
+
+	stream := makeTestStream(t, s)
+	defer stream.Close()
+	pushed := make(chan strongholdapi.Trade, 10)
+	stream.OnFill(func(trade strongholdapi.Trade) {
+		pushed <- trade
+	})
+	stream.SubscribeFills()
+
+	start := nowMillis()
+	if !waitFor(func() bool {
+		_, ok := stream.Fills("", nowMillis()+1)
+		return ok
+	}) {
+		t.Fatalf("Fills() should be synced once subscribed")
+	}
+
+	executedAt := time.Now().Add(10 * time.Millisecond).UTC()
+	since := executedAt.UnixNano() / int64(time.Millisecond)
+	s.AddTrade(strongholdapi.Trade{ID: "public", MarketID: "XLMUSD", Side: strongholdapi.SideBuy, Price: "0.1", Size: "1", ExecutedAt: executedAt})
+	s.AddTrade(strongholdapi.Trade{ID: "own", OrderID: "order-1", MarketID: "XLMUSD", Side: strongholdapi.SideSell, Price: "0.1", Size: "2", ExecutedAt: executedAt})
+
+	select {
+	case trade := <-pushed:
+		if trade.ID != "own" {
+			t.Errorf("OnFill() should only receive the account's fills, got %+v", trade)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("OnFill() should receive the fill")
+	}
+
+	fills, ok := stream.Fills("XLMUSD", since)
+	if !ok || len(fills) != 1 || fills[0].ID != "own" || fills[0].Size != "2" {
+		t.Errorf("Fills() returned unexpected fills (ok=%v): %+v", ok, fills)
+	}
+
+	// fills from before the subscription are not known to the cache
+	if _, ok := stream.Fills("XLMUSD", start); ok {
+		t.Errorf("Fills() should not be synced for a time before the subscription")
+	}
+}
+
+func TestStreamReconnect(t *testing.T) {
+	s := makeTestServer()
+	defer s.Close()
+
+	stream := makeTestStream(t, s)
+	defer stream.Close()
+	stream.SubscribeOrderBook("XLMUSD")
+	stream.SubscribeFills()
+	if !waitFor(bookMatches(stream, "XLMUSD", []string{}, []string{})) {
+		t.Fatalf("OrderBook() should be synced from the snapshot")
+	}
+	if !waitFor(func() bool {
+		_, ok := stream.Fills("", nowMillis()+1)
+		return ok
+	}) {
+		t.Fatalf("Fills() should be synced once subscribed")
+	}
+
+	before := nowMillis() + 1
+	s.CloseStreams()
+	s.SetOrderBook(strongholdapi.OrderBook{
+		MarketID: "XLMUSD",
+		Asks:     []strongholdapi.OrderBookItem{{Price: "0.1010", Size: "50"}},
+		Bids:     []strongholdapi.OrderBookItem{},
+	})
+	if !waitFor(bookMatches(stream, "XLMUSD", []string{"0.1010"}, []string{})) {
+		t.Fatalf("OrderBook() should be rebuilt after reconnecting")
+	}
+
+	// fills may have been missed while disconnected so the cache only covers the new subscription
+	if !waitFor(func() bool {
+		_, ok := stream.Fills("", nowMillis()+1)
+		return ok
+	}) {
+		t.Fatalf("Fills() should be synced again after reconnecting")
+	}
+	if _, ok := stream.Fills("", before); ok {
+		t.Errorf("Fills() should not be synced for a time before the reconnect")
+	}
+}
+
+func TestStreamBadCredentials(t *testing.T) {
+	s := makeTestServer()
+	defer s.Close()
+
+	api := strongholdapi.New(testKey, "d3Jvbmctc2VjcmV0")
+	api.SetBaseURL(s.URL)
+	stream, err := api.NewStream()
+	if err != nil {
+		t.Fatalf("NewStream() should not return an error, got %s", err)
+	}
+	defer stream.Close()
+	stream.SubscribeFills()
+
+	time.Sleep(100 * time.Millisecond)
+	if _, ok := stream.Fills("", nowMillis()+1); ok {
+		t.Errorf("Fills() should not be synced with bad credentials")
+	}
+}
@@ -1,94 +1,52 @@
 package strongholdapi
 
 import (
+	"bytes"
 	"crypto/hmac"
 	"crypto/sha256"
-	"crypto/sha512"
 	"encoding/base64"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io/ioutil"
-	"mime"
 	"net/http"
 	"net/url"
 	"strconv"
-	"strings"
+	"sync"
 	"time"
+
+	"github.com/pkg/errors"
 )
 
 const (
 	// APIURL is the official stronghold API Endpoint
-	APIURL = ""
+	APIURL = "https://api.stronghold.co"
 	// APIVersion is the official stronghold API Version Number
-	APIVersion = "0"
+	APIVersion = "v1"
 	// APIUserAgent identifies this library with the stronghold API
-	APIUserAgent = "stronghold GO API Agent (https://github.com/beldur/stronghold-go-api-client)"
+	APIUserAgent = "stronghold GO API Agent (https://github.com/stellar/kelp)"
 )
 
-// List of valid public methods
-var publicMethods = []string{
-	"Time",
-	"Assets",
-	"AssetPairs",
-	"Ticker",
-	"OHLC",
-	"Depth",
-	"Trades",
-	"Spread",
-}
-
-// List of valid private methods
-var privateMethods = []string{
-	"Balance",
-	"TradeBalance",
-	"OpenOrders",
-	"ClosedOrders",
-	"QueryOrders",
-	"TradesHistory",
-	"QueryTrades",
-	"OpenPositions",
-	"Ledgers",
-	"QueryLedgers",
-	"TradeVolume",
-	"AddOrder",
-	"CancelOrder",
-	"DepositMethods",
-	"DepositAddresses",
-	"DepositStatus",
-	"WithdrawInfo",
-	"Withdraw",
-	"WithdrawStatus",
-	"WithdrawCancel",
-}
-
-// These represent the minimum order sizes for the respective coins
-// Should be monitored through here: https://support.stronghold.com/hc/en-us/articles/205893708-What-is-the-minimum-order-size-
+// headers used to authenticate private requests
 const (
-	MinimumREP  = 0.3
-	MinimumXBT  = 0.002
-	MinimumBCH  = 0.002
-	MinimumDASH = 0.03
-	MinimumDOGE = 3000.0
-	MinimumEOS  = 3.0
-	MinimumETH  = 0.02
-	MinimumETC  = 0.3
-	MinimumGNO  = 0.03
-	MinimumICN  = 2.0
-	MinimumLTC  = 0.1
-	MinimumMLN  = 0.1
-	MinimumXMR  = 0.1
-	MinimumXRP  = 30.0
-	MinimumXLM  = 300.0
-	MinimumZEC  = 0.02
-	MinimumUSDT = 5.0
+	HeaderCredID   = "SH-CRED-ID"
+	HeaderCredSig  = "SH-CRED-SIG"
+	HeaderCredTime = "SH-CRED-TIME"
 )
 
+// PaymentMethodStellar is the payment method used for deposits and withdrawals over the stellar network
+const PaymentMethodStellar = "stellar"
+
 // StrongholdApi represents a stronghold API Client connection
 type StrongholdApi struct {
-	key    string
-	secret string
-	client *http.Client
+	key     string
+	secret  string
+	client  *http.Client
+	baseURL string
+
+	// venueID and accountID are resolved lazily from the API when they are not set explicitly
+	mutex     *sync.Mutex
+	venueID   string
+	accountID string
 }
 
 // New creates a new stronghold API client
@@ -96,398 +54,435 @@ func New(key, secret string) *StrongholdApi {
 	return NewWithClient(key, secret, http.DefaultClient)
 }
 
+// NewWithClient creates a new stronghold API client with custom http client
 func NewWithClient(key, secret string, httpClient *http.Client) *StrongholdApi {
-	return &StrongholdApi{key, secret, httpClient}
+	return &StrongholdApi{
+		key:     key,
+		secret:  secret,
+		client:  httpClient,
+		baseURL: APIURL,
+		mutex:   &sync.Mutex{},
+	}
+}
+
+// SetBaseURL overrides the base URL of the API, i.e. everything before /v1
+func (api *StrongholdApi) SetBaseURL(baseURL string) {
+	api.baseURL = baseURL
+}
+
+// SetVenueID pins the venue used for all venue-scoped requests
+func (api *StrongholdApi) SetVenueID(venueID string) {
+	api.mutex.Lock()
+	defer api.mutex.Unlock()
+	api.venueID = venueID
+}
+
+// SetAccountID pins the account used for all account-scoped requests
+func (api *StrongholdApi) SetAccountID(accountID string) {
+	api.mutex.Lock()
+	defer api.mutex.Unlock()
+	api.accountID = accountID
 }
 
 // Time returns the server's time
 func (api *StrongholdApi) Time() (*TimeResponse, error) {
-	resp, err := api.queryPublic("Time", nil, &TimeResponse{})
+	var resp TimeResponse
+	err := api.doRequest("GET", "/utilities/time", nil, nil, false, &resp)
 	if err != nil {
 		return nil, err
 	}
-
-	return resp.(*TimeResponse), nil
+	return &resp, nil
 }
 
-// Assets returns the servers available assets
-func (api *StrongholdApi) Assets() (*AssetsResponse, error) {
-	resp, err := api.queryPublic("Assets", nil, &AssetsResponse{})
+// Venues returns the venues hosted by stronghold
+func (api *StrongholdApi) Venues() ([]Venue, error) {
+	var resp []Venue
+	err := api.doRequest("GET", "/venues", nil, nil, false, &resp)
 	if err != nil {
 		return nil, err
 	}
-
-	return resp.(*AssetsResponse), nil
+	return resp, nil
 }
 
-// AssetPairs returns the servers available asset pairs
-func (api *StrongholdApi) AssetPairs() (*AssetPairsResponse, error) {
-	resp, err := api.queryPublic("AssetPairs", nil, &AssetPairsResponse{})
+// Assets returns the assets listed on the venue
+func (api *StrongholdApi) Assets() ([]Asset, error) {
+	venuePath, err := api.venuePath()
 	if err != nil {
 		return nil, err
 	}
 
-	return resp.(*AssetPairsResponse), nil
+	var resp []Asset
+	err = api.doRequest("GET", venuePath+"/assets", nil, nil, false, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
 }
 
-// Ticker returns the ticker for given comma separated pairs
-func (api *StrongholdApi) Ticker(pairs ...string) (*TickerResponse, error) {
-	resp, err := api.queryPublic("Ticker", url.Values{
-		"pair": {strings.Join(pairs, ",")},
-	}, &TickerResponse{})
+// Markets returns the markets listed on the venue
+func (api *StrongholdApi) Markets() ([]Market, error) {
+	venuePath, err := api.venuePath()
 	if err != nil {
 		return nil, err
 	}
 
-	return resp.(*TickerResponse), nil
+	var resp []Market
+	err = api.doRequest("GET", venuePath+"/markets", nil, nil, false, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
 }
 
-// Trades returns the recent trades for given pair
-func (api *StrongholdApi) Trades(pair string, since int64) (*TradesResponse, error) {
-	values := url.Values{"pair": {pair}}
-	if since > 0 {
-		values.Set("since", strconv.FormatInt(since, 10))
-	}
-	resp, err := api.queryPublic("Trades", values, nil)
+// OrderBook returns the order book for the market
+func (api *StrongholdApi) OrderBook(marketID string) (*OrderBook, error) {
+	venuePath, err := api.venuePath()
 	if err != nil {
 		return nil, err
 	}
 
-	v := resp.(map[string]interface{})
-
-	last, err := strconv.ParseInt(v["last"].(string), 10, 64)
+	var resp OrderBook
+	err = api.doRequest("GET", venuePath+"/markets/"+url.PathEscape(marketID)+"/orderbook", nil, nil, false, &resp)
 	if err != nil {
 		return nil, err
 	}
+	return &resp, nil
+}
 
-	result := &TradesResponse{
-		Last:   last,
-		Trades: make([]TradeInfo, 0),
+// MarketTrades returns the most recent public trades for the market, newest first
+func (api *StrongholdApi) MarketTrades(marketID string, limit int) ([]Trade, error) {
+	venuePath, err := api.venuePath()
+	if err != nil {
+		return nil, err
 	}
 
-	trades := v[pair].([]interface{})
-	for _, v := range trades {
-		trade := v.([]interface{})
-
-		priceString := trade[0].(string)
-		price, _ := strconv.ParseFloat(priceString, 64)
-
-		volumeString := trade[1].(string)
-		volume, _ := strconv.ParseFloat(trade[1].(string), 64)
-
-		tradeInfo := TradeInfo{
-			Price:         priceString,
-			PriceFloat:    price,
-			Volume:        volumeString,
-			VolumeFloat:   volume,
-			Time:          int64(trade[2].(float64)),
-			Buy:           trade[3].(string) == BUY,
-			Sell:          trade[3].(string) == SELL,
-			Market:        trade[4].(string) == MARKET,
-			Limit:         trade[4].(string) == LIMIT,
-			Miscellaneous: trade[5].(string),
-		}
-
-		result.Trades = append(result.Trades, tradeInfo)
+	query := url.Values{}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
 	}
 
-	return result, nil
-}
-
-// Balance returns all account asset balances
-func (api *StrongholdApi) Balance() (*BalanceResponse, error) {
-	resp, err := api.queryPrivate("Balance", url.Values{}, &BalanceResponse{})
+	var resp []Trade
+	err = api.doRequest("GET", venuePath+"/markets/"+url.PathEscape(marketID)+"/trades", query, nil, false, &resp)
 	if err != nil {
 		return nil, err
 	}
-
-	return resp.(*BalanceResponse), nil
+	return resp, nil
 }
 
-// OpenOrders returns all open orders
-func (api *StrongholdApi) OpenOrders(args map[string]string) (*OpenOrdersResponse, error) {
-	params := url.Values{}
-	if value, ok := args["trades"]; ok {
-		params.Add("trades", value)
-	}
-	if value, ok := args["userref"]; ok {
-		params.Add("userref", value)
+// Account returns the account along with its balances
+func (api *StrongholdApi) Account() (*Account, error) {
+	accountPath, err := api.accountPath()
+	if err != nil {
+		return nil, err
 	}
 
-	resp, err := api.queryPrivate("OpenOrders", params, &OpenOrdersResponse{})
-
+	var resp Account
+	err = api.doRequest("GET", accountPath, nil, nil, true, &resp)
 	if err != nil {
 		return nil, err
 	}
-
-	return resp.(*OpenOrdersResponse), nil
+	return &resp, nil
 }
 
-// ClosedOrders returns all closed orders
-func (api *StrongholdApi) ClosedOrders(args map[string]string) (*ClosedOrdersResponse, error) {
-	params := url.Values{}
-	if value, ok := args["trades"]; ok {
-		params.Add("trades", value)
-	}
-	if value, ok := args["userref"]; ok {
-		params.Add("userref", value)
-	}
-	if value, ok := args["start"]; ok {
-		params.Add("start", value)
-	}
-	if value, ok := args["end"]; ok {
-		params.Add("end", value)
-	}
-	if value, ok := args["ofs"]; ok {
-		params.Add("ofs", value)
-	}
-	if value, ok := args["closetime"]; ok {
-		params.Add("closetime", value)
+// Accounts returns all the accounts on the venue that are available to the credentials
+func (api *StrongholdApi) Accounts() ([]Account, error) {
+	venuePath, err := api.venuePath()
+	if err != nil {
+		return nil, err
 	}
-	resp, err := api.queryPrivate("ClosedOrders", params, &ClosedOrdersResponse{})
 
+	var resp []Account
+	err = api.doRequest("GET", venuePath+"/accounts", nil, nil, true, &resp)
 	if err != nil {
 		return nil, err
 	}
-
-	return resp.(*ClosedOrdersResponse), nil
+	return resp, nil
 }
 
-// Depth returns the order book for given pair and orders count.
-func (api *StrongholdApi) Depth(pair string, count int) (*OrderBook, error) {
-	dr := DepthResponse{}
-	_, err := api.queryPublic("Depth", url.Values{
-		"pair": {pair}, "count": {strconv.Itoa(count)},
-	}, &dr)
-
+// OpenOrders returns the account's open orders, marketID is optional
+func (api *StrongholdApi) OpenOrders(marketID string) ([]Order, error) {
+	accountPath, err := api.accountPath()
 	if err != nil {
 		return nil, err
 	}
 
-	if book, found := dr[pair]; found {
-		return &book, nil
+	query := url.Values{}
+	if marketID != "" {
+		query.Set("marketId", marketID)
 	}
 
-	return nil, errors.New("invalid response")
+	var resp []Order
+	err = api.doRequest("GET", accountPath+"/orders", query, nil, true, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
 }
 
-// CancelOrder cancels order
-func (api *StrongholdApi) CancelOrder(txid string) (*CancelOrderResponse, error) {
-	params := url.Values{}
-	params.Add("txid", txid)
-	resp, err := api.queryPrivate("CancelOrder", params, &CancelOrderResponse{})
-
+// Order returns the account's order with the ID, closed orders are returned as well
+func (api *StrongholdApi) Order(orderID string) (*Order, error) {
+	accountPath, err := api.accountPath()
 	if err != nil {
 		return nil, err
 	}
 
-	return resp.(*CancelOrderResponse), nil
+	var resp Order
+	err = api.doRequest("GET", accountPath+"/orders/"+url.PathEscape(orderID), nil, nil, true, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
 }
 
-// QueryOrders shows order
-func (api *StrongholdApi) QueryOrders(txids string, args map[string]string) (*QueryOrdersResponse, error) {
-	params := url.Values{"txid": {txids}}
-	if value, ok := args["trades"]; ok {
-		params.Add("trades", value)
-	}
-	if value, ok := args["userref"]; ok {
-		params.Add("userref", value)
+// AddOrder places a new order
+func (api *StrongholdApi) AddOrder(request OrderRequest) (*Order, error) {
+	accountPath, err := api.accountPath()
+	if err != nil {
+		return nil, err
 	}
-	resp, err := api.queryPrivate("QueryOrders", params, &QueryOrdersResponse{})
 
+	var resp Order
+	err = api.doRequest("POST", accountPath+"/orders", nil, request, true, &resp)
 	if err != nil {
 		return nil, err
 	}
-
-	return resp.(*QueryOrdersResponse), nil
+	return &resp, nil
 }
 
-// AddOrder adds new order
-func (api *StrongholdApi) AddOrder(pair string, direction string, orderType string, volume string, args map[string]string) (*AddOrderResponse, error) {
-	params := url.Values{
-		"pair":      {pair},
-		"type":      {direction},
-		"ordertype": {orderType},
-		"volume":    {volume},
+// CancelOrder cancels an open order
+func (api *StrongholdApi) CancelOrder(orderID string) error {
+	accountPath, err := api.accountPath()
+	if err != nil {
+		return err
 	}
 
-	if value, ok := args["price"]; ok {
-		params.Add("price", value)
-	}
-	if value, ok := args["price2"]; ok {
-		params.Add("price2", value)
-	}
-	if value, ok := args["leverage"]; ok {
-		params.Add("leverage", value)
+	return api.doRequest("DELETE", accountPath+"/orders/"+url.PathEscape(orderID), nil, nil, true, nil)
+}
+
+// Trades returns the account's trades matching the params sorted by execution time ascending
+func (api *StrongholdApi) Trades(params TradesParams) ([]Trade, error) {
+	accountPath, err := api.accountPath()
+	if err != nil {
+		return nil, err
 	}
-	if value, ok := args["oflags"]; ok {
-		params.Add("oflags", value)
+
+	query := url.Values{}
+	if params.MarketID != "" {
+		query.Set("marketId", params.MarketID)
 	}
-	if value, ok := args["starttm"]; ok {
-		params.Add("starttm", value)
+	if params.StartTime > 0 {
+		query.Set("startTime", strconv.FormatInt(params.StartTime, 10))
 	}
-	if value, ok := args["expiretm"]; ok {
-		params.Add("expiretm", value)
+	if params.EndTime > 0 {
+		query.Set("endTime", strconv.FormatInt(params.EndTime, 10))
 	}
-	if value, ok := args["validate"]; ok {
-		params.Add("validate", value)
+	if params.Limit > 0 {
+		query.Set("limit", strconv.Itoa(params.Limit))
 	}
-	if value, ok := args["close_order_type"]; ok {
-		params.Add("close[ordertype]", value)
+
+	var resp []Trade
+	err = api.doRequest("GET", accountPath+"/trades", query, nil, true, &resp)
+	if err != nil {
+		return nil, err
 	}
-	if value, ok := args["close_price"]; ok {
-		params.Add("close[price]", value)
+	return resp, nil
+}
+
+// DepositAddress requests the instructions for depositing the asset into the account
+func (api *StrongholdApi) DepositAddress(assetID string, paymentMethod string) (*DepositInstructions, error) {
+	accountPath, err := api.accountPath()
+	if err != nil {
+		return nil, err
 	}
-	if value, ok := args["close_price2"]; ok {
-		params.Add("close[price2]", value)
+
+	request := DepositRequest{
+		AssetID:       assetID,
+		PaymentMethod: paymentMethod,
 	}
-	if value, ok := args["trading_agreement"]; ok {
-		params.Add("trading_agreement", value)
+	var resp DepositInstructions
+	err = api.doRequest("POST", accountPath+"/deposit", nil, request, true, &resp)
+	if err != nil {
+		return nil, err
 	}
-	resp, err := api.queryPrivate("AddOrder", params, &AddOrderResponse{})
+	return &resp, nil
+}
 
+// Withdraw requests a withdrawal of the asset from the account to the address
+func (api *StrongholdApi) Withdraw(request WithdrawalRequest) (*Withdrawal, error) {
+	accountPath, err := api.accountPath()
 	if err != nil {
 		return nil, err
 	}
 
-	return resp.(*AddOrderResponse), nil
+	var resp Withdrawal
+	err = api.doRequest("POST", accountPath+"/withdrawal", nil, request, true, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
 }
 
-// Query sends a query to stronghold api for given method and parameters
-func (api *StrongholdApi) Query(method string, data map[string]string) (interface{}, error) {
-	values := url.Values{}
-	for key, value := range data {
-		values.Set(key, value)
+// venuePath returns the path prefix for venue-scoped requests, resolving the venue if needed
+func (api *StrongholdApi) venuePath() (string, error) {
+	venueID, err := api.resolveVenueID()
+	if err != nil {
+		return "", err
 	}
+	return "/venues/" + url.PathEscape(venueID), nil
+}
 
-	// Check if method is public or private
-	if isStringInSlice(method, publicMethods) {
-		return api.queryPublic(method, values, nil)
-	} else if isStringInSlice(method, privateMethods) {
-		return api.queryPrivate(method, values, nil)
+// accountPath returns the path prefix for account-scoped requests, resolving the venue and account if needed
+func (api *StrongholdApi) accountPath() (string, error) {
+	venuePath, err := api.venuePath()
+	if err != nil {
+		return "", err
 	}
 
-	return nil, fmt.Errorf("Method '%s' is not valid", method)
+	accountID, err := api.resolveAccountID()
+	if err != nil {
+		return "", err
+	}
+	return venuePath + "/accounts/" + url.PathEscape(accountID), nil
 }
 
-// Execute a public method query
-func (api *StrongholdApi) queryPublic(method string, values url.Values, typ interface{}) (interface{}, error) {
-	//url := fmt.Sprintf("%s/%s/public/%s", APIURL, APIVersion, method)
-	url := "https://orderbook-api-mm.strongholdtech.co.nz/orderbooks/latest/json/"+values.Get("pair")
-	println(url)
-	resp, err := api.doRequest(url, values, nil, typ)
-
-	return resp, err
-}
+func (api *StrongholdApi) resolveVenueID() (string, error) {
+	api.mutex.Lock()
+	venueID := api.venueID
+	api.mutex.Unlock()
+	if venueID != "" {
+		return venueID, nil
+	}
 
-// queryPrivate executes a private method query
-func (api *StrongholdApi) queryPrivate(method string, values url.Values, typ interface{}) (interface{}, error) {
-	urlPath := fmt.Sprintf("/%s/private/%s", APIVersion, method)
-	reqURL := fmt.Sprintf("%s%s", APIURL, urlPath)
-	secret, _ := base64.StdEncoding.DecodeString(api.secret)
-	values.Set("nonce", fmt.Sprintf("%d", time.Now().UnixNano()))
+	venues, err := api.Venues()
+	if err != nil {
+		return "", errors.Wrap(err, "could not resolve venue")
+	}
+	if len(venues) == 0 {
+		return "", fmt.Errorf("could not resolve venue: no venues listed")
+	}
 
-	// Create signature
-	signature := createSignature(urlPath, values, secret)
+	api.SetVenueID(venues[0].ID)
+	return venues[0].ID, nil
+}
 
-	// Add Key and signature to request headers
-	headers := map[string]string{
-		"API-Key":  api.key,
-		"API-Sign": signature,
+func (api *StrongholdApi) resolveAccountID() (string, error) {
+	api.mutex.Lock()
+	accountID := api.accountID
+	api.mutex.Unlock()
+	if accountID != "" {
+		return accountID, nil
 	}
 
-	resp, err := api.doRequest(reqURL, values, headers, typ)
+	accounts, err := api.Accounts()
+	if err != nil {
+		return "", errors.Wrap(err, "could not resolve account")
+	}
+	if len(accounts) == 0 {
+		return "", fmt.Errorf("could not resolve account: no accounts available to the credentials on the venue")
+	}
 
-	return resp, err
+	api.SetAccountID(accounts[0].ID)
+	return accounts[0].ID, nil
 }
 
-// doRequest executes a HTTP Request to the stronghold API and returns the result
-func (api *StrongholdApi) doRequest(reqURL string, values url.Values, headers map[string]string, typ interface{}) (interface{}, error) {
+// doRequest executes a HTTP Request to the stronghold API and unmarshals the result into the result parameter
+func (api *StrongholdApi) doRequest(method string, path string, query url.Values, body interface{}, private bool, result interface{}) error {
+	requestPath := "/" + APIVersion + path
+	if len(query) > 0 {
+		requestPath += "?" + query.Encode()
+	}
 
-	// Create request
-	req, err := http.NewRequest("GET", reqURL, strings.NewReader(values.Encode()))
-	if err != nil {
-		return nil, fmt.Errorf("Could not execute request! #1 (%s)", err.Error())
+	var bodyBytes []byte
+	if body != nil {
+		var err error
+		bodyBytes, err = json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("could not marshal request body for %s %s: %s", method, requestPath, err)
+		}
 	}
 
+	req, err := http.NewRequest(method, api.baseURL+requestPath, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return fmt.Errorf("could not create request for %s %s: %s", method, requestPath, err)
+	}
 	req.Header.Add("User-Agent", APIUserAgent)
-	for key, value := range headers {
-		req.Header.Add(key, value)
+	req.Header.Add("Accept", "application/json")
+	if body != nil {
+		req.Header.Add("Content-Type", "application/json")
+	}
+
+	if private {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		signature, err := createSignature(api.secret, timestamp, method, requestPath, string(bodyBytes))
+		if err != nil {
+			return fmt.Errorf("could not sign request for %s %s: %s", method, requestPath, err)
+		}
+		req.Header.Add(HeaderCredID, api.key)
+		req.Header.Add(HeaderCredSig, signature)
+		req.Header.Add(HeaderCredTime, timestamp)
 	}
 
-	// Execute request
 	resp, err := api.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("Could not execute request! #2 (%s)", err.Error())
+		return &NetworkError{Method: method, Path: requestPath, Err: err}
 	}
 	defer resp.Body.Close()
 
-	// Read request
-	body, err := ioutil.ReadAll(resp.Body)
+	respBody, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("Could not execute request! #3 (%s)", err.Error())
+		return &NetworkError{Method: method, Path: requestPath, Err: fmt.Errorf("could not read response: %s", err)}
 	}
 
-	// Check mime type of response
-	mimeType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	var shResp StrongholdResponse
+	err = json.Unmarshal(respBody, &shResp)
 	if err != nil {
-		return nil, fmt.Errorf("Could not execute request #4! (%s)", err.Error())
-	}
-	if mimeType != "application/json" {
-		return nil, fmt.Errorf("Could not execute request #5! (%s)", fmt.Sprintf("Response Content-Type is '%s', but should be 'application/json'.", mimeType))
+		return &APIError{
+			StatusCode: resp.StatusCode,
+			Message:    fmt.Sprintf("could not parse response body (%s): %s", err, string(respBody)),
+			Method:     method,
+			Path:       requestPath,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
 	}
 
-	// Parse request
-	var jsonData StrongholdResponse
-
-	// Set the strongholdResponse.Result to typ so `json.Unmarshal` will
-	// unmarshal it into given type, instead of `interface{}`.
-	if typ != nil {
-		jsonData.Result = typ
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 || !shResp.Success {
+		return &APIError{
+			StatusCode: resp.StatusCode,
+			Code:       shResp.ErrorCode,
+			Message:    shResp.ErrorMessage,
+			Method:     method,
+			Path:       requestPath,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
 	}
 
-	print(body)
-
-	err = json.Unmarshal(body, &jsonData)
-	if err != nil {
-		return nil, fmt.Errorf("Could not execute request! #6 (%s)", err.Error())
+	if result == nil || len(shResp.Result) == 0 {
+		return nil
 	}
-
-	// Check for stronghold API error
-	if len(jsonData.Error) > 0 {
-		return nil, fmt.Errorf("Could not execute request! #7 (%s)", jsonData.Error)
+	err = json.Unmarshal(shResp.Result, result)
+	if err != nil {
+		return fmt.Errorf("could not unmarshal result for %s %s: %s", method, requestPath, err)
 	}
-
-	return jsonData.Result, nil
+	return nil
 }
 
-// isStringInSlice is a helper function to test if given term is in a list of strings
-func isStringInSlice(term string, list []string) bool {
-	for _, found := range list {
-		if term == found {
-			return true
-		}
+// parseRetryAfter parses the Retry-After header when it is given in seconds, which is what stronghold sends
+func parseRetryAfter(value string) time.Duration {
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || seconds < 0 {
+		return 0
 	}
-	return false
-}
-
-// getSha256 creates a sha256 hash for given []byte
-func getSha256(input []byte) []byte {
-	sha := sha256.New()
-	sha.Write(input)
-	return sha.Sum(nil)
+	return time.Duration(seconds) * time.Second
 }
 
-// getHMacSha512 creates a hmac hash with sha512
-func getHMacSha512(message, secret []byte) []byte {
-	mac := hmac.New(sha512.New, secret)
-	mac.Write(message)
-	return mac.Sum(nil)
-}
+// createSignature creates a stronghold request signature: base64(HMAC-SHA256(base64decode(secret), timestamp + method + path + body))
+func createSignature(secret string, timestamp string, method string, requestPath string, body string) (string, error) {
+	decodedSecret, err := base64.StdEncoding.DecodeString(secret)
+	if err != nil {
+		return "", fmt.Errorf("secret is not base64 encoded: %s", err)
+	}
 
-func createSignature(urlPath string, values url.Values, secret []byte) string {
-	// See https://www.stronghold.com/help/api#general-usage for more information
-	shaSum := getSha256([]byte(values.Get("nonce") + values.Encode()))
-	macSum := getHMacSha512(append([]byte(urlPath), shaSum...), secret)
-	return base64.StdEncoding.EncodeToString(macSum)
+	mac := hmac.New(sha256.New, decodedSecret)
+	mac.Write([]byte(timestamp + method + requestPath + body))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil)), nil
 }
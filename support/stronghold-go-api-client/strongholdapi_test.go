@@ -1,33 +1,50 @@
-package strongholdapi
+package strongholdapi_test
 
 import (
-	"encoding/base64"
-	"net/url"
-	"reflect"
+	"net/http"
 	"testing"
+	"time"
+
+	strongholdapi "github.com/stellar/kelp/support/stronghold-go-api-client"
+	"github.com/stellar/kelp/support/stronghold-go-api-client/strongholdtest"
 )
 
-var publicAPI = New("", "")
+const testKey = "test-key"
 
-func TestCreateSignature(t *testing.T) {
-	expectedSig := "Uog0MyIKZmXZ4/VFOh0g1u2U+A0ohuK8oCh0HFUiHLE2Csm23CuPCDaPquh/hpnAg/pSQLeXyBELpJejgOftCQ=="
-	urlPath := "/0/private/"
-	secret, _ := base64.StdEncoding.DecodeString("SECRET")
-	values := url.Values{
-		"TestKey": {"TestValue"},
-	}
+// base64 encoded secret
+const testSecret = "c2VjcmV0LWZvci10ZXN0aW5nLXN0cm9uZ2hvbGQtY2xpZW50"
 
-	sig := createSignature(urlPath, values, secret)
+func makeTestServer() *strongholdtest.Server {
+	s := strongholdtest.NewServer(testKey, testSecret)
+	s.AddAsset(strongholdapi.Asset{ID: "XLM", Precision: 7, WithdrawalFee: "0.01", MinimumWithdrawal: "1", MaximumWithdrawal: "100000"})
+	s.AddAsset(strongholdapi.Asset{ID: "USD", Precision: 2, WithdrawalFee: "1", MinimumWithdrawal: "10", MaximumWithdrawal: "10000"})
+	s.AddMarket(strongholdapi.Market{
+		ID:                    "XLMUSD",
+		BaseAssetID:           "XLM",
+		CounterAssetID:        "USD",
+		MinimumPriceIncrement: "0.00001",
+		MinimumSizeIncrement:  "0.1",
+		MinimumSize:           "1",
+		MinimumValue:          "0.1",
+	})
+	s.SetBalance("XLM", 1000)
+	s.SetBalance("USD", 100)
+	return s
+}
 
-	if sig != expectedSig {
-		t.Errorf("Expected Signature to be %s, got: %s\n", expectedSig, sig)
-	}
+func makeTestAPI(s *strongholdtest.Server) *strongholdapi.StrongholdApi {
+	api := strongholdapi.New(testKey, testSecret)
+	api.SetBaseURL(s.URL)
+	return api
 }
 
 func TestTime(t *testing.T) {
-	resp, err := publicAPI.Time()
+	s := makeTestServer()
+	defer s.Close()
+
+	resp, err := makeTestAPI(s).Time()
 	if err != nil {
-		t.Errorf("Time() should not return an error, got %s", err)
+		t.Fatalf("Time() should not return an error, got %s", err)
 	}
 
 	if resp.Unixtime <= 0 {
@@ -35,104 +52,330 @@ func TestTime(t *testing.T) {
 	}
 }
 
-func TestAssets(t *testing.T) {
-	_, err := publicAPI.Assets()
+func TestResolveVenueAndAccount(t *testing.T) {
+	s := makeTestServer()
+	defer s.Close()
+
+	api := makeTestAPI(s)
+	account, err := api.Account()
+	if err != nil {
+		t.Fatalf("Account() should not return an error, got %s", err)
+	}
+	if account.ID != strongholdtest.AccountID || account.VenueID != strongholdtest.VenueID {
+		t.Errorf("Account() should resolve the venue and account, got %+v", account)
+	}
+
+	// the resolved ids are cached so only the account is requested the second time
+	_, err = api.Account()
 	if err != nil {
-		t.Errorf("Assets() should not return an error, got %s", err)
+		t.Fatalf("Account() should not return an error, got %s", err)
+	}
+	reqs := s.Requests()
+	if len(reqs) != 4 || reqs[3] != "GET /v1/venues/test-venue/accounts/test-account" {
+		t.Errorf("unexpected requests: %v", reqs)
 	}
 }
 
-func TestAssetPairs(t *testing.T) {
-	resp, err := publicAPI.AssetPairs()
+func TestBadCredentials(t *testing.T) {
+	s := makeTestServer()
+	defer s.Close()
+
+	api := strongholdapi.New(testKey, "d3Jvbmctc2VjcmV0")
+	api.SetBaseURL(s.URL)
+	api.SetVenueID(strongholdtest.VenueID)
+	api.SetAccountID(strongholdtest.AccountID)
+
+	_, err := api.Account()
+	apiErr, ok := err.(*strongholdapi.APIError)
+	if !ok {
+		t.Fatalf("Account() should return an *APIError, got %v", err)
+	}
+	if apiErr.StatusCode != http.StatusUnauthorized || apiErr.Code != strongholdtest.ErrorCodeUnauthorized {
+		t.Errorf("Account() should be unauthorized, got %+v", apiErr)
+	}
+}
+
+func TestMarketsAndAssets(t *testing.T) {
+	s := makeTestServer()
+	defer s.Close()
+
+	api := makeTestAPI(s)
+	markets, err := api.Markets()
 	if err != nil {
-		t.Errorf("AssetPairs() should not return an error, got %s", err)
+		t.Fatalf("Markets() should not return an error, got %s", err)
+	}
+	if len(markets) != 1 || markets[0].ID != "XLMUSD" || markets[0].MinimumPriceIncrement != "0.00001" {
+		t.Errorf("Markets() returned unexpected markets: %+v", markets)
 	}
 
-	if resp.XXBTZEUR.Base+resp.XXBTZEUR.Quote != XXBTZEUR {
-		t.Errorf("AssetPairs() should return valid response, got %+v", resp.XXBTZEUR)
+	assets, err := api.Assets()
+	if err != nil {
+		t.Fatalf("Assets() should not return an error, got %s", err)
+	}
+	if len(assets) != 2 || assets[0].ID != "XLM" || assets[1].WithdrawalFee != "1" {
+		t.Errorf("Assets() returned unexpected assets: %+v", assets)
 	}
 }
 
-func TestTicker(t *testing.T) {
-	resp, err := publicAPI.Ticker(XXBTZEUR, XXRPZEUR)
+func TestOrderBook(t *testing.T) {
+	s := makeTestServer()
+	defer s.Close()
+	s.SetOrderBook(strongholdapi.OrderBook{
+		MarketID: "XLMUSD",
+		Asks:     []strongholdapi.OrderBookItem{{Price: "0.1010", Size: "50"}, {Price: "0.1020", Size: "75"}},
+		Bids:     []strongholdapi.OrderBookItem{{Price: "0.0990", Size: "20"}},
+	})
+
+	book, err := makeTestAPI(s).OrderBook("XLMUSD")
 	if err != nil {
-		t.Errorf("Ticker() should not return an error, got %s", err)
+		t.Fatalf("OrderBook() should not return an error, got %s", err)
+	}
+	if len(book.Asks) != 2 || book.Asks[1].Price != "0.1020" || book.Asks[1].Size != "75" {
+		t.Errorf("OrderBook() returned unexpected asks: %+v", book.Asks)
+	}
+	if len(book.Bids) != 1 || book.Bids[0].Price != "0.0990" {
+		t.Errorf("OrderBook() returned unexpected bids: %+v", book.Bids)
 	}
 
-	if resp.XXBTZEUR.OpeningPrice == 0 {
-		t.Errorf("Ticker() should return valid OpeningPrice, got %+v", resp.XXBTZEUR.OpeningPrice)
+	_, err = makeTestAPI(s).OrderBook("BTCUSD")
+	if apiErr, ok := err.(*strongholdapi.APIError); !ok || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("OrderBook() should return a not found error for unknown markets, got %v", err)
 	}
 }
 
-func TestQueryTime(t *testing.T) {
-	result, err := publicAPI.Query("Time", map[string]string{})
-	resultKind := reflect.TypeOf(result).Kind()
+func TestOrderLifecycle(t *testing.T) {
+	s := makeTestServer()
+	defer s.Close()
 
+	api := makeTestAPI(s)
+	order, err := api.AddOrder(strongholdapi.OrderRequest{
+		MarketID: "XLMUSD",
+		Type:     strongholdapi.TypeLimit,
+		Side:     strongholdapi.SideSell,
+		Price:    "0.12",
+		Size:     "400",
+	})
 	if err != nil {
-		t.Errorf("Query should not return an error, got %s", err)
+		t.Fatalf("AddOrder() should not return an error, got %s", err)
+	}
+	if order.ID == "" || order.Status != strongholdapi.StatusOpen {
+		t.Errorf("AddOrder() returned unexpected order: %+v", order)
 	}
-	if resultKind != reflect.Map {
-		t.Errorf("Query `Time` should return a Map, got: %s", resultKind)
+
+	account, err := api.Account()
+	if err != nil {
+		t.Fatalf("Account() should not return an error, got %s", err)
+	}
+	if account.Balances[1].AssetID != "XLM" || account.Balances[1].Amount != "1000" || account.Balances[1].Available != "600" {
+		t.Errorf("Account() should hold the sold amount, got %+v", account.Balances)
+	}
+
+	orders, err := api.OpenOrders("XLMUSD")
+	if err != nil {
+		t.Fatalf("OpenOrders() should not return an error, got %s", err)
+	}
+	if len(orders) != 1 || orders[0].ID != order.ID {
+		t.Errorf("OpenOrders() returned unexpected orders: %+v", orders)
+	}
+
+	err = api.CancelOrder(order.ID)
+	if err != nil {
+		t.Fatalf("CancelOrder() should not return an error, got %s", err)
+	}
+	orders, err = api.OpenOrders("")
+	if err != nil {
+		t.Fatalf("OpenOrders() should not return an error, got %s", err)
+	}
+	if len(orders) != 0 {
+		t.Errorf("OpenOrders() should be empty after cancelling, got %+v", orders)
+	}
+
+	err = api.CancelOrder(order.ID)
+	apiErr, ok := err.(*strongholdapi.APIError)
+	if !ok || apiErr.Code != strongholdtest.ErrorCodeOrderNotFound {
+		t.Errorf("CancelOrder() should fail for a cancelled order, got %v", err)
 	}
 }
 
-func TestQueryTicker(t *testing.T) {
-	result, err := publicAPI.Query("Ticker", map[string]string{
-		"pair": "XXBTZEUR",
+func TestAddOrderInsufficientFunds(t *testing.T) {
+	s := makeTestServer()
+	defer s.Close()
+
+	_, err := makeTestAPI(s).AddOrder(strongholdapi.OrderRequest{
+		MarketID: "XLMUSD",
+		Type:     strongholdapi.TypeLimit,
+		Side:     strongholdapi.SideBuy,
+		Price:    "0.1",
+		Size:     "1001",
 	})
-	resultKind := reflect.TypeOf(result).Kind()
+	apiErr, ok := err.(*strongholdapi.APIError)
+	if !ok || apiErr.Code != strongholdtest.ErrorCodeInsufficientFunds {
+		t.Errorf("AddOrder() should fail with insufficient funds, got %v", err)
+	}
+}
 
+func TestOrderStatus(t *testing.T) {
+	s := makeTestServer()
+	defer s.Close()
+
+	api := makeTestAPI(s)
+	order, err := api.AddOrder(strongholdapi.OrderRequest{
+		MarketID: "XLMUSD",
+		Type:     strongholdapi.TypeLimit,
+		Side:     strongholdapi.SideSell,
+		Price:    "0.1",
+		Size:     "100",
+	})
 	if err != nil {
-		t.Errorf("Query should not return an error, got %s", err)
+		t.Fatalf("AddOrder() should not return an error, got %s", err)
+	}
+
+	err = s.FillOrder(order.ID, 40)
+	if err != nil {
+		t.Fatalf("FillOrder() should not return an error, got %s", err)
+	}
+	got, err := api.Order(order.ID)
+	if err != nil {
+		t.Fatalf("Order() should not return an error, got %s", err)
+	}
+	if got.Status != strongholdapi.StatusOpen || got.SizeFilled != "40" {
+		t.Errorf("Order() should return the partially filled order, got %+v", got)
+	}
+
+	err = s.FillOrder(order.ID, 60)
+	if err != nil {
+		t.Fatalf("FillOrder() should not return an error, got %s", err)
+	}
+	got, err = api.Order(order.ID)
+	if err != nil {
+		t.Fatalf("Order() should not return an error, got %s", err)
+	}
+	if got.Status != strongholdapi.StatusFilled || got.SizeFilled != "100" {
+		t.Errorf("Order() should return the filled order, got %+v", got)
+	}
+
+	account, err := api.Account()
+	if err != nil {
+		t.Fatalf("Account() should not return an error, got %s", err)
+	}
+	if account.Balances[0].AssetID != "USD" || account.Balances[0].Amount != "110" || account.Balances[1].Amount != "900" || account.Balances[1].Available != "900" {
+		t.Errorf("Account() should settle the filled order, got %+v", account.Balances)
 	}
 
-	if resultKind != reflect.Map {
-		t.Errorf("Query `Ticker` should return a Map, got: %s", resultKind)
+	_, err = api.Order("unknown")
+	apiErr, ok := err.(*strongholdapi.APIError)
+	if !ok || apiErr.Code != strongholdtest.ErrorCodeOrderNotFound {
+		t.Errorf("Order() should fail for an unknown order, got %v", err)
 	}
 }
 
-func TestQueryTrades(t *testing.T) {
-	result, err := publicAPI.Trades(XXBTZEUR, 1495777604391411290)
+func TestTrades(t *testing.T) {
+	s := makeTestServer()
+	defer s.Close()
 
+	base := time.Unix(1555000000, 0).UTC()
+	for i, id := range []string{"t1", "t2", "t3"} {
+		s.AddTrade(strongholdapi.Trade{
+			ID:         id,
+			OrderID:    "order-1",
+			MarketID:   "XLMUSD",
+			Side:       strongholdapi.SideBuy,
+			Price:      "0.1",
+			Size:       "10",
+			Fee:        "0.001",
+			FeeAssetID: "USD",
+			ExecutedAt: base.Add(time.Duration(i) * time.Second),
+		})
+	}
+
+	api := makeTestAPI(s)
+	trades, err := api.Trades(strongholdapi.TradesParams{
+		MarketID:  "XLMUSD",
+		StartTime: base.Add(time.Second).UnixNano() / int64(time.Millisecond),
+	})
 	if err != nil {
-		t.Errorf("Trades should not return an error, got %s", err)
+		t.Fatalf("Trades() should not return an error, got %s", err)
+	}
+	if len(trades) != 2 || trades[0].ID != "t2" || trades[1].ID != "t3" || trades[0].Fee != "0.001" {
+		t.Errorf("Trades() returned unexpected trades: %+v", trades)
 	}
 
-	if result.Last == 0 {
-		t.Errorf("Returned parameter `last` should always have a value...")
+	trades, err = api.Trades(strongholdapi.TradesParams{Limit: 1})
+	if err != nil {
+		t.Fatalf("Trades() should not return an error, got %s", err)
+	}
+	if len(trades) != 1 || trades[0].ID != "t1" {
+		t.Errorf("Trades() should respect the limit, got %+v", trades)
 	}
 
-	if len(result.Trades) > 0 {
-		for _, trade := range result.Trades {
-			if trade.Buy == trade.Sell {
-				t.Errorf("Trade should be buy or sell")
-			}
-			if trade.Market == trade.Limit {
-				t.Errorf("Trade type should be market or limit")
-			}
-		}
+	trades, err = api.MarketTrades("XLMUSD", 2)
+	if err != nil {
+		t.Fatalf("MarketTrades() should not return an error, got %s", err)
+	}
+	if len(trades) != 2 || trades[0].ID != "t3" || trades[0].OrderID != "" {
+		t.Errorf("MarketTrades() returned unexpected trades: %+v", trades)
 	}
 }
 
-func TestQueryDepth(t *testing.T) {
-	pair := "XETHZEUR"
-	count := 10
-	result, err := publicAPI.Depth(pair, count)
+func TestDepositAndWithdraw(t *testing.T) {
+	s := makeTestServer()
+	defer s.Close()
+
+	api := makeTestAPI(s)
+	instructions, err := api.DepositAddress("XLM", strongholdapi.PaymentMethodStellar)
 	if err != nil {
-		t.Errorf("Depth should not return an error, got %s", err)
+		t.Fatalf("DepositAddress() should not return an error, got %s", err)
+	}
+	if instructions.Address != strongholdtest.DepositAddress || instructions.Memo != strongholdtest.AccountID {
+		t.Errorf("DepositAddress() returned unexpected instructions: %+v", instructions)
 	}
 
-	resultType := reflect.TypeOf(result)
+	withdrawal, err := api.Withdraw(strongholdapi.WithdrawalRequest{
+		AssetID:       "XLM",
+		Amount:        "100",
+		PaymentMethod: strongholdapi.PaymentMethodStellar,
+		Address:       "GBQ5HSO4C3BTAPHXMBRRHDN3WHJKH7GC6VPOQ5DQ3BFFN7G3LKYAK6AK",
+	})
+	if err != nil {
+		t.Fatalf("Withdraw() should not return an error, got %s", err)
+	}
+	if withdrawal.Amount != "100" || withdrawal.Fee != "0.01" || len(s.Withdrawals()) != 1 {
+		t.Errorf("Withdraw() returned unexpected withdrawal: %+v", withdrawal)
+	}
 
-	if resultType != reflect.TypeOf(&OrderBook{}) {
-		t.Errorf("Depth should return an OrderBook, got %s", resultType)
+	account, err := api.Account()
+	if err != nil {
+		t.Fatalf("Account() should not return an error, got %s", err)
+	}
+	if account.Balances[1].Amount != "900" {
+		t.Errorf("Withdraw() should debit the balance, got %+v", account.Balances)
 	}
+}
+
+func TestInjectedError(t *testing.T) {
+	s := makeTestServer()
+	defer s.Close()
+	s.FailNext(http.StatusTooManyRequests, strongholdtest.ErrorCodeRateLimited, "slow down")
 
-	if len(result.Asks) > count {
-		t.Errorf("Asks length must be less than count , got %d > %d", len(result.Asks), count)
+	_, err := makeTestAPI(s).Time()
+	apiErr, ok := err.(*strongholdapi.APIError)
+	if !ok || apiErr.StatusCode != http.StatusTooManyRequests || apiErr.Message != "slow down" {
+		t.Errorf("Time() should surface the server error, got %v", err)
+	}
+	if ok && apiErr.RetryAfter != time.Second {
+		t.Errorf("Time() should surface the Retry-After header, got %s", apiErr.RetryAfter)
 	}
+}
+
+func TestNetworkError(t *testing.T) {
+	s := makeTestServer()
+	url := s.URL
+	s.Close()
 
-	if len(result.Bids) > count {
-		t.Errorf("Bids length must be less than count , got %d > %d", len(result.Bids), count)
+	api := strongholdapi.New(testKey, testSecret)
+	api.SetBaseURL(url)
+	_, err := api.Time()
+	if _, ok := err.(*strongholdapi.NetworkError); !ok {
+		t.Errorf("Time() should return a *NetworkError when the server is down, got %v", err)
 	}
 }
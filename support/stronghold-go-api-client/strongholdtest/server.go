@@ -0,0 +1,772 @@
+// Package strongholdtest provides an in-memory fake of the stronghold venue API so the client and the exchange
+// adapter can be tested without network access.
+package strongholdtest
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	strongholdapi "github.com/stellar/kelp/support/stronghold-go-api-client"
+)
+
+// identifiers of the single venue and account served by the fake
+const (
+	VenueID   = "test-venue"
+	AccountID = "test-account"
+)
+
+// DepositAddress is the address handed out for all deposits
+const DepositAddress = "GDUKMGUGDZQK6YHYA5Z6AY2G4XDSZPSZ3SW5UN3ARVMO6QSRDWP5YLEX"
+
+// error codes returned by the fake
+const (
+	ErrorCodeRateLimited       = strongholdapi.ErrorCodeRateLimited
+	ErrorCodeUnauthorized      = strongholdapi.ErrorCodeUnauthorized
+	ErrorCodeNotFound          = "NOT_FOUND"
+	ErrorCodeBadRequest        = "BAD_REQUEST"
+	ErrorCodeInsufficientFunds = strongholdapi.ErrorCodeInsufficientFunds
+	ErrorCodeOrderNotFound     = strongholdapi.ErrorCodeOrderNotFound
+	ErrorCodePostOnlyRejected  = strongholdapi.ErrorCodePostOnlyRejected
+)
+
+type balance struct {
+	amount float64
+	hold   float64
+}
+
+type injectedError struct {
+	statusCode int
+	code       string
+	message    string
+}
+
+// Server is a fake stronghold API server backed by httptest.Server
+type Server struct {
+	*httptest.Server
+
+	key    string
+	secret []byte
+
+	mutex         *sync.Mutex
+	assets        []strongholdapi.Asset
+	markets       map[string]strongholdapi.Market
+	books         map[string]strongholdapi.OrderBook
+	bookSequences map[string]int64
+	balances      map[string]*balance
+	orders        map[string]*strongholdapi.Order
+	trades        []strongholdapi.Trade
+	withdrawals   []strongholdapi.Withdrawal
+	nextID        int
+	nextErrors    []injectedError
+	requests      []string
+	streamConns   map[*streamConn]bool
+}
+
+// NewServer starts a fake server that accepts the given credentials, secret must be base64 encoded
+func NewServer(key string, secret string) *Server {
+	decodedSecret, e := base64.StdEncoding.DecodeString(secret)
+	if e != nil {
+		panic(fmt.Sprintf("secret should be base64 encoded: %s", e))
+	}
+
+	s := &Server{
+		key:           key,
+		secret:        decodedSecret,
+		mutex:         &sync.Mutex{},
+		assets:        []strongholdapi.Asset{},
+		markets:       map[string]strongholdapi.Market{},
+		books:         map[string]strongholdapi.OrderBook{},
+		bookSequences: map[string]int64{},
+		balances:      map[string]*balance{},
+		orders:        map[string]*strongholdapi.Order{},
+		trades:        []strongholdapi.Trade{},
+		streamConns:   map[*streamConn]bool{},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// AddAsset lists an asset on the venue
+func (s *Server) AddAsset(asset strongholdapi.Asset) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.assets = append(s.assets, asset)
+}
+
+// AddMarket lists a market on the venue with an empty order book
+func (s *Server) AddMarket(market strongholdapi.Market) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.markets[market.ID] = market
+	s.books[market.ID] = strongholdapi.OrderBook{
+		MarketID: market.ID,
+		Asks:     []strongholdapi.OrderBookItem{},
+		Bids:     []strongholdapi.OrderBookItem{},
+	}
+}
+
+// SetOrderBook replaces the order book served for the market, stream subscribers receive it as a new snapshot
+func (s *Server) SetOrderBook(book strongholdapi.OrderBook) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.books[book.MarketID] = book
+	s.bookSequences[book.MarketID]++
+	s.pushSnapshot(book.MarketID)
+}
+
+// SetBalance sets the total balance of the asset in the account, nothing is on hold
+func (s *Server) SetBalance(assetID string, amount float64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.balances[assetID] = &balance{amount: amount}
+}
+
+// AddTrade records a trade for the account, the trade is also visible on the public trades endpoint of its market.
+// Trades with an OrderID belong to the account and are pushed to the subscribers of the fills stream.
+func (s *Server) AddTrade(trade strongholdapi.Trade) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.trades = append(s.trades, trade)
+	if trade.OrderID != "" {
+		s.pushFill(trade)
+	}
+}
+
+// FillOrder fills size of the open order and settles the balances, the order is filled once its full size is filled
+func (s *Server) FillOrder(orderID string, size float64) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	o, ok := s.orders[orderID]
+	if !ok || o.Status != strongholdapi.StatusOpen {
+		return fmt.Errorf("no open order with id %s", orderID)
+	}
+	price, _ := strconv.ParseFloat(o.Price, 64)
+	total, _ := strconv.ParseFloat(o.Size, 64)
+	filled, _ := strconv.ParseFloat(o.SizeFilled, 64)
+	if filled+size > total {
+		return fmt.Errorf("cannot fill %s of order %s, only %s remains", formatFloat(size), orderID, formatFloat(total-filled))
+	}
+
+	market := s.markets[o.MarketID]
+	if o.Side == strongholdapi.SideBuy {
+		s.settle(market.CounterAssetID, -price*size, -price*size)
+		s.settle(market.BaseAssetID, size, 0)
+	} else {
+		s.settle(market.BaseAssetID, -size, -size)
+		s.settle(market.CounterAssetID, price*size, 0)
+	}
+
+	o.SizeFilled = formatFloat(filled + size)
+	if filled+size == total {
+		o.Status = strongholdapi.StatusFilled
+	}
+	return nil
+}
+
+// settle changes the balance and hold of the asset, must be called with the mutex held
+func (s *Server) settle(assetID string, amount float64, hold float64) {
+	b, ok := s.balances[assetID]
+	if !ok {
+		b = &balance{}
+		s.balances[assetID] = b
+	}
+	b.amount += amount
+	b.hold += hold
+}
+
+// Orders returns a copy of all the orders placed on the server, including closed ones
+func (s *Server) Orders() []strongholdapi.Order {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	orders := []strongholdapi.Order{}
+	for _, o := range s.orders {
+		orders = append(orders, *o)
+	}
+	sort.Slice(orders, func(i int, j int) bool {
+		return orders[i].ID < orders[j].ID
+	})
+	return orders
+}
+
+// Withdrawals returns a copy of all the withdrawals requested from the server
+func (s *Server) Withdrawals() []strongholdapi.Withdrawal {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]strongholdapi.Withdrawal{}, s.withdrawals...)
+}
+
+// Requests returns the "METHOD path" of every request received, in order
+func (s *Server) Requests() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]string{}, s.requests...)
+}
+
+// FailNext makes the next request fail with the given status code and error, calls are queued
+func (s *Server) FailNext(statusCode int, code string, message string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.nextErrors = append(s.nextErrors, injectedError{
+		statusCode: statusCode,
+		code:       code,
+		message:    message,
+	})
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "GET" && r.URL.Path == StreamPath {
+		// the stream is long-lived so it cannot hold the mutex for the duration of the request
+		s.serveStream(w, r)
+		return
+	}
+
+	body, e := ioutil.ReadAll(r.Body)
+	if e != nil {
+		writeError(w, http.StatusBadRequest, ErrorCodeBadRequest, fmt.Sprintf("could not read body: %s", e))
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+
+	if len(s.nextErrors) > 0 {
+		ie := s.nextErrors[0]
+		s.nextErrors = s.nextErrors[1:]
+		writeError(w, ie.statusCode, ie.code, ie.message)
+		return
+	}
+
+	segments := []string{}
+	for _, seg := range strings.Split(strings.Trim(r.URL.EscapedPath(), "/"), "/") {
+		unescaped, e := url.PathUnescape(seg)
+		if e != nil {
+			writeError(w, http.StatusBadRequest, ErrorCodeBadRequest, fmt.Sprintf("invalid path segment: %s", seg))
+			return
+		}
+		segments = append(segments, unescaped)
+	}
+	if len(segments) < 2 || segments[0] != strongholdapi.APIVersion {
+		writeError(w, http.StatusNotFound, ErrorCodeNotFound, "unknown path: "+r.URL.Path)
+		return
+	}
+	segments = segments[1:]
+
+	if r.Method == "GET" && match(segments, "utilities", "time") {
+		now := time.Now()
+		writeResult(w, strongholdapi.TimeResponse{
+			Unixtime: now.Unix(),
+			Rfc1123:  now.UTC().Format(time.RFC1123),
+		})
+		return
+	}
+
+	if segments[0] != "venues" {
+		writeError(w, http.StatusNotFound, ErrorCodeNotFound, "unknown path: "+r.URL.Path)
+		return
+	}
+	if len(segments) == 1 {
+		writeResult(w, []strongholdapi.Venue{{ID: VenueID, Name: "Test Venue"}})
+		return
+	}
+	if segments[1] != VenueID {
+		writeError(w, http.StatusNotFound, ErrorCodeNotFound, "unknown venue: "+segments[1])
+		return
+	}
+	segments = segments[2:]
+
+	switch {
+	case r.Method == "GET" && match(segments, "assets"):
+		writeResult(w, s.assets)
+	case r.Method == "GET" && match(segments, "markets"):
+		s.handleMarkets(w)
+	case r.Method == "GET" && match(segments, "markets", "*", "orderbook"):
+		s.handleOrderBook(w, segments[1])
+	case r.Method == "GET" && match(segments, "markets", "*", "trades"):
+		s.handleMarketTrades(w, r, segments[1])
+	case len(segments) >= 1 && segments[0] == "accounts":
+		if !s.authenticate(w, r, body) {
+			return
+		}
+		s.handleAccounts(w, r, segments[1:], body)
+	default:
+		writeError(w, http.StatusNotFound, ErrorCodeNotFound, "unknown path: "+r.URL.Path)
+	}
+}
+
+func (s *Server) handleAccounts(w http.ResponseWriter, r *http.Request, segments []string, body []byte) {
+	if r.Method == "GET" && len(segments) == 0 {
+		writeResult(w, []strongholdapi.Account{s.account()})
+		return
+	}
+	if segments[0] != AccountID {
+		writeError(w, http.StatusNotFound, ErrorCodeNotFound, "unknown account: "+segments[0])
+		return
+	}
+	segments = segments[1:]
+
+	switch {
+	case r.Method == "GET" && len(segments) == 0:
+		writeResult(w, s.account())
+	case r.Method == "GET" && match(segments, "orders"):
+		s.handleOpenOrders(w, r)
+	case r.Method == "POST" && match(segments, "orders"):
+		s.handleAddOrder(w, body)
+	case r.Method == "GET" && match(segments, "orders", "*"):
+		s.handleGetOrder(w, segments[1])
+	case r.Method == "DELETE" && match(segments, "orders", "*"):
+		s.handleCancelOrder(w, segments[1])
+	case r.Method == "GET" && match(segments, "trades"):
+		s.handleTrades(w, r)
+	case r.Method == "POST" && match(segments, "deposit"):
+		s.handleDeposit(w, body)
+	case r.Method == "POST" && match(segments, "withdrawal"):
+		s.handleWithdrawal(w, body)
+	default:
+		writeError(w, http.StatusNotFound, ErrorCodeNotFound, "unknown path: "+r.URL.Path)
+	}
+}
+
+func (s *Server) handleMarkets(w http.ResponseWriter) {
+	markets := []strongholdapi.Market{}
+	for _, m := range s.markets {
+		markets = append(markets, m)
+	}
+	sort.Slice(markets, func(i int, j int) bool {
+		return markets[i].ID < markets[j].ID
+	})
+	writeResult(w, markets)
+}
+
+func (s *Server) handleOrderBook(w http.ResponseWriter, marketID string) {
+	book, ok := s.books[marketID]
+	if !ok {
+		writeError(w, http.StatusNotFound, ErrorCodeNotFound, "unknown market: "+marketID)
+		return
+	}
+	writeResult(w, book)
+}
+
+func (s *Server) handleMarketTrades(w http.ResponseWriter, r *http.Request, marketID string) {
+	if _, ok := s.markets[marketID]; !ok {
+		writeError(w, http.StatusNotFound, ErrorCodeNotFound, "unknown market: "+marketID)
+		return
+	}
+
+	trades := []strongholdapi.Trade{}
+	for i := len(s.trades) - 1; i >= 0; i-- {
+		t := s.trades[i]
+		if t.MarketID != marketID {
+			continue
+		}
+		// public trades do not expose account specific fields
+		t.OrderID = ""
+		t.Fee = ""
+		t.FeeAssetID = ""
+		trades = append(trades, t)
+	}
+	sort.SliceStable(trades, func(i int, j int) bool {
+		return trades[i].ExecutedAt.After(trades[j].ExecutedAt)
+	})
+
+	limit, e := intParam(r, "limit")
+	if e != nil {
+		writeError(w, http.StatusBadRequest, ErrorCodeBadRequest, e.Error())
+		return
+	}
+	if limit > 0 && len(trades) > limit {
+		trades = trades[:limit]
+	}
+	writeResult(w, trades)
+}
+
+func (s *Server) handleOpenOrders(w http.ResponseWriter, r *http.Request) {
+	marketID := r.URL.Query().Get("marketId")
+	orders := []strongholdapi.Order{}
+	for _, o := range s.orders {
+		if o.Status != strongholdapi.StatusOpen {
+			continue
+		}
+		if marketID != "" && o.MarketID != marketID {
+			continue
+		}
+		orders = append(orders, *o)
+	}
+	sort.Slice(orders, func(i int, j int) bool {
+		return orders[i].ID < orders[j].ID
+	})
+	writeResult(w, orders)
+}
+
+func (s *Server) handleAddOrder(w http.ResponseWriter, body []byte) {
+	var req strongholdapi.OrderRequest
+	e := json.Unmarshal(body, &req)
+	if e != nil {
+		writeError(w, http.StatusBadRequest, ErrorCodeBadRequest, fmt.Sprintf("invalid order request: %s", e))
+		return
+	}
+
+	market, ok := s.markets[req.MarketID]
+	if !ok {
+		writeError(w, http.StatusBadRequest, ErrorCodeBadRequest, "unknown market: "+req.MarketID)
+		return
+	}
+	if req.Type != strongholdapi.TypeLimit {
+		writeError(w, http.StatusBadRequest, ErrorCodeBadRequest, "unsupported order type: "+req.Type)
+		return
+	}
+	price, e := strconv.ParseFloat(req.Price, 64)
+	if e != nil || price <= 0 {
+		writeError(w, http.StatusBadRequest, ErrorCodeBadRequest, "invalid price: "+req.Price)
+		return
+	}
+	size, e := strconv.ParseFloat(req.Size, 64)
+	if e != nil || size <= 0 {
+		writeError(w, http.StatusBadRequest, ErrorCodeBadRequest, "invalid size: "+req.Size)
+		return
+	}
+
+	holdAsset, holdAmount := "", 0.0
+	switch req.Side {
+	case strongholdapi.SideBuy:
+		holdAsset, holdAmount = market.CounterAssetID, price*size
+	case strongholdapi.SideSell:
+		holdAsset, holdAmount = market.BaseAssetID, size
+	default:
+		writeError(w, http.StatusBadRequest, ErrorCodeBadRequest, "invalid side: "+req.Side)
+		return
+	}
+	switch req.TimeInForce {
+	case "", strongholdapi.TimeInForceGTC, strongholdapi.TimeInForceIOC, strongholdapi.TimeInForceFOK:
+	default:
+		writeError(w, http.StatusBadRequest, ErrorCodeBadRequest, "invalid time in force: "+req.TimeInForce)
+		return
+	}
+	if req.PostOnly && s.crossesBook(req.MarketID, req.Side, price) {
+		writeError(w, http.StatusBadRequest, ErrorCodePostOnlyRejected, "post-only order would take liquidity")
+		return
+	}
+	b := s.balances[holdAsset]
+	if b == nil || b.amount-b.hold < holdAmount {
+		writeError(w, http.StatusBadRequest, ErrorCodeInsufficientFunds, "insufficient funds in "+holdAsset)
+		return
+	}
+
+	s.nextID++
+	o := &strongholdapi.Order{
+		ID:            fmt.Sprintf("order-%06d", s.nextID),
+		MarketID:      req.MarketID,
+		Type:          req.Type,
+		Side:          req.Side,
+		Price:         req.Price,
+		Size:          req.Size,
+		SizeFilled:    "0",
+		Status:        strongholdapi.StatusOpen,
+		PlacedAt:      time.Now().UTC(),
+		ClientOrderID: req.ClientOrderID,
+	}
+	if req.TimeInForce == strongholdapi.TimeInForceIOC || req.TimeInForce == strongholdapi.TimeInForceFOK {
+		// incoming orders are never matched, so orders that cannot rest on the book are cancelled without a fill
+		o.Status = strongholdapi.StatusCancelled
+	} else {
+		b.hold += holdAmount
+	}
+	s.orders[o.ID] = o
+	writeResult(w, o)
+}
+
+// crossesBook returns true if an order at the price would match the best price on the other side of the book
+func (s *Server) crossesBook(marketID string, side string, price float64) bool {
+	book := s.books[marketID]
+	levels := book.Asks
+	if side == strongholdapi.SideSell {
+		levels = book.Bids
+	}
+	if len(levels) == 0 {
+		return false
+	}
+
+	best, e := strconv.ParseFloat(levels[0].Price, 64)
+	if e != nil {
+		return false
+	}
+	if side == strongholdapi.SideSell {
+		return price <= best
+	}
+	return price >= best
+}
+
+func (s *Server) handleGetOrder(w http.ResponseWriter, orderID string) {
+	o, ok := s.orders[orderID]
+	if !ok {
+		writeError(w, http.StatusNotFound, ErrorCodeOrderNotFound, "no order with id "+orderID)
+		return
+	}
+	writeResult(w, o)
+}
+
+func (s *Server) handleCancelOrder(w http.ResponseWriter, orderID string) {
+	o, ok := s.orders[orderID]
+	if !ok || o.Status != strongholdapi.StatusOpen {
+		writeError(w, http.StatusNotFound, ErrorCodeOrderNotFound, "no open order with id "+orderID)
+		return
+	}
+
+	market := s.markets[o.MarketID]
+	price, _ := strconv.ParseFloat(o.Price, 64)
+	size, _ := strconv.ParseFloat(o.Size, 64)
+	filled, _ := strconv.ParseFloat(o.SizeFilled, 64)
+	if o.Side == strongholdapi.SideBuy {
+		s.balances[market.CounterAssetID].hold -= price * (size - filled)
+	} else {
+		s.balances[market.BaseAssetID].hold -= size - filled
+	}
+	o.Status = strongholdapi.StatusCancelled
+	writeResult(w, nil)
+}
+
+func (s *Server) handleTrades(w http.ResponseWriter, r *http.Request) {
+	startTime, e := intParam(r, "startTime")
+	if e != nil {
+		writeError(w, http.StatusBadRequest, ErrorCodeBadRequest, e.Error())
+		return
+	}
+	endTime, e := intParam(r, "endTime")
+	if e != nil {
+		writeError(w, http.StatusBadRequest, ErrorCodeBadRequest, e.Error())
+		return
+	}
+	limit, e := intParam(r, "limit")
+	if e != nil {
+		writeError(w, http.StatusBadRequest, ErrorCodeBadRequest, e.Error())
+		return
+	}
+	marketID := r.URL.Query().Get("marketId")
+
+	trades := []strongholdapi.Trade{}
+	for _, t := range s.trades {
+		if t.OrderID == "" {
+			continue
+		}
+		if marketID != "" && t.MarketID != marketID {
+			continue
+		}
+		millis := t.ExecutedAt.UnixNano() / int64(time.Millisecond)
+		if startTime > 0 && millis < int64(startTime) {
+			continue
+		}
+		if endTime > 0 && millis >= int64(endTime) {
+			continue
+		}
+		trades = append(trades, t)
+	}
+	sort.SliceStable(trades, func(i int, j int) bool {
+		return trades[i].ExecutedAt.Before(trades[j].ExecutedAt)
+	})
+	if limit > 0 && len(trades) > limit {
+		trades = trades[:limit]
+	}
+	writeResult(w, trades)
+}
+
+func (s *Server) handleDeposit(w http.ResponseWriter, body []byte) {
+	var req strongholdapi.DepositRequest
+	e := json.Unmarshal(body, &req)
+	if e != nil {
+		writeError(w, http.StatusBadRequest, ErrorCodeBadRequest, fmt.Sprintf("invalid deposit request: %s", e))
+		return
+	}
+	if !s.hasAsset(req.AssetID) {
+		writeError(w, http.StatusBadRequest, ErrorCodeBadRequest, "unknown asset: "+req.AssetID)
+		return
+	}
+	if req.PaymentMethod != strongholdapi.PaymentMethodStellar {
+		writeError(w, http.StatusBadRequest, ErrorCodeBadRequest, "unsupported payment method: "+req.PaymentMethod)
+		return
+	}
+
+	writeResult(w, strongholdapi.DepositInstructions{
+		AssetID:       req.AssetID,
+		PaymentMethod: req.PaymentMethod,
+		Address:       DepositAddress,
+		Memo:          AccountID,
+		Fee:           "0",
+	})
+}
+
+func (s *Server) handleWithdrawal(w http.ResponseWriter, body []byte) {
+	var req strongholdapi.WithdrawalRequest
+	e := json.Unmarshal(body, &req)
+	if e != nil {
+		writeError(w, http.StatusBadRequest, ErrorCodeBadRequest, fmt.Sprintf("invalid withdrawal request: %s", e))
+		return
+	}
+
+	var asset *strongholdapi.Asset
+	for i := range s.assets {
+		if s.assets[i].ID == req.AssetID {
+			asset = &s.assets[i]
+		}
+	}
+	if asset == nil {
+		writeError(w, http.StatusBadRequest, ErrorCodeBadRequest, "unknown asset: "+req.AssetID)
+		return
+	}
+	amount, e := strconv.ParseFloat(req.Amount, 64)
+	if e != nil || amount <= 0 {
+		writeError(w, http.StatusBadRequest, ErrorCodeBadRequest, "invalid amount: "+req.Amount)
+		return
+	}
+	if req.Address == "" {
+		writeError(w, http.StatusBadRequest, ErrorCodeBadRequest, "address is required")
+		return
+	}
+	b := s.balances[req.AssetID]
+	if b == nil || b.amount-b.hold < amount {
+		writeError(w, http.StatusBadRequest, ErrorCodeInsufficientFunds, "insufficient funds in "+req.AssetID)
+		return
+	}
+	b.amount -= amount
+
+	s.nextID++
+	withdrawal := strongholdapi.Withdrawal{
+		ID:      fmt.Sprintf("withdrawal-%06d", s.nextID),
+		AssetID: req.AssetID,
+		Amount:  req.Amount,
+		Fee:     asset.WithdrawalFee,
+		Address: req.Address,
+		Status:  "pending",
+	}
+	s.withdrawals = append(s.withdrawals, withdrawal)
+	writeResult(w, withdrawal)
+}
+
+func (s *Server) account() strongholdapi.Account {
+	assetIDs := []string{}
+	for assetID := range s.balances {
+		assetIDs = append(assetIDs, assetID)
+	}
+	sort.Strings(assetIDs)
+
+	balances := []strongholdapi.Balance{}
+	for _, assetID := range assetIDs {
+		b := s.balances[assetID]
+		balances = append(balances, strongholdapi.Balance{
+			AssetID:   assetID,
+			Amount:    formatFloat(b.amount),
+			Available: formatFloat(b.amount - b.hold),
+		})
+	}
+	return strongholdapi.Account{
+		ID:       AccountID,
+		VenueID:  VenueID,
+		Balances: balances,
+	}
+}
+
+func (s *Server) hasAsset(assetID string) bool {
+	for _, a := range s.assets {
+		if a.ID == assetID {
+			return true
+		}
+	}
+	return false
+}
+
+// authenticate verifies the signature headers independently of the client's implementation
+func (s *Server) authenticate(w http.ResponseWriter, r *http.Request, body []byte) bool {
+	if r.Header.Get(strongholdapi.HeaderCredID) != s.key {
+		writeError(w, http.StatusUnauthorized, ErrorCodeUnauthorized, "unknown credential id")
+		return false
+	}
+
+	timestamp := r.Header.Get(strongholdapi.HeaderCredTime)
+	ts, e := strconv.ParseInt(timestamp, 10, 64)
+	if e != nil || time.Since(time.Unix(ts, 0)) > time.Minute {
+		writeError(w, http.StatusUnauthorized, ErrorCodeUnauthorized, "invalid or expired timestamp")
+		return false
+	}
+
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(timestamp + r.Method + r.URL.RequestURI() + string(body)))
+	expected := mac.Sum(nil)
+	actual, e := base64.StdEncoding.DecodeString(r.Header.Get(strongholdapi.HeaderCredSig))
+	if e != nil || !hmac.Equal(expected, actual) {
+		writeError(w, http.StatusUnauthorized, ErrorCodeUnauthorized, "invalid signature")
+		return false
+	}
+	return true
+}
+
+// match checks that the path segments match the pattern, where "*" matches any single segment
+func match(segments []string, pattern ...string) bool {
+	if len(segments) != len(pattern) {
+		return false
+	}
+	for i, p := range pattern {
+		if p != "*" && p != segments[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func intParam(r *http.Request, name string) (int, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return 0, nil
+	}
+	i, e := strconv.Atoi(v)
+	if e != nil {
+		return 0, fmt.Errorf("invalid %s: %s", name, v)
+	}
+	return i, nil
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func writeResult(w http.ResponseWriter, result interface{}) {
+	resultBytes, e := json.Marshal(result)
+	if e != nil {
+		writeError(w, http.StatusInternalServerError, "INTERNAL", e.Error())
+		return
+	}
+	write(w, http.StatusOK, strongholdapi.StrongholdResponse{
+		Success:    true,
+		StatusCode: http.StatusOK,
+		Result:     resultBytes,
+	})
+}
+
+func writeError(w http.ResponseWriter, statusCode int, code string, message string) {
+	if statusCode == http.StatusTooManyRequests {
+		w.Header().Set("Retry-After", "1")
+	}
+	write(w, statusCode, strongholdapi.StrongholdResponse{
+		Success:      false,
+		StatusCode:   statusCode,
+		ErrorCode:    code,
+		ErrorMessage: message,
+	})
+}
+
+func write(w http.ResponseWriter, statusCode int, resp strongholdapi.StrongholdResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,252 @@
+package strongholdtest
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	strongholdapi "github.com/stellar/kelp/support/stronghold-go-api-client"
+)
+
+// StreamPath is the path of the venue stream served by the fake
+const StreamPath = "/" + strongholdapi.APIVersion + "/venues/" + VenueID + "/stream"
+
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// streamConn is a single client connected to the stream, all fields are guarded by the server's mutex
+type streamConn struct {
+	conn          *websocket.Conn
+	writeMutex    *sync.Mutex
+	authenticated bool
+	books         map[string]bool
+	fills         bool
+}
+
+func (c *streamConn) send(msg strongholdapi.StreamMessage) {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+	// write errors surface as read errors in serveStream which drops the connection
+	c.conn.WriteJSON(msg)
+}
+
+// UpdateOrderBook applies the levels to the market's order book and pushes them to the subscribers as an update,
+// a level with a zero size removes the price level
+func (s *Server) UpdateOrderBook(marketID string, bids []strongholdapi.OrderBookItem, asks []strongholdapi.OrderBookItem) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	book := s.books[marketID]
+	book.MarketID = marketID
+	book.Bids = applyLevels(book.Bids, bids, false)
+	book.Asks = applyLevels(book.Asks, asks, true)
+	s.books[marketID] = book
+	s.bookSequences[marketID]++
+
+	for c := range s.streamConns {
+		if c.books[marketID] {
+			c.send(strongholdapi.StreamMessage{
+				Type:     strongholdapi.StreamTypeUpdate,
+				Channel:  strongholdapi.StreamChannelOrderBook,
+				MarketID: marketID,
+				Sequence: s.bookSequences[marketID],
+				Bids:     bids,
+				Asks:     asks,
+			})
+		}
+	}
+}
+
+// SkipStreamSequence advances the sequence of the market's order book without sending an update so that
+// subscribers see a gap on the next update
+func (s *Server) SkipStreamSequence(marketID string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.bookSequences[marketID]++
+}
+
+// StreamConnections returns the number of clients currently connected to the stream
+func (s *Server) StreamConnections() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.streamConns)
+}
+
+// CloseStreams drops all the stream connections, clients are expected to reconnect
+func (s *Server) CloseStreams() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for c := range s.streamConns {
+		c.conn.Close()
+		delete(s.streamConns, c)
+	}
+}
+
+// Close drops the stream connections and shuts down the server
+func (s *Server) Close() {
+	s.CloseStreams()
+	s.Server.Close()
+}
+
+// pushFill sends the account's trade to the subscribers of the fills channel, must be called with the mutex held
+func (s *Server) pushFill(trade strongholdapi.Trade) {
+	for c := range s.streamConns {
+		if c.fills {
+			t := trade
+			c.send(strongholdapi.StreamMessage{
+				Type:    strongholdapi.StreamTypeFill,
+				Channel: strongholdapi.StreamChannelFills,
+				Trade:   &t,
+			})
+		}
+	}
+}
+
+// pushSnapshot sends the market's order book to the subscribers, must be called with the mutex held
+func (s *Server) pushSnapshot(marketID string) {
+	for c := range s.streamConns {
+		if c.books[marketID] {
+			s.sendSnapshot(c, marketID)
+		}
+	}
+}
+
+func (s *Server) sendSnapshot(c *streamConn, marketID string) {
+	book := s.books[marketID]
+	c.send(strongholdapi.StreamMessage{
+		Type:     strongholdapi.StreamTypeSnapshot,
+		Channel:  strongholdapi.StreamChannelOrderBook,
+		MarketID: marketID,
+		Sequence: s.bookSequences[marketID],
+		Bids:     book.Bids,
+		Asks:     book.Asks,
+	})
+}
+
+// serveStream handles a websocket connection to the venue stream until the client disconnects
+func (s *Server) serveStream(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+	s.mutex.Unlock()
+
+	conn, e := upgrader.Upgrade(w, r, nil)
+	if e != nil {
+		// the upgrader already responded with an error
+		return
+	}
+	c := &streamConn{
+		conn:       conn,
+		writeMutex: &sync.Mutex{},
+		books:      map[string]bool{},
+	}
+
+	s.mutex.Lock()
+	s.streamConns[c] = true
+	s.mutex.Unlock()
+	defer func() {
+		s.mutex.Lock()
+		delete(s.streamConns, c)
+		s.mutex.Unlock()
+		conn.Close()
+	}()
+
+	for {
+		var req strongholdapi.StreamRequest
+		e := conn.ReadJSON(&req)
+		if e != nil {
+			return
+		}
+
+		s.mutex.Lock()
+		s.handleStreamRequest(c, &req)
+		s.mutex.Unlock()
+	}
+}
+
+func (s *Server) handleStreamRequest(c *streamConn, req *strongholdapi.StreamRequest) {
+	switch {
+	case req.Op == strongholdapi.StreamOpAuth:
+		if !s.authenticateStream(req) {
+			c.send(strongholdapi.StreamMessage{Type: strongholdapi.StreamTypeError, Message: "invalid credentials"})
+			return
+		}
+		c.authenticated = true
+		c.send(strongholdapi.StreamMessage{Type: strongholdapi.StreamTypeAuthenticated})
+	case req.Op == strongholdapi.StreamOpSubscribe && req.Channel == strongholdapi.StreamChannelOrderBook:
+		if _, ok := s.books[req.MarketID]; !ok {
+			c.send(strongholdapi.StreamMessage{Type: strongholdapi.StreamTypeError, Message: "unknown market: " + req.MarketID})
+			return
+		}
+		c.books[req.MarketID] = true
+		s.sendSnapshot(c, req.MarketID)
+	case req.Op == strongholdapi.StreamOpSubscribe && req.Channel == strongholdapi.StreamChannelFills:
+		if !c.authenticated {
+			c.send(strongholdapi.StreamMessage{Type: strongholdapi.StreamTypeError, Message: "authentication required"})
+			return
+		}
+		c.fills = true
+		c.send(strongholdapi.StreamMessage{
+			Type:      strongholdapi.StreamTypeSubscribed,
+			Channel:   strongholdapi.StreamChannelFills,
+			Timestamp: time.Now().UnixNano() / int64(time.Millisecond),
+		})
+	default:
+		c.send(strongholdapi.StreamMessage{Type: strongholdapi.StreamTypeError, Message: "unsupported request: " + req.Op + " " + req.Channel})
+	}
+}
+
+// authenticateStream verifies the signature of timestamp + "GET" + stream path independently of the client's implementation
+func (s *Server) authenticateStream(req *strongholdapi.StreamRequest) bool {
+	if req.Key != s.key {
+		return false
+	}
+	ts, e := strconv.ParseInt(req.Timestamp, 10, 64)
+	if e != nil || time.Since(time.Unix(ts, 0)) > time.Minute {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(req.Timestamp + "GET" + StreamPath))
+	actual, e := base64.StdEncoding.DecodeString(req.Signature)
+	return e == nil && hmac.Equal(mac.Sum(nil), actual)
+}
+
+// applyLevels returns the side of the book with the levels applied, keeping it sorted
+func applyLevels(side []strongholdapi.OrderBookItem, levels []strongholdapi.OrderBookItem, ascending bool) []strongholdapi.OrderBookItem {
+	byPrice := map[float64]strongholdapi.OrderBookItem{}
+	for _, level := range side {
+		price, _ := strconv.ParseFloat(level.Price, 64)
+		byPrice[price] = level
+	}
+	for _, level := range levels {
+		price, _ := strconv.ParseFloat(level.Price, 64)
+		size, _ := strconv.ParseFloat(level.Size, 64)
+		if size == 0 {
+			delete(byPrice, price)
+		} else {
+			byPrice[price] = level
+		}
+	}
+
+	prices := []float64{}
+	for price := range byPrice {
+		prices = append(prices, price)
+	}
+	sort.Float64s(prices)
+	if !ascending {
+		sort.Sort(sort.Reverse(sort.Float64Slice(prices)))
+	}
+
+	result := []strongholdapi.OrderBookItem{}
+	for _, price := range prices {
+		result = append(result, byPrice[price])
+	}
+	return result
+}
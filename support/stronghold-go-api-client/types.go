@@ -2,480 +2,264 @@ package strongholdapi
 
 import (
 	"encoding/json"
-	"reflect"
-	"strconv"
+	"fmt"
+	"time"
 )
 
+// Order sides and types as understood by the stronghold API
 const (
-	BCHEUR   = "BCHEUR"
-	BCHUSD   = "BCHUSD"
-	BCHXBT   = "BCHXBT"
-	DASHEUR  = "DASHEUR"
-	DASHUSD  = "DASHUSD"
-	DASHXBT  = "DASHXBT"
-	EOSETH   = "EOSETH"
-	EOSEUR   = "EOSEUR"
-	EOSUSD   = "EOSUSD"
-	EOSXBT   = "EOSXBT"
-	GNOETH   = "GNOETH"
-	GNOEUR   = "GNOEUR"
-	GNOUSD   = "GNOUSD"
-	GNOXBT   = "GNOXBT"
-	USDTZUSD = "USDTZUSD"
-	XETCXETH = "XETCXETH"
-	XETCXXBT = "XETCXXBT"
-	XETCZEUR = "XETCZEUR"
-	XETCXUSD = "XETCXUSD"
-	XETHXXBT = "XETHXXBT"
-	XETHZCAD = "XETHZCAD"
-	XETHZEUR = "XETHZEUR"
-	XETHZGBP = "XETHZGBP"
-	XETHZJPY = "XETHZJPY"
-	XETHZUSD = "XETHZUSD"
-	XICNXETH = "XICNXETH"
-	XICNXXBT = "XICNXXBT"
-	XLTCXXBT = "XLTCXXBT"
-	XLTCZEUR = "XLTCZEUR"
-	XLTCZUSD = "XLTCZUSD"
-	XMLNXETH = "XMLNXETH"
-	XMLNXXBT = "XMLNXXBT"
-	XREPXETH = "XREPXETH"
-	XREPXXBT = "XREPXXBT"
-	XREPZEUR = "XREPZEUR"
-	XREPZUSD = "XREPZUSD"
-	XXBTZCAD = "XXBTZCAD"
-	XXBTZEUR = "XXBTZEUR"
-	XXBTZGBP = "XXBTZGBP"
-	XXBTZJPY = "XXBTZJPY"
-	XXBTZUSD = "XXBTZUSD"
-	XXDGXXBT = "XXDGXXBT"
-	XXLMXXBT = "XXLMXXBT"
-	XXLMZEUR = "XXLMZEUR"
-	XXLMZUSD = "XXLMZUSD"
-	XXMRXXBT = "XXMRXXBT"
-	XXMRZEUR = "XXMRZEUR"
-	XXMRZUSD = "XXMRZUSD"
-	XXRPXXBT = "XXRPXXBT"
-	XXRPZCAD = "XXRPZCAD"
-	XXRPZEUR = "XXRPZEUR"
-	XXRPZJPY = "XXRPZJPY"
-	XXRPZUSD = "XXRPZUSD"
-	XZECXXBT = "XZECXXBT"
-	XZECZEUR = "XZECZEUR"
-	XZECZUSD = "XZECZUSD"
+	SideBuy   = "buy"
+	SideSell  = "sell"
+	TypeLimit = "limit"
 )
 
+// time in force values accepted by the stronghold API, orders are good till canceled when it is not set
 const (
-	BUY    = "b"
-	SELL   = "s"
-	MARKET = "m"
-	LIMIT  = "l"
+	TimeInForceGTC = "GTC"
+	TimeInForceIOC = "IOC"
+	TimeInForceFOK = "FOK"
 )
 
-// KrakenResponse wraps the Kraken API JSON response
+// Order statuses as returned by the stronghold API
+const (
+	StatusOpen      = "open"
+	StatusFilled    = "filled"
+	StatusCancelled = "cancelled"
+)
+
+// error codes returned by the stronghold API that callers may want to handle
+const (
+	ErrorCodeRateLimited       = "RATE_LIMITED"
+	ErrorCodeUnauthorized      = "UNAUTHORIZED"
+	ErrorCodeInsufficientFunds = "INSUFFICIENT_FUNDS"
+	ErrorCodeOrderNotFound     = "ORDER_NOT_FOUND"
+	ErrorCodePostOnlyRejected  = "POST_ONLY_REJECTED"
+)
+
+// StrongholdResponse wraps the stronghold API JSON response
 type StrongholdResponse struct {
-	Error  []string    `json:"error"`
-	Result interface{} `json:"result"`
+	Success      bool            `json:"success"`
+	StatusCode   int             `json:"statusCode"`
+	ErrorCode    string          `json:"errorCode"`
+	ErrorMessage string          `json:"errorMessage"`
+	Result       json.RawMessage `json:"result"`
+}
+
+// APIError is returned when the stronghold API responds with success=false or a non-2xx status code
+type APIError struct {
+	StatusCode int
+	Code       string
+	Message    string
+	Method     string
+	Path       string
+	// RetryAfter is the value of the Retry-After header, zero when it was not sent
+	RetryAfter time.Duration
+}
+
+// Error impl.
+func (e *APIError) Error() string {
+	return fmt.Sprintf("stronghold API error on %s %s (statusCode=%d, errorCode=%s): %s", e.Method, e.Path, e.StatusCode, e.Code, e.Message)
+}
+
+// NetworkError is returned when a request could not be sent or its response could not be read
+type NetworkError struct {
+	Method string
+	Path   string
+	Err    error
+}
+
+// Error impl.
+func (e *NetworkError) Error() string {
+	return fmt.Sprintf("network error on %s %s: %s", e.Method, e.Path, e.Err)
 }
 
 // TimeResponse represents the server's time
 type TimeResponse struct {
 	// Unix timestamp
-	Unixtime int64
+	Unixtime int64 `json:"unixtime"`
 	// RFC 1123 time format
-	Rfc1123 string
+	Rfc1123 string `json:"rfc1123"`
 }
 
-// AssetPairsResponse includes asset pair informations
-type AssetPairsResponse struct {
-	BCHEUR   AssetPairInfo
-	BCHUSD   AssetPairInfo
-	BCHXBT   AssetPairInfo
-	DASHEUR  AssetPairInfo
-	DASHUSD  AssetPairInfo
-	DASHXBT  AssetPairInfo
-	EOSETH   AssetPairInfo
-	EOSEUR   AssetPairInfo
-	EOSUSD   AssetPairInfo
-	EOSXBT   AssetPairInfo
-	GNOETH   AssetPairInfo
-	GNOEUR   AssetPairInfo
-	GNOUSD   AssetPairInfo
-	GNOXBT   AssetPairInfo
-	USDTZUSD AssetPairInfo
-	XETCXETH AssetPairInfo
-	XETCXXBT AssetPairInfo
-	XETCZEUR AssetPairInfo
-	XETCXUSD AssetPairInfo
-	XETHXXBT AssetPairInfo
-	XETHZCAD AssetPairInfo
-	XETHZEUR AssetPairInfo
-	XETHZGBP AssetPairInfo
-	XETHZJPY AssetPairInfo
-	XETHZUSD AssetPairInfo
-	XICNXETH AssetPairInfo
-	XICNXXBT AssetPairInfo
-	XLTCXXBT AssetPairInfo
-	XLTCZEUR AssetPairInfo
-	XLTCZUSD AssetPairInfo
-	XMLNXETH AssetPairInfo
-	XMLNXXBT AssetPairInfo
-	XREPXETH AssetPairInfo
-	XREPXXBT AssetPairInfo
-	XREPZEUR AssetPairInfo
-	XREPZUSD AssetPairInfo
-	XXBTZCAD AssetPairInfo
-	XXBTZEUR AssetPairInfo
-	XXBTZGBP AssetPairInfo
-	XXBTZJPY AssetPairInfo
-	XXBTZUSD AssetPairInfo
-	XXDGXXBT AssetPairInfo
-	XXLMXXBT AssetPairInfo
-	XXLMZEUR AssetPairInfo
-	XXLMZUSD AssetPairInfo
-	XXMRXXBT AssetPairInfo
-	XXMRZEUR AssetPairInfo
-	XXMRZUSD AssetPairInfo
-	XXRPXXBT AssetPairInfo
-	XXRPZCAD AssetPairInfo
-	XXRPZEUR AssetPairInfo
-	XXRPZJPY AssetPairInfo
-	XXRPZUSD AssetPairInfo
-	XZECXXBT AssetPairInfo
-	XZECZEUR AssetPairInfo
-	XZECZUSD AssetPairInfo
+// Venue is a trading venue hosted by stronghold
+type Venue struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
 }
 
-// AssetPairInfo represents asset pair information
-type AssetPairInfo struct {
-	// Alternate pair name
-	Altname string `json:"altname"`
-	// Asset class of base component
-	AssetClassBase string `json:"aclass_base"`
-	// Asset id of base component
-	Base string `json:"base"`
-	// Asset class of quote component
-	AssetClassQuote string `json:"aclass_quote"`
-	// Asset id of quote component
-	Quote string `json:"quote"`
-	// Volume lot size
-	Lot string `json:"lot"`
-	// Scaling decimal places for pair
-	PairDecimals int `json:"pair_decimals"`
-	// Scaling decimal places for volume
-	LotDecimals int `json:"lot_decimals"`
-	// Amount to multiply lot volume by to get currency volume
-	LotMultiplier int `json:"lot_multiplier"`
-	// Array of leverage amounts available when buying
-	LeverageBuy []float64 `json:"leverage_buy"`
-	// Array of leverage amounts available when selling
-	LeverageSell []float64 `json:"leverage_sell"`
-	// Fee schedule array in [volume, percent fee] tuples
-	Fees [][]float64 `json:"fees"`
-	// // Maker fee schedule array in [volume, percent fee] tuples (if on maker/taker)
-	FeesMaker [][]float64 `json:"fees_maker"`
-	// // Volume discount currency
-	FeeVolumeCurrency string `json:"fee_volume_currency"`
-	// Margin call level
-	MarginCall int `json:"margin_call"`
-	// Stop-out/Liquidation margin level
-	MarginStop int `json:"margin_stop"`
+// Asset describes an asset listed on a venue
+type Asset struct {
+	ID                string `json:"id"`
+	Precision         int8   `json:"precision"`
+	WithdrawalFee     string `json:"withdrawalFee"`
+	MinimumWithdrawal string `json:"minimumWithdrawal"`
+	MaximumWithdrawal string `json:"maximumWithdrawal"`
 }
 
-// AssetsResponse includes asset informations
-type AssetsResponse struct {
-	BCH  AssetInfo
-	DASH AssetInfo
-	EOS  AssetInfo
-	GNO  AssetInfo
-	KFEE AssetInfo
-	USDT AssetInfo
-	XDAO AssetInfo
-	XETC AssetInfo
-	XETH AssetInfo
-	XICN AssetInfo
-	XLTC AssetInfo
-	XMLN AssetInfo
-	XNMC AssetInfo
-	XREP AssetInfo
-	XXBT AssetInfo
-	XXDG AssetInfo
-	XXLM AssetInfo
-	XXMR AssetInfo
-	XXRP AssetInfo
-	XXVN AssetInfo
-	XZEC AssetInfo
-	ZCAD AssetInfo
-	ZEUR AssetInfo
-	ZGBP AssetInfo
-	ZJPY AssetInfo
-	ZKRW AssetInfo
-	ZUSD AssetInfo
+// Market describes a market listed on a venue
+type Market struct {
+	ID                    string `json:"id"`
+	BaseAssetID           string `json:"baseAssetId"`
+	CounterAssetID        string `json:"counterAssetId"`
+	MinimumPriceIncrement string `json:"minimumPriceIncrement"`
+	MinimumSizeIncrement  string `json:"minimumSizeIncrement"`
+	MinimumSize           string `json:"minimumSize"`
+	MinimumValue          string `json:"minimumValue"`
 }
 
-// AssetInfo represents an asset information
-type AssetInfo struct {
-	// Alternate name
-	Altname string
-	// Asset class
-	AssetClass string `json:"aclass"`
-	// Scaling decimal places for record keeping
-	Decimals int
-	// Scaling decimal places for output display
-	DisplayDecimals int `json:"display_decimals"`
+// OrderBookItem is a single price level in the order book
+type OrderBookItem struct {
+	Price string
+	Size  string
 }
 
-type BalanceResponse struct {
-	BCH  float64 `json:"BCH,string"`
-	DASH float64 `json:"DASH,string"`
-	EOS  float64 `json:"EOS,string"`
-	GNO  float64 `json:"GNO,string"`
-	KFEE float64 `json:"KFEE,string"`
-	USDT float64 `json:"USDT,string"`
-	XDAO float64 `json:"XDAO,string"`
-	XETC float64 `json:"XETC,string"`
-	XETH float64 `json:"XETH,string"`
-	XICN float64 `json:"XICN,string"`
-	XLTC float64 `json:"XLTC,string"`
-	XMLN float64 `json:"XMLN,string"`
-	XNMC float64 `json:"XNMC,string"`
-	XREP float64 `json:"XREP,string"`
-	XXBT float64 `json:"XXBT,string"`
-	XXDG float64 `json:"XXDG,string"`
-	XXLM float64 `json:"XXLM,string"`
-	XXMR float64 `json:"XXMR,string"`
-	XXRP float64 `json:"XXRP,string"`
-	XXVN float64 `json:"XXVN,string"`
-	XZEC float64 `json:"XZEC,string"`
-	ZCAD float64 `json:"ZCAD,string"`
-	ZEUR float64 `json:"ZEUR,string"`
-	ZGBP float64 `json:"ZGBP,string"`
-	ZJPY float64 `json:"ZJPY,string"`
-	ZKRW float64 `json:"ZKRW,string"`
-	ZUSD float64 `json:"ZUSD,string"`
-}
+// UnmarshalJSON takes a json array of the form ["price", "size"] and converts it into an OrderBookItem
+func (o *OrderBookItem) UnmarshalJSON(data []byte) error {
+	var arr []string
+	err := json.Unmarshal(data, &arr)
+	if err != nil {
+		return err
+	}
 
-// TickerResponse includes the requested ticker pairs
-type TickerResponse struct {
-	BCHEUR   PairTickerInfo
-	BCHUSD   PairTickerInfo
-	BCHXBT   PairTickerInfo
-	DASHEUR  PairTickerInfo
-	DASHUSD  PairTickerInfo
-	DASHXBT  PairTickerInfo
-	EOSETH   PairTickerInfo
-	EOSEUR   PairTickerInfo
-	EOSUSD   PairTickerInfo
-	EOSXBT   PairTickerInfo
-	GNOETH   PairTickerInfo
-	GNOEUR   PairTickerInfo
-	GNOUSD   PairTickerInfo
-	GNOXBT   PairTickerInfo
-	USDTZUSD PairTickerInfo
-	XETCXETH PairTickerInfo
-	XETCXXBT PairTickerInfo
-	XETCZEUR PairTickerInfo
-	XETCXUSD PairTickerInfo
-	XETHXXBT PairTickerInfo
-	XETHZCAD PairTickerInfo
-	XETHZEUR PairTickerInfo
-	XETHZGBP PairTickerInfo
-	XETHZJPY PairTickerInfo
-	XETHZUSD PairTickerInfo
-	XICNXETH PairTickerInfo
-	XICNXXBT PairTickerInfo
-	XLTCXXBT PairTickerInfo
-	XLTCZEUR PairTickerInfo
-	XLTCZUSD PairTickerInfo
-	XMLNXETH PairTickerInfo
-	XMLNXXBT PairTickerInfo
-	XREPXETH PairTickerInfo
-	XREPXXBT PairTickerInfo
-	XREPZEUR PairTickerInfo
-	XREPZUSD PairTickerInfo
-	XXBTZCAD PairTickerInfo
-	XXBTZEUR PairTickerInfo
-	XXBTZGBP PairTickerInfo
-	XXBTZJPY PairTickerInfo
-	XXBTZUSD PairTickerInfo
-	XXDGXXBT PairTickerInfo
-	XXLMXXBT PairTickerInfo
-	XXLMZEUR PairTickerInfo
-	XXLMZUSD PairTickerInfo
-	XXMRXXBT PairTickerInfo
-	XXMRZEUR PairTickerInfo
-	XXMRZUSD PairTickerInfo
-	XXRPXXBT PairTickerInfo
-	XXRPZCAD PairTickerInfo
-	XXRPZEUR PairTickerInfo
-	XXRPZJPY PairTickerInfo
-	XXRPZUSD PairTickerInfo
-	XZECXXBT PairTickerInfo
-	XZECZEUR PairTickerInfo
-	XZECZUSD PairTickerInfo
+	if len(arr) != 2 {
+		return fmt.Errorf("expected order book item to have 2 elements, found %d: %s", len(arr), string(data))
+	}
+	o.Price = arr[0]
+	o.Size = arr[1]
+	return nil
 }
 
-// GetPairTickerInfo is a helper method that returns given `pair`'s `PairTickerInfo`
-func (v *TickerResponse) GetPairTickerInfo(pair string) PairTickerInfo {
-	r := reflect.ValueOf(v)
-	f := reflect.Indirect(r).FieldByName(pair)
-
-	return f.Interface().(PairTickerInfo)
+// MarshalJSON converts an OrderBookItem into the json array of the form ["price", "size"]
+func (o OrderBookItem) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]string{o.Price, o.Size})
 }
 
-// PairTickerInfo represents ticker information for a pair
-type PairTickerInfo struct {
-	// Ask array(<price>, <whole lot volume>, <lot volume>)
-	Ask []string `json:"a"`
-	// Bid array(<price>, <whole lot volume>, <lot volume>)
-	Bid []string `json:"b"`
-	// Last trade closed array(<price>, <lot volume>)
-	Close []string `json:"c"`
-	// Volume array(<today>, <last 24 hours>)
-	Volume []string `json:"v"`
-	// Volume weighted average price array(<today>, <last 24 hours>)
-	VolumeAveragePrice []string `json:"p"`
-	// Number of trades array(<today>, <last 24 hours>)
-	Trades []int `json:"t"`
-	// Low array(<today>, <last 24 hours>)
-	Low []string `json:"l"`
-	// High array(<today>, <last 24 hours>)
-	High []string `json:"h"`
-	// Today's opening price
-	OpeningPrice float64 `json:"o,string"`
+// OrderBook contains top asks and bids, asks are sorted ascending and bids are sorted descending by price
+type OrderBook struct {
+	MarketID string          `json:"marketId"`
+	Asks     []OrderBookItem `json:"asks"`
+	Bids     []OrderBookItem `json:"bids"`
 }
 
-// TradesResponse represents a list of the last trades
-type TradesResponse struct {
-	Last   int64
-	Trades []TradeInfo
+// Balance is the balance of a single asset held in an account
+type Balance struct {
+	AssetID   string `json:"assetId"`
+	Amount    string `json:"amount"`
+	Available string `json:"available"`
 }
 
-// TradeInfo represents a trades information
-type TradeInfo struct {
-	Price         string
-	PriceFloat    float64
-	Volume        string
-	VolumeFloat   float64
-	Time          int64
-	Buy           bool
-	Sell          bool
-	Market        bool
-	Limit         bool
-	Miscellaneous string
+// Account is a trading account on a venue
+type Account struct {
+	ID       string    `json:"id"`
+	VenueID  string    `json:"venueId"`
+	Balances []Balance `json:"balances"`
 }
 
-// OrderTypes for AddOrder
-const (
-	OTMarket              = "market"
-	OTLimit               = "limit"                  // (price = limit price)
-	OTStopLoss            = "stop-loss"              // (price = stop loss price)
-	OTTakeProfi           = "take-profit"            // (price = take profit price)
-	OTStopLossProfit      = "stop-loss-profit"       // (price = stop loss price, price2 = take profit price)
-	OTStopLossProfitLimit = "stop-loss-profit-limit" // (price = stop loss price, price2 = take profit price)
-	OTStopLossLimit       = "stop-loss-limit"        // (price = stop loss trigger price, price2 = triggered limit price)
-	OTTakeProfitLimit     = "take-profit-limit"      // (price = take profit trigger price, price2 = triggered limit price)
-	OTTrailingStop        = "trailing-stop"          // (price = trailing stop offset)
-	OTTrailingStopLimit   = "trailing-stop-limit"    // (price = trailing stop offset, price2 = triggered limit offset)
-	OTStopLossAndLimit    = "stop-loss-and-limit"    // (price = stop loss price, price2 = limit price)
-	OTSettlePosition      = "settle-position"
-)
-
-// OrderDescription represents an orders description
-type OrderDescription struct {
-	AssetPair      string `json:"pair"`
-	Close          string `json:"close"`
-	Leverage       string `json:"leverage"`
-	Order          string `json:"order"`
-	OrderType      string `json:"ordertype"`
-	PrimaryPrice   string `json:"price"`
-	SecondaryPrice string `json:"price2"`
-	Type           string `json:"type"`
+// OrderRequest is the body sent when placing an order
+type OrderRequest struct {
+	MarketID string `json:"marketId"`
+	Type     string `json:"type"`
+	Side     string `json:"side"`
+	Price    string `json:"price"`
+	Size     string `json:"size"`
+	// the fields below are optional
+	TimeInForce   string `json:"timeInForce,omitempty"`
+	PostOnly      bool   `json:"postOnly,omitempty"`
+	ClientOrderID string `json:"clientOrderId,omitempty"`
 }
 
-// Order represents a single order
+// Order represents a single order on the venue
 type Order struct {
-	TransactionID  string           `json:"-"`
-	ReferenceID    string           `json:"refid"`
-	UserRef        int              `json:"userref"`
-	Status         string           `json:"status"`
-	OpenTime       float64          `json:"opentm"`
-	StartTime      float64          `json:"starttm"`
-	ExpireTime     float64          `json:"expiretm"`
-	Description    OrderDescription `json:"descr"`
-	Volume         string           `json:"vol"`
-	VolumeExecuted float64          `json:"vol_exec,string"`
-	Cost           float64          `json:"cost,string"`
-	Fee            float64          `json:"fee,string"`
-	Price          float64          `json:"price,string"`
-	StopPrice      float64          `json:"stopprice.string"`
-	LimitPrice     float64          `json:"limitprice,string"`
-	Misc           string           `json:"misc"`
-	OrderFlags     string           `json:"oflags"`
-	CloseTime      float64          `json:"closetm"`
-	Reason         string           `json:"reason"`
+	ID         string    `json:"id"`
+	MarketID   string    `json:"marketId"`
+	Type       string    `json:"type"`
+	Side       string    `json:"side"`
+	Price      string    `json:"price"`
+	Size       string    `json:"size"`
+	SizeFilled string    `json:"sizeFilled"`
+	Status     string    `json:"status"`
+	PlacedAt   time.Time `json:"placedAt"`
+	// ClientOrderID is only set when the order was placed with one
+	ClientOrderID string `json:"clientOrderId,omitempty"`
 }
 
-// ClosedOrdersResponse represents a list of closed orders, indexed by id
-type ClosedOrdersResponse struct {
-	Closed map[string]Order `json:"closed"`
-	Count  int              `json:"count"`
+// Trade represents an executed trade, OrderID, Fee and FeeAssetID are only populated for the account's own trades
+type Trade struct {
+	ID         string    `json:"id"`
+	OrderID    string    `json:"orderId,omitempty"`
+	MarketID   string    `json:"marketId"`
+	Side       string    `json:"side"`
+	Price      string    `json:"price"`
+	Size       string    `json:"size"`
+	Fee        string    `json:"fee,omitempty"`
+	FeeAssetID string    `json:"feeAssetId,omitempty"`
+	ExecutedAt time.Time `json:"executedAt"`
 }
 
-// OrderBookItem is a piece of information about an order.
-type OrderBookItem struct {
-	Price  float64
-	Amount float64
-	Ts     int64
+// TradesParams are the optional filters used when listing the account's trades
+type TradesParams struct {
+	MarketID string
+	// StartTime and EndTime are unix millis, 0 means unbounded
+	StartTime int64
+	EndTime   int64
+	// Limit of 0 uses the server's default page size
+	Limit int
 }
 
-// UnmarshalJSON takes a json array from kraken and converts it into an OrderBookItem.
-func (o *OrderBookItem) UnmarshalJSON(data []byte) error {
-	tmp_struct := struct {
-		price  string
-		amount string
-		ts     int64
-	}{}
-	tmp_arr := []interface{}{&tmp_struct.price, &tmp_struct.amount, &tmp_struct.ts}
-	err := json.Unmarshal(data, &tmp_arr)
-	if err != nil {
-		return err
-	}
-
-	o.Price, err = strconv.ParseFloat(tmp_struct.price, 64)
-	if err != nil {
-		return err
-	}
-	o.Amount, err = strconv.ParseFloat(tmp_struct.amount, 64)
-	if err != nil {
-		return err
-	}
-	o.Ts = tmp_struct.ts
-	return nil
+// DepositRequest is the body sent when requesting deposit instructions
+type DepositRequest struct {
+	AssetID       string `json:"assetId"`
+	PaymentMethod string `json:"paymentMethod"`
 }
 
-// DepthResponse is a response from kraken to Depth request.
-type DepthResponse map[string]OrderBook
-
-// OrderBook contains top asks and bids.
-type OrderBook struct {
-	Asks []OrderBookItem
-	Bids []OrderBookItem
+// DepositInstructions tells you where to send funds for a deposit
+type DepositInstructions struct {
+	AssetID       string     `json:"assetId"`
+	PaymentMethod string     `json:"paymentMethod"`
+	Address       string     `json:"address"`
+	Memo          string     `json:"memo,omitempty"`
+	Fee           string     `json:"fee"`
+	ExpiresAt     *time.Time `json:"expiresAt,omitempty"`
 }
 
-type OpenOrdersResponse struct {
-	Open  map[string]Order `json:"open"`
-	Count int              `json:"count"`
+// WithdrawalRequest is the body sent when requesting a withdrawal
+type WithdrawalRequest struct {
+	AssetID       string `json:"assetId"`
+	Amount        string `json:"amount"`
+	PaymentMethod string `json:"paymentMethod"`
+	Address       string `json:"address"`
 }
 
-type AddOrderResponse struct {
-	Description    OrderDescription `json:"descr"`
-	TransactionIds []string         `json:"txid"`
+// Withdrawal is the result of a withdrawal request
+type Withdrawal struct {
+	ID      string `json:"id"`
+	AssetID string `json:"assetId"`
+	Amount  string `json:"amount"`
+	Fee     string `json:"fee"`
+	Address string `json:"address"`
+	Status  string `json:"status"`
 }
 
-type CancelOrderResponse struct {
-	Count   int  `json:"count"`
-	Pending bool `json:"pending"`
+// StreamRequest is a message sent to the venue stream
+type StreamRequest struct {
+	Op        string `json:"op"`
+	Channel   string `json:"channel,omitempty"`
+	MarketID  string `json:"marketId,omitempty"`
+	Key       string `json:"key,omitempty"`
+	Timestamp string `json:"timestamp,omitempty"`
+	Signature string `json:"signature,omitempty"`
 }
 
-type QueryOrdersResponse map[string]Order
+// StreamMessage is a message received from the venue stream, only the fields relevant to the Type are populated
+type StreamMessage struct {
+	Type     string          `json:"type"`
+	Channel  string          `json:"channel,omitempty"`
+	MarketID string          `json:"marketId,omitempty"`
+	Sequence int64           `json:"sequence,omitempty"`
+	Bids     []OrderBookItem `json:"bids,omitempty"`
+	Asks     []OrderBookItem `json:"asks,omitempty"`
+	Trade    *Trade          `json:"trade,omitempty"`
+	// Timestamp is the server time in unix millis at which a subscription became active
+	Timestamp int64  `json:"timestamp,omitempty"`
+	Message   string `json:"message,omitempty"`
+}
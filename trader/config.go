@@ -2,6 +2,7 @@ package trader
 
 import (
 	"fmt"
+	"regexp"
 
 	"github.com/stellar/go/clients/horizon"
 	"github.com/stellar/kelp/support/utils"
@@ -10,6 +11,8 @@ import (
 // XLM is a constant for XLM
 const XLM = "XLM"
 
+var clientOrderIDPrefixRegex = regexp.MustCompile("^[A-Za-z0-9_-]*$")
+
 // FeeConfig represents input data for how to deal with network fees
 type FeeConfig struct {
 	CapacityTrigger float64 `valid:"-" toml:"CAPACITY_TRIGGER"`   // trigger when "ledger_capacity_usage" in /fee_stats is >= this value
@@ -39,6 +42,8 @@ type BotConfig struct {
 	MinCentralizedBaseVolumeDeprecated *float64 `valid:"-" toml:"MIN_CENTRALIZED_BASE_VOLUME" deprecated:"true"`
 	CentralizedMinBaseVolumeOverride   *float64 `valid:"-" toml:"CENTRALIZED_MIN_BASE_VOLUME_OVERRIDE"`
 	CentralizedMinQuoteVolumeOverride  *float64 `valid:"-" toml:"CENTRALIZED_MIN_QUOTE_VOLUME_OVERRIDE"`
+	CentralizedSubmitConcurrency       int      `valid:"-" toml:"CENTRALIZED_SUBMIT_CONCURRENCY"`
+	CentralizedClientOrderIDPrefix     string   `valid:"-" toml:"CENTRALIZED_CLIENT_ORDER_ID_PREFIX"`
 	AlertType                          string   `valid:"-" toml:"ALERT_TYPE"`
 	AlertAPIKey                        string   `valid:"-" toml:"ALERT_API_KEY"`
 	MonitoringPort                     uint16   `valid:"-" toml:"MONITORING_PORT"`
@@ -48,6 +53,7 @@ type BotConfig struct {
 	GoogleClientSecret                 string   `valid:"-" toml:"GOOGLE_CLIENT_SECRET"`
 	AcceptableEmails                   string   `valid:"-" toml:"ACCEPTABLE_GOOGLE_EMAILS"`
 	TradingExchange                    string   `valid:"-" toml:"TRADING_EXCHANGE"`
+	CcxtRestURL                        string   `valid:"-" toml:"CCXT_REST_URL"`
 	ExchangeAPIKeys                    []struct {
 		Key    string `valid:"-" toml:"KEY"`
 		Secret string `valid:"-" toml:"SECRET"`
@@ -121,6 +127,11 @@ func (b *BotConfig) IsTradingSdex() bool {
 func (b *BotConfig) Init() error {
 	b.isTradingSdex = b.TradingExchange == "" || b.TradingExchange == "sdex"
 
+	// exchanges limit the length of client order IDs and the characters in them, the bot appends up to 13 characters
+	if len(b.CentralizedClientOrderIDPrefix) > 16 || !clientOrderIDPrefixRegex.MatchString(b.CentralizedClientOrderIDPrefix) {
+		return fmt.Errorf("invalid CENTRALIZED_CLIENT_ORDER_ID_PREFIX '%s', it can have up to 16 letters, digits, '-' or '_'", b.CentralizedClientOrderIDPrefix)
+	}
+
 	if b.AssetCodeA == b.AssetCodeB && b.IssuerA == b.IssuerB {
 		return fmt.Errorf("error: both assets cannot be the same '%s:%s'", b.AssetCodeA, b.IssuerA)
 	}